@@ -0,0 +1,349 @@
+// Package acp implements the Agent Client Protocol, with which editors
+// such as Zed and JetBrains IDEs drive a coding agent: JSON-RPC 2.0,
+// one message per line, over stdin and stdout (apipod-cli --acp).
+//
+// The editor calls initialize, session/new, session/prompt and sends
+// session/cancel; the agent streams session/update notifications and
+// asks session/request_permission before tool calls that need approval.
+package acp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/conversation"
+)
+
+// ProtocolVersion is the ACP version spoken.
+const ProtocolVersion = 1
+
+// maxLine bounds a single message.
+const maxLine = 16 << 20
+
+// JSON-RPC error codes.
+const (
+	codeParse         = -32700
+	codeNoMethod      = -32601
+	codeInvalidParams = -32602
+	codeFailed        = -32603
+	codeAuthRequired  = -32000
+)
+
+// Run serves ACP on stdin and stdout until stdin closes. Everything the
+// session prints for the terminal goes to stderr instead, keeping stdout
+// for the protocol.
+func Run(cfg *config.Config) error {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = out }()
+	return New(cfg).Serve(os.Stdin, out)
+}
+
+// Agent answers one editor.
+type Agent struct {
+	cfg *config.Config
+	out io.Writer
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	nextID      int
+	nextSession int
+	sessions    map[string]*session
+	// pending are the agent's own requests waiting for a response.
+	pending map[int]chan *message
+}
+
+// New returns an agent creating its sessions from cfg.
+func New(cfg *config.Config) *Agent {
+	return &Agent{cfg: cfg, sessions: make(map[string]*session), pending: make(map[int]chan *message)}
+}
+
+// message is any JSON-RPC message: a request, a notification or a
+// response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads messages from in and writes to out until in ends, then
+// cancels the running turns and closes the sessions.
+func (a *Agent) Serve(in io.Reader, out io.Writer) error {
+	a.out = out
+	defer a.closeAll()
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), maxLine)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			a.write(map[string]interface{}{"jsonrpc": "2.0", "id": nil, "error": rpcError{Code: codeParse, Message: err.Error()}})
+			continue
+		}
+		if msg.Method == "" {
+			a.deliver(&msg)
+			continue
+		}
+		// A prompt runs until its turn ends, and both it and a new session,
+		// which may ask whether to trust the project's hooks, can wait for
+		// permission responses this loop has to keep reading.
+		if msg.Method == "session/prompt" || msg.Method == "session/new" {
+			go a.handle(&msg)
+			continue
+		}
+		a.handle(&msg)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	return nil
+}
+
+func (a *Agent) write(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	a.out.Write(append(data, '\n'))
+}
+
+func (a *Agent) reply(id json.RawMessage, result interface{}, rerr *rpcError) {
+	if len(id) == 0 {
+		return
+	}
+	msg := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if rerr != nil {
+		msg["error"] = rerr
+	} else {
+		msg["result"] = result
+	}
+	a.write(msg)
+}
+
+func (a *Agent) notify(method string, params interface{}) {
+	a.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// call sends a request to the editor and waits for its response; the
+// response is nil if cancel is closed first.
+func (a *Agent) call(method string, params interface{}, cancel <-chan struct{}) *message {
+	a.mu.Lock()
+	a.nextID++
+	id := a.nextID
+	ch := make(chan *message, 1)
+	a.pending[id] = ch
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, id)
+		a.mu.Unlock()
+	}()
+
+	a.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	select {
+	case resp := <-ch:
+		return resp
+	case <-cancel:
+		return nil
+	}
+}
+
+// deliver hands a response to the call waiting for it.
+func (a *Agent) deliver(msg *message) {
+	var id int
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+	a.mu.Lock()
+	ch := a.pending[id]
+	a.mu.Unlock()
+	if ch != nil {
+		ch <- msg
+	}
+}
+
+func (a *Agent) handle(msg *message) {
+	switch msg.Method {
+	case "initialize":
+		a.reply(msg.ID, map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"agentCapabilities": map[string]interface{}{
+				"loadSession": false,
+				"promptCapabilities": map[string]bool{
+					"image":           false,
+					"audio":           false,
+					"embeddedContext": true,
+				},
+			},
+			"authMethods": []interface{}{},
+		}, nil)
+	case "authenticate":
+		a.reply(msg.ID, map[string]interface{}{}, nil)
+	case "session/new":
+		var p struct {
+			Cwd string `json:"cwd"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			a.reply(msg.ID, nil, &rpcError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		id, rerr := a.newSession(p.Cwd)
+		if rerr != nil {
+			a.reply(msg.ID, nil, rerr)
+			return
+		}
+		a.reply(msg.ID, map[string]string{"sessionId": id}, nil)
+	case "session/prompt":
+		var p struct {
+			SessionID string         `json:"sessionId"`
+			Prompt    []contentBlock `json:"prompt"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			a.reply(msg.ID, nil, &rpcError{Code: codeInvalidParams, Message: err.Error()})
+			return
+		}
+		sess := a.session(p.SessionID)
+		if sess == nil {
+			a.reply(msg.ID, nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown session %q", p.SessionID)})
+			return
+		}
+		stop, err := sess.prompt(promptText(p.Prompt, sess.s.WorkDir()))
+		if err != nil {
+			a.reply(msg.ID, nil, &rpcError{Code: codeFailed, Message: err.Error()})
+			return
+		}
+		a.reply(msg.ID, map[string]string{"stopReason": stop}, nil)
+	case "session/cancel":
+		var p struct {
+			SessionID string `json:"sessionId"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		if sess := a.session(p.SessionID); sess != nil {
+			sess.cancel()
+		}
+	default:
+		a.reply(msg.ID, nil, &rpcError{Code: codeNoMethod, Message: fmt.Sprintf("unknown method: %s", msg.Method)})
+	}
+}
+
+func (a *Agent) session(id string) *session {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sessions[id]
+}
+
+// newSession connects a session for cwd the way the CLI does, with the
+// review queue off since reviewing needs the terminal.
+func (a *Agent) newSession(cwd string) (string, *rpcError) {
+	if !filepath.IsAbs(cwd) {
+		return "", &rpcError{Code: codeInvalidParams, Message: "cwd must be an absolute path"}
+	}
+	if info, err := os.Stat(cwd); err != nil || !info.IsDir() {
+		return "", &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("cwd %s is not a directory", cwd)}
+	}
+	if a.cfg.APIKey == "" {
+		return "", &rpcError{Code: codeAuthRequired, Message: "not logged in; run apipod-cli login"}
+	}
+	c, err := client.NewWithOptions(a.cfg.BaseURL, a.cfg.APIKey, a.cfg.ClientOptions())
+	if err != nil {
+		return "", &rpcError{Code: codeFailed, Message: err.Error()}
+	}
+
+	a.mu.Lock()
+	a.nextSession++
+	id := fmt.Sprintf("sess_%d", a.nextSession)
+	a.mu.Unlock()
+
+	sess := &session{id: id, agent: a, s: conversation.NewSession(c, a.cfg.Model, cwd), done: make(chan struct{})}
+	sess.s.SetApprover(sess.approve)
+	sess.s.SetListener(sess)
+	sess.s.SetSteerer(sess)
+	if err := sess.s.ApplyConfig(a.cfg); err != nil {
+		sess.s.Close()
+		return "", &rpcError{Code: codeFailed, Message: err.Error()}
+	}
+	sess.s.SetReviewChanges(false)
+
+	a.mu.Lock()
+	a.sessions[id] = sess
+	a.mu.Unlock()
+	return id, nil
+}
+
+func (a *Agent) closeAll() {
+	a.mu.Lock()
+	sessions := a.sessions
+	a.sessions = make(map[string]*session)
+	a.mu.Unlock()
+	for _, sess := range sessions {
+		sess.cancel()
+		sess.close()
+	}
+}
+
+// contentBlock is a piece of a prompt: text, a link to a resource or a
+// resource embedded with its content.
+type contentBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	Resource *struct {
+		URI  string `json:"uri"`
+		Text string `json:"text,omitempty"`
+	} `json:"resource,omitempty"`
+}
+
+// promptText turns a prompt into the text sent to the session. Linked
+// files in the project become @mentions, so they are attached the way
+// typed mentions are; embedded resources are quoted.
+func promptText(blocks []contentBlock, workDir string) string {
+	var parts []string
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, b.Text)
+		case "resource_link":
+			parts = append(parts, mention(b.URI, workDir))
+		case "resource":
+			if b.Resource == nil {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("<context uri=%q>\n%s\n</context>", b.Resource.URI, b.Resource.Text))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func mention(uri, workDir string) string {
+	path := strings.TrimPrefix(uri, "file://")
+	if path == uri {
+		return uri
+	}
+	if rel, err := filepath.Rel(workDir, path); err == nil && filepath.IsLocal(rel) && !strings.ContainsAny(rel, " \t") {
+		return "@" + filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,258 @@
+package acp
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/rpay/apipod-cli/internal/conversation"
+)
+
+// cancelMessage steers the model once the editor cancels a prompt; the
+// tool calls it has left are skipped.
+const cancelMessage = "The user cancelled this request. Stop now: do not call any more tools and reply with one short sentence."
+
+// session is an agent session and the prompt it may be running.
+type session struct {
+	id    string
+	agent *Agent
+	s     *conversation.Session
+
+	mu        sync.Mutex
+	running   bool
+	cancelled bool
+	steered   bool
+	// done is closed to abandon the permission request waiting.
+	done chan struct{}
+	// toolCall is the latest tool call, which an approval is about.
+	toolCall map[string]interface{}
+}
+
+// prompt runs a turn and returns its stop reason.
+func (sess *session) prompt(text string) (string, error) {
+	sess.mu.Lock()
+	if sess.running {
+		sess.mu.Unlock()
+		return "", fmt.Errorf("session %s is already running a prompt", sess.id)
+	}
+	sess.running = true
+	sess.cancelled, sess.steered = false, false
+	sess.done = make(chan struct{})
+	sess.mu.Unlock()
+
+	err := sess.s.SendMessage(text)
+
+	sess.mu.Lock()
+	sess.running = false
+	cancelled := sess.cancelled
+	sess.mu.Unlock()
+	switch {
+	case cancelled:
+		return "cancelled", nil
+	case err != nil:
+		return "", err
+	case sess.s.TurnLimit() != "":
+		return "max_turn_requests", nil
+	}
+	return "end_turn", nil
+}
+
+// cancel abandons the waiting permission request, stops the running
+// command and steers the model to a stop.
+func (sess *session) cancel() {
+	sess.mu.Lock()
+	if !sess.running || sess.cancelled {
+		sess.mu.Unlock()
+		return
+	}
+	sess.cancelled = true
+	close(sess.done)
+	sess.mu.Unlock()
+	sess.s.Interrupt()
+}
+
+func (sess *session) close() {
+	sess.s.Close()
+}
+
+// Steering and TakeSteering make the session a conversation.Steerer that
+// has instructions once the prompt is cancelled.
+func (sess *session) Steering() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.cancelled
+}
+
+func (sess *session) TakeSteering() []string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if !sess.cancelled || sess.steered {
+		return nil
+	}
+	sess.steered = true
+	return []string{cancelMessage}
+}
+
+func (sess *session) update(update map[string]interface{}) {
+	sess.agent.notify("session/update", map[string]interface{}{"sessionId": sess.id, "update": update})
+}
+
+func (sess *session) Text(text string) {
+	sess.update(map[string]interface{}{
+		"sessionUpdate": "agent_message_chunk",
+		"content":       map[string]string{"type": "text", "text": text},
+	})
+}
+
+func (sess *session) Thinking(text string) {
+	sess.update(map[string]interface{}{
+		"sessionUpdate": "agent_thought_chunk",
+		"content":       map[string]string{"type": "text", "text": text},
+	})
+}
+
+func (sess *session) ToolCall(id, name string, input map[string]interface{}) {
+	call := map[string]interface{}{
+		"toolCallId": id,
+		"title":      toolTitle(name, input),
+		"kind":       toolKind(name),
+		"status":     "pending",
+		"rawInput":   input,
+	}
+	if path := inputPath(input, sess.s.WorkDir()); path != "" {
+		call["locations"] = []map[string]string{{"path": path}}
+		if diff := toolDiff(name, path, input); diff != nil {
+			call["content"] = []interface{}{diff}
+		}
+	}
+	sess.mu.Lock()
+	sess.toolCall = call
+	sess.mu.Unlock()
+	update := map[string]interface{}{"sessionUpdate": "tool_call"}
+	for k, v := range call {
+		update[k] = v
+	}
+	sess.update(update)
+}
+
+func (sess *session) ToolResult(id, name, content string, isError, denied bool) {
+	status := "completed"
+	if isError || denied {
+		status = "failed"
+	}
+	sess.update(map[string]interface{}{
+		"sessionUpdate": "tool_call_update",
+		"toolCallId":    id,
+		"status":        status,
+		"content": []interface{}{map[string]interface{}{
+			"type":    "content",
+			"content": map[string]string{"type": "text", "text": content},
+		}},
+	})
+}
+
+// approve asks the editor with session/request_permission. Approvals
+// that are not about the latest tool call, such as trusting the
+// project's hooks, are asked about a call of their own.
+func (sess *session) approve(a conversation.Approval) bool {
+	sess.mu.Lock()
+	call, done := sess.toolCall, sess.done
+	sess.mu.Unlock()
+	own := a.Tool == "" || call == nil
+	if own {
+		call = map[string]interface{}{
+			"toolCallId": fmt.Sprintf("approval_%s", sess.id),
+			"title":      a.Question,
+			"kind":       "other",
+			"status":     "pending",
+			"rawInput":   a.Input,
+		}
+	}
+	resp := sess.agent.call("session/request_permission", map[string]interface{}{
+		"sessionId": sess.id,
+		"toolCall":  call,
+		"options": []map[string]string{
+			{"optionId": "allow", "name": "Allow", "kind": "allow_once"},
+			{"optionId": "reject", "name": "Reject", "kind": "reject_once"},
+		},
+	}, done)
+	if resp == nil || resp.Error != nil {
+		return false
+	}
+	var result struct {
+		Outcome struct {
+			Outcome  string `json:"outcome"`
+			OptionID string `json:"optionId"`
+		} `json:"outcome"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return false
+	}
+	allowed := result.Outcome.Outcome == "selected" && result.Outcome.OptionID == "allow"
+	if allowed && !own {
+		sess.update(map[string]interface{}{
+			"sessionUpdate": "tool_call_update",
+			"toolCallId":    call["toolCallId"],
+			"status":        "in_progress",
+		})
+	}
+	return allowed
+}
+
+// toolKind is the ACP kind editors pick an icon by.
+func toolKind(name string) string {
+	switch name {
+	case "Read", "Stat":
+		return "read"
+	case "Write", "Edit", "MultiEdit", "EditLines", "ApplyPatch":
+		return "edit"
+	case "Glob", "Grep", "SemanticSearch":
+		return "search"
+	case "Bash":
+		return "execute"
+	case "HttpRequest", "ApiDiff", "WebSocket", "Grpc", "GitHost":
+		return "fetch"
+	default:
+		return "other"
+	}
+}
+
+// toolTitle names the call after its main input.
+func toolTitle(name string, input map[string]interface{}) string {
+	for _, key := range []string{"command", "file_path", "path", "pattern", "query", "url"} {
+		if v, _ := input[key].(string); v != "" {
+			if len(v) > 80 {
+				v = v[:77] + "..."
+			}
+			return name + ": " + v
+		}
+	}
+	return name
+}
+
+// inputPath is the absolute path of the file a call is about, if any.
+func inputPath(input map[string]interface{}, workDir string) string {
+	path, _ := input["file_path"].(string)
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	return path
+}
+
+// toolDiff shows an Edit or Write as a diff the editor can render.
+func toolDiff(name, path string, input map[string]interface{}) map[string]interface{} {
+	switch name {
+	case "Edit":
+		oldText, _ := input["old_string"].(string)
+		newText, _ := input["new_string"].(string)
+		return map[string]interface{}{"type": "diff", "path": path, "oldText": oldText, "newText": newText}
+	case "Write":
+		content, _ := input["content"].(string)
+		return map[string]interface{}{"type": "diff", "path": path, "oldText": nil, "newText": content}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package audit writes an append-only JSONL trail of what a session did:
+// prompts, tool calls, hashes of their results, approval decisions and API
+// usage. Results are stored as hashes so the log can be kept without
+// holding file contents or command output.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the size at which a log is rotated.
+	DefaultMaxSize = 10 << 20
+	// DefaultRetention is how long logs are kept.
+	DefaultRetention = 90 * 24 * time.Hour
+)
+
+// Entry is one line of an audit log. Seq numbers the entries of a session
+// across rotated files, so a gap shows that lines are missing.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Seq     int       `json:"seq"`
+	Session string    `json:"session"`
+	Type    string    `json:"type"`
+
+	User    string `json:"user,omitempty"`
+	Model   string `json:"model,omitempty"`
+	WorkDir string `json:"work_dir,omitempty"`
+
+	Prompt string `json:"prompt,omitempty"`
+	// Resumed is the saved session this one continues.
+	Resumed string `json:"resumed,omitempty"`
+
+	Tool      string                 `json:"tool,omitempty"`
+	ToolUseID string                 `json:"tool_use_id,omitempty"`
+	Input     map[string]interface{} `json:"input,omitempty"`
+
+	// Decision is "allowed" or "denied" for approvals, and "error",
+	// "denied" or "ok" for tool results.
+	Decision   string `json:"decision,omitempty"`
+	ResultHash string `json:"result_sha256,omitempty"`
+	ResultSize int    `json:"result_size,omitempty"`
+
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+}
+
+// Logger appends entries to a session's log, rotating it when it grows
+// past MaxSize.
+type Logger struct {
+	mu      sync.Mutex
+	dir     string
+	session string
+	maxSize int64
+	f       *os.File
+	size    int64
+	seq     int
+	part    int
+}
+
+// Open starts the log of session in dir and removes logs older than
+// retention. A zero maxSize or retention uses the default.
+func Open(dir, session string, maxSize int64, retention time.Duration) (*Logger, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+	prune(dir, retention)
+	l := &Logger{dir: dir, session: session, maxSize: maxSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Logger) path() string {
+	name := l.session
+	if l.part > 0 {
+		name += fmt.Sprintf(".%d", l.part)
+	}
+	return filepath.Join(l.dir, name+".jsonl")
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// Path is the file currently written.
+func (l *Logger) Path() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.path()
+}
+
+func (l *Logger) write(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+	l.seq++
+	e.Time, e.Seq, e.Session = time.Now().UTC(), l.seq, l.session
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+	data = append(data, '\n')
+	if l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		l.f.Close()
+		l.part++
+		if err := l.open(); err != nil {
+			l.f = nil
+			return err
+		}
+	}
+	n, err := l.f.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// Start records who started the session, where and with which model.
+func (l *Logger) Start(model, workDir string) error {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	return l.write(Entry{Type: "session_start", User: user, Model: model, WorkDir: workDir})
+}
+
+// Resume records that the session continues a saved one.
+func (l *Logger) Resume(id string) error {
+	return l.write(Entry{Type: "resume", Resumed: id})
+}
+
+func (l *Logger) Prompt(text string) error {
+	return l.write(Entry{Type: "prompt", Prompt: text})
+}
+
+// maxInputString is the longest input value logged as is; longer ones,
+// such as the content of a Write, are logged as their hash.
+const maxInputString = 1024
+
+func (l *Logger) ToolCall(name, id string, input map[string]interface{}) error {
+	logged := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if str, ok := v.(string); ok && len(str) > maxInputString {
+			v = fmt.Sprintf("sha256:%s (%d bytes)", hash(str), len(str))
+		}
+		logged[k] = v
+	}
+	return l.write(Entry{Type: "tool_call", Tool: name, ToolUseID: id, Input: logged})
+}
+
+// Approval records whether a call was allowed to run, by the user or by
+// the approval rules, once it passed the deny list and hooks.
+func (l *Logger) Approval(name, id string, allowed bool) error {
+	decision := "allowed"
+	if !allowed {
+		decision = "denied"
+	}
+	return l.write(Entry{Type: "approval", Tool: name, ToolUseID: id, Decision: decision})
+}
+
+// ToolResult records the outcome of a call and the SHA-256 of its output.
+func (l *Logger) ToolResult(name, id, content string, isError, denied bool) error {
+	decision := "ok"
+	switch {
+	case denied:
+		decision = "denied"
+	case isError:
+		decision = "error"
+	}
+	return l.write(Entry{Type: "tool_result", Tool: name, ToolUseID: id, Decision: decision,
+		ResultHash: hash(content), ResultSize: len(content)})
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// Usage records the tokens of one API request.
+func (l *Logger) Usage(model string, input, output int) error {
+	return l.write(Entry{Type: "usage", Model: model, InputTokens: input, OutputTokens: output})
+}
+
+// Close records the end of the session and closes the file.
+func (l *Logger) Close() error {
+	l.write(Entry{Type: "session_end"})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// prune removes logs last written more than retention ago.
+func prune(dir string, retention time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-retention)
+	var old []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		if info, err := e.Info(); err == nil && info.ModTime().Before(cutoff) {
+			old = append(old, e.Name())
+		}
+	}
+	sort.Strings(old)
+	for _, name := range old {
+		os.Remove(filepath.Join(dir, name))
+	}
+}
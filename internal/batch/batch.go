@@ -0,0 +1,357 @@
+// Package batch runs many independent prompts as one message batch, for
+// offline jobs such as generating docs or refactoring file by file. A
+// batch costs half as much as the same requests sent one at a time, but
+// is processed asynchronously, usually within an hour.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/ignore"
+	"github.com/rpay/apipod-cli/internal/index"
+)
+
+// StateFile is written to the output directory when a batch is submitted,
+// so its results can be collected by a later run, e.g. with
+// `apipod-cli batch --resume DIR` after the terminal was closed.
+const StateFile = ".apipod-batch.json"
+
+// maxFileSize bounds a file attached to a prompt.
+const maxFileSize = 256 * 1024
+
+// DefaultPollInterval is how often an unfinished batch is checked.
+const DefaultPollInterval = 30 * time.Second
+
+const system = `You are processing one item of an offline batch job; nobody reads your answer interactively.
+Your answer is written to a file exactly as you give it, so answer with the requested content only:
+no greeting, no explanation of what you did, and no questions. When asked to rewrite a file, answer with the complete new file.`
+
+// Job is one prompt of a batch. Files are attached to the prompt with
+// their contents and Output is where its answer is written, relative to
+// the output directory; it defaults to "<ID>.md".
+type Job struct {
+	ID     string   `json:"id"`
+	Prompt string   `json:"prompt"`
+	Files  []string `json:"files,omitempty"`
+	Output string   `json:"output,omitempty"`
+}
+
+// Options configure a batch.
+type Options struct {
+	Model     string
+	MaxTokens int
+	// System is added to the batch instructions, e.g. a style guide.
+	System string
+	// WorkDir is what job files are relative to, OutDir where results
+	// are written.
+	WorkDir string
+	OutDir  string
+	// PollInterval defaults to DefaultPollInterval.
+	PollInterval time.Duration
+}
+
+// State records a submitted batch and where each job's answer goes.
+type State struct {
+	BatchID string    `json:"batch_id"`
+	Model   string    `json:"model"`
+	Created time.Time `json:"created"`
+	// Jobs maps the custom IDs sent with the requests to the jobs.
+	Jobs map[string]Job `json:"jobs"`
+}
+
+// Summary counts the outcome of a batch.
+type Summary struct {
+	Written int
+	Failed  []string
+}
+
+// LoadJobs reads jobs from a JSONL file, one Job object per line. Jobs
+// without an ID are numbered.
+func LoadJobs(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jobs: %w", err)
+	}
+	var jobs []Job
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(line), &job); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if strings.TrimSpace(job.Prompt) == "" {
+			return nil, fmt.Errorf("%s:%d: the job has no prompt", path, n)
+		}
+		if job.ID == "" {
+			job.ID = fmt.Sprintf("job-%d", len(jobs)+1)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, scanner.Err()
+}
+
+// FileJobs makes one job per file of workDir matching pattern ("**"
+// allowed, ignored files left out), with "{file}" in prompt replaced by
+// the file's path and the file attached. Each answer is written to the
+// file's path under the output directory with suffix added, e.g. ".md"
+// for docs; without one it mirrors the tree, as for a refactor.
+func FileJobs(workDir, prompt, pattern, suffix string) ([]Job, error) {
+	ignored := ignore.New(workDir)
+	var jobs []Job
+	err := filepath.WalkDir(workDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || p != workDir && ignored.Ignored(p, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(workDir, p)
+		if err != nil || ignored.Ignored(p, false) || !index.MatchGlob(pattern, filepath.ToSlash(rel)) {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		jobs = append(jobs, Job{
+			ID:     rel,
+			Prompt: strings.ReplaceAll(prompt, "{file}", rel),
+			Files:  []string{rel},
+			Output: rel + suffix,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return jobs, nil
+}
+
+// Submit sends jobs as one batch and saves its State in the output
+// directory.
+func Submit(c *client.Client, jobs []Job, opts Options) (*State, error) {
+	state := &State{Model: opts.Model, Created: time.Now(), Jobs: make(map[string]Job)}
+	requests := make([]client.BatchRequest, 0, len(jobs))
+	for i, job := range jobs {
+		content, err := jobPrompt(opts.WorkDir, job)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.ID, err)
+		}
+		id := fmt.Sprintf("job-%d", i+1)
+		if job.Output == "" {
+			name := safeName(job.ID)
+			if name == "" {
+				name = id
+			}
+			job.Output = name + ".md"
+		}
+		if !filepath.IsLocal(filepath.FromSlash(job.Output)) {
+			return nil, fmt.Errorf("job %s: output %s is outside the output directory", job.ID, job.Output)
+		}
+		state.Jobs[id] = job
+		sys := system
+		if opts.System != "" {
+			sys += "\n\n" + opts.System
+		}
+		requests = append(requests, client.BatchRequest{
+			CustomID: id,
+			Params: &client.MessagesRequest{
+				Model:     opts.Model,
+				MaxTokens: opts.MaxTokens,
+				System:    sys,
+				Messages:  []client.Message{{Role: "user", Content: content}},
+			},
+		})
+	}
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	b, err := c.CreateBatch(requests)
+	if err != nil {
+		return nil, fmt.Errorf("submit batch: %w", err)
+	}
+	state.BatchID = b.ID
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal batch state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(opts.OutDir, StateFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("save batch state: %w", err)
+	}
+	return state, nil
+}
+
+// LoadState reads the State a Submit saved in outDir.
+func LoadState(outDir string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(outDir, StateFile))
+	if err != nil {
+		return nil, fmt.Errorf("read batch state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse batch state: %w", err)
+	}
+	return &state, nil
+}
+
+// Wait polls the batch until it has ended, showing progress whenever it
+// changes.
+func Wait(c *client.Client, id string, interval time.Duration) (*client.Batch, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	var last client.BatchRequestCounts
+	for first := true; ; first = false {
+		b, err := c.GetBatch(id)
+		if err != nil {
+			return nil, fmt.Errorf("check batch: %w", err)
+		}
+		if b.ProcessingStatus == "ended" {
+			return b, nil
+		}
+		if first || b.RequestCounts != last {
+			display.BatchProgress(b.ID, b.RequestCounts.Processing, b.RequestCounts.Succeeded, b.RequestCounts.Errored)
+			last = b.RequestCounts
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Collect writes the answer of every succeeded job of an ended batch to
+// its output file and returns which jobs failed.
+func Collect(c *client.Client, b *client.Batch, state *State, outDir string) (*Summary, error) {
+	sum := &Summary{}
+	err := c.BatchResults(b, func(r *client.BatchResult) error {
+		job, ok := state.Jobs[r.CustomID]
+		if !ok {
+			return nil
+		}
+		if r.Result.Type != "succeeded" || r.Result.Message == nil {
+			reason := r.Result.Type
+			if r.Result.Error != nil {
+				reason = r.Result.Error.Error.Message
+			}
+			sum.Failed = append(sum.Failed, job.ID+": "+reason)
+			return nil
+		}
+		var text strings.Builder
+		for _, block := range r.Result.Message.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+		answer := unfence(text.String())
+		if r.Result.Message.StopReason == "max_tokens" {
+			sum.Failed = append(sum.Failed, job.ID+": the answer was cut off at the output token limit")
+		}
+		if !filepath.IsLocal(filepath.FromSlash(job.Output)) {
+			sum.Failed = append(sum.Failed, job.ID+": output "+job.Output+" is outside the output directory")
+			return nil
+		}
+		path := filepath.Join(outDir, filepath.FromSlash(job.Output))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("create output dir: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(answer), 0o644); err != nil {
+			return fmt.Errorf("write result: %w", err)
+		}
+		sum.Written++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("collect batch results: %w", err)
+	}
+	return sum, nil
+}
+
+// Run submits jobs, waits for the batch and writes the results.
+func Run(c *client.Client, jobs []Job, opts Options) (*Summary, error) {
+	state, err := Submit(c, jobs, opts)
+	if err != nil {
+		return nil, err
+	}
+	display.InfoMessage(fmt.Sprintf("Submitted batch %s with %d %s; results go to %s", state.BatchID, len(jobs), plural(len(jobs), "prompt", "prompts"), opts.OutDir))
+	return Resume(c, state, opts)
+}
+
+// Resume waits for a submitted batch and writes its results.
+func Resume(c *client.Client, state *State, opts Options) (*Summary, error) {
+	b, err := Wait(c, state.BatchID, opts.PollInterval)
+	if err != nil {
+		return nil, err
+	}
+	return Collect(c, b, state, opts.OutDir)
+}
+
+// jobPrompt is the job's prompt followed by its files.
+func jobPrompt(workDir string, job Job) (string, error) {
+	var b strings.Builder
+	b.WriteString(job.Prompt)
+	for _, name := range job.Files {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, name)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() > maxFileSize {
+			return "", fmt.Errorf("%s is larger than %d KB", name, maxFileSize/1024)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if bytes.IndexByte(data, 0) >= 0 {
+			return "", fmt.Errorf("%s is a binary file", name)
+		}
+		fmt.Fprintf(&b, "\n\n<file path=%q>\n%s\n</file>", name, data)
+	}
+	return b.String(), nil
+}
+
+// fenced matches an answer that is a single code block.
+var fenced = regexp.MustCompile("(?s)^\\s*```[^\\n]*\\n(.*?)\\n?```\\s*$")
+
+// unfence returns the body of an answer that is only a code block, which
+// models tend to give for "the complete new file".
+func unfence(text string) string {
+	if m := fenced.FindStringSubmatch(text); m != nil && !strings.Contains(m[1], "```") {
+		return m[1] + "\n"
+	}
+	return text
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// safeName turns a job ID into a file name.
+func safeName(id string) string {
+	return strings.Trim(unsafeChars.ReplaceAllString(id, "-"), "-.")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrLoginRequired means the API rejected the credentials and they could
+// not be refreshed; the user has to log in again.
+var ErrLoginRequired = errors.New("your login has expired or was revoked; run `apipod-cli login` to sign in again")
+
+// ErrInvalidAPIKey means the API did not accept a key given to
+// `apipod-cli login --api-key`.
+var ErrInvalidAPIKey = errors.New("the API key was rejected")
+
+// refreshMargin refreshes tokens this long before they expire, so a token
+// does not run out during a long streamed response.
+const refreshMargin = 2 * time.Minute
+
+// Token is an API token from the device flow with what is needed to renew
+// it. Tokens without a refresh token or expiry are used until rejected.
+type Token struct {
+	APIKey       string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// tokenState is the renewable part of a client's credentials.
+type tokenState struct {
+	refreshToken string
+	expiry       time.Time
+	onRefresh    func(Token)
+}
+
+// ensureFresh refreshes the token when it is about to expire.
+func (c *Client) ensureFresh() error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.token.refreshToken == "" || c.token.expiry.IsZero() || time.Until(c.token.expiry) > refreshMargin {
+		return nil
+	}
+	return c.refreshLocked()
+}
+
+// refreshAfterReject renews the token after the API answered 401 with
+// rejected, unless another request renewed it in the meantime. It reports
+// whether the request is worth retrying.
+func (c *Client) refreshAfterReject(rejected string) bool {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.apiKey != rejected {
+		return true
+	}
+	if c.token.refreshToken == "" {
+		return false
+	}
+	return c.refreshLocked() == nil
+}
+
+func (c *Client) refreshLocked() error {
+	c.trace.Logf("refreshing the API token (expiry %s)", c.token.expiry.Format(time.RFC3339))
+	res, err := c.RefreshToken(c.token.refreshToken)
+	if err != nil {
+		c.trace.Logf("token refresh failed: %v", err)
+		return err
+	}
+	c.apiKey = res.APIToken
+	if res.RefreshToken != "" {
+		c.token.refreshToken = res.RefreshToken
+	}
+	c.token.expiry = time.Time{}
+	if res.ExpiresIn > 0 {
+		c.token.expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+	if c.token.onRefresh != nil {
+		c.token.onRefresh(Token{APIKey: c.apiKey, RefreshToken: c.token.refreshToken, Expiry: c.token.expiry})
+	}
+	return nil
+}
+
+func (c *Client) currentKey() string {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.apiKey
+}
+
+// RefreshToken exchanges a refresh token for a new API token. An invalid
+// or revoked refresh token yields ErrLoginRequired.
+func (c *Client) RefreshToken(refreshToken string) (*DeviceTokenResponse, error) {
+	body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	resp, err := c.httpClient.Post(c.baseURL+"/auth/token/refresh", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("refresh token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized:
+		return nil, ErrLoginRequired
+	case resp.StatusCode != http.StatusOK:
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("refresh token error (status %d): %s", resp.StatusCode, string(errBody))
+	}
+	var result DeviceTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if result.APIToken == "" {
+		return nil, ErrLoginRequired
+	}
+	return &result, nil
+}
+
+// Account is the user an API key belongs to.
+type Account struct {
+	Username string `json:"username"`
+	Plan     string `json:"plan"`
+}
+
+// Whoami returns the account of the client's API key, so a key can be
+// checked before it is saved. A rejected key yields ErrInvalidAPIKey.
+func (c *Client) Whoami() (*Account, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/auth/whoami", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.currentKey())
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("whoami: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrInvalidAPIKey
+	case resp.StatusCode != http.StatusOK:
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("whoami error (status %d): %s", resp.StatusCode, string(errBody))
+	}
+	var result Account
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// BatchRequest is one request of a message batch. CustomID, up to 64
+// letters, digits, "-" and "_", matches it to its result.
+type BatchRequest struct {
+	CustomID string           `json:"custom_id"`
+	Params   *MessagesRequest `json:"params"`
+}
+
+// Batch is a message batch and how far it has got. ProcessingStatus is
+// "in_progress", "canceling" or "ended"; results can be read once it has
+// ended.
+type Batch struct {
+	ID               string             `json:"id"`
+	ProcessingStatus string             `json:"processing_status"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	ResultsURL       string             `json:"results_url"`
+	CreatedAt        string             `json:"created_at"`
+	ExpiresAt        string             `json:"expires_at"`
+}
+
+// BatchRequestCounts counts the requests of a batch by state.
+type BatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// BatchResult is the outcome of one request of an ended batch. Type is
+// "succeeded", with Message set, "errored", with Error set, "canceled" or
+// "expired".
+type BatchResult struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string            `json:"type"`
+		Message *MessagesResponse `json:"message,omitempty"`
+		Error   *struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"error,omitempty"`
+	} `json:"result"`
+}
+
+// CreateBatch submits requests as a message batch, which is processed
+// asynchronously at a lower price, usually within an hour.
+func (c *Client) CreateBatch(requests []BatchRequest) (*Batch, error) {
+	for _, r := range requests {
+		if r.Params.MaxTokens == 0 {
+			r.Params.MaxTokens = DefaultMaxTokens
+		}
+		r.Params.Stream = false
+	}
+	body, err := json.Marshal(map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, fmt.Errorf("marshal batch: %w", err)
+	}
+	c.trace.Logf("create batch requests=%d body=%d bytes", len(requests), len(body))
+	var batch Batch
+	if err := c.batchCall("POST", c.baseURL+"/v1/messages/batches", body, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// GetBatch returns the current state of batch id.
+func (c *Client) GetBatch(id string) (*Batch, error) {
+	var batch Batch
+	if err := c.batchCall("GET", c.baseURL+"/v1/messages/batches/"+id, nil, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// BatchResults calls fn with each result of an ended batch, in no
+// particular order.
+func (c *Client) BatchResults(batch *Batch, fn func(*BatchResult) error) error {
+	url := batch.ResultsURL
+	if url == "" {
+		url = c.baseURL + "/v1/messages/batches/" + batch.ID + "/results"
+	}
+	resp, err := c.batchDo("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	// A line holds a whole response.
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var result BatchResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return fmt.Errorf("decode batch result: %w", err)
+		}
+		if err := fn(&result); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read batch results: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) batchCall(method, url string, body []byte, out interface{}) error {
+	resp, err := c.batchDo(method, url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// batchDo sends a batch API request and returns the response when it
+// succeeded, the typed API error otherwise.
+func (c *Client) batchDo(method, url string, body []byte) (*http.Response, error) {
+	if err := c.ensureFresh(); err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("x-api-key", c.currentKey())
+	req.Header.Set("anthropic-version", "2023-06-01")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, ErrLoginRequired
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, parseAPIError(resp)
+	}
+	return resp, nil
+}
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,6 +17,17 @@ type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+
+	// authMu guards apiKey and token, which change when the token is
+	// refreshed.
+	authMu sync.Mutex
+	token  tokenState
+
+	// trace is nil unless debug tracing is on.
+	trace *Tracer
+
+	// limiter is nil unless a client-side rate limit is set.
+	limiter *RateLimiter
 }
 
 func New(baseURL, apiKey string) *Client {
@@ -27,24 +40,58 @@ func New(baseURL, apiKey string) *Client {
 	}
 }
 
+// BaseURL is the API endpoint the client sends requests to.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
 type Message struct {
 	Role    string      `json:"role"`
 	Content interface{} `json:"content"`
 }
 
 type ToolDefinition struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema interface{} `json:"input_schema"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	InputSchema  interface{}   `json:"input_schema"`
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+}
+
+type CacheControl struct {
+	Type string `json:"type"`
 }
 
 type MessagesRequest struct {
-	Model     string           `json:"model"`
-	Messages  []Message        `json:"messages"`
-	System    string           `json:"system,omitempty"`
-	MaxTokens int              `json:"max_tokens"`
-	Stream    bool             `json:"stream"`
-	Tools     []ToolDefinition `json:"tools,omitempty"`
+	Model      string           `json:"model"`
+	Messages   []Message        `json:"messages"`
+	System     string           `json:"system,omitempty"`
+	MaxTokens  int              `json:"max_tokens"`
+	Stream     bool             `json:"stream"`
+	Tools      []ToolDefinition `json:"tools,omitempty"`
+	ToolChoice *ToolChoice      `json:"tool_choice,omitempty"`
+	Thinking   *Thinking        `json:"thinking,omitempty"`
+
+	// Sampling settings; unset ones keep the API's defaults.
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// DefaultMaxTokens is the output limit of a request that sets none.
+const DefaultMaxTokens = 16384
+
+// Thinking enables extended thinking with a token budget (at least 1024,
+// and below max_tokens).
+type Thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// ToolChoice forces ("tool"), allows ("auto", "any") or forbids ("none")
+// tool use.
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type ContentBlock struct {
@@ -53,6 +100,11 @@ type ContentBlock struct {
 	Text  string          `json:"text,omitempty"`
 	Name  string          `json:"name,omitempty"`
 	Input json.RawMessage `json:"input,omitempty"`
+	// Thinking and Signature belong to "thinking" blocks, Data to
+	// "redacted_thinking" blocks; both must be sent back unchanged.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
 }
 
 type MessagesResponse struct {
@@ -94,6 +146,8 @@ type StreamContentBlockDelta struct {
 		Type        string `json:"type"`
 		Text        string `json:"text,omitempty"`
 		PartialJSON string `json:"partial_json,omitempty"`
+		Thinking    string `json:"thinking,omitempty"`
+		Signature   string `json:"signature,omitempty"`
 	} `json:"delta"`
 }
 
@@ -107,18 +161,25 @@ type StreamMessageDelta struct {
 
 type StreamCallback struct {
 	OnText           func(text string)
+	OnThinking       func(text string)
 	OnToolUseStart   func(id, name string)
 	OnToolUseInput   func(partialJSON string)
 	OnMessageStart   func(resp *MessagesResponse)
 	OnMessageDelta   func(stopReason string, usage *Usage)
 	OnContentBlockStop func(index int)
 	OnError          func(err error)
+	// OnRateLimitWait is called while the client-side rate limiter holds
+	// the request back, with the time left, and with 0 when it is sent.
+	OnRateLimitWait func(left time.Duration)
 }
 
 func (c *Client) SendMessageStream(req *MessagesRequest, cb *StreamCallback) (*MessagesResponse, error) {
 	req.Stream = true
 	if req.MaxTokens == 0 {
-		req.MaxTokens = 16384
+		req.MaxTokens = DefaultMaxTokens
+	}
+	if req.Thinking != nil && req.MaxTokens <= req.Thinking.BudgetTokens {
+		req.MaxTokens = req.Thinking.BudgetTokens + DefaultMaxTokens
 	}
 
 	body, err := json.Marshal(req)
@@ -126,27 +187,76 @@ func (c *Client) SendMessageStream(req *MessagesRequest, cb *StreamCallback) (*M
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
+	c.trace.Logf("request model=%s messages=%d tools=%d max_tokens=%d body=%d bytes",
+		req.Model, len(req.Messages), len(req.Tools), req.MaxTokens, len(body))
+	if err := c.ensureFresh(); err != nil {
+		return nil, err
+	}
+	estimated := EstimateTokens(req)
+	var onWait func(time.Duration)
+	if cb != nil {
+		onWait = cb.OnRateLimitWait
+	}
+	c.limiter.Wait(estimated, func(left time.Duration) {
+		if left > 0 {
+			c.trace.Logf("rate limit: waiting %s", left.Round(time.Second))
+		}
+		if onWait != nil {
+			onWait(left)
+		}
+	})
+	key := c.currentKey()
+	resp, err := c.postMessages(body, key)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		c.trace.Logf("401: refreshing the token and retrying once")
+		if !c.refreshAfterReject(key) {
+			return nil, ErrLoginRequired
+		}
+		if resp, err = c.postMessages(body, c.currentKey()); err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			return nil, ErrLoginRequired
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := parseAPIError(resp)
+		var limited *RateLimitError
+		if errors.As(err, &limited) {
+			c.limiter.Pause(limited.RetryAfter)
+		}
+		return nil, err
+	}
+
+	result, err := c.parseSSEStream(resp.Body, cb)
+	if result != nil {
+		c.limiter.Record(estimated, result.Usage.InputTokens+result.Usage.OutputTokens)
+	}
+	return result, err
+}
+
+func (c *Client) postMessages(body []byte, apiKey string) (*http.Response, error) {
 	httpReq, err := http.NewRequest("POST", c.baseURL+"/v1/messages", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("x-api-key", apiKey)
 	httpReq.Header.Set("anthropic-version", "2023-06-01")
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("send request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		errBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(errBody))
-	}
-
-	return c.parseSSEStream(resp.Body, cb)
+	return resp, nil
 }
 
 func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*MessagesResponse, error) {
@@ -156,6 +266,9 @@ func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*Messages
 	var result MessagesResponse
 	var currentEvent string
 	var toolInputs = make(map[int]*strings.Builder)
+	// Deltas are counted rather than traced one by one.
+	var deltas int
+	started := time.Now()
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -170,6 +283,11 @@ func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*Messages
 		}
 
 		data := strings.TrimPrefix(line, "data: ")
+		if currentEvent == "content_block_delta" {
+			deltas++
+		} else {
+			c.trace.Logf("sse %s after %s (%d deltas so far)", currentEvent, time.Since(started).Round(time.Millisecond), deltas)
+		}
 
 		switch currentEvent {
 		case "message_start":
@@ -207,6 +325,17 @@ func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*Messages
 					if cb != nil && cb.OnText != nil {
 						cb.OnText(delta.Delta.Text)
 					}
+				case "thinking_delta":
+					if delta.Index < len(result.Content) {
+						result.Content[delta.Index].Thinking += delta.Delta.Thinking
+					}
+					if cb != nil && cb.OnThinking != nil {
+						cb.OnThinking(delta.Delta.Thinking)
+					}
+				case "signature_delta":
+					if delta.Index < len(result.Content) {
+						result.Content[delta.Index].Signature += delta.Delta.Signature
+					}
 				case "input_json_delta":
 					if sb, ok := toolInputs[delta.Index]; ok {
 						sb.WriteString(delta.Delta.PartialJSON)
@@ -238,7 +367,12 @@ func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*Messages
 			if err := json.Unmarshal([]byte(data), &delta); err == nil {
 				result.StopReason = delta.Delta.StopReason
 				if delta.Usage != nil {
-					result.Usage = *delta.Usage
+					// message_delta usually carries only output_tokens; keep
+					// the input count reported by message_start.
+					if delta.Usage.InputTokens > 0 {
+						result.Usage.InputTokens = delta.Usage.InputTokens
+					}
+					result.Usage.OutputTokens = delta.Usage.OutputTokens
 				}
 				if cb != nil && cb.OnMessageDelta != nil {
 					cb.OnMessageDelta(delta.Delta.StopReason, delta.Usage)
@@ -248,11 +382,12 @@ func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*Messages
 		case "error":
 			var errData struct {
 				Error struct {
+					Type    string `json:"type"`
 					Message string `json:"message"`
 				} `json:"error"`
 			}
 			if err := json.Unmarshal([]byte(data), &errData); err == nil {
-				apiErr := fmt.Errorf("stream error: %s", errData.Error.Message)
+				apiErr := classify(&APIError{Type: errData.Error.Type, Message: errData.Error.Message})
 				if cb != nil && cb.OnError != nil {
 					cb.OnError(apiErr)
 				}
@@ -262,8 +397,11 @@ func (c *Client) parseSSEStream(reader io.Reader, cb *StreamCallback) (*Messages
 	}
 
 	if err := scanner.Err(); err != nil {
+		c.trace.Logf("stream read failed after %s: %v", time.Since(started).Round(time.Millisecond), err)
 		return nil, fmt.Errorf("read stream: %w", err)
 	}
+	c.trace.Logf("stream done in %s: stop_reason=%s input_tokens=%d output_tokens=%d deltas=%d",
+		time.Since(started).Round(time.Millisecond), result.StopReason, result.Usage.InputTokens, result.Usage.OutputTokens, deltas)
 
 	return &result, nil
 }
@@ -280,6 +418,10 @@ type DeviceCodeResponse struct {
 type DeviceTokenResponse struct {
 	Status   string `json:"status"`
 	APIToken string `json:"api_token,omitempty"`
+	// RefreshToken and ExpiresIn (seconds) are set for tokens that
+	// expire; see Client.RefreshToken.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
 	Username string `json:"username,omitempty"`
 	Plan     string `json:"plan,omitempty"`
 	Error    string `json:"error,omitempty"`
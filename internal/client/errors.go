@@ -0,0 +1,146 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/i18n"
+)
+
+// APIError is an error returned by the API. Errors the user can act on are
+// returned as AuthError, RateLimitError, OverloadedError or
+// ContextTooLongError, which wrap it; use errors.As to tell them apart.
+type APIError struct {
+	// Status is the HTTP status, or 0 for an error sent mid-stream.
+	Status    int
+	Type      string
+	Message   string
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	var detail []string
+	if e.Status != 0 {
+		detail = append(detail, strconv.Itoa(e.Status))
+	}
+	if e.Type != "" {
+		detail = append(detail, e.Type)
+	}
+	if len(detail) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, strings.Join(detail, " "))
+}
+
+// Hint suggests what to do about the error, or returns "".
+func (e *APIError) Hint() string {
+	if e.Status >= 500 {
+		return i18n.T("error.server")
+	}
+	return ""
+}
+
+// AuthError means the credentials were rejected or lack permission.
+type AuthError struct{ *APIError }
+
+func (e *AuthError) Unwrap() error { return e.APIError }
+
+func (e *AuthError) Hint() string {
+	if e.Type == "permission_error" || e.Status == http.StatusForbidden {
+		return i18n.T("error.permission")
+	}
+	return i18n.T("error.auth")
+}
+
+// RateLimitError means too many requests or tokens were sent recently.
+type RateLimitError struct {
+	*APIError
+	// RetryAfter is how long the API asked to wait, or 0 if it did not say.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Unwrap() error { return e.APIError }
+
+func (e *RateLimitError) Hint() string {
+	if e.RetryAfter > 0 {
+		return i18n.T("error.rate_limit_after", e.RetryAfter.Round(time.Second))
+	}
+	return i18n.T("error.rate_limit")
+}
+
+// OverloadedError means the API is temporarily over capacity.
+type OverloadedError struct{ *APIError }
+
+func (e *OverloadedError) Unwrap() error { return e.APIError }
+
+func (e *OverloadedError) Hint() string {
+	return i18n.T("error.overloaded")
+}
+
+// ContextTooLongError means the conversation no longer fits the model's
+// context window.
+type ContextTooLongError struct{ *APIError }
+
+func (e *ContextTooLongError) Unwrap() error { return e.APIError }
+
+func (e *ContextTooLongError) Hint() string {
+	return i18n.T("error.context_too_long")
+}
+
+// maxErrorMessage bounds a message taken from a body that is not an API
+// error document, e.g. an HTML page from a proxy.
+const maxErrorMessage = 300
+
+// parseAPIError reads the error response of a failed request.
+func parseAPIError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	e := &APIError{Status: resp.StatusCode, RequestID: resp.Header.Get("request-id")}
+	var doc struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &doc) == nil && doc.Error.Message != "" {
+		e.Type, e.Message = doc.Error.Type, doc.Error.Message
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > maxErrorMessage {
+			e.Message = e.Message[:maxErrorMessage] + "…"
+		}
+		if e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	err := classify(e)
+	if rl, ok := err.(*RateLimitError); ok {
+		if secs, convErr := strconv.Atoi(resp.Header.Get("retry-after")); convErr == nil {
+			rl.RetryAfter = time.Duration(secs) * time.Second
+		}
+	}
+	return err
+}
+
+// classify returns the typed error for e, or e itself.
+func classify(e *APIError) error {
+	msg := strings.ToLower(e.Message)
+	switch {
+	case e.Type == "authentication_error" || e.Type == "permission_error" ||
+		e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden:
+		return &AuthError{e}
+	case e.Type == "rate_limit_error" || e.Status == http.StatusTooManyRequests:
+		return &RateLimitError{APIError: e}
+	case e.Type == "overloaded_error" || e.Status == 529:
+		return &OverloadedError{e}
+	case e.Type == "request_too_large" || e.Status == http.StatusRequestEntityTooLarge ||
+		strings.Contains(msg, "prompt is too long") || strings.Contains(msg, "context length") ||
+		strings.Contains(msg, "context window"):
+		return &ContextTooLongError{e}
+	}
+	return e
+}
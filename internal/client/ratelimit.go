@@ -0,0 +1,129 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter holds requests back on the client so a busy agent loop stays
+// within the requests and tokens per minute of the plan, instead of
+// running into 429s. Both limits are token buckets refilled evenly over a
+// minute, so short bursts up to a minute's allowance go through at once.
+// Methods of a nil RateLimiter do nothing.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	// pausedUntil holds every request back after a 429 until the time the
+	// API asked to wait.
+	pausedUntil time.Time
+}
+
+// NewRateLimiter returns a limiter for requestsPerMinute and
+// tokensPerMinute, either of which may be 0 for no limit, or nil when
+// both are.
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		requests: newBucket(requestsPerMinute),
+		tokens:   newBucket(tokensPerMinute),
+	}
+}
+
+// Wait blocks until a request of about tokens tokens may be sent and takes
+// it from the limits. While it waits, onWait, when set, is called about
+// every second with the time left, and with 0 once the wait is over.
+func (r *RateLimiter) Wait(tokens int, onWait func(left time.Duration)) {
+	if r == nil {
+		return
+	}
+	waited := false
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		left := max(r.requests.wait(1, now), r.tokens.wait(float64(tokens), now), r.pausedUntil.Sub(now))
+		if left <= 0 {
+			r.requests.take(1)
+			r.tokens.take(float64(tokens))
+			r.mu.Unlock()
+			break
+		}
+		r.mu.Unlock()
+		waited = true
+		if onWait != nil {
+			onWait(left)
+		}
+		time.Sleep(min(left, time.Second))
+	}
+	if waited && onWait != nil {
+		onWait(0)
+	}
+}
+
+// Record corrects the tokens taken for a request by Wait, an estimate of
+// its input, to what the request actually used, output included.
+func (r *RateLimiter) Record(estimated, used int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens.take(float64(used - estimated))
+}
+
+// Pause holds requests back for d, e.g. the Retry-After of a 429.
+func (r *RateLimiter) Pause(d time.Duration) {
+	if r == nil || d <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if until := time.Now().Add(d); until.After(r.pausedUntil) {
+		r.pausedUntil = until
+	}
+}
+
+// bucket is one token bucket; a nil bucket never limits.
+type bucket struct {
+	capacity float64
+	level    float64
+	perSec   float64
+	last     time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{
+		capacity: float64(perMinute),
+		level:    float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// wait refills b and returns how long until n can be taken. A request
+// larger than the whole bucket goes once the bucket is full.
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.perSec)
+	b.last = now
+	need := min(n, b.capacity)
+	if b.level >= need {
+		return 0
+	}
+	return time.Duration((need - b.level) / b.perSec * float64(time.Second))
+}
+
+// take removes n from b; the level may go below zero, which makes the
+// next requests wait longer.
+func (b *bucket) take(n float64) {
+	if b != nil {
+		b.level -= n
+	}
+}
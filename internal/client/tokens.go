@@ -0,0 +1,103 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type countTokensRequest struct {
+	Model    string           `json:"model"`
+	Messages []Message        `json:"messages"`
+	System   string           `json:"system,omitempty"`
+	Tools    []ToolDefinition `json:"tools,omitempty"`
+	Thinking *Thinking        `json:"thinking,omitempty"`
+}
+
+// CountTokens asks the API how many input tokens req would use, without
+// running it.
+func (c *Client) CountTokens(req *MessagesRequest) (int, error) {
+	body, err := json.Marshal(countTokensRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		System:   req.System,
+		Tools:    req.Tools,
+		Thinking: req.Thinking,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", c.baseURL+"/v1/messages/count_tokens", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("count tokens (status %d): %s", resp.StatusCode, string(errBody))
+	}
+
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+	return result.InputTokens, nil
+}
+
+// EstimateTokens approximates the input tokens of req locally, at about
+// four bytes of JSON per token, for when the count endpoint is unavailable.
+// Images and documents are not billed by their encoded size and are
+// estimated apart.
+func EstimateTokens(req *MessagesRequest) int {
+	n := len(req.System)
+	if data, err := json.Marshal(req.Messages); err == nil {
+		n += len(data)
+	}
+	if data, err := json.Marshal(req.Tools); err == nil && len(req.Tools) > 0 {
+		n += len(data)
+	}
+	tokens := 0
+	for _, m := range req.Messages {
+		blocks, _ := m.Content.([]interface{})
+		for _, b := range blocks {
+			block, _ := b.(map[string]interface{})
+			// Sources read back from a saved session are generic maps.
+			var kind, data string
+			switch src := block["source"].(type) {
+			case map[string]string:
+				kind, data = src["type"], src["data"]
+			case map[string]interface{}:
+				kind, _ = src["type"].(string)
+				data, _ = src["data"].(string)
+			}
+			if kind != "base64" {
+				continue
+			}
+			n -= len(data)
+			if block["type"] == "image" {
+				tokens += imageTokens
+			} else {
+				// Mostly a document's text, which is far smaller than
+				// the file.
+				tokens += len(data) / 40
+			}
+		}
+	}
+	return tokens + (n+3)/4
+}
+
+// imageTokens is about what the largest images the API accepts cost.
+const imageTokens = 1600
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer writes timestamped debug lines about requests, stream events,
+// retries and token refreshes. Credentials are never written.
+type Tracer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{w: w, start: time.Now()}
+}
+
+// Logf writes one line prefixed with the time since the tracer started.
+// It does nothing on a nil Tracer.
+func (t *Tracer) Logf(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "[%9.3fs] %s\n", time.Since(t.start).Seconds(), fmt.Sprintf(format, args...))
+}
+
+// secretHeaders are shown as [REDACTED] in traces.
+var secretHeaders = map[string]bool{
+	"authorization": true, "x-api-key": true, "proxy-authorization": true, "cookie": true, "set-cookie": true,
+}
+
+func traceHeaders(h http.Header) string {
+	var parts []string
+	for k, vs := range h {
+		v := strings.Join(vs, ",")
+		if secretHeaders[strings.ToLower(k)] {
+			v = "[REDACTED]"
+		}
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// traceTransport logs every request and its response headers with timing.
+type traceTransport struct {
+	base  http.RoundTripper
+	trace *Tracer
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	t.trace.Logf("→ %s %s (%d bytes) %s", req.Method, req.URL.Redacted(), req.ContentLength, traceHeaders(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.trace.Logf("✗ %s %s failed after %s: %v", req.Method, req.URL.Redacted(), time.Since(started).Round(time.Millisecond), err)
+		return nil, err
+	}
+	t.trace.Logf("← %s %s after %s %s", resp.Status, req.URL.Path, time.Since(started).Round(time.Millisecond), traceHeaders(resp.Header))
+	return resp, nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Options adjust how the client reaches the API, e.g. from behind a
+// corporate proxy that intercepts TLS.
+type Options struct {
+	// Proxy is the proxy URL for every request. When empty HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY apply.
+	Proxy string
+	// CABundle is a PEM file of certificates trusted in addition to the
+	// system roots.
+	CABundle string
+	// InsecureSkipVerify turns off certificate verification.
+	InsecureSkipVerify bool
+	// Headers are added to every request, e.g. a gateway token.
+	Headers map[string]string
+
+	// Token renews the API key before it expires and when the API rejects
+	// it; OnRefresh is called with each new token so it can be saved.
+	Token     Token
+	OnRefresh func(Token)
+
+	// Trace, when set, receives debug lines about every request, stream
+	// event and retry.
+	Trace *Tracer
+
+	// RateLimiter, when set, holds message requests back to stay within
+	// the plan's limits. Clients sharing one share the limits.
+	RateLimiter *RateLimiter
+}
+
+// NewWithOptions is New with a transport and token renewal set up from
+// opts.
+func NewWithOptions(baseURL, apiKey string, opts Options) (*Client, error) {
+	c := New(baseURL, apiKey)
+	transport, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.httpClient = &http.Client{Timeout: 10 * time.Minute, Transport: transport}
+	if opts.Token.APIKey != "" {
+		c.apiKey = opts.Token.APIKey
+	}
+	c.token = tokenState{refreshToken: opts.Token.RefreshToken, expiry: opts.Token.Expiry, onRefresh: opts.OnRefresh}
+	c.trace = opts.Trace
+	c.limiter = opts.RateLimiter
+	return c, nil
+}
+
+// NewTransport returns a transport honoring opts.
+func NewTransport(opts Options) (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	if opts.CABundle != "" || opts.InsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+		if opts.CABundle != "" {
+			pem, err := os.ReadFile(opts.CABundle)
+			if err != nil {
+				return nil, fmt.Errorf("read CA bundle: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", opts.CABundle)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+	var rt http.RoundTripper = t
+	if len(opts.Headers) > 0 {
+		rt = &headerTransport{base: rt, headers: opts.Headers}
+	}
+	if opts.Trace != nil {
+		rt = &traceTransport{base: rt, trace: opts.Trace}
+	}
+	return rt, nil
+}
+
+// headerTransport adds fixed headers to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (h *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
+	return h.base.RoundTrip(req)
+}
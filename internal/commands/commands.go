@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/config"
+)
+
+// Command is a custom slash command defined by a markdown file. The body is
+// the prompt; $ARGUMENTS is replaced with whatever follows the command.
+type Command struct {
+	Name         string
+	Description  string
+	ArgumentHint string
+	// AllowedTools restricts the tools offered while the command runs;
+	// empty means every tool.
+	AllowedTools []string
+	Body         string
+	// Source is "user" (~/.apipod/commands) or "project"
+	// (<workdir>/.apipod/commands).
+	Source string
+	Path   string
+}
+
+// Expand returns the prompt for the given arguments. Bodies without
+// $ARGUMENTS get the arguments appended.
+func (c *Command) Expand(args string) string {
+	args = strings.TrimSpace(args)
+	if strings.Contains(c.Body, "$ARGUMENTS") {
+		return strings.ReplaceAll(c.Body, "$ARGUMENTS", args)
+	}
+	if args == "" {
+		return c.Body
+	}
+	return c.Body + "\n\n" + args
+}
+
+// Load returns the user and project commands sorted by name. A project
+// command overrides a user command of the same name. Files in
+// subdirectories are namespaced, so frontend/test.md becomes frontend:test.
+func Load(workDir string) []*Command {
+	byName := make(map[string]*Command)
+	home, _ := os.UserHomeDir()
+	dirs := []struct{ dir, source string }{
+		{filepath.Join(home, config.ConfigDir, "commands"), "user"},
+		{filepath.Join(workDir, config.ConfigDir, "commands"), "project"},
+	}
+	for _, d := range dirs {
+		filepath.WalkDir(d.dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+				return nil
+			}
+			rel, _ := filepath.Rel(d.dir, path)
+			name := strings.ReplaceAll(filepath.ToSlash(strings.TrimSuffix(rel, ".md")), "/", ":")
+			cmd, err := parseFile(path)
+			if err != nil {
+				return nil
+			}
+			cmd.Name, cmd.Source, cmd.Path = name, d.source, path
+			byName[name] = cmd
+			return nil
+		})
+	}
+
+	cmds := make([]*Command, 0, len(byName))
+	for _, c := range byName {
+		cmds = append(cmds, c)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+// Find returns the command called name (without the leading slash).
+func Find(cmds []*Command, name string) *Command {
+	name = strings.TrimPrefix(name, "/")
+	for _, c := range cmds {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func parseFile(path string) (*Command, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	meta, body := splitFrontmatter(strings.ReplaceAll(string(data), "\r\n", "\n"))
+
+	cmd := &Command{Body: strings.TrimSpace(body)}
+	cmd.Description = meta["description"]
+	cmd.ArgumentHint = meta["argument-hint"]
+	cmd.AllowedTools = parseList(meta["allowed-tools"])
+	if cmd.Description == "" {
+		cmd.Description = firstLine(cmd.Body)
+	}
+	return cmd, nil
+}
+
+// splitFrontmatter separates a leading "---" block of "key: value" lines
+// from the body.
+func splitFrontmatter(s string) (map[string]string, string) {
+	meta := make(map[string]string)
+	if !strings.HasPrefix(s, "---\n") {
+		return meta, s
+	}
+	rest := s[4:]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return meta, s
+	}
+	scanner := bufio.NewScanner(strings.NewReader(rest[:end]))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		meta[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	body := rest[end+4:]
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = ""
+	}
+	return meta, body
+}
+
+// parseList accepts "A, B", "[A, B]" or "A B". Tool patterns such as
+// Bash(git:*) are kept whole, spaces inside the parentheses included.
+func parseList(s string) []string {
+	var out []string
+	var cur strings.Builder
+	depth := 0
+	flush := func() {
+		if f := strings.Trim(cur.String(), `"'`); f != "" {
+			out = append(out, f)
+		}
+		cur.Reset()
+	}
+	for _, r := range strings.Trim(strings.TrimSpace(s), "[]") {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case (r == ',' || r == ' ') && depth == 0:
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	flush()
+	return out
+}
+
+// SplitTool splits an allowed-tools entry such as "Bash(git:*)" into the
+// tool name and the pattern its calls must match, "" for any call.
+func SplitTool(entry string) (string, string) {
+	name, pattern, ok := strings.Cut(entry, "(")
+	if !ok || !strings.HasSuffix(pattern, ")") {
+		return entry, ""
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(strings.TrimSuffix(pattern, ")"))
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	line = strings.TrimLeft(line, "# ")
+	if r := []rune(line); len(r) > 60 {
+		line = string(r[:57]) + "..."
+	}
+	return line
+}
@@ -3,15 +3,26 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/telemetry"
+	"github.com/rpay/apipod-cli/internal/update"
 )
 
 const (
 	DefaultBaseURL = "https://api.apipod.net"
 	DefaultModel   = "claude-sonnet-4-20250514"
-	ConfigDir      = ".apipod"
-	ConfigFile     = "config.json"
+	// DefaultSmallModel answers quick side questions, such as explaining a
+	// command before it is approved.
+	DefaultSmallModel = "claude-3-5-haiku-20241022"
+	ConfigDir         = ".apipod"
+	ConfigFile        = "config.json"
 )
 
 type Config struct {
@@ -20,6 +31,395 @@ type Config struct {
 	Model    string `json:"model,omitempty"`
 	Username string `json:"username,omitempty"`
 	Plan     string `json:"plan,omitempty"`
+
+	// SmallModel is the cheaper model used for side questions, such as
+	// "e" at a Bash prompt explaining the command.
+	SmallModel string `json:"small_model,omitempty"`
+
+	// RefreshToken and TokenExpiry renew a login token that expires; they
+	// are stored alongside the API key.
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	TokenExpiry  *time.Time `json:"token_expiry,omitempty"`
+
+	// Profile names the profile in Profiles to use when neither --profile
+	// nor APIPOD_PROFILE picks one. After Load it holds the active profile.
+	Profile  string              `json:"profile,omitempty"`
+	Profiles map[string]*Profile `json:"profiles,omitempty"`
+
+	// CredentialStore is "file" to keep API keys in this file. By default
+	// they are moved to the OS keychain (macOS Keychain, Windows Credential
+	// Manager or a Secret Service via secret-tool) when one is available.
+	CredentialStore string `json:"credential_store,omitempty"`
+
+	// ToolDefinitions controls how tool schemas are resent each request:
+	// "full" (default), "cache" (mark them for prompt caching) or "slim"
+	// (shortened descriptions after the first request).
+	ToolDefinitions string `json:"tool_definitions,omitempty"`
+
+	// Proxy, CABundle and InsecureSkipVerify let the client reach the API
+	// through a corporate proxy, including ones that re-sign TLS traffic.
+	// Without Proxy, HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply. Headers
+	// are added to every API request.
+	Proxy              string            `json:"proxy,omitempty"`
+	CABundle           string            `json:"ca_bundle,omitempty"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"`
+
+	// Daemon lets invocations attach to the warm-start daemon, spawning it
+	// on first use.
+	Daemon bool `json:"daemon,omitempty"`
+
+	SemanticSearch *SemanticSearch `json:"semantic_search,omitempty"`
+
+	// Tool results are scanned for credentials before they are sent to the
+	// API or recorded. RedactPatterns adds custom regular expressions.
+	RedactPatterns   []string `json:"redact_patterns,omitempty"`
+	DisableRedaction bool     `json:"disable_redaction,omitempty"`
+
+	// InjectionGuard marks instruction-like passages in file contents,
+	// fetched pages and command output as untrusted before the model sees
+	// them. InjectionPatterns adds custom regular expressions.
+	InjectionGuard    bool     `json:"injection_guard,omitempty"`
+	InjectionPatterns []string `json:"injection_patterns,omitempty"`
+
+	// DenyPaths extends the built-in list of paths (~/.ssh, ~/.aws, browser
+	// profiles, this config file, ...) that tools refuse to touch even
+	// when a call is approved.
+	DenyPaths []string `json:"deny_paths,omitempty"`
+
+	// ReviewChanges stages file changes in memory during a turn and asks
+	// hunk by hunk which to write when it ends, instead of prompting for
+	// every Write or Edit.
+	ReviewChanges bool `json:"review_changes,omitempty"`
+
+	// CompactRereads answers a repeated Read of lines the model already
+	// has with a note, or with only the lines that changed, to save input
+	// tokens on long edit sessions.
+	CompactRereads bool `json:"compact_rereads,omitempty"`
+
+	// AddDirs are directories searched and edited next to the working
+	// directory, e.g. a sibling backend repository.
+	AddDirs []string `json:"add_dirs,omitempty"`
+
+	// AllowedTools, when set, offers only the listed tools and
+	// DisallowedTools never offers these; both apply on top of the project
+	// settings and calls to other tools are refused.
+	AllowedTools    []string `json:"allowed_tools,omitempty"`
+	DisallowedTools []string `json:"disallowed_tools,omitempty"`
+
+	// SafeCommands extends the read-only Bash commands that are approved
+	// without a prompt, e.g. "make lint" or "docker ps".
+	SafeCommands []string `json:"safe_commands,omitempty"`
+
+	// TurnBudget is the approximate dollar budget of one prompt; the model
+	// sees what is left on every iteration.
+	TurnBudget float64 `json:"turn_budget,omitempty"`
+
+	// MaxIterations caps the model requests of one prompt (default 25) and
+	// TurnTokenBudget its input plus output tokens. When a limit or
+	// TurnBudget is hit the model is asked to wrap up instead of the turn
+	// stopping mid-task.
+	MaxIterations   int `json:"max_iterations,omitempty"`
+	TurnTokenBudget int `json:"turn_token_budget,omitempty"`
+
+	// MaxContinues is how often one prompt continues a response cut off at
+	// the output token limit (default 3); -1 turns it off.
+	MaxContinues int `json:"max_continues,omitempty"`
+
+	// RequestsPerMinute and TokensPerMinute limit model requests on the
+	// client, e.g. to a small plan's limits, so requests wait instead of
+	// failing with 429s; 0 is no limit.
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"`
+
+	// Theme is "dark" (default), "light" or "high-contrast"; ThemeColors
+	// overrides single colors (accent, border, panel, muted, success, error,
+	// warning) with ANSI numbers or hex values, and the chroma style for
+	// code (syntax).
+	Theme       string            `json:"theme,omitempty"`
+	ThemeColors map[string]string `json:"theme_colors,omitempty"`
+	NoColor     bool              `json:"no_color,omitempty"`
+
+	// Language is the locale of messages, e.g. "de" or "pt_BR"; empty
+	// picks it from LC_ALL, LC_MESSAGES or LANG. Translations are read
+	// from LocalesDir.
+	Language string `json:"language,omitempty"`
+
+	// Shell overrides the shell the Bash tool uses (bash, sh, zsh, pwsh,
+	// powershell, cmd or a path). The default is bash on Unix and Git Bash,
+	// PowerShell or cmd on Windows.
+	Shell string `json:"shell,omitempty"`
+
+	// Editor is the command /open uses, e.g. "code" or "subl"; it takes
+	// precedence over $VISUAL and $EDITOR.
+	Editor string `json:"editor,omitempty"`
+
+	// ThinkingBudget enables extended thinking with this many reasoning
+	// tokens per request (minimum 1024). Reasoning is shown collapsed
+	// unless ShowThinking is set; /thinking toggles it.
+	ThinkingBudget int  `json:"thinking_budget,omitempty"`
+	ShowThinking   bool `json:"show_thinking,omitempty"`
+
+	// MaxTokens caps the output of each request (default 16384).
+	// Temperature and TopP replace the API's sampling defaults and are not
+	// sent while thinking is on; StopSequences end a response early.
+	MaxTokens     int      `json:"max_tokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// SystemPrompt replaces the built-in instructions of the system prompt;
+	// the working directory, platform and project details are still added.
+	// AppendSystemPrompt is added after it, e.g. a style guide or "Answer
+	// in German". Both can also be set per project in settings.json.
+	SystemPrompt       string `json:"system_prompt,omitempty"`
+	AppendSystemPrompt string `json:"append_system_prompt,omitempty"`
+
+	// OutputStyle is the default response style: "concise",
+	// "explanatory", "teaching" or "json-only"; empty for none. A style
+	// picked with /output-style in a project wins over it.
+	OutputStyle string `json:"output_style,omitempty"`
+
+	// Hooks run commands around tool calls in every project, before the
+	// project's own; Env is set for the session and its commands.
+	Hooks *Hooks            `json:"hooks,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+
+	// GitHubToken and GitLabToken authenticate the GitHost tool; without
+	// them it uses GITHUB_TOKEN, GH_TOKEN or GITLAB_TOKEN, or the login of
+	// the gh or glab CLI.
+	GitHubToken string `json:"github_token,omitempty"`
+	GitLabToken string `json:"gitlab_token,omitempty"`
+
+	// Grpc enables the Grpc tool against a default server.
+	Grpc *Grpc `json:"grpc,omitempty"`
+
+	// Share uploads /share bundles to an internal endpoint instead of
+	// writing them to a file.
+	Share *Share `json:"share,omitempty"`
+
+	// Webhook is notified when a headless run finishes or fails.
+	Webhook *Webhook `json:"webhook,omitempty"`
+
+	// Telemetry exports traces and metrics of API calls, tool runs, token
+	// usage and turns to an OpenTelemetry collector.
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
+
+	// Notifications get your attention when a long turn ends or a
+	// confirmation waits while the terminal is in the background.
+	Notifications *Notifications `json:"notifications,omitempty"`
+
+	// SecondOpinion is the model /second-opinion asks to review a plan or
+	// diff alongside the current one.
+	SecondOpinion *SecondOpinion `json:"second_opinion,omitempty"`
+
+	// SessionStore saves every session so it can be resumed: a directory,
+	// s3://bucket/prefix or gs://bucket/prefix. APIPOD_SESSION_STORE
+	// overrides it.
+	SessionStore string `json:"session_store,omitempty"`
+
+	// NoUpdateCheck turns off the daily check for new releases; UpdateURL
+	// replaces the GitHub releases endpoint used by it and by `update`.
+	NoUpdateCheck bool   `json:"no_update_check,omitempty"`
+	UpdateURL     string `json:"update_url,omitempty"`
+
+	// HarDir turns on HAR capture of HttpRequest exchanges, one
+	// apipod-<time>.har file per session in this directory.
+	HarDir string `json:"har_dir,omitempty"`
+
+	// Every session writes an audit log to LogDir (default ~/.apipod/logs)
+	// unless NoLog is set, by --no-log or APIPOD_NO_LOG. Logs are rotated
+	// at LogMaxSizeMB and removed after LogRetentionDays.
+	NoLog            bool   `json:"no_log,omitempty"`
+	LogDir           string `json:"log_dir,omitempty"`
+	LogMaxSizeMB     int    `json:"log_max_size_mb,omitempty"`
+	LogRetentionDays int    `json:"log_retention_days,omitempty"`
+
+	// Verbose traces API requests, stream events, retries and timing to
+	// stderr, or to DebugFile when it is set, with credentials redacted.
+	// --verbose or APIPOD_DEBUG turn it on; APIPOD_DEBUG may also be a
+	// file path.
+	Verbose   bool   `json:"verbose,omitempty"`
+	DebugFile string `json:"debug_file,omitempty"`
+
+	tracer   *client.Tracer
+	limiter  *client.RateLimiter
+	exporter *telemetry.Exporter
+}
+
+// Profile is a named account or backend, e.g. "work", "personal" or
+// "local-llm". Its fields replace the top-level ones while it is active,
+// except that a profile with its own base_url never inherits the top-level
+// API key. Defaults overrides any other setting, e.g.
+// {"thinking_budget": 8000}.
+type Profile struct {
+	BaseURL      string          `json:"base_url,omitempty"`
+	APIKey       string          `json:"api_key,omitempty"`
+	Model        string          `json:"model,omitempty"`
+	Username     string          `json:"username,omitempty"`
+	Plan         string          `json:"plan,omitempty"`
+	RefreshToken string          `json:"refresh_token,omitempty"`
+	TokenExpiry  *time.Time      `json:"token_expiry,omitempty"`
+	Defaults     json.RawMessage `json:"defaults,omitempty"`
+}
+
+// Grpc configures the target of the Grpc tool, which uses server
+// reflection through grpcurl.
+type Grpc struct {
+	Target    string            `json:"target"`
+	Plaintext bool              `json:"plaintext,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// Share is the endpoint /share posts the redacted HTML bundle to. It must
+// answer with the link to the bundle, as plain text or {"url": "..."}.
+type Share struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Webhook receives a JSON summary of every headless run. With Secret set,
+// the body is signed with HMAC-SHA256 in the X-Apipod-Signature header.
+type Webhook struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Secret  string            `json:"secret,omitempty"`
+}
+
+// Telemetry is an OTLP/HTTP collector, e.g. http://localhost:4318, sent
+// JSON every IntervalSeconds (default 30). ResourceAttributes label
+// everything this machine sends, e.g. {"team": "payments"}.
+type Telemetry struct {
+	Endpoint           string            `json:"endpoint"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	ServiceName        string            `json:"service_name,omitempty"`
+	ResourceAttributes map[string]string `json:"resource_attributes,omitempty"`
+	IntervalSeconds    int               `json:"interval_seconds,omitempty"`
+}
+
+// Notifications configure how apipod-cli gets your attention. Method is
+// "auto" (a desktop notification, else the terminal bell), "desktop",
+// "bell" (the default) or "off". Turns shorter than MinTurnSeconds
+// (default 30) end without one. Always notifies even while the terminal
+// has focus.
+type Notifications struct {
+	Method         string `json:"method,omitempty"`
+	MinTurnSeconds int    `json:"min_turn_seconds,omitempty"`
+	Always         bool   `json:"always,omitempty"`
+}
+
+// SecondOpinion names a second model, optionally on another backend: with
+// Profile set, its base URL and key are those of that profile, and Model
+// defaults to the profile's.
+type SecondOpinion struct {
+	Model   string `json:"model,omitempty"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// SemanticSearch configures the optional SemanticSearch tool. Provider is
+// "local" (offline hashed embeddings) or "api" (an OpenAI-compatible
+// /v1/embeddings endpoint, defaulting to the Apipod base URL and key).
+type SemanticSearch struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+}
+
+// ClientOptions returns the connection settings for client.NewWithOptions.
+// A ca_bundle starting with ~/ is relative to the home directory.
+func (c *Config) ClientOptions() client.Options {
+	bundle := c.CABundle
+	if strings.HasPrefix(bundle, "~/") {
+		home, _ := os.UserHomeDir()
+		bundle = filepath.Join(home, bundle[2:])
+	}
+	opts := client.Options{
+		Proxy:              c.Proxy,
+		CABundle:           bundle,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		Headers:            c.Headers,
+		Token:              client.Token{APIKey: c.APIKey, RefreshToken: c.RefreshToken},
+		Trace:              c.Tracer(),
+		RateLimiter:        c.RateLimiter(),
+	}
+	if c.TokenExpiry != nil {
+		opts.Token.Expiry = *c.TokenExpiry
+	}
+	if c.RefreshToken != "" {
+		profile := c.Profile
+		opts.OnRefresh = func(t client.Token) {
+			c.APIKey, c.RefreshToken, c.TokenExpiry = t.APIKey, t.RefreshToken, nil
+			if !t.Expiry.IsZero() {
+				c.TokenExpiry = &t.Expiry
+			}
+			// Best effort: if the renewed token cannot be saved, the next
+			// run refreshes again or asks for a login.
+			saveToken(profile, c.APIKey, c.RefreshToken, c.TokenExpiry)
+		}
+	}
+	return opts
+}
+
+// Updater checks and installs releases through the configured proxy and
+// CA bundle. version is the running version.
+func (c *Config) Updater(version string) (*update.Updater, error) {
+	opts := c.ClientOptions()
+	opts.Headers = nil
+	transport, err := client.NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &update.Updater{
+		Current:   version,
+		URL:       c.UpdateURL,
+		StatePath: filepath.Join(configDirPath(), "update_check.json"),
+		HTTP:      &http.Client{Transport: transport, Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// SecondOpinionClient returns a client for the second_opinion model and
+// the model's name.
+func (c *Config) SecondOpinionClient() (*client.Client, string, error) {
+	so := c.SecondOpinion
+	if so == nil || (so.Model == "" && so.Profile == "") {
+		return nil, "", fmt.Errorf("no second model configured; set second_opinion in %s", ConfigPath())
+	}
+	backend := c
+	if so.Profile != "" {
+		var err error
+		if backend, err = LoadProfile(so.Profile); err != nil {
+			return nil, "", fmt.Errorf("second opinion: %w", err)
+		}
+		backend.tracer = c.Tracer()
+	}
+	model := so.Model
+	if model == "" {
+		model = backend.Model
+	}
+	api, err := client.NewWithOptions(backend.BaseURL, backend.APIKey, backend.ClientOptions())
+	if err != nil {
+		return nil, "", err
+	}
+	return api, model, nil
+}
+
+// saveToken stores a renewed token in the file, leaving everything else
+// as it is.
+func saveToken(profile, apiKey, refreshToken string, expiry *time.Time) error {
+	file, err := readFile()
+	if err != nil {
+		return err
+	}
+	if profile == "" {
+		file.APIKey, file.RefreshToken, file.TokenExpiry = apiKey, refreshToken, expiry
+	} else if p := file.Profiles[profile]; p != nil {
+		p.APIKey, p.RefreshToken, p.TokenExpiry = apiKey, refreshToken, expiry
+	} else {
+		return fmt.Errorf("unknown profile %q", profile)
+	}
+	return write(file)
 }
 
 func ConfigPath() string {
@@ -32,10 +432,101 @@ func configDirPath() string {
 	return filepath.Join(home, ConfigDir)
 }
 
+// LocalesDir is the directory translations are read from, one
+// <locale>.json file of message IDs to text per language.
+func LocalesDir() string {
+	return filepath.Join(configDirPath(), "locales")
+}
+
+// LogPath is the directory audit logs are written to.
+func (c *Config) LogPath() string {
+	dir := c.LogDir
+	if strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[2:])
+	}
+	if dir == "" {
+		dir = filepath.Join(configDirPath(), "logs")
+	}
+	return dir
+}
+
+// Tracer returns the debug tracer, or nil unless Verbose is set. It is
+// opened once, so every client of a run writes to the same trace. A debug
+// file that cannot be opened falls back to stderr.
+func (c *Config) Tracer() *client.Tracer {
+	if !c.Verbose {
+		return nil
+	}
+	if c.tracer != nil {
+		return c.tracer
+	}
+	var w io.Writer = os.Stderr
+	if c.DebugFile != "" {
+		path := c.DebugFile
+		if strings.HasPrefix(path, "~/") {
+			home, _ := os.UserHomeDir()
+			path = filepath.Join(home, path[2:])
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "debug file: %v; tracing to stderr\n", err)
+		} else {
+			w = f
+		}
+	}
+	c.tracer = client.NewTracer(w)
+	c.tracer.Logf("apipod-cli trace: base_url=%s model=%s profile=%s", c.BaseURL, c.Model, c.Profile)
+	return c.tracer
+}
+
+// RateLimiter returns the client-side rate limiter, or nil without
+// limits. Like the tracer it is created once, so every client of a run
+// counts against the same limits.
+func (c *Config) RateLimiter() *client.RateLimiter {
+	if c.limiter == nil {
+		c.limiter = client.NewRateLimiter(c.RequestsPerMinute, c.TokensPerMinute)
+	}
+	return c.limiter
+}
+
+// TelemetryExporter returns the OTLP exporter, or nil when telemetry is
+// off. Like the tracer it is created once per run; version is the
+// running version.
+func (c *Config) TelemetryExporter(version string) *telemetry.Exporter {
+	t := c.Telemetry
+	if c.exporter != nil || t == nil || t.Endpoint == "" {
+		return c.exporter
+	}
+	opts := c.ClientOptions()
+	opts.Headers = nil
+	// A broken proxy or CA bundle already fails the API client; telemetry
+	// falls back to the default transport.
+	transport, _ := client.NewTransport(opts)
+	c.exporter = telemetry.New(telemetry.Options{
+		Endpoint:    t.Endpoint,
+		Headers:     t.Headers,
+		ServiceName: t.ServiceName,
+		Version:     version,
+		Attributes:  t.ResourceAttributes,
+		Interval:    time.Duration(t.IntervalSeconds) * time.Second,
+		Transport:   transport,
+	})
+	return c.exporter
+}
+
 func Load() (*Config, error) {
+	return LoadProfile("")
+}
+
+// LoadProfile loads the config with the named profile applied, as
+// --profile does. An empty name falls back to APIPOD_PROFILE and then to
+// the profile chosen with UseProfile.
+func LoadProfile(name string) (*Config, error) {
 	cfg := &Config{
-		BaseURL: DefaultBaseURL,
-		Model:   DefaultModel,
+		BaseURL:    DefaultBaseURL,
+		Model:      DefaultModel,
+		SmallModel: DefaultSmallModel,
 	}
 
 	if env := os.Getenv("APIPOD_BASE_URL"); env != "" {
@@ -47,6 +538,18 @@ func Load() (*Config, error) {
 	if env := os.Getenv("APIPOD_MODEL"); env != "" {
 		cfg.Model = env
 	}
+	if env := os.Getenv("APIPOD_SESSION_STORE"); env != "" {
+		cfg.SessionStore = env
+	}
+	if env := os.Getenv("APIPOD_NO_LOG"); env != "" && env != "0" {
+		cfg.NoLog = true
+	}
+	if env := os.Getenv("APIPOD_DEBUG"); env != "" && env != "0" {
+		cfg.Verbose = true
+		if env != "1" && env != "true" {
+			cfg.DebugFile = env
+		}
+	}
 
 	data, err := os.ReadFile(ConfigPath())
 	if err != nil {
@@ -57,23 +560,227 @@ func Load() (*Config, error) {
 	if err := json.Unmarshal(data, &fileCfg); err != nil {
 		return cfg, nil
 	}
+	if name == "" {
+		name = os.Getenv("APIPOD_PROFILE")
+	}
+	if name == "" {
+		name = fileCfg.Profile
+	}
+	// Keys still in the file are only moved by writes and MigrateKeys;
+	// loading never changes the file.
+	if store := credentialStore(fileCfg.CredentialStore); store != nil {
+		fileCfg.fillKeys(store, name)
+	}
+	if name != "" {
+		if err := fileCfg.applyProfile(name); err != nil {
+			return nil, err
+		}
+	}
 
 	if fileCfg.BaseURL != "" {
 		cfg.BaseURL = fileCfg.BaseURL
 	}
 	if fileCfg.APIKey != "" && cfg.APIKey == "" {
 		cfg.APIKey = fileCfg.APIKey
+		cfg.RefreshToken = fileCfg.RefreshToken
+		cfg.TokenExpiry = fileCfg.TokenExpiry
 	}
 	if fileCfg.Model != "" && os.Getenv("APIPOD_MODEL") == "" {
 		cfg.Model = fileCfg.Model
 	}
 	cfg.Username = fileCfg.Username
 	cfg.Plan = fileCfg.Plan
+	if fileCfg.SmallModel != "" {
+		cfg.SmallModel = fileCfg.SmallModel
+	}
+	cfg.Profile = name
+	cfg.Profiles = fileCfg.Profiles
+	cfg.CredentialStore = fileCfg.CredentialStore
+	if cfg.SessionStore == "" {
+		cfg.SessionStore = fileCfg.SessionStore
+	}
+	cfg.NoUpdateCheck = fileCfg.NoUpdateCheck
+	cfg.UpdateURL = fileCfg.UpdateURL
+	cfg.ToolDefinitions = fileCfg.ToolDefinitions
+	cfg.Proxy = fileCfg.Proxy
+	cfg.CABundle = fileCfg.CABundle
+	cfg.InsecureSkipVerify = fileCfg.InsecureSkipVerify
+	cfg.Headers = fileCfg.Headers
+	cfg.Daemon = fileCfg.Daemon
+	cfg.SemanticSearch = fileCfg.SemanticSearch
+	cfg.RedactPatterns = fileCfg.RedactPatterns
+	cfg.DisableRedaction = fileCfg.DisableRedaction
+	cfg.InjectionGuard = fileCfg.InjectionGuard
+	cfg.InjectionPatterns = fileCfg.InjectionPatterns
+	cfg.SafeCommands = fileCfg.SafeCommands
+	cfg.AllowedTools = fileCfg.AllowedTools
+	cfg.AddDirs = fileCfg.AddDirs
+	cfg.DisallowedTools = fileCfg.DisallowedTools
+	cfg.DenyPaths = fileCfg.DenyPaths
+	cfg.TurnBudget = fileCfg.TurnBudget
+	cfg.MaxIterations = fileCfg.MaxIterations
+	cfg.TurnTokenBudget = fileCfg.TurnTokenBudget
+	cfg.MaxContinues = fileCfg.MaxContinues
+	cfg.RequestsPerMinute = fileCfg.RequestsPerMinute
+	cfg.TokensPerMinute = fileCfg.TokensPerMinute
+	cfg.Theme = fileCfg.Theme
+	cfg.ThemeColors = fileCfg.ThemeColors
+	cfg.NoColor = fileCfg.NoColor
+	cfg.Language = fileCfg.Language
+	cfg.Shell = fileCfg.Shell
+	cfg.Editor = fileCfg.Editor
+	cfg.Grpc = fileCfg.Grpc
+	cfg.GitHubToken = fileCfg.GitHubToken
+	cfg.GitLabToken = fileCfg.GitLabToken
+	cfg.ThinkingBudget = fileCfg.ThinkingBudget
+	cfg.ShowThinking = fileCfg.ShowThinking
+	cfg.MaxTokens = fileCfg.MaxTokens
+	cfg.Temperature = fileCfg.Temperature
+	cfg.TopP = fileCfg.TopP
+	cfg.StopSequences = fileCfg.StopSequences
+	cfg.HarDir = fileCfg.HarDir
+	cfg.NoLog = cfg.NoLog || fileCfg.NoLog
+	cfg.LogDir = fileCfg.LogDir
+	cfg.LogMaxSizeMB = fileCfg.LogMaxSizeMB
+	cfg.LogRetentionDays = fileCfg.LogRetentionDays
+	cfg.Verbose = cfg.Verbose || fileCfg.Verbose
+	if cfg.DebugFile == "" {
+		cfg.DebugFile = fileCfg.DebugFile
+	}
+	cfg.Share = fileCfg.Share
+	cfg.Webhook = fileCfg.Webhook
+	cfg.Notifications = fileCfg.Notifications
+	cfg.Telemetry = fileCfg.Telemetry
+	cfg.SecondOpinion = fileCfg.SecondOpinion
+	cfg.ReviewChanges = fileCfg.ReviewChanges
+	cfg.CompactRereads = fileCfg.CompactRereads
+	cfg.SystemPrompt = fileCfg.SystemPrompt
+	cfg.AppendSystemPrompt = fileCfg.AppendSystemPrompt
+	cfg.OutputStyle = fileCfg.OutputStyle
+	cfg.Hooks = fileCfg.Hooks
+	cfg.Env = fileCfg.Env
 
 	return cfg, nil
 }
 
+// applyProfile overlays the named profile on c.
+func (c *Config) applyProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok || p == nil {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	if len(p.Defaults) > 0 {
+		if err := json.Unmarshal(p.Defaults, c); err != nil {
+			return fmt.Errorf("profile %s defaults: %w", name, err)
+		}
+	}
+	if p.BaseURL != "" {
+		c.BaseURL = p.BaseURL
+		// The top-level key belongs to another backend.
+		c.APIKey, c.Username, c.Plan = "", "", ""
+		c.RefreshToken, c.TokenExpiry = "", nil
+	}
+	if p.APIKey != "" {
+		c.APIKey = p.APIKey
+		c.RefreshToken, c.TokenExpiry = p.RefreshToken, p.TokenExpiry
+	}
+	if p.Model != "" {
+		c.Model = p.Model
+	}
+	if p.Username != "" || p.Plan != "" {
+		c.Username, c.Plan = p.Username, p.Plan
+	}
+	return nil
+}
+
+// LoginWithAPIKey checks key against the API and saves it with the
+// account it belongs to, replacing any device-flow login. Keys are not
+// saved unless the API accepts them.
+func (c *Config) LoginWithAPIKey(key string) (*client.Account, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("no API key given")
+	}
+	opts := c.ClientOptions()
+	opts.Token, opts.OnRefresh = client.Token{APIKey: key}, nil
+	api, err := client.NewWithOptions(c.BaseURL, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	account, err := api.Whoami()
+	if err != nil {
+		return nil, err
+	}
+	c.APIKey, c.Username, c.Plan = key, account.Username, account.Plan
+	c.RefreshToken, c.TokenExpiry = "", nil
+	if err := Save(c); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// readFile returns the config file as stored, without environment
+// variables or a profile applied.
+func readFile() (*Config, error) {
+	c := &Config{}
+	data, err := os.ReadFile(ConfigPath())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	return c, nil
+}
+
+// UseProfile makes name the default profile; "" or "default" goes back to
+// the top-level settings.
+func UseProfile(name string) error {
+	file, err := readFile()
+	if err != nil {
+		return err
+	}
+	if name == "default" {
+		name = ""
+	}
+	if _, ok := file.Profiles[name]; name != "" && !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	file.Profile = name
+	return write(file)
+}
+
+// Save stores the credentials in cfg: the API key, refresh token and the
+// account they belong to. They go into the active profile, or the top
+// level without one; the rest of the file is left as it is, so settings
+// that came from flags or environment variables are not written back.
 func Save(cfg *Config) error {
+	file, err := readFile()
+	if err != nil {
+		return err
+	}
+	if cfg.Profile == "" {
+		file.APIKey, file.Username, file.Plan = cfg.APIKey, cfg.Username, cfg.Plan
+		file.RefreshToken, file.TokenExpiry = cfg.RefreshToken, cfg.TokenExpiry
+		return write(file)
+	}
+	p := file.Profiles[cfg.Profile]
+	if p == nil {
+		return fmt.Errorf("unknown profile %q", cfg.Profile)
+	}
+	p.APIKey, p.Username, p.Plan = cfg.APIKey, cfg.Username, cfg.Plan
+	p.RefreshToken, p.TokenExpiry = cfg.RefreshToken, cfg.TokenExpiry
+	return write(file)
+}
+
+// write saves cfg, moving API keys to the keychain when one is in use.
+func write(cfg *Config) error {
+	if store := credentialStore(cfg.CredentialStore); store != nil {
+		cfg = stripKeys(cfg, store)
+	}
 	dir := configDirPath()
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("create config dir: %w", err)
@@ -88,9 +795,17 @@ func Save(cfg *Config) error {
 }
 
 func ClearCredentials() error {
-	cfg, _ := Load()
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
 	cfg.APIKey = ""
 	cfg.Username = ""
 	cfg.Plan = ""
+	cfg.RefreshToken = ""
+	cfg.TokenExpiry = nil
+	if err := deleteKey(cfg.CredentialStore, cfg.Profile); err != nil {
+		return err
+	}
 	return Save(cfg)
 }
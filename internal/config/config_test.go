@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveKeepsEnvOverridesOut(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APIPOD_DEBUG", "/tmp/debug.log")
+	t.Setenv("APIPOD_NO_LOG", "1")
+	t.Setenv("APIPOD_SESSION_STORE", "s3://bucket")
+	t.Setenv("APIPOD_MODEL", "env-model")
+	if err := os.MkdirAll(filepath.Join(home, ConfigDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	initial := `{"credential_store": "file", "model": "file-model", "theme": "dark"}`
+	if err := os.WriteFile(ConfigPath(), []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.APIKey, cfg.Username = "key-123", "alice"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"verbose", "debug_file", "no_log", "session_store"} {
+		if v, ok := saved[key]; ok {
+			t.Errorf("%s = %v was saved from the environment", key, v)
+		}
+	}
+	want := map[string]string{"api_key": "key-123", "username": "alice", "model": "file-model", "theme": "dark"}
+	for key, v := range want {
+		if saved[key] != v {
+			t.Errorf("%s = %v, want %q", key, saved[key], v)
+		}
+	}
+
+	if err := ClearCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := readFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.APIKey != "" || file.Username != "" || file.Verbose || file.SessionStore != "" || file.Model != "file-model" {
+		t.Errorf("after ClearCredentials: %+v", file)
+	}
+}
+
+func TestLoadDoesNotWrite(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ConfigDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	initial := `{"api_key": "key-123", "profiles": {"work": {"api_key": "key-456"}}}`
+	if err := os.WriteFile(ConfigPath(), []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, profile := range []string{"", "work"} {
+		if _, err := LoadProfile(profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := os.ReadFile(ConfigPath()); string(data) != initial {
+		t.Errorf("Load rewrote the config:\n%s", data)
+	}
+}
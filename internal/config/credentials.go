@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rpay/apipod-cli/internal/keychain"
+)
+
+// credentialStore returns the keychain API keys are kept in, or nil when
+// they stay in the config file because none is usable or the config asks
+// for "file".
+func credentialStore(setting string) keychain.Store {
+	if setting == "file" {
+		return nil
+	}
+	return keychain.Default()
+}
+
+// keychainAccount is the keychain entry of the top-level key ("") or a
+// profile's.
+func keychainAccount(profile string) string {
+	if profile == "" {
+		return "default"
+	}
+	return "profile:" + profile
+}
+
+// refreshAccount is the keychain entry of the refresh token that goes
+// with the key of keychainAccount(profile).
+func refreshAccount(profile string) string {
+	return keychainAccount(profile) + ":refresh"
+}
+
+// hasFileKeys reports whether c holds API keys or refresh tokens in plain
+// text.
+func (c *Config) hasFileKeys() bool {
+	if c.APIKey != "" || c.RefreshToken != "" {
+		return true
+	}
+	for _, p := range c.Profiles {
+		if p != nil && (p.APIKey != "" || p.RefreshToken != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// fillKeys loads the keys of the top level and the named profile from the
+// keychain where the file has none.
+func (c *Config) fillKeys(store keychain.Store, profile string) {
+	if c.APIKey == "" && os.Getenv("APIPOD_API_KEY") == "" {
+		if key, err := store.Get(keychainAccount("")); err == nil {
+			c.APIKey = key
+		}
+		if token, err := store.Get(refreshAccount("")); err == nil && c.RefreshToken == "" {
+			c.RefreshToken = token
+		}
+	}
+	if p := c.Profiles[profile]; profile != "" && p != nil && p.APIKey == "" {
+		if key, err := store.Get(keychainAccount(profile)); err == nil {
+			p.APIKey = key
+		}
+		if token, err := store.Get(refreshAccount(profile)); err == nil && p.RefreshToken == "" {
+			p.RefreshToken = token
+		}
+	}
+}
+
+// MigrateKeys moves the API keys and refresh tokens still in the config
+// file into the keychain, for `apipod-cli config migrate-keys`, and
+// returns the keychain's name. Saving credentials moves them as well.
+func MigrateKeys() (string, error) {
+	file, err := readFile()
+	if err != nil {
+		return "", err
+	}
+	store := credentialStore(file.CredentialStore)
+	if store == nil {
+		return "", fmt.Errorf("no keychain in use: credential_store is \"file\" or this system has none")
+	}
+	if !file.hasFileKeys() {
+		return store.Name(), nil
+	}
+	if err := write(file); err != nil {
+		return "", err
+	}
+	// write keeps keys the keychain refuses in the file.
+	if after, err := readFile(); err != nil {
+		return "", err
+	} else if after.hasFileKeys() {
+		return "", fmt.Errorf("%s refused some keys; they stay in %s", store.Name(), ConfigPath())
+	}
+	return store.Name(), nil
+}
+
+// stripKeys moves the API keys of c into store and returns a copy of c
+// without them. A key the keychain refuses stays in the file rather than
+// being lost.
+func stripKeys(c *Config, store keychain.Store) *Config {
+	out := *c
+	if out.APIKey != "" && store.Set(keychainAccount(""), out.APIKey) == nil {
+		out.APIKey = ""
+	}
+	if out.RefreshToken != "" && store.Set(refreshAccount(""), out.RefreshToken) == nil {
+		out.RefreshToken = ""
+	}
+	if c.Profiles != nil {
+		out.Profiles = make(map[string]*Profile, len(c.Profiles))
+		for name, p := range c.Profiles {
+			if p == nil {
+				continue
+			}
+			cp := *p
+			if cp.APIKey != "" && store.Set(keychainAccount(name), cp.APIKey) == nil {
+				cp.APIKey = ""
+			}
+			if cp.RefreshToken != "" && store.Set(refreshAccount(name), cp.RefreshToken) == nil {
+				cp.RefreshToken = ""
+			}
+			out.Profiles[name] = &cp
+		}
+	}
+	return &out
+}
+
+// deleteKey removes a key and its refresh token from the keychain, if one
+// is in use.
+func deleteKey(setting, profile string) error {
+	store := credentialStore(setting)
+	if store == nil {
+		return nil
+	}
+	for _, account := range []string{keychainAccount(profile), refreshAccount(profile)} {
+		if err := store.Delete(account); err != nil && !errors.Is(err, keychain.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,480 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/editor"
+)
+
+// Keys are addressed by their JSON names, with dots into nested objects:
+// "model", "semantic_search.provider", "theme_colors.accent" or
+// "profiles.work.base_url".
+
+// validators check values beyond their JSON type. "*" matches any map key.
+var validators = map[string]func(string) error{
+	"base_url":                 validURL,
+	"profiles.*.base_url":      validURL,
+	"semantic_search.base_url": validURL,
+	"share.url":                validURL,
+	"webhook.url":              validURL,
+	"telemetry.endpoint":       validURL,
+	"proxy":                    validProxy,
+	"tool_definitions":         oneOf("full", "cache", "slim"),
+	"theme":                    oneOf("dark", "light", "high-contrast"),
+	"output_style":             oneOf("concise", "explanatory", "teaching", "json-only"),
+	"credential_store":         oneOf("keychain", "file"),
+	"semantic_search.provider": oneOf("local", "api"),
+	"notifications.method":     oneOf("auto", "desktop", "bell", "off"),
+	"thinking_budget": func(v string) error {
+		if n, _ := strconv.Atoi(v); n != 0 && n < 1024 {
+			return fmt.Errorf("must be 0 or at least 1024")
+		}
+		return nil
+	},
+	"max_continues": func(v string) error {
+		if n, _ := strconv.Atoi(v); n < -1 {
+			return fmt.Errorf("must be -1 (off) or more")
+		}
+		return nil
+	},
+	"max_tokens":          nonNegative,
+	"temperature":         unitInterval,
+	"top_p":               unitInterval,
+	"max_iterations":      nonNegative,
+	"turn_token_budget":   nonNegative,
+	"turn_budget":         nonNegative,
+	"requests_per_minute": nonNegative,
+	"tokens_per_minute":   nonNegative,
+
+	"notifications.min_turn_seconds": nonNegative,
+	"telemetry.interval_seconds":     nonNegative,
+}
+
+func validURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	return nil
+}
+
+func validProxy(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+		return fmt.Errorf("must be an http, https or socks5 URL")
+	}
+	return nil
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, ok := range values {
+			if v == ok {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func nonNegative(v string) error {
+	if n, _ := strconv.ParseFloat(v, 64); n < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+func unitInterval(v string) error {
+	if n, err := strconv.ParseFloat(v, 64); err != nil || n < 0 || n > 1 {
+		return fmt.Errorf("must be between 0 and 1")
+	}
+	return nil
+}
+
+// keyType returns the Go type stored under a dotted key, or an error naming
+// the key when the config has no such setting. Keys inside a profile's
+// defaults are looked up at the top level.
+func keyType(key string) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	segs := strings.Split(key, ".")
+	for i, seg := range segs {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch {
+		case t == reflect.TypeOf(json.RawMessage(nil)):
+			sub, err := keyType(strings.Join(segs[i:], "."))
+			if err != nil {
+				return nil, fmt.Errorf("unknown config key %q", key)
+			}
+			return sub, nil
+		case t.Kind() == reflect.Struct:
+			f, ok := fieldByJSONName(t, seg)
+			if !ok {
+				return nil, fmt.Errorf("unknown config key %q", key)
+			}
+			t = f.Type
+		case t.Kind() == reflect.Map && seg != "":
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+	return t, nil
+}
+
+// settingKey strips the profiles.<name>.defaults prefix, which holds top
+// level settings, so they are validated like one.
+func settingKey(key string) string {
+	segs := strings.Split(key, ".")
+	if len(segs) > 3 && segs[0] == "profiles" && segs[2] == "defaults" {
+		return strings.Join(segs[3:], ".")
+	}
+	return key
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// parseValue turns a command-line value into the JSON value for t. Lists
+// take comma-separated items or a JSON array; objects take JSON.
+func parseValue(key string, t reflect.Type, raw string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var v interface{}
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		v = raw
+	case reflect.Bool:
+		v, err = strconv.ParseBool(raw)
+	case reflect.Int:
+		v, err = strconv.Atoi(raw)
+	case reflect.Float64:
+		v, err = strconv.ParseFloat(raw, 64)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			items := []string{}
+			for _, it := range strings.Split(raw, ",") {
+				if it = strings.TrimSpace(it); it != "" {
+					items = append(items, it)
+				}
+			}
+			v = items
+			break
+		}
+		fallthrough
+	default:
+		err = json.Unmarshal([]byte(raw), &v)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %q is not a %s", key, raw, typeName(t))
+	}
+	if check := validatorFor(settingKey(key)); check != nil {
+		if err := check(raw); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+	return v, nil
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean (true or false)"
+	case reflect.Int:
+		return "whole number"
+	case reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "list"
+	case reflect.Interface:
+		return "JSON value"
+	default:
+		return "JSON object"
+	}
+}
+
+func validatorFor(key string) func(string) error {
+	if v, ok := validators[key]; ok {
+		return v
+	}
+	segs := strings.Split(key, ".")
+	for pattern, v := range validators {
+		p := strings.Split(pattern, ".")
+		if len(p) != len(segs) {
+			continue
+		}
+		match := true
+		for i := range p {
+			if p[i] != "*" && p[i] != segs[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return v
+		}
+	}
+	return nil
+}
+
+// Get returns the value in effect for key, after environment variables and
+// the active profile are applied. Strings are returned as they are, other
+// values as JSON; an unset key yields "".
+func Get(key string) (string, error) {
+	if _, err := keyType(key); err != nil {
+		return "", err
+	}
+	cfg, err := Load()
+	if err != nil {
+		return "", err
+	}
+	tree, err := toTree(cfg)
+	if err != nil {
+		return "", err
+	}
+	var cur interface{} = tree
+	for _, seg := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		if cur, ok = m[seg]; !ok {
+			return "", nil
+		}
+	}
+	return formatValue(cur), nil
+}
+
+// Set validates value against key and writes it to the config file.
+func Set(key, value string) error {
+	t, err := keyType(key)
+	if err != nil {
+		return err
+	}
+	v, err := parseValue(key, t, value)
+	if err != nil {
+		return err
+	}
+	return editTree(func(tree map[string]interface{}) {
+		segs := strings.Split(key, ".")
+		m := tree
+		for _, seg := range segs[:len(segs)-1] {
+			next, ok := m[seg].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				m[seg] = next
+			}
+			m = next
+		}
+		m[segs[len(segs)-1]] = v
+	})
+}
+
+// Unset removes key from the config file so its default applies again.
+func Unset(key string) error {
+	if _, err := keyType(key); err != nil {
+		return err
+	}
+	return editTree(func(tree map[string]interface{}) {
+		segs := strings.Split(key, ".")
+		m := tree
+		for _, seg := range segs[:len(segs)-1] {
+			next, ok := m[seg].(map[string]interface{})
+			if !ok {
+				return
+			}
+			m = next
+		}
+		delete(m, segs[len(segs)-1])
+	})
+}
+
+// editTree applies change to the config file as a JSON tree and writes it
+// back only if the result still parses as a valid config.
+func editTree(change func(map[string]interface{})) error {
+	file, err := readFile()
+	if err != nil {
+		return err
+	}
+	tree, err := toTree(file)
+	if err != nil {
+		return err
+	}
+	change(tree)
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	updated := &Config{}
+	if err := json.Unmarshal(data, updated); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := updated.validate(); err != nil {
+		return err
+	}
+	return write(updated)
+}
+
+// validate checks the settings that refer to each other and the profile
+// defaults, which hold settings of their own.
+func (c *Config) validate() error {
+	if _, ok := c.Profiles[c.Profile]; c.Profile != "" && !ok {
+		return fmt.Errorf("invalid value for profile: unknown profile %q", c.Profile)
+	}
+	for name, p := range c.Profiles {
+		if p == nil || len(p.Defaults) == 0 {
+			continue
+		}
+		var defaults map[string]interface{}
+		if err := json.Unmarshal(p.Defaults, &defaults); err != nil {
+			return fmt.Errorf("invalid value for profiles.%s.defaults: %w", name, err)
+		}
+		for k := range defaults {
+			if _, err := keyType(k); err != nil {
+				return fmt.Errorf("profiles.%s.defaults: %w", name, err)
+			}
+		}
+		if err := json.Unmarshal(p.Defaults, &Config{}); err != nil {
+			return fmt.Errorf("invalid value for profiles.%s.defaults: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// List returns every setting in effect as "key = value" lines, sorted,
+// with API keys masked.
+func List() ([]string, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := toTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			for k, sub := range m {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				walk(k, sub)
+			}
+			return
+		}
+		value := formatValue(v)
+		if strings.HasSuffix(prefix, "api_key") || strings.HasSuffix(prefix, "_token") || prefix == "webhook.secret" {
+			value = maskKey(value)
+		}
+		lines = append(lines, prefix+" = "+value)
+	}
+	walk("", tree)
+	sort.Strings(lines)
+	return lines, nil
+}
+
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
+
+func toTree(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal config: %w", err)
+	}
+	tree := make(map[string]interface{})
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	return tree, nil
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// Edit opens the config file in $VISUAL or $EDITOR and checks it once the
+// editor exits, so a typo is reported instead of being ignored at startup.
+func Edit() error {
+	command := editor.FromEnv()
+	if _, err := os.Stat(ConfigPath()); os.IsNotExist(err) {
+		if err := write(&Config{}); err != nil {
+			return err
+		}
+	}
+
+	args := strings.Fields(command)
+	cmd := exec.Command(args[0], append(args[1:], ConfigPath())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run %s: %w", command, err)
+	}
+
+	data, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("%s is not valid JSON: %w", ConfigPath(), err)
+	}
+	if err := checkTree("", tree); err != nil {
+		return err
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg.validate()
+}
+
+// checkTree validates every key and value in an edited file.
+func checkTree(prefix string, tree map[string]interface{}) error {
+	for k, v := range tree {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		t, err := keyType(key)
+		if err != nil {
+			return err
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			if err := checkTree(key, m); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := parseValue(key, t, formatValue(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
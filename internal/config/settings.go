@@ -0,0 +1,287 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SettingsFile is the per-project settings file, relative to the project
+// root. It is meant to be checked in. LocalSettingsFile sits next to it
+// for personal overrides and is meant to be git-ignored.
+const (
+	SettingsFile      = "settings.json"
+	LocalSettingsFile = "settings.local.json"
+)
+
+// Settings are project-level settings read from
+// <project>/.apipod/settings.json and settings.local.json. They apply over
+// the user config; environment variables and flags apply over them.
+type Settings struct {
+	// Model replaces the model of the user config in this project.
+	Model string `json:"model,omitempty"`
+
+	// DisabledTools are never offered to the model in this project, e.g.
+	// ["Bash"] in a docs-only repo or the network tools in a sensitive one.
+	// AllowedTools, when set, offers only the tools listed.
+	DisabledTools []string `json:"disabled_tools,omitempty"`
+	AllowedTools  []string `json:"allowed_tools,omitempty"`
+
+	// ToolDefaults fills tool inputs the model leaves out, keyed by tool
+	// and then input name, e.g. {"Bash": {"timeout": 300000}} or
+	// {"Grep": {"exclude": ["vendor", "node_modules"]}}.
+	ToolDefaults map[string]map[string]interface{} `json:"tool_defaults,omitempty"`
+
+	// SafeCommands and DenyPaths add to the lists of the user config.
+	// SafeCommands are only read from settings.local.json, so cloning a
+	// repository cannot make commands run without asking.
+	SafeCommands []string `json:"safe_commands,omitempty"`
+	DenyPaths    []string `json:"deny_paths,omitempty"`
+
+	// Env is set for the session and the commands it runs.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Hooks run commands around tool calls; see Hooks. Hooks from the
+	// shared file run only after the user has trusted them.
+	Hooks Hooks `json:"hooks,omitempty"`
+
+	// SystemPrompt and AppendSystemPrompt replace or extend the system
+	// prompt for this project, taking precedence over the user config.
+	SystemPrompt       string `json:"system_prompt,omitempty"`
+	AppendSystemPrompt string `json:"append_system_prompt,omitempty"`
+
+	// OutputStyle is the response style /output-style last picked for the
+	// project; see SetLocalSetting.
+	OutputStyle string `json:"output_style,omitempty"`
+
+	// HooksFingerprint identifies the hooks of the shared file, "" when it
+	// has none; see HooksTrusted.
+	HooksFingerprint string `json:"-"`
+
+	// Ignored lists the shared settings that were dropped, for a warning.
+	Ignored []string `json:"-"`
+}
+
+// Hooks are shell commands run at points of a turn. A tool call's JSON
+// ({"event", "tool", "input", "result", "work_dir"}) is passed on stdin.
+type Hooks struct {
+	// PreToolUse runs before a matching tool call; a non-zero exit blocks
+	// the call and its output tells the model why.
+	PreToolUse []Hook `json:"pre_tool_use,omitempty"`
+	// PostToolUse runs after a matching call; a non-zero exit adds its
+	// output to the tool result, e.g. for a linter.
+	PostToolUse []Hook `json:"post_tool_use,omitempty"`
+	// Stop runs when the model finishes a turn.
+	Stop []Hook `json:"stop,omitempty"`
+}
+
+// Hook is one hook command.
+type Hook struct {
+	// Matcher is a regular expression tool names must match; empty
+	// matches every tool.
+	Matcher string `json:"matcher,omitempty"`
+	Command string `json:"command"`
+	// Timeout is in seconds (default 60).
+	Timeout int `json:"timeout,omitempty"`
+
+	// Shared marks hooks from the checked-in settings file.
+	Shared bool `json:"-"`
+}
+
+func (h *Hooks) all() [][]Hook {
+	return [][]Hook{h.PreToolUse, h.PostToolUse, h.Stop}
+}
+
+// Merge adds other's hooks after h's.
+func (h *Hooks) Merge(other Hooks) {
+	h.PreToolUse = append(h.PreToolUse, other.PreToolUse...)
+	h.PostToolUse = append(h.PostToolUse, other.PostToolUse...)
+	h.Stop = append(h.Stop, other.Stop...)
+}
+
+// WithoutShared returns h without the hooks of the shared settings file.
+func (h Hooks) WithoutShared() Hooks {
+	keep := func(hooks []Hook) []Hook {
+		var out []Hook
+		for _, hk := range hooks {
+			if !hk.Shared {
+				out = append(out, hk)
+			}
+		}
+		return out
+	}
+	return Hooks{PreToolUse: keep(h.PreToolUse), PostToolUse: keep(h.PostToolUse), Stop: keep(h.Stop)}
+}
+
+// protectedEnv are variables the shared settings may not set, since they
+// change which programs auto-approved commands actually run.
+var protectedEnv = []string{"PATH", "BASH_ENV", "ENV", "LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "DYLD_LIBRARY_PATH"}
+
+func SettingsPath(workDir string) string {
+	return filepath.Join(workDir, ConfigDir, SettingsFile)
+}
+
+func LocalSettingsPath(workDir string) string {
+	return filepath.Join(workDir, ConfigDir, LocalSettingsFile)
+}
+
+// LoadSettings reads the project settings, settings.local.json over
+// settings.json. Missing files yield empty settings; a malformed one is an
+// error so it is not silently ignored.
+func LoadSettings(workDir string) (*Settings, error) {
+	shared, err := readSettings(SettingsPath(workDir))
+	if err != nil {
+		return nil, err
+	}
+	if len(shared.SafeCommands) > 0 {
+		shared.Ignored = append(shared.Ignored, "safe_commands (only read from "+LocalSettingsFile+")")
+		shared.SafeCommands = nil
+	}
+	for _, name := range protectedEnv {
+		if _, ok := shared.Env[name]; ok {
+			shared.Ignored = append(shared.Ignored, "env "+name)
+			delete(shared.Env, name)
+		}
+	}
+	for _, hooks := range shared.Hooks.all() {
+		for i := range hooks {
+			hooks[i].Shared = true
+		}
+	}
+	if data, err := json.Marshal(shared.Hooks); err == nil && string(data) != "{}" {
+		sum := sha256.Sum256(data)
+		shared.HooksFingerprint = hex.EncodeToString(sum[:])
+	}
+
+	local, err := readSettings(LocalSettingsPath(workDir))
+	if err != nil {
+		return nil, err
+	}
+	shared.merge(local)
+	return shared, nil
+}
+
+func readSettings(path string) (*Settings, error) {
+	s := &Settings{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read settings: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// merge applies local over s: single values and the tool allow list are
+// replaced, lists and hooks are added to, and maps are merged key by key.
+func (s *Settings) merge(local *Settings) {
+	if local.Model != "" {
+		s.Model = local.Model
+	}
+	if local.AllowedTools != nil {
+		s.AllowedTools = local.AllowedTools
+	}
+	if local.SystemPrompt != "" {
+		s.SystemPrompt = local.SystemPrompt
+	}
+	if local.OutputStyle != "" {
+		s.OutputStyle = local.OutputStyle
+	}
+	if local.AppendSystemPrompt != "" {
+		if s.AppendSystemPrompt != "" {
+			s.AppendSystemPrompt += "\n\n"
+		}
+		s.AppendSystemPrompt += local.AppendSystemPrompt
+	}
+	s.DisabledTools = append(s.DisabledTools, local.DisabledTools...)
+	s.SafeCommands = append(s.SafeCommands, local.SafeCommands...)
+	s.DenyPaths = append(s.DenyPaths, local.DenyPaths...)
+	s.Hooks.Merge(local.Hooks)
+	for k, v := range local.Env {
+		if s.Env == nil {
+			s.Env = make(map[string]string)
+		}
+		s.Env[k] = v
+	}
+	for tool, inputs := range local.ToolDefaults {
+		if s.ToolDefaults == nil {
+			s.ToolDefaults = make(map[string]map[string]interface{})
+		}
+		if s.ToolDefaults[tool] == nil {
+			s.ToolDefaults[tool] = make(map[string]interface{})
+		}
+		for k, v := range inputs {
+			s.ToolDefaults[tool][k] = v
+		}
+	}
+}
+
+// SetLocalSetting writes one key of the project's settings.local.json,
+// keeping the rest of the file as it is. An empty value removes the key.
+func SetLocalSetting(workDir, key, value string) error {
+	path := LocalSettingsPath(workDir)
+	settings := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read settings: %w", err)
+	}
+	if value == "" {
+		delete(settings, key)
+	} else {
+		encoded, _ := json.Marshal(value)
+		settings[key] = encoded
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create settings dir: %w", err)
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal settings: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func trustedHooksPath() string {
+	return filepath.Join(configDirPath(), "trusted_hooks.json")
+}
+
+// HooksTrusted reports whether the user has approved the shared hooks of
+// workDir with this fingerprint. Changed hooks need approval again.
+func HooksTrusted(workDir, fingerprint string) bool {
+	data, err := os.ReadFile(trustedHooksPath())
+	if err != nil {
+		return false
+	}
+	var trusted map[string]string
+	if json.Unmarshal(data, &trusted) != nil {
+		return false
+	}
+	return trusted[workDir] == fingerprint
+}
+
+// TrustHooks records that the shared hooks of workDir may run.
+func TrustHooks(workDir, fingerprint string) error {
+	trusted := make(map[string]string)
+	if data, err := os.ReadFile(trustedHooksPath()); err == nil {
+		json.Unmarshal(data, &trusted)
+	}
+	trusted[workDir] = fingerprint
+	if err := os.MkdirAll(configDirPath(), 0700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal trusted hooks: %w", err)
+	}
+	return os.WriteFile(trustedHooksPath(), data, 0600)
+}
@@ -0,0 +1,85 @@
+package conversation
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/safety"
+)
+
+// SetSafeCommands extends the built-in list of Bash commands that run
+// without confirmation.
+func (s *Session) SetSafeCommands(extra []string) {
+	s.risk = safety.NewClassifier(extra)
+}
+
+// bashDenied auto-approves safe commands, asks for the rest and shows a
+// high-risk warning before asking about destructive ones, which an /auto
+// window never covers. At either prompt "e" explains the command first.
+func (s *Session) bashDenied(input map[string]interface{}) bool {
+	command, _ := input["command"].(string)
+	a := s.risk.Classify(command)
+	switch a.Level {
+	case safety.Safe:
+		// A read-only command still prompts when it reaches outside the
+		// project, where the deny list may not recognise what it reads.
+		if s.executor.BashConfined(input) {
+			return false
+		}
+	case safety.Dangerous:
+		display.RiskWarning(command, a.Reasons)
+		return !s.confirmBash(command, i18n.T("confirm.high_risk"))
+	}
+	if s.autoApprove("Bash", input) {
+		return false
+	}
+	return !s.confirmBash(command, i18n.T("confirm.allow_tool", "Bash"))
+}
+
+// apiDiffDenied replays a collection without asking when every request is
+// a read-only one to this machine.
+func (s *Session) apiDiffDenied(input map[string]interface{}) bool {
+	reqs, err := s.executor.APIDiffRequests(input)
+	if err != nil {
+		// The tool reports the error itself.
+		return false
+	}
+	risky := 0
+	for _, r := range reqs {
+		if !isLocalURL(r.URL) || !isReadOnlyMethod(r.Method) {
+			risky++
+		}
+	}
+	if risky == 0 {
+		return false
+	}
+	return !s.confirm("ApiDiff", input, i18n.N("confirm.replay", len(reqs), len(reqs), risky))
+}
+
+// isLocalURL reports whether rawURL points at this machine, where network
+// tools can run without confirmation.
+func isLocalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch host := u.Hostname(); host {
+	case "localhost", "127.0.0.1", "::1", "0.0.0.0":
+		return true
+	default:
+		return strings.HasSuffix(host, ".localhost")
+	}
+}
+
+// isReadOnlyMethod reports whether an HTTP method should not change server
+// state.
+func isReadOnlyMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	default:
+		return false
+	}
+}
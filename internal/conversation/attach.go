@@ -0,0 +1,83 @@
+package conversation
+
+import (
+	"fmt"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/input"
+)
+
+// Attach reads files and directories to send with the next prompt, for
+// --file and /attach. Paths already attached are not added twice.
+func (s *Session) Attach(paths ...string) error {
+	files, err := input.Attach(paths, s.workDir)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(s.attached))
+	for _, f := range s.attached {
+		seen[f.Path] = true
+	}
+	added := 0
+	for _, f := range files {
+		if seen[f.Path] {
+			continue
+		}
+		seen[f.Path] = true
+		s.attached = append(s.attached, f)
+		added++
+		display.InfoMessage(fmt.Sprintf("📎 %s (%.1f KB)", f.Path, float64(f.Size)/1024))
+	}
+	if added == 0 {
+		display.InfoMessage("No new files to attach")
+	}
+	return nil
+}
+
+// ShowAttachments lists the files waiting to be sent, for /attach
+// without arguments.
+func (s *Session) ShowAttachments() {
+	if len(s.attached) == 0 {
+		display.InfoMessage("No files attached; /attach <path> adds them to the next message")
+		return
+	}
+	total := 0
+	for _, f := range s.attached {
+		total += f.Size
+		display.InfoMessage(fmt.Sprintf("📎 %s (%.1f KB)", f.Path, float64(f.Size)/1024))
+	}
+	display.InfoMessage(fmt.Sprintf("%.1f KB in all, sent with the next message", float64(total)/1024))
+}
+
+// withAttachments puts the attached files before the prompt text as
+// content blocks of their own.
+func (s *Session) withAttachments(text string) interface{} {
+	if len(s.attached) == 0 {
+		return text
+	}
+	blocks := make([]interface{}, 0, len(s.attached)+1)
+	for _, f := range s.attached {
+		blocks = append(blocks, f.Block)
+	}
+	return append(blocks, map[string]interface{}{"type": "text", "text": text})
+}
+
+// appendUserContent adds content, text or blocks, to the trailing user
+// message.
+func (s *Session) appendUserContent(content interface{}) {
+	blocks, ok := content.([]interface{})
+	if !ok {
+		s.appendUserText(content.(string))
+		return
+	}
+	last := &s.messages[len(s.messages)-1]
+	switch c := last.Content.(type) {
+	case string:
+		last.Content = append([]interface{}{map[string]interface{}{"type": "text", "text": c}}, blocks...)
+	case []interface{}:
+		last.Content = append(c, blocks...)
+	default:
+		s.messages = append(s.messages, client.Message{Role: "user", Content: blocks})
+	}
+}
@@ -0,0 +1,48 @@
+package conversation
+
+import (
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/audit"
+)
+
+// EnableAuditLog appends an audit trail of the session to dir and returns
+// the log's path. Logs larger than maxSizeMB are rotated and logs older
+// than retentionDays are removed; zero uses the defaults.
+func (s *Session) EnableAuditLog(dir string, maxSizeMB, retentionDays int) (string, error) {
+	if s.audit != nil {
+		return s.audit.Path(), nil
+	}
+	l, err := audit.Open(dir, s.SessionID(), int64(maxSizeMB)<<20, time.Duration(retentionDays)*24*time.Hour)
+	if err != nil {
+		return "", err
+	}
+	s.audit = l
+	l.Start(s.model, s.workDir)
+	return l.Path(), nil
+}
+
+// CloseAuditLog ends the audit trail; it is safe to call without one.
+func (s *Session) CloseAuditLog() error {
+	if s.audit == nil {
+		return nil
+	}
+	err := s.audit.Close()
+	s.audit = nil
+	return err
+}
+
+// auditInput redacts secrets from string inputs before they are logged.
+func (s *Session) auditInput(input map[string]interface{}) map[string]interface{} {
+	if s.redactor == nil {
+		return input
+	}
+	out := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		if str, ok := v.(string); ok {
+			v, _ = s.redactor.Redact(str)
+		}
+		out[k] = v
+	}
+	return out
+}
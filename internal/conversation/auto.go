@@ -0,0 +1,119 @@
+package conversation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/safety"
+)
+
+// defaultAutoWindow is the length of /auto without an argument.
+const defaultAutoWindow = 10 * time.Minute
+
+// autoApproved are the tools an auto-approval window covers: file changes
+// inside the working directory and Bash commands that are not high-risk.
+// Requests to remote servers keep asking.
+var autoApproved = map[string]bool{
+	"Bash": true, "Write": true, "Edit": true, "MultiEdit": true,
+	"EditLines": true, "ApplyPatch": true, "Archive": true,
+}
+
+// StartAuto approves low-risk tool calls without asking for a while: spec
+// is a duration such as "10m", a number of turns such as "3" or "3
+// turns", or "" for ten minutes. "off" ends the window early. It returns
+// a description of the window.
+func (s *Session) StartAuto(spec string) (string, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	switch {
+	case spec == "off":
+		s.StopAuto()
+		return "Auto-approval off", nil
+	case spec == "":
+		s.autoUntil, s.autoTurns = time.Now().Add(defaultAutoWindow), 0
+	default:
+		turns := strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(spec, "turns"), "turn"))
+		if n, err := strconv.Atoi(turns); err == nil {
+			if n < 1 {
+				return "", fmt.Errorf("the number of turns must be at least 1")
+			}
+			s.autoUntil, s.autoTurns = time.Time{}, n
+			break
+		}
+		d, err := time.ParseDuration(spec)
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("use a duration such as 10m or a number of turns, not %q", spec)
+		}
+		s.autoUntil, s.autoTurns = time.Now().Add(d), 0
+	}
+	return "Auto-approving edits and low-risk commands " + s.autoRemaining() + "; high-risk commands and remote requests still ask", nil
+}
+
+// StopAuto ends the auto-approval window.
+func (s *Session) StopAuto() {
+	s.autoUntil, s.autoTurns = time.Time{}, 0
+}
+
+// AutoActive reports whether an auto-approval window is open.
+func (s *Session) AutoActive() bool {
+	return s.autoTurns > 0 || time.Now().Before(s.autoUntil)
+}
+
+// autoRemaining describes what is left of the window, e.g. "for 9m30s".
+func (s *Session) autoRemaining() string {
+	if s.autoTurns > 0 {
+		return fmt.Sprintf("for %d more %s", s.autoTurns, pluralize(s.autoTurns, "turn", "turns"))
+	}
+	return "for " + time.Until(s.autoUntil).Round(time.Second).String()
+}
+
+func pluralize(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// autoApprove reports whether a call that would ask is approved by the
+// auto-approval window.
+func (s *Session) autoApprove(toolName string, input map[string]interface{}) bool {
+	if !s.AutoActive() || !autoApproved[toolName] {
+		return false
+	}
+	if toolName == "Bash" {
+		command, _ := input["command"].(string)
+		if s.risk.Classify(command).Level == safety.Dangerous {
+			return false
+		}
+	}
+	paths := s.modifiedPaths(toolName, input)
+	if dest, _ := input["destination"].(string); toolName == "Archive" && dest != "" {
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(s.executor.WorkDir(), dest)
+		}
+		paths = append(paths, filepath.Clean(dest))
+	}
+	for _, p := range paths {
+		if rel, err := filepath.Rel(s.workDir, p); err != nil || strings.HasPrefix(rel, "..") {
+			return false
+		}
+	}
+	display.AutoApproved(toolName, s.autoRemaining())
+	return true
+}
+
+// endAutoTurn counts a finished turn against the window and says when it
+// is over.
+func (s *Session) endAutoTurn() {
+	wasActive := s.AutoActive()
+	if s.autoTurns > 0 {
+		s.autoTurns--
+	}
+	if !s.AutoActive() && (wasActive || !s.autoUntil.IsZero()) {
+		s.StopAuto()
+		display.InfoMessage("Auto-approval ended; tool calls ask again")
+	}
+}
@@ -0,0 +1,121 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// contextWindow is the model context size used to estimate headroom.
+const contextWindow = 200_000
+
+// SetTurnBudget sets the dollar budget of a single prompt, reported to the
+// model each iteration; zero means no budget.
+func (s *Session) SetTurnBudget(usd float64) {
+	s.turnBudget = usd
+}
+
+// SetTurnTokenBudget caps the input plus output tokens of a single prompt;
+// zero means no cap.
+func (s *Session) SetTurnTokenBudget(tokens int) {
+	s.turnTokenBudget = tokens
+}
+
+// SetMaxIterations sets how many model requests one prompt may make; zero
+// or less restores the default.
+func (s *Session) SetMaxIterations(n int) {
+	if n <= 0 {
+		n = defaultMaxIterations
+	}
+	s.maxIterations = n
+}
+
+// limitReached describes the limit that makes iteration the last one of the
+// turn, or returns "" while the turn may continue.
+func (s *Session) limitReached(iteration int) string {
+	if s.turnBudget > 0 {
+		if spent := display.EstimateCost(s.turnUsage.InputTokens, s.turnUsage.OutputTokens); spent >= s.turnBudget {
+			return fmt.Sprintf("spent $%.2f of the $%.2f budget for this prompt", spent, s.turnBudget)
+		}
+	}
+	if s.turnTokenBudget > 0 {
+		if used := s.turnUsage.InputTokens + s.turnUsage.OutputTokens; used >= s.turnTokenBudget {
+			return fmt.Sprintf("used %d of the %d-token budget for this prompt", used, s.turnTokenBudget)
+		}
+	}
+	if iteration >= s.maxIterations-1 {
+		return fmt.Sprintf("reached the limit of %d iterations for this prompt", s.maxIterations)
+	}
+	return ""
+}
+
+func wrapUpInstruction(limit string) string {
+	return "<turn_limit>You have " + limit + ". Do not call any more tools. " +
+		"Wrap up now: summarize what is done, what remains, and how to continue.</turn_limit>"
+}
+
+// appendUserText adds a text block to the trailing user message.
+func (s *Session) appendUserText(text string) {
+	if len(s.messages) == 0 || s.messages[len(s.messages)-1].Role != "user" {
+		s.messages = append(s.messages, client.Message{Role: "user", Content: text})
+		return
+	}
+	last := &s.messages[len(s.messages)-1]
+	block := map[string]interface{}{"type": "text", "text": text}
+	switch c := last.Content.(type) {
+	case string:
+		last.Content = []interface{}{map[string]interface{}{"type": "text", "text": c}, block}
+	case []interface{}:
+		last.Content = append(c, block)
+	default:
+		s.messages = append(s.messages, client.Message{Role: "user", Content: text})
+	}
+}
+
+// budgetNote tells the model how many iterations, how much budget and how
+// much context remain. It goes in the latest user message of each request
+// rather than the system prompt, which stays the same so it can be cached.
+func (s *Session) budgetNote(iteration int) string {
+	left := s.maxIterations - iteration
+	parts := []string{fmt.Sprintf("iteration %d of %d (%d left)", iteration+1, s.maxIterations, left)}
+
+	if s.turnBudget > 0 {
+		spent := display.EstimateCost(s.turnUsage.InputTokens, s.turnUsage.OutputTokens)
+		parts = append(parts, fmt.Sprintf("$%.2f of $%.2f budget spent", spent, s.turnBudget))
+	}
+	if s.turnTokenBudget > 0 {
+		used := s.turnUsage.InputTokens + s.turnUsage.OutputTokens
+		parts = append(parts, fmt.Sprintf("%dk of %dk turn tokens used", used/1000, s.turnTokenBudget/1000))
+	}
+	if s.lastContext > 0 {
+		headroom := 100 - s.lastContext*100/contextWindow
+		parts = append(parts, fmt.Sprintf("context %dk of %dk tokens (%d%% headroom)",
+			s.lastContext/1000, contextWindow/1000, headroom))
+	}
+
+	note := "<turn_status>" + strings.Join(parts, " · ") + "</turn_status>"
+	if left <= 3 {
+		note += "\nFew iterations remain: stop exploring and finish the most important change, then summarize what is left."
+	}
+	return note
+}
+
+// withNote returns messages with text added to the last one, a user prompt
+// or tool results, leaving the history itself unchanged.
+func withNote(messages []client.Message, text string) []client.Message {
+	if len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		return messages
+	}
+	out := append([]client.Message(nil), messages...)
+	last := &out[len(out)-1]
+	block := map[string]interface{}{"type": "text", "text": text}
+	switch c := last.Content.(type) {
+	case string:
+		last.Content = []interface{}{map[string]interface{}{"type": "text", "text": c}, block}
+	case []interface{}:
+		last.Content = append(append([]interface{}(nil), c...), block)
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/commands"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/safety"
+)
+
+// CustomCommands returns the slash commands defined in ~/.apipod/commands
+// and the project's .apipod/commands.
+func (s *Session) CustomCommands() []*commands.Command {
+	return commands.Load(s.workDir)
+}
+
+// RunCommand sends the expanded prompt of a custom command, offering only
+// the command's allowed tools while it runs.
+func (s *Session) RunCommand(cmd *commands.Command, args string) error {
+	if len(cmd.AllowedTools) > 0 {
+		// A template's tool list applies again afterwards.
+		prev := s.allowedTools
+		s.setAllowedTools(cmd.AllowedTools)
+		defer func() { s.allowedTools = prev }()
+	}
+	return s.SendMessage(cmd.Expand(args))
+}
+
+// setAllowedTools offers only the tools listed. An entry with a pattern,
+// such as Bash(git:*) or Edit(src/**), allows only the calls that match
+// it; the tool name alone allows every call.
+func (s *Session) setAllowedTools(names []string) {
+	s.allowedTools = make(map[string][]string, len(names))
+	unrestricted := make(map[string]bool)
+	for _, entry := range names {
+		name, pattern := commands.SplitTool(entry)
+		if pattern == "" {
+			unrestricted[name] = true
+		}
+		s.allowedTools[name] = append(s.allowedTools[name], pattern)
+	}
+	for name := range unrestricted {
+		s.allowedTools[name] = nil
+	}
+}
+
+func (s *Session) toolAllowed(name string) bool {
+	if s.allowedTools != nil {
+		if _, ok := s.allowedTools[name]; !ok {
+			return false
+		}
+	}
+	return s.executor.ToolEnabled(name)
+}
+
+// patternDenied refuses a call that matches none of its tool's allowed
+// patterns, or returns "". Bash patterns are command prefixes, "git:*"
+// allowing any git command and "make test" only that command; every
+// command of a list or pipeline must match. Other tools' patterns are
+// globs for the path they are given; for Glob and Grep the whole search
+// must fall inside the pattern.
+func (s *Session) patternDenied(name string, input map[string]interface{}) string {
+	patterns := s.allowedTools[name]
+	if len(patterns) == 0 {
+		return ""
+	}
+	if name == "Bash" {
+		command, _ := input["command"].(string)
+		var prefixes []string
+		for _, p := range patterns {
+			if prefix, ok := strings.CutSuffix(p, ":*"); ok {
+				prefixes = append(prefixes, prefix)
+			} else if strings.TrimSpace(command) == p {
+				return ""
+			}
+		}
+		if len(prefixes) > 0 && safety.Allows(prefixes, command) {
+			return ""
+		}
+		return fmt.Sprintf("Bash is only allowed for %s in this context", strings.Join(patterns, ", "))
+	}
+	if target := s.patternTarget(name, input); target != "" {
+		for _, p := range patterns {
+			if globCovers(strings.Split(strings.TrimPrefix(p, "./"), "/"), strings.Split(target, "/")) {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("%s is only allowed for %s in this context", name, strings.Join(patterns, ", "))
+}
+
+// ShowHelp lists the built-in and custom slash commands.
+func (s *Session) ShowHelp() {
+	var rows []display.CommandRow
+	for _, c := range s.CustomCommands() {
+		rows = append(rows, display.CommandRow{
+			Name:         c.Name,
+			ArgumentHint: c.ArgumentHint,
+			Description:  c.Description,
+			Source:       c.Source,
+		})
+	}
+	display.SlashHelp(rows...)
+}
+
+// patternTarget returns the slash-separated path, relative to the working
+// directory, that a call touches. Glob and Grep search a directory, which
+// defaults to the working directory: Glob's is joined with its pattern and
+// Grep's with "**" and its include filter.
+func (s *Session) patternTarget(name string, input map[string]interface{}) string {
+	target := ""
+	switch name {
+	case "Glob":
+		dir, _ := input["path"].(string)
+		pattern, _ := input["pattern"].(string)
+		if pattern == "" {
+			return ""
+		}
+		target = pattern
+		if !filepath.IsAbs(pattern) {
+			target = filepath.Join(orDot(dir), pattern)
+		}
+	case "Grep":
+		dir, _ := input["path"].(string)
+		target = orDot(dir)
+		abs := target
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(s.executor.WorkDir(), abs)
+		}
+		if info, err := os.Stat(abs); err != nil || info.IsDir() {
+			include, _ := input["include"].(string)
+			target = filepath.Join(target, "**", include)
+		}
+	default:
+		for _, k := range []string{"file_path", "path", "notebook_path"} {
+			if target, _ = input[k].(string); target != "" {
+				break
+			}
+		}
+	}
+	if target == "" {
+		return ""
+	}
+	if filepath.IsAbs(target) {
+		if rel, err := filepath.Rel(s.executor.WorkDir(), target); err == nil {
+			target = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(target))
+}
+
+func orDot(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// globCovers reports whether every path matched by the glob segments
+// parts also matches pat. Wildcards in parts are only covered by "**", or
+// by "*" or the same text within one segment, so Glob(src/*) does not let
+// a call search src/**.
+func globCovers(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if globCovers(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 || parts[0] == "**" {
+			return false
+		}
+		if strings.ContainsAny(parts[0], "*?[{\\") {
+			if pat[0] != "*" && pat[0] != parts[0] {
+				return false
+			}
+		} else if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
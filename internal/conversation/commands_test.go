@@ -0,0 +1,70 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rpay/apipod-cli/internal/tools"
+)
+
+func TestPatternDenied(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Session{executor: tools.NewExecutor(dir)}
+	s.setAllowedTools([]string{"Glob(src/**)", "Grep(src/**)", "Edit(src/*.go)", "Bash(git:*)", "Bash(make test)"})
+
+	tests := []struct {
+		tool  string
+		input map[string]interface{}
+		allow bool
+	}{
+		{"Glob", map[string]interface{}{"pattern": "src/**/*.go"}, true},
+		{"Glob", map[string]interface{}{"pattern": "*.go", "path": "src"}, true},
+		{"Glob", map[string]interface{}{"pattern": "**/*.go"}, false},
+		{"Glob", map[string]interface{}{"pattern": "../**", "path": "src"}, false},
+		{"Glob", map[string]interface{}{"pattern": "*", "path": filepath.Join(dir, "src")}, true},
+		{"Grep", map[string]interface{}{"pattern": "TODO", "path": "src"}, true},
+		{"Grep", map[string]interface{}{"pattern": "TODO", "path": "src/main.go"}, true},
+		{"Grep", map[string]interface{}{"pattern": "TODO"}, false},
+		{"Grep", map[string]interface{}{"pattern": "TODO", "include": "src/*.go"}, false},
+		{"Edit", map[string]interface{}{"file_path": "src/main.go"}, true},
+		{"Edit", map[string]interface{}{"file_path": filepath.Join(dir, "src", "main.go")}, true},
+		{"Edit", map[string]interface{}{"file_path": "src/pkg/x.go"}, false},
+		{"Edit", map[string]interface{}{"file_path": "main.go"}, false},
+		{"Bash", map[string]interface{}{"command": "git status && git diff"}, true},
+		{"Bash", map[string]interface{}{"command": "make test"}, true},
+		{"Bash", map[string]interface{}{"command": "make deploy"}, false},
+	}
+	for _, tt := range tests {
+		if got := s.patternDenied(tt.tool, tt.input) == ""; got != tt.allow {
+			t.Errorf("%s %v allowed = %v, want %v", tt.tool, tt.input, got, tt.allow)
+		}
+	}
+}
+
+func TestGlobCovers(t *testing.T) {
+	tests := []struct {
+		pattern, glob string
+		want          bool
+	}{
+		{"src/**", "src/a/b.go", true},
+		{"src/**", "src/**/*.go", true},
+		{"src/*", "src/*", true},
+		{"src/*", "src/**", false},
+		{"src/*.go", "src/*.go", true},
+		{"src/a?", "src/a*", false},
+		{"src/*.go", "src/*", false},
+	}
+	for _, tt := range tests {
+		if got := globCovers(strings.Split(tt.pattern, "/"), strings.Split(tt.glob, "/")); got != tt.want {
+			t.Errorf("globCovers(%q, %q) = %v, want %v", tt.pattern, tt.glob, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package conversation
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+const commitSystem = `You write git commit messages from a diff.
+Use the Conventional Commits format: a subject line "type(scope): summary" of at most 72 characters, where type is one of feat, fix, refactor, perf, docs, test, build, ci, chore or style and the scope is optional; then, when the change is not obvious from the subject, a blank line and a short body wrapped at 72 columns explaining what changed and why.
+Write the summary in the imperative mood, without a trailing period. Follow the style of the recent commits you are shown where it does not conflict with this.
+Answer with the commit message only, without code fences or commentary.`
+
+// maxCommitDiff bounds the diff sent to draft a commit message; larger
+// changes are summarized from the file list and the start of the diff.
+const maxCommitDiff = 60000
+
+// Commit drafts a commit message for everything changed in the working
+// tree, staged or not, shows it for approval or editing and commits it,
+// for /commit. hint is passed to the model, e.g. an issue number.
+func (s *Session) Commit(hint string) error {
+	dir := s.executor.WorkDir()
+	status, err := gitOutput(dir, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		display.InfoMessage("Nothing to commit")
+		return nil
+	}
+	diff, _ := gitOutput(dir, "diff", "HEAD")
+	if diff == "" {
+		// A repository without commits yet.
+		diff, _ = gitOutput(dir, "diff")
+	}
+	// New files are not in the diff yet.
+	if untracked, _ := gitOutput(dir, "ls-files", "--others", "--exclude-standard", "--full-name"); untracked != "" {
+		root, _ := gitOutput(dir, "rev-parse", "--show-toplevel")
+		for _, name := range strings.Split(untracked, "\n") {
+			if len(diff) > maxCommitDiff {
+				break
+			}
+			data, err := os.ReadFile(filepath.Join(root, name))
+			if err != nil || bytes.IndexByte(data, 0) >= 0 {
+				diff += fmt.Sprintf("\nnew file %s\n", name)
+				continue
+			}
+			diff += fmt.Sprintf("\nnew file %s:\n%s\n", name, data)
+		}
+	}
+	if len(diff) > maxCommitDiff {
+		diff = diff[:maxCommitDiff] + "\n[diff truncated]"
+	}
+	recent, _ := gitOutput(dir, "log", "-10", "--format=%s")
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Changed files (git status --porcelain):\n%s\n\n", status)
+	if recent != "" {
+		fmt.Fprintf(&prompt, "Recent commits:\n%s\n\n", recent)
+	}
+	if hint = strings.TrimSpace(hint); hint != "" {
+		fmt.Fprintf(&prompt, "Note from the author: %s\n\n", hint)
+	}
+	fmt.Fprintf(&prompt, "Diff:\n%s", diff)
+	message, err := s.askSmallModel(commitSystem, prompt.String(), 1024, "Drafting a commit message...")
+	if err != nil {
+		return fmt.Errorf("draft commit message: %w", err)
+	}
+	message = strings.TrimSpace(strings.Trim(strings.TrimSpace(message), "`"))
+
+	display.CommitMessage(message)
+	answer := display.CommitPrompt()
+	if answer == "n" {
+		display.InfoMessage("Commit cancelled")
+		return nil
+	}
+	if _, err := gitOutput(dir, "add", "-A"); err != nil {
+		return err
+	}
+	if err := gitCommit(dir, message, answer == "e"); err != nil {
+		return err
+	}
+	committed, _ := gitOutput(dir, "log", "-1", "--format=%h %s")
+	display.SuccessMessage("Committed " + committed)
+	return nil
+}
+
+// gitCommit commits the index with message; with edit, git first opens
+// the user's commit editor on it.
+func gitCommit(dir, message string, edit bool) error {
+	if !edit {
+		cmd := exec.Command("git", "-C", dir, "commit", "-q", "-F", "-")
+		cmd.Stdin = strings.NewReader(message + "\n")
+		var stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = os.Stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("git commit: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+	f, err := os.CreateTemp("", "apipod-commit-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(message + "\n")
+	f.Close()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("git", "-C", dir, "commit", "-q", "-e", "-F", f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
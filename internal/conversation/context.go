@@ -0,0 +1,48 @@
+package conversation
+
+import (
+	"fmt"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// contextWarnPercent is the context usage from which prompts get a warning.
+const contextWarnPercent = 80
+
+// ContextTokens counts the input tokens the next request would send, using
+// the API's count endpoint when the server supports it and a local
+// estimate otherwise.
+func (s *Session) ContextTokens() (tokens int, estimated bool) {
+	req := &client.MessagesRequest{
+		Model:    s.model,
+		Messages: s.messages,
+		System:   s.system,
+		Tools:    s.toolDefinitionsFor(s.getToolDefinitions()),
+		Thinking: s.thinkingParam(),
+	}
+	if s.client != nil && s.player == nil && !s.countUnsupported {
+		n, err := s.client.CountTokens(req)
+		if err == nil {
+			return n, false
+		}
+		// Proxies without the endpoint fail every time; stop asking.
+		s.countUnsupported = true
+	}
+	return client.EstimateTokens(req), true
+}
+
+// checkContext counts the pending request before it is sent. It refuses a
+// prompt that cannot fit and warns when the window is nearly full.
+func (s *Session) checkContext() error {
+	tokens, estimated := s.ContextTokens()
+	if tokens >= contextWindow {
+		return &client.ContextTooLongError{APIError: &client.APIError{Message: fmt.Sprintf(
+			"this prompt needs about %dk tokens, more than the %dk context window; use /compact or /clear first",
+			tokens/1000, contextWindow/1000)}}
+	}
+	if tokens*100/contextWindow >= contextWarnPercent {
+		display.ContextMeter(tokens, contextWindow, estimated)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package conversation
+
+import (
+	"os"
+
+	"github.com/rpay/apipod-cli/internal/linediff"
+)
+
+// snapshot is a file as it was before the turn first changed it.
+type snapshot struct {
+	content string
+	existed bool
+}
+
+// snapshotBefore remembers the files a file tool is about to change, the
+// first time each is touched in a turn.
+func (s *Session) snapshotBefore(toolName string, input map[string]interface{}) {
+	for _, p := range s.modifiedPaths(toolName, input) {
+		if _, ok := s.turnBefore[p]; ok {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		s.turnBefore[p] = snapshot{content: string(data), existed: err == nil}
+	}
+}
+
+// TurnDiffStat counts the lines added and removed by the current or last
+// turn in the files it changed through file tools, as they are on disk now.
+func (s *Session) TurnDiffStat() (added, removed, files int) {
+	for p, before := range s.turnBefore {
+		data, err := os.ReadFile(p)
+		if err != nil && !before.existed {
+			continue
+		}
+		a, r := 0, 0
+		for _, l := range linediff.Diff(linediff.Split(before.content), linediff.Split(string(data))) {
+			switch l.Kind {
+			case linediff.Insert:
+				a++
+			case linediff.Delete:
+				r++
+			}
+		}
+		if a+r > 0 || err != nil || !before.existed {
+			added, removed, files = added+a, removed+r, files+1
+		}
+	}
+	return added, removed, files
+}
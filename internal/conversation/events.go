@@ -0,0 +1,65 @@
+package conversation
+
+import (
+	"strings"
+	"time"
+)
+
+// maxEvents is how many recent events a session keeps.
+const maxEvents = 50
+
+// Event is one step of the agent loop, kept so a failed headless run can
+// report what led up to the failure. Type is "tool_call", "tool_denied",
+// "tool_blocked", "limit", "steering" or "api_error".
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Tool    string    `json:"tool,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	IsError bool      `json:"is_error,omitempty"`
+}
+
+// maxEventDetail bounds the output kept with an event.
+const maxEventDetail = 300
+
+func (s *Session) recordEvent(typ, tool, detail string, isError bool) {
+	detail = strings.TrimSpace(detail)
+	if len(detail) > maxEventDetail {
+		detail = detail[:maxEventDetail] + "…"
+	}
+	s.events = append(s.events, Event{Time: time.Now().UTC(), Type: typ, Tool: tool, Detail: detail, IsError: isError})
+	if len(s.events) > maxEvents {
+		s.events = s.events[len(s.events)-maxEvents:]
+	}
+}
+
+// refusedEvent records a tool call that did not run: denied by the user,
+// or blocked by a hook, policy or confinement.
+func (s *Session) refusedEvent(tool, reason string, byUser bool) {
+	typ := "tool_blocked"
+	if byUser {
+		typ = "tool_denied"
+	}
+	s.turnDenials++
+	s.recordEvent(typ, tool, reason, true)
+}
+
+// RecentEvents returns the last n events, oldest first.
+func (s *Session) RecentEvents(n int) []Event {
+	if n > len(s.events) {
+		n = len(s.events)
+	}
+	return append([]Event(nil), s.events[len(s.events)-n:]...)
+}
+
+// TurnLimit returns the budget or iteration limit that ended the last
+// turn, or "" if none did.
+func (s *Session) TurnLimit() string {
+	return s.turnLimit
+}
+
+// TurnDenials is the number of tool calls denied in the last turn, by the
+// user or by a hook, policy or confinement.
+func (s *Session) TurnDenials() int {
+	return s.turnDenials
+}
@@ -0,0 +1,76 @@
+package conversation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Tool results are kept for /expand up to these limits, oldest dropped
+// first.
+const (
+	maxKeptOutputs     = 200
+	maxKeptOutputBytes = 8 << 20
+)
+
+// keptOutput is the full result of a tool call, of which only the first
+// lines were shown.
+type keptOutput struct {
+	id      int
+	title   string
+	path    string
+	content string
+	isError bool
+}
+
+// keepOutput stores a tool result and returns its number for /expand.
+func (s *Session) keepOutput(toolName string, input map[string]interface{}, content string, isError bool) int {
+	s.lastOutputID++
+	title := toolName
+	for _, key := range previewKeys[toolName] {
+		if v, _ := input[key].(string); v != "" {
+			title += " " + strings.SplitN(v, "\n", 2)[0]
+			break
+		}
+	}
+	out := keptOutput{id: s.lastOutputID, title: title, content: content, isError: isError}
+	if toolName == "Read" {
+		out.path, _ = input["file_path"].(string)
+	}
+	s.outputs = append(s.outputs, out)
+	s.outputBytes += len(content)
+	for len(s.outputs) > maxKeptOutputs || (s.outputBytes > maxKeptOutputBytes && len(s.outputs) > 1) {
+		s.outputBytes -= len(s.outputs[0].content)
+		s.outputs = s.outputs[1:]
+	}
+	return out.id
+}
+
+// Expand renders the complete result of tool call n, or with "" of the
+// latest one that was cut short.
+func (s *Session) Expand(arg string) (string, error) {
+	arg = strings.TrimPrefix(strings.TrimSpace(arg), "#")
+	if arg == "" {
+		for i := len(s.outputs) - 1; i >= 0; i-- {
+			if o := s.outputs[i]; strings.Count(strings.TrimRight(o.content, "\n"), "\n") >= display.CollapsedLines {
+				return display.ExpandedResult(o.id, o.title, o.path, o.content, o.isError), nil
+			}
+		}
+		return "", fmt.Errorf("no tool output was cut short")
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return "", fmt.Errorf("usage: /expand [n]")
+	}
+	for _, o := range s.outputs {
+		if o.id == n {
+			return display.ExpandedResult(o.id, o.title, o.path, o.content, o.isError), nil
+		}
+	}
+	if len(s.outputs) > 0 && n < s.outputs[0].id {
+		return "", fmt.Errorf("output #%d is no longer kept", n)
+	}
+	return "", fmt.Errorf("no tool output #%d", n)
+}
@@ -0,0 +1,94 @@
+package conversation
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+)
+
+const explainSystem = `You explain shell commands to a developer who must decide whether to let an AI assistant run them.
+Explain exactly what the command will do, part by part: each program, each flag and argument, pipes and redirections.
+Then list its side effects: files created, changed or deleted, network access, installed packages, processes started or killed, and anything that cannot be undone.
+Be concrete and brief, use a short bulleted list, and do not judge whether the command should be run.`
+
+// confirmBash asks question about command, offering "e" to have the small
+// model explain the command first. An approver is asked without the
+// explanation.
+func (s *Session) confirmBash(command, question string) bool {
+	if s.approver != nil {
+		return s.confirm("Bash", map[string]interface{}{"command": command}, question)
+	}
+	s.attention(i18n.T("notify.confirm", question))
+	explained := false
+	for {
+		switch display.ExplainPrompt(question, !explained) {
+		case display.Allow:
+			return true
+		case display.Explain:
+			explained = true
+			text, err := s.explainCommand(command)
+			if err != nil {
+				display.ErrorMessage(i18n.T("explain.failed", err))
+				continue
+			}
+			display.CommandExplanation(text)
+		default:
+			return false
+		}
+	}
+}
+
+// explainCommand asks the small model what command does. The conversation
+// is not sent, only the command and where it runs, so the request is cheap
+// and stays out of the session's context.
+func (s *Session) explainCommand(command string) (string, error) {
+	return s.askSmallModel(explainSystem,
+		fmt.Sprintf("Platform: %s\nWorking directory: %s\n\nCommand:\n%s", runtime.GOOS, s.executor.WorkDir(), command),
+		1024, "Explaining...")
+}
+
+// askSmallModel sends a one-off request without the conversation to the
+// small model, or the session's model when none is configured, and
+// returns the text of the answer. Its usage counts towards the session.
+func (s *Session) askSmallModel(system, prompt string, maxTokens int, status string) (string, error) {
+	model := s.smallModel
+	if model == "" {
+		model = s.model
+	}
+	req := &client.MessagesRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		System:    system,
+		Messages:  []client.Message{{Role: "user", Content: prompt}},
+	}
+	if s.client == nil {
+		return "", fmt.Errorf("not connected to the API")
+	}
+	spinner := display.NewSpinner(status)
+	// The request skips send so a recording being made stays replayable:
+	// replays never make these side requests.
+	resp, err := s.client.SendMessageStream(req, nil)
+	spinner.Stop()
+	if err != nil {
+		return "", err
+	}
+	s.usage.InputTokens += resp.Usage.InputTokens
+	s.usage.OutputTokens += resp.Usage.OutputTokens
+	if s.audit != nil {
+		s.audit.Usage(model, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+	}
+	var b strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			b.WriteString(block.Text)
+		}
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return "", fmt.Errorf("empty answer from %s", model)
+	}
+	return b.String(), nil
+}
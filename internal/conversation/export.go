@@ -0,0 +1,120 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/transcript"
+)
+
+// messageBlock is the union of the content block shapes kept in history.
+type messageBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   interface{}     `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+func messageBlocks(m client.Message) []messageBlock {
+	if text, ok := m.Content.(string); ok {
+		return []messageBlock{{Type: "text", Text: text}}
+	}
+	data, err := json.Marshal(m.Content)
+	if err != nil {
+		return nil
+	}
+	var blocks []messageBlock
+	json.Unmarshal(data, &blocks)
+	return blocks
+}
+
+// Transcript returns the conversation so far with tool outputs truncated.
+func (s *Session) Transcript() *transcript.Transcript {
+	t := &transcript.Transcript{
+		Model:    s.model,
+		WorkDir:  s.workDir,
+		Exported: time.Now(),
+		Usage:    transcript.Usage{InputTokens: s.usage.InputTokens, OutputTokens: s.usage.OutputTokens},
+		Entries:  []transcript.Entry{},
+	}
+	for _, st := range s.ToolStats() {
+		t.Tools = append(t.Tools, transcript.ToolStat{
+			Name:        st.Name,
+			Calls:       st.Calls,
+			Errors:      st.Errors,
+			Denied:      st.Denied,
+			DurationMS:  st.Duration.Milliseconds(),
+			OutputBytes: st.OutputBytes,
+		})
+	}
+
+	toolNames := make(map[string]string)
+	for i, m := range s.messages {
+		start := len(t.Entries)
+		for _, b := range messageBlocks(m) {
+			e := transcript.Entry{Role: m.Role, Type: b.Type}
+			switch b.Type {
+			case "text":
+				e.Text = b.Text
+			case "thinking":
+				e.Text = b.Thinking
+			case "tool_use":
+				toolNames[b.ID] = b.Name
+				e.Tool, e.ToolUseID, e.Input = b.Name, b.ID, b.Input
+			case "tool_result":
+				e.Tool, e.ToolUseID, e.IsError = toolNames[b.ToolUseID], b.ToolUseID, b.IsError
+				e.Text = transcript.Truncate(resultText(b.Content))
+			default:
+				continue
+			}
+			t.Entries = append(t.Entries, e)
+		}
+		if u, ok := s.usageAt[i]; ok && len(t.Entries) > start {
+			t.Entries[len(t.Entries)-1].Usage = &transcript.Usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens}
+		}
+	}
+	return t
+}
+
+func resultText(content interface{}) string {
+	if text, ok := content.(string); ok {
+		return text
+	}
+	data, _ := json.Marshal(content)
+	return string(data)
+}
+
+// Export writes the transcript as markdown, json or html. An empty path
+// writes apipod-transcript-<time>.<ext> in the working directory; the path
+// written is returned.
+func (s *Session) Export(format, path string) (string, error) {
+	ext, err := transcript.Extension(format)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		path = "apipod-transcript-" + time.Now().Format("20060102-150405") + ext
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workDir, path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("create export: %w", err)
+	}
+	defer f.Close()
+	if err := s.Transcript().Write(f, ext); err != nil {
+		return "", fmt.Errorf("write export: %w", err)
+	}
+	return path, nil
+}
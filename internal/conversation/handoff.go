@@ -0,0 +1,127 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/handoff"
+)
+
+const handoffInstruction = `Another agent will continue this task without seeing this conversation. Describe the task state by calling task_state.
+State the goal as the user asked for it, the constraints they set or that you discovered, and what is already done and verified.
+List concrete next steps in order and the questions that still need the user. Include every file you changed or that the next agent must read, with a short note.
+Only report what actually happened in this conversation.`
+
+func stringList(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]string{"type": "string"},
+		"description": description,
+	}
+}
+
+var handoffTool = client.ToolDefinition{
+	Name:        "task_state",
+	Description: "Record the state of the current task for another agent to continue it.",
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"goal":           map[string]interface{}{"type": "string", "description": "What the user wants achieved"},
+			"constraints":    stringList("Requirements and limits the work must respect"),
+			"progress":       stringList("What is done, and how it was verified"),
+			"next_steps":     stringList("What remains, in order"),
+			"open_questions": stringList("Decisions that need the user"),
+			"files": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path":   map[string]string{"type": "string"},
+						"status": map[string]interface{}{"type": "string", "enum": []string{"modified", "created", "deleted", "read"}},
+						"note":   map[string]string{"type": "string"},
+					},
+					"required": []string{"path", "status"},
+				},
+			},
+		},
+		"required": []string{"goal", "constraints", "progress", "next_steps", "open_questions", "files"},
+	},
+}
+
+// Handoff asks the model for the state of the task and writes it as a
+// handoff document another session can continue from with ImportHandoff.
+// An empty path writes apipod-handoff-<time>.json in the working
+// directory; the path written is returned.
+func (s *Session) Handoff(path string) (string, error) {
+	if len(s.messages) == 0 {
+		return "", fmt.Errorf("nothing to hand off yet")
+	}
+	raw, err := s.StructuredOutput(handoffInstruction, handoffTool)
+	if err != nil {
+		return "", fmt.Errorf("handoff: %w", err)
+	}
+	doc := &handoff.Document{}
+	if err := json.Unmarshal(raw, doc); err != nil {
+		return "", fmt.Errorf("handoff: %w", err)
+	}
+	doc.Version = handoff.Version
+	doc.Source = handoff.Source{Model: s.model, WorkDir: s.workDir, Session: s.sessionID, Created: time.Now().UTC()}
+
+	// Files changed through tools are listed whatever the model reported.
+	listed := make(map[string]bool)
+	for _, f := range doc.Files {
+		listed[filepath.Clean(f.Path)] = true
+	}
+	for _, p := range s.ModifiedFiles() {
+		if !listed[p] {
+			doc.Files = append(doc.Files, handoff.File{Path: p, Status: "modified"})
+		}
+	}
+
+	if path == "" {
+		path = "apipod-handoff-" + time.Now().Format("20060102-150405") + ".json"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workDir, path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("create handoff: %w", err)
+	}
+	defer f.Close()
+	if err := doc.Write(f); err != nil {
+		return "", fmt.Errorf("write handoff: %w", err)
+	}
+	return path, nil
+}
+
+// ImportHandoff reads a handoff document, shows what is being taken over
+// and briefs the model with it on the next prompt.
+func (s *Session) ImportHandoff(path string) (*handoff.Document, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workDir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open handoff: %w", err)
+	}
+	defer f.Close()
+	doc, err := handoff.Read(f)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Source.WorkDir != "" && doc.Source.WorkDir != s.workDir {
+		display.WarningMessage(fmt.Sprintf("Handoff was written in %s; file paths are relative to it", doc.Source.WorkDir))
+	}
+	if s.pendingNote != "" {
+		s.pendingNote += "\n\n"
+	}
+	s.pendingNote += doc.Prompt()
+	display.HandoffSummary(doc.Goal, doc.NextSteps, doc.OpenQuestions)
+	return doc, nil
+}
@@ -0,0 +1,195 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// interruptedResult answers a tool call whose result never made it into
+// history, e.g. because the turn was cut off while it ran.
+const interruptedResult = "The tool call was interrupted and has no result"
+
+// repairHistory makes history acceptable to the API before a turn starts.
+// An interrupted turn, a failed compaction or a hand-edited session file
+// can leave it with empty messages, two messages of the same role in a
+// row, an assistant message first, tool calls without results or results
+// without calls; each of those is a 400 on every request that follows.
+func (s *Session) repairHistory() {
+	repaired, fixes := repairMessages(s.messages)
+	if len(fixes) == 0 {
+		return
+	}
+	s.messages = repaired
+	// Usage was recorded by message index, which no longer holds.
+	s.usageAt = make(map[int]client.Usage)
+	display.WarningMessage("Repaired the conversation history: " + strings.Join(fixes, ", "))
+}
+
+// repairMessages returns msgs fixed up to the API's rules and what was
+// changed. Messages that need no change are kept as they are.
+func repairMessages(msgs []client.Message) ([]client.Message, []string) {
+	var fixes []string
+	// Dropping a message can make its neighbours collide, so the passes
+	// repeat until nothing changes.
+	for pass := 0; pass < 3; pass++ {
+		var changed []string
+		msgs, changed = mergeRoles(msgs)
+		var paired []string
+		msgs, paired = pairToolCalls(msgs)
+		changed = append(changed, paired...)
+		if len(changed) == 0 {
+			break
+		}
+		fixes = append(fixes, changed...)
+	}
+	return msgs, fixes
+}
+
+// mergeRoles drops empty messages and leading assistant messages and
+// joins consecutive messages of the same role.
+func mergeRoles(msgs []client.Message) ([]client.Message, []string) {
+	var out []client.Message
+	var empty, leading, merged int
+	for _, m := range msgs {
+		switch {
+		case len(contentBlocks(m.Content)) == 0:
+			empty++
+		case len(out) == 0 && m.Role != "user":
+			leading++
+		case len(out) > 0 && out[len(out)-1].Role == m.Role:
+			last := &out[len(out)-1]
+			last.Content = append(contentBlocks(last.Content), contentBlocks(m.Content)...)
+			merged++
+		default:
+			out = append(out, m)
+		}
+	}
+	var fixes []string
+	if empty > 0 {
+		fixes = append(fixes, fmt.Sprintf("dropped %d empty %s", empty, pluralize(empty, "message", "messages")))
+	}
+	if leading > 0 {
+		fixes = append(fixes, fmt.Sprintf("dropped %d assistant %s before the first prompt", leading, pluralize(leading, "message", "messages")))
+	}
+	if merged > 0 {
+		fixes = append(fixes, fmt.Sprintf("joined %d %s of the same role", merged, pluralize(merged, "message", "messages")))
+	}
+	return out, fixes
+}
+
+// pairToolCalls makes the message after each assistant message answer
+// exactly its tool calls: missing results are filled in as interrupted and
+// results for calls it did not make are dropped. It expects alternating
+// roles starting with the user.
+func pairToolCalls(msgs []client.Message) ([]client.Message, []string) {
+	out := make([]client.Message, 0, len(msgs)+1)
+	var filled, orphans int
+	var calls []string
+	for _, m := range msgs {
+		if m.Role == "assistant" {
+			calls = toolUseIDs(m.Content)
+			out = append(out, m)
+			continue
+		}
+		blocks := contentBlocks(m.Content)
+		answered := make(map[string]bool)
+		var kept []interface{}
+		for _, b := range blocks {
+			id, isResult := toolResultID(b)
+			if isResult && (!contains(calls, id) || answered[id]) {
+				orphans++
+				continue
+			}
+			if isResult {
+				answered[id] = true
+			}
+			kept = append(kept, b)
+		}
+		var missing []interface{}
+		for _, id := range calls {
+			if !answered[id] {
+				missing = append(missing, interruptedBlock(id))
+				filled++
+			}
+		}
+		if len(kept) != len(blocks) || len(missing) > 0 {
+			// Results go first; text after them is still read.
+			m.Content = append(missing, kept...)
+		}
+		out = append(out, m)
+		calls = nil
+	}
+	if len(calls) > 0 {
+		results := make([]interface{}, 0, len(calls))
+		for _, id := range calls {
+			results = append(results, interruptedBlock(id))
+			filled++
+		}
+		out = append(out, client.Message{Role: "user", Content: results})
+	}
+	var fixes []string
+	if filled > 0 {
+		fixes = append(fixes, fmt.Sprintf("marked %d unanswered tool %s as interrupted", filled, pluralize(filled, "call", "calls")))
+	}
+	if orphans > 0 {
+		fixes = append(fixes, fmt.Sprintf("dropped %d tool %s without a matching call", orphans, pluralize(orphans, "result", "results")))
+	}
+	return out, fixes
+}
+
+// contentBlocks returns message content as a list of blocks; a plain
+// string becomes one text block, and an empty one none.
+func contentBlocks(content interface{}) []interface{} {
+	switch c := content.(type) {
+	case string:
+		if strings.TrimSpace(c) == "" {
+			return nil
+		}
+		return []interface{}{map[string]interface{}{"type": "text", "text": c}}
+	case []interface{}:
+		return c
+	}
+	return nil
+}
+
+func toolUseIDs(content interface{}) []string {
+	var ids []string
+	for _, b := range contentBlocks(content) {
+		if m, ok := b.(map[string]interface{}); ok && m["type"] == "tool_use" {
+			if id, _ := m["id"].(string); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func toolResultID(block interface{}) (string, bool) {
+	m, ok := block.(map[string]interface{})
+	if !ok || m["type"] != "tool_result" {
+		return "", false
+	}
+	id, _ := m["tool_use_id"].(string)
+	return id, true
+}
+
+func interruptedBlock(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "tool_result",
+		"tool_use_id": id,
+		"content":     interruptedResult,
+		"is_error":    true,
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
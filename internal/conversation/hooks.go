@@ -0,0 +1,135 @@
+package conversation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/tools"
+)
+
+const defaultHookTimeout = 60 * time.Second
+
+// hookEvent is what a hook receives on stdin.
+type hookEvent struct {
+	Event   string                 `json:"event"`
+	Tool    string                 `json:"tool,omitempty"`
+	Input   map[string]interface{} `json:"input,omitempty"`
+	Result  *tools.ToolResult      `json:"result,omitempty"`
+	WorkDir string                 `json:"work_dir"`
+}
+
+// SetHooks replaces the hooks run around tool calls and at the end of a
+// turn.
+func (s *Session) SetHooks(h config.Hooks) {
+	s.hooks = h
+}
+
+// trustSharedHooks asks once per change whether the hooks checked in with
+// the project may run, and drops them if not.
+func (s *Session) trustSharedHooks(settings *config.Settings) {
+	fp := settings.HooksFingerprint
+	if fp == "" || config.HooksTrusted(s.workDir, fp) {
+		return
+	}
+	var commands []string
+	for _, hooks := range [][]config.Hook{settings.Hooks.PreToolUse, settings.Hooks.PostToolUse, settings.Hooks.Stop} {
+		for _, h := range hooks {
+			if h.Shared {
+				commands = append(commands, h.Command)
+			}
+		}
+	}
+	display.WarningMessage(fmt.Sprintf("%s defines hooks that run automatically:\n    %s",
+		config.SettingsPath(s.workDir), strings.Join(commands, "\n    ")))
+	if !s.confirm("", map[string]interface{}{"commands": commands}, i18n.T("confirm.trust_hooks")) {
+		settings.Hooks = settings.Hooks.WithoutShared()
+		return
+	}
+	if err := config.TrustHooks(s.workDir, fp); err != nil {
+		display.WarningMessage(fmt.Sprintf("Could not remember the decision: %v", err))
+	}
+}
+
+// preToolHooks runs the pre_tool_use hooks of a call and returns why it is
+// blocked, or "" to let it run.
+func (s *Session) preToolHooks(name string, input map[string]interface{}) string {
+	for _, h := range matchingHooks(s.hooks.PreToolUse, name) {
+		out, err := s.runHook(h, hookEvent{Event: "pre_tool_use", Tool: name, Input: input, WorkDir: s.workDir})
+		if err != nil {
+			return fmt.Sprintf("Blocked by hook %q: %s", h.Command, hookOutput(out, err))
+		}
+	}
+	return ""
+}
+
+// postToolHooks runs the post_tool_use hooks of a call; the output of a
+// failing hook is added to the result so the model can react to it.
+func (s *Session) postToolHooks(name string, input map[string]interface{}, result *tools.ToolResult) {
+	for _, h := range matchingHooks(s.hooks.PostToolUse, name) {
+		out, err := s.runHook(h, hookEvent{Event: "post_tool_use", Tool: name, Input: input, Result: result, WorkDir: s.workDir})
+		if err != nil {
+			result.Content += fmt.Sprintf("\n\nHook %q failed: %s", h.Command, hookOutput(out, err))
+		}
+	}
+}
+
+// stopHooks runs when a turn ends; failures are only reported.
+func (s *Session) stopHooks() {
+	for _, h := range s.hooks.Stop {
+		if out, err := s.runHook(h, hookEvent{Event: "stop", WorkDir: s.workDir}); err != nil {
+			display.WarningMessage(fmt.Sprintf("Hook %q failed: %s", h.Command, hookOutput(out, err)))
+		}
+	}
+}
+
+func matchingHooks(hooks []config.Hook, tool string) []config.Hook {
+	var out []config.Hook
+	for _, h := range hooks {
+		if h.Matcher != "" {
+			re, err := regexp.Compile("^(?:" + h.Matcher + ")$")
+			if err != nil || !re.MatchString(tool) {
+				continue
+			}
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+func (s *Session) runHook(h config.Hook, event hookEvent) (string, error) {
+	timeout := defaultHookTimeout
+	if h.Timeout > 0 {
+		timeout = time.Duration(h.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	cmd := s.executor.Shell().Command(ctx, h.Command)
+	cmd.Dir = s.executor.WorkDir()
+	cmd.Env = append(s.executor.Environ(), "APIPOD_HOOK_EVENT="+event.Event, "APIPOD_TOOL_NAME="+event.Tool)
+	cmd.Stdin = bytes.NewReader(payload)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return string(out), err
+}
+
+func hookOutput(out string, err error) string {
+	if out = strings.TrimSpace(out); out != "" {
+		return out
+	}
+	return err.Error()
+}
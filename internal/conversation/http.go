@@ -0,0 +1,48 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/har"
+)
+
+// CurlCommands returns the last n requests made by the HttpRequest tool as
+// curl command lines, oldest first; n <= 0 returns all of them.
+func (s *Session) CurlCommands(n int) []string {
+	history := s.executor.HTTPHistory()
+	if n > 0 && n < len(history) {
+		history = history[len(history)-n:]
+	}
+	cmds := make([]string, 0, len(history))
+	for _, r := range history {
+		cmds = append(cmds, r.Curl())
+	}
+	return cmds
+}
+
+func (s *Session) ShowCurl(n int) {
+	display.CurlCommands(s.CurlCommands(n))
+}
+
+// EnableHAR records the session's HttpRequest exchanges to a new HAR file
+// in dir (relative to the working directory) and returns its path.
+// Credentials in headers are masked so the file can be shared.
+func (s *Session) EnableHAR(dir string) (string, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.workDir, dir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("create har dir: %w", err)
+	}
+	path := filepath.Join(dir, "apipod-"+time.Now().Format("20060102-150405")+".har")
+	r, err := har.NewRecorder(path)
+	if err != nil {
+		return "", err
+	}
+	s.executor.SetHARRecorder(r)
+	return path, nil
+}
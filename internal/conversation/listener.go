@@ -0,0 +1,54 @@
+package conversation
+
+import (
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+)
+
+// Listener receives the steps of a turn as they happen, for front ends
+// that show the session somewhere other than the terminal, such as an
+// editor connected to apipod-cli serve. Calls come from the goroutine
+// running the turn.
+type Listener interface {
+	// Text is a chunk of the model's answer.
+	Text(text string)
+	// Thinking is a chunk of the model's extended thinking.
+	Thinking(text string)
+	// ToolCall is a tool call the model made, before it is approved.
+	ToolCall(id, name string, input map[string]interface{})
+	// ToolResult is what the tool call id returned, or why it did not run.
+	ToolResult(id, name, content string, isError, denied bool)
+}
+
+// SetListener reports the steps of every turn to l; nil stops reporting.
+func (s *Session) SetListener(l Listener) {
+	s.listener = l
+}
+
+// Approval is a question the session needs answered before it goes on.
+// Tool and Input are set when it is about a tool call.
+type Approval struct {
+	Tool     string                 `json:"tool,omitempty"`
+	Input    map[string]interface{} `json:"input,omitempty"`
+	Question string                 `json:"question"`
+}
+
+// Approver answers approvals in place of the terminal prompt, reporting
+// whether the action may go ahead.
+type Approver func(a Approval) bool
+
+// SetApprover sends the session's confirmations to fn instead of asking
+// on the terminal; nil asks on the terminal again.
+func (s *Session) SetApprover(fn Approver) {
+	s.approver = fn
+}
+
+// confirm asks question about a tool call, through the approver when one
+// is set.
+func (s *Session) confirm(tool string, input map[string]interface{}, question string) bool {
+	if s.approver != nil {
+		return s.approver(Approval{Tool: tool, Input: input, Question: question})
+	}
+	s.attention(i18n.T("notify.confirm", question))
+	return display.ConfirmPrompt(question)
+}
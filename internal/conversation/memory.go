@@ -0,0 +1,138 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/linediff"
+)
+
+// MemoryFile is the project memory: notes on how to build, test and work
+// in the repository that every session starts with.
+const MemoryFile = "APIPOD.md"
+
+// maxMemory bounds the memory put in the system prompt.
+const maxMemory = 32 * 1024
+
+// initTools may be used while /init scans the repository.
+var initTools = []string{"Read", "Glob", "Grep", "Stat"}
+
+const initPrompt = `Scan this repository to write %s, the project memory every future session in it starts with.
+Use Read, Glob and Grep to find out: the build system and the exact commands that build, test, lint and format the code (check manifests, Makefiles and CI configuration); how the source is laid out and what the main packages or modules do; the conventions the code follows (naming, error handling, tests, comments, commit messages); and anything non-obvious a newcomer would get wrong. Read a few representative files rather than everything. Do not change any files.%s`
+
+const initInstruction = `Write %s from what you found by calling project_memory. Use Markdown with short sections such as Commands, Layout, Conventions and Gotchas. Be concrete: exact commands and paths, not general advice. Only include what you verified in this repository, and keep it under 150 lines.`
+
+var memoryTool = client.ToolDefinition{
+	Name:        "project_memory",
+	Description: "Provide the complete contents of the project memory file.",
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"content": map[string]interface{}{"type": "string", "description": "The Markdown contents of the file"},
+		},
+		"required": []string{"content"},
+	},
+}
+
+// findMemory looks for the memory file in dir and its parents up to the
+// repository root, returning its path and contents.
+func findMemory(dir string) (string, string) {
+	for {
+		path := filepath.Join(dir, MemoryFile)
+		if data, err := os.ReadFile(path); err == nil {
+			return path, string(data)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+func memorySection(path, content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	if len(content) > maxMemory {
+		content = content[:maxMemory] + "\n[truncated]"
+	}
+	return fmt.Sprintf("Project memory from %s, written for this repository; follow it:\n%s\n", path, content)
+}
+
+// Init scans the repository with read-only tools and has the model write
+// the project memory, for /init. The result is shown as a diff against
+// the current file and written once the user approves; sessions started
+// afterwards, and this one, follow it.
+func (s *Session) Init() error {
+	path := s.memoryPath
+	if path == "" {
+		path = filepath.Join(s.workDir, MemoryFile)
+	}
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	prev := s.allowedTools
+	s.setAllowedTools(initTools)
+	defer func() { s.allowedTools = prev }()
+	note := ""
+	if len(old) > 0 {
+		note = fmt.Sprintf("\n\n%s already exists; keep what is still accurate and improve the rest:\n%s", MemoryFile, old)
+	}
+	if err := s.SendMessage(fmt.Sprintf(initPrompt, MemoryFile, note)); err != nil {
+		return err
+	}
+
+	raw, err := s.StructuredOutput(fmt.Sprintf(initInstruction, MemoryFile), memoryTool)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", MemoryFile, err)
+	}
+	var out struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return fmt.Errorf("write %s: %w", MemoryFile, err)
+	}
+	content := strings.TrimSpace(out.Content) + "\n"
+	if content == string(old) {
+		display.InfoMessage(MemoryFile + " is up to date")
+		return nil
+	}
+
+	rel, _ := filepath.Rel(s.workDir, path)
+	note = "updated"
+	if len(old) == 0 {
+		note = "new file"
+	}
+	display.ReviewFile(rel, note, 1, 1)
+	for _, h := range linediff.Hunks(linediff.Diff(linediff.Split(string(old)), linediff.Split(content)), reviewContext) {
+		lines := make([]string, len(h.Lines))
+		for k, l := range h.Lines {
+			lines[k] = string(l.Kind) + l.Text
+		}
+		display.DiffHunk(rel, h.Header(), lines)
+	}
+	if !display.ConfirmPrompt(i18n.T("confirm.write_memory", rel)) {
+		display.InfoMessage(MemoryFile + " not written")
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", MemoryFile, err)
+	}
+	s.memoryPath, s.memory = path, content
+	s.system = s.composeSystem()
+	display.SuccessMessage("Wrote " + rel)
+	return nil
+}
@@ -0,0 +1,62 @@
+package conversation
+
+import (
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/notify"
+)
+
+// defaultMinTurn is how long a turn must run before its end is announced.
+const defaultMinTurn = 30 * time.Second
+
+// FocusSource tells whether the terminal has focus, such as input.Reader.
+type FocusSource interface {
+	// Focused reports whether the terminal has focus and whether that is
+	// known at all.
+	Focused() (focused, known bool)
+}
+
+// SetFocusSource turns on notifications: when the terminal is not
+// focused, or focus is unknown, the end of a long turn and confirmations
+// are announced as configured in "notifications".
+func (s *Session) SetFocusSource(f FocusSource) {
+	s.focus = f
+}
+
+// attention notifies the user unless they are looking at the terminal.
+func (s *Session) attention(message string) {
+	if s.focus == nil {
+		return
+	}
+	n := s.notifications
+	if n == nil {
+		n = &config.Notifications{}
+	}
+	if n.Method == notify.Off {
+		return
+	}
+	if focused, known := s.focus.Focused(); focused && known && !n.Always {
+		return
+	}
+	notify.Send(n.Method, message)
+}
+
+// announceTurn notifies the end of a turn that took a while.
+func (s *Session) announceTurn(err error) {
+	minTurn := defaultMinTurn
+	if s.notifications != nil && s.notifications.MinTurnSeconds > 0 {
+		minTurn = time.Duration(s.notifications.MinTurnSeconds) * time.Second
+	}
+	took := time.Since(s.turnStarted)
+	if s.turnStarted.IsZero() || took < minTurn {
+		return
+	}
+	took = took.Round(time.Second)
+	if err != nil {
+		s.attention(i18n.T("notify.failed", took, err))
+		return
+	}
+	s.attention(i18n.T("notify.done", took))
+}
@@ -0,0 +1,80 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/editor"
+)
+
+// Open opens "file" or "file:line" in the configured editor, falling back
+// to $VISUAL and $EDITOR. An empty spec opens the file changed last; a
+// name that does not exist is looked up among the files changed this
+// session, so "/open handler.go" finds internal/api/handler.go.
+func (s *Session) Open(spec string) (string, error) {
+	path, line := splitLine(strings.TrimSpace(spec))
+	if path == "" {
+		if s.lastModified == "" {
+			return "", fmt.Errorf("no file changed yet; use /open <file>[:line]")
+		}
+		path = s.lastModified
+	}
+	resolved, err := s.resolveOpenPath(path)
+	if err != nil {
+		return "", err
+	}
+	err = s.withTerminal(func() error {
+		return editor.Open(editor.Resolve(s.editor), resolved, line)
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// splitLine separates a trailing ":line" from a path.
+func splitLine(spec string) (string, int) {
+	i := strings.LastIndexByte(spec, ':')
+	if i < 0 {
+		return spec, 0
+	}
+	n, err := strconv.Atoi(spec[i+1:])
+	if err != nil || n < 1 {
+		return spec, 0
+	}
+	return spec[:i], n
+}
+
+func (s *Session) resolveOpenPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.executor.WorkDir(), path)
+	}
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	var matches []string
+	suffix := string(filepath.Separator) + filepath.Base(path)
+	for p := range s.modified {
+		if strings.HasSuffix(p, suffix) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return "", fmt.Errorf("%s does not exist", path)
+	default:
+		return "", fmt.Errorf("%s matches several changed files; give more of the path", filepath.Base(path))
+	}
+}
@@ -0,0 +1,211 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/injection"
+	"github.com/rpay/apipod-cli/internal/redact"
+	"github.com/rpay/apipod-cli/internal/sessionstore"
+	"github.com/rpay/apipod-cli/internal/tools"
+)
+
+// ApplyConfig applies the config-driven session options.
+func (s *Session) ApplyConfig(cfg *config.Config) error {
+	if cfg.NoColor {
+		display.DisableColor()
+	}
+	if err := i18n.LoadDir(config.LocalesDir()); err != nil {
+		display.WarningMessage(fmt.Sprintf("Translations not loaded: %v", err))
+	}
+	i18n.SetLocale(cfg.Language)
+	if err := display.SetTheme(cfg.Theme, cfg.ThemeColors); err != nil {
+		return err
+	}
+	if cfg.InsecureSkipVerify {
+		display.WarningMessage("TLS certificate verification is off (insecure_skip_verify)")
+	}
+	transport, err := client.NewTransport(cfg.ClientOptions())
+	if err != nil {
+		return err
+	}
+	s.transport = transport
+	settings, err := config.LoadSettings(s.workDir)
+	if err != nil {
+		return err
+	}
+	for _, ignored := range settings.Ignored {
+		display.WarningMessage(fmt.Sprintf("Ignoring %s from %s", ignored, config.SettingsPath(s.workDir)))
+	}
+	s.trustSharedHooks(settings)
+	// Project settings apply over the user config, and APIPOD_MODEL over
+	// both; flags are applied by the caller afterwards.
+	if settings.Model != "" && os.Getenv("APIPOD_MODEL") == "" {
+		s.SetModel(settings.Model)
+	}
+	env := make(map[string]string)
+	for _, vars := range []map[string]string{cfg.Env, settings.Env} {
+		for k, v := range vars {
+			env[k] = v
+		}
+	}
+	s.executor.SetEnv(env)
+	var hooks config.Hooks
+	if cfg.Hooks != nil {
+		hooks = *cfg.Hooks
+	}
+	hooks.Merge(settings.Hooks)
+	s.SetHooks(hooks)
+	allowed, disabled, err := toolFilter(cfg, settings)
+	if err != nil {
+		return err
+	}
+	s.executor.SetToolSettings(disabled, allowed, settings.ToolDefaults)
+	// The project's prompt wins over the user's; additions from both apply.
+	override := cfg.SystemPrompt
+	if settings.SystemPrompt != "" {
+		override = settings.SystemPrompt
+	}
+	s.systemAppend = nil
+	s.OverrideSystemPrompt(override)
+	s.AppendSystemPrompt(cfg.AppendSystemPrompt)
+	s.AppendSystemPrompt(settings.AppendSystemPrompt)
+	// A style picked with /output-style in the project wins over the config.
+	style := cfg.OutputStyle
+	if settings.OutputStyle != "" {
+		style = settings.OutputStyle
+	}
+	if err := s.SetOutputStyle(style); err != nil {
+		return err
+	}
+	s.SetToolDefinitionMode(cfg.ToolDefinitions)
+	s.EnableSemanticSearch(cfg.SemanticSearch, cfg.BaseURL, cfg.APIKey)
+	s.SetSafeCommands(append(append([]string(nil), cfg.SafeCommands...), settings.SafeCommands...))
+	s.SetReviewChanges(cfg.ReviewChanges)
+	s.notifications = cfg.Notifications
+	s.telemetry = cfg.TelemetryExporter(display.Version())
+	s.executor.SetDenyPaths(append(append([]string(nil), cfg.DenyPaths...), settings.DenyPaths...))
+	if cfg.Shell != "" {
+		if err := s.SetShell(cfg.Shell); err != nil {
+			return err
+		}
+	}
+	if cfg.Grpc != nil && cfg.Grpc.Target != "" {
+		s.executor.SetGrpcTarget(&tools.GrpcTarget{
+			Address:   cfg.Grpc.Target,
+			Plaintext: cfg.Grpc.Plaintext,
+			Headers:   cfg.Grpc.Headers,
+		})
+	}
+	s.executor.SetGitHostTokens(cfg.GitHubToken, cfg.GitLabToken)
+	for _, dir := range cfg.AddDirs {
+		// Applying the config again keeps the directories already added.
+		if err := s.AddDir(dir); err != nil && !errors.Is(err, tools.ErrRootExists) {
+			return err
+		}
+	}
+	s.share = cfg.Share
+	s.editor = cfg.Editor
+	s.smallModel = cfg.SmallModel
+	s.secondOpinion = cfg.SecondOpinionClient
+	s.executor.SetCompactRereads(cfg.CompactRereads)
+	if cfg.SessionStore != "" {
+		store, err := sessionstore.Open(cfg.SessionStore)
+		if err != nil {
+			return err
+		}
+		s.SetSessionStore(store)
+	}
+	if cfg.HarDir != "" {
+		if _, err := s.EnableHAR(cfg.HarDir); err != nil {
+			return err
+		}
+	}
+	if !cfg.NoLog {
+		if _, err := s.EnableAuditLog(cfg.LogPath(), cfg.LogMaxSizeMB, cfg.LogRetentionDays); err != nil {
+			display.WarningMessage(fmt.Sprintf("Audit log disabled: %v", err))
+		}
+	}
+	s.SetThinkingBudget(cfg.ThinkingBudget)
+	s.SetShowThinking(cfg.ShowThinking)
+	s.SetSampling(Sampling{
+		MaxTokens:     cfg.MaxTokens,
+		Temperature:   cfg.Temperature,
+		TopP:          cfg.TopP,
+		StopSequences: cfg.StopSequences,
+	})
+	s.SetTurnBudget(cfg.TurnBudget)
+	s.SetTurnTokenBudget(cfg.TurnTokenBudget)
+	s.SetMaxIterations(cfg.MaxIterations)
+	s.SetMaxContinues(cfg.MaxContinues)
+
+	if cfg.DisableRedaction {
+		s.SetRedactor(nil)
+	} else {
+		r, err := redact.New(cfg.RedactPatterns)
+		if err != nil {
+			return err
+		}
+		s.SetRedactor(r)
+	}
+	s.SetInjectionGuard(nil)
+	if cfg.InjectionGuard {
+		g, err := injection.New(cfg.InjectionPatterns)
+		if err != nil {
+			return err
+		}
+		s.SetInjectionGuard(g)
+	}
+	return nil
+}
+
+// SetShell changes the shell the Bash tool runs commands with and tells
+// the model about it.
+func (s *Session) SetShell(name string) error {
+	old := s.executor.Shell().Name
+	if err := s.executor.SetShell(name); err != nil {
+		return err
+	}
+	s.SetSystemPrompt(strings.Replace(s.baseSystem, "Shell: "+old+" ", "Shell: "+s.executor.Shell().Name+" ", 1))
+	return nil
+}
+
+// toolFilter combines the tool lists of the config and the project
+// settings: a tool is offered when both allow it and neither disables it.
+// A nil allowed list allows every tool.
+func toolFilter(cfg *config.Config, settings *config.Settings) (allowed, disabled []string, err error) {
+	known := make(map[string]bool)
+	for _, name := range tools.ToolNames() {
+		known[name] = true
+	}
+	for _, list := range [][]string{cfg.AllowedTools, cfg.DisallowedTools, settings.AllowedTools, settings.DisabledTools} {
+		for _, name := range list {
+			if !known[name] {
+				return nil, nil, fmt.Errorf("unknown tool %q in the allowed or disallowed tools", name)
+			}
+		}
+	}
+	disabled = append(append(disabled, cfg.DisallowedTools...), settings.DisabledTools...)
+	switch {
+	case cfg.AllowedTools == nil:
+		allowed = settings.AllowedTools
+	case settings.AllowedTools == nil:
+		allowed = cfg.AllowedTools
+	default:
+		allowed = []string{}
+		for _, name := range cfg.AllowedTools {
+			for _, other := range settings.AllowedTools {
+				if name == other {
+					allowed = append(allowed, name)
+				}
+			}
+		}
+	}
+	return allowed, disabled, nil
+}
@@ -0,0 +1,86 @@
+package conversation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// outputStyles are the instructions each response style adds to the
+// system prompt.
+var outputStyles = map[string]string{
+	"concise": "Output style: concise. Answer in as few words as the task allows. " +
+		"Skip preambles, summaries of what you did and offers of further help; " +
+		"prefer a code block or a one-line answer over prose.",
+	"explanatory": "Output style: explanatory. Along with the work, explain the reasoning " +
+		"behind your choices: why this approach, what the alternatives were and what " +
+		"trade-offs they carry, and point out anything in the codebase that is worth knowing.",
+	"teaching": "Output style: teaching. The user is learning. Explain concepts as you use " +
+		"them, step by step, and show small examples. When a change is a good exercise, " +
+		"describe what to do and leave a clearly marked TODO for the user instead of " +
+		"writing all of it yourself.",
+	"json-only": "Output style: json-only. Your final answer of each turn must be a single " +
+		"valid JSON value and nothing else: no prose before or after it and no markdown " +
+		"code fences. Use tools as usual while working.",
+}
+
+// OutputStyleNames returns the response styles in alphabetical order.
+func OutputStyleNames() []string {
+	names := make([]string, 0, len(outputStyles))
+	for name := range outputStyles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OutputStyle returns the response style in effect, "" for the default.
+func (s *Session) OutputStyle() string {
+	return s.outputStyle
+}
+
+// SetOutputStyle changes the response style; "" or "default" goes back to
+// the default. json-only also prints responses without markdown rendering
+// so they can be copied or piped as they are.
+func (s *Session) SetOutputStyle(name string) error {
+	if name == "default" {
+		name = ""
+	}
+	if _, ok := outputStyles[name]; name != "" && !ok {
+		return fmt.Errorf("unknown output style %q; use %s or default", name, strings.Join(OutputStyleNames(), ", "))
+	}
+	s.outputStyle = name
+	display.SetRawResponses(name == "json-only")
+	s.system = s.composeSystem()
+	return nil
+}
+
+// SwitchOutputStyle changes the response style for /output-style and
+// remembers it in the project's settings.local.json for later sessions.
+func (s *Session) SwitchOutputStyle(name string) error {
+	if err := s.SetOutputStyle(name); err != nil {
+		return err
+	}
+	if err := config.SetLocalSetting(s.workDir, "output_style", s.outputStyle); err != nil {
+		return fmt.Errorf("save output style: %w", err)
+	}
+	if s.outputStyle == "" {
+		display.SuccessMessage("Output style: default")
+	} else {
+		display.SuccessMessage("Output style: " + s.outputStyle)
+	}
+	return nil
+}
+
+// ShowOutputStyles lists the response styles with the current one marked,
+// for /output-style without arguments.
+func (s *Session) ShowOutputStyles() {
+	current := s.outputStyle
+	if current == "" {
+		current = "default"
+	}
+	display.InfoMessage(fmt.Sprintf("Output style: %s (available: default, %s)", current, strings.Join(OutputStyleNames(), ", ")))
+}
@@ -0,0 +1,50 @@
+package conversation
+
+import (
+	"fmt"
+
+	"github.com/rpay/apipod-cli/internal/presence"
+)
+
+// StartPairing lets teammates on this machine watch the session read-only
+// with `apipod-cli attach <id>`, and returns the ID to give them.
+func (s *Session) StartPairing() (string, error) {
+	if s.presence != nil {
+		return s.presence.ID(), nil
+	}
+	b, err := presence.Share(s.SessionID())
+	if err != nil {
+		return "", fmt.Errorf("start pairing: %w", err)
+	}
+	s.presence = b
+	return b.ID(), nil
+}
+
+// StopPairing disconnects the watchers.
+func (s *Session) StopPairing() error {
+	if s.presence == nil {
+		return nil
+	}
+	err := s.presence.Close()
+	s.presence = nil
+	return err
+}
+
+// Pairing reports whether the session is shared and how many watch it.
+func (s *Session) Pairing() (shared bool, watchers int) {
+	if s.presence == nil {
+		return false, 0
+	}
+	return true, s.presence.Watchers()
+}
+
+// withTerminal runs fn with the real terminal as stdout, for programs such
+// as an editor that take it over while the session is shared.
+func (s *Session) withTerminal(fn func() error) error {
+	if s.presence == nil {
+		return fn()
+	}
+	resume := s.presence.Pause()
+	defer resume()
+	return fn()
+}
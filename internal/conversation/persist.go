@@ -0,0 +1,167 @@
+package conversation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/sessionstore"
+)
+
+const sessionFormatVersion = 1
+
+// savedSession is the document a session is persisted as.
+type savedSession struct {
+	Version  int              `json:"version"`
+	ID       string           `json:"id"`
+	Model    string           `json:"model"`
+	WorkDir  string           `json:"work_dir"`
+	Created  time.Time        `json:"created"`
+	Updated  time.Time        `json:"updated"`
+	Usage    client.Usage     `json:"usage"`
+	Modified []string         `json:"modified,omitempty"`
+	Messages []client.Message `json:"messages"`
+}
+
+// SetSessionStore saves the conversation to store after every prompt so it
+// can be resumed later, possibly on another machine; nil stops saving.
+func (s *Session) SetSessionStore(store sessionstore.Store) {
+	s.store = store
+}
+
+// SessionID returns the name the session is saved under, e.g.
+// "20261015-061536-3f9a2c1b".
+func (s *Session) SessionID() string {
+	if s.sessionID == "" {
+		b := make([]byte, 4)
+		rand.Read(b)
+		s.created = time.Now().UTC()
+		s.sessionID = s.created.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+	}
+	return s.sessionID
+}
+
+// TranscriptLocation is where the session is saved, e.g.
+// "s3://bucket/runs/20261015-061536-3f9a2c1b.json", or "" without a
+// session store.
+func (s *Session) TranscriptLocation() string {
+	if s.store == nil {
+		return ""
+	}
+	return strings.TrimSuffix(s.store.Location(), "/") + "/" + s.SessionID() + ".json"
+}
+
+// SaveSession writes the conversation to the session store.
+func (s *Session) SaveSession() error {
+	if s.store == nil {
+		return fmt.Errorf("no session store configured (set session_store)")
+	}
+	doc := savedSession{
+		Version:  sessionFormatVersion,
+		ID:       s.SessionID(),
+		Model:    s.model,
+		WorkDir:  s.workDir,
+		Created:  s.created,
+		Updated:  time.Now().UTC(),
+		Usage:    s.usage,
+		Messages: s.messages,
+	}
+	for p := range s.modified {
+		doc.Modified = append(doc.Modified, p)
+	}
+	sort.Strings(doc.Modified)
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encode session: %w", err)
+	}
+	return s.store.Put(doc.ID+".json", data)
+}
+
+// ListSessions returns the IDs of the saved sessions, oldest first.
+func (s *Session) ListSessions() ([]string, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("no session store configured (set session_store)")
+	}
+	names, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(names))
+	for i, n := range names {
+		ids[i] = strings.TrimSuffix(n, ".json")
+	}
+	return ids, nil
+}
+
+// ResumeSession loads a saved conversation, the most recent one when id is
+// empty, and continues it under the same ID.
+func (s *Session) ResumeSession(id string) error {
+	if id == "" {
+		ids, err := s.ListSessions()
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no saved sessions in %s", s.store.Location())
+		}
+		id = ids[len(ids)-1]
+	} else if s.store == nil {
+		return fmt.Errorf("no session store configured (set session_store)")
+	}
+	data, err := s.store.Get(strings.TrimSuffix(id, ".json") + ".json")
+	if err != nil {
+		return fmt.Errorf("resume %s: %w", id, err)
+	}
+	var doc savedSession
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse session %s: %w", id, err)
+	}
+	if doc.Version > sessionFormatVersion {
+		return fmt.Errorf("session %s was saved by a newer version of apipod-cli", id)
+	}
+
+	if s.audit != nil {
+		s.audit.Resume(doc.ID)
+	}
+	s.sessionID, s.created = doc.ID, doc.Created
+	s.messages = doc.Messages
+	s.executor.ForgetReads()
+	s.usage = doc.Usage
+	s.usageAt = make(map[int]client.Usage)
+	s.modified = make(map[string]bool)
+	for _, p := range doc.Modified {
+		s.modified[p] = true
+	}
+	if doc.WorkDir != s.workDir {
+		display.WarningMessage(fmt.Sprintf("Session %s was started in %s", doc.ID, doc.WorkDir))
+	}
+	display.InfoMessage(fmt.Sprintf("Resumed session %s (%d messages)", doc.ID, len(doc.Messages)))
+	return nil
+}
+
+// ShowSessions lists the saved sessions.
+func (s *Session) ShowSessions() error {
+	ids, err := s.ListSessions()
+	if err != nil {
+		return err
+	}
+	display.Sessions(ids, s.sessionID, s.store.Location())
+	return nil
+}
+
+// autosave saves after a prompt when a store is configured; a failure is
+// reported but does not fail the prompt.
+func (s *Session) autosave() {
+	if s.store == nil {
+		return
+	}
+	if err := s.SaveSession(); err != nil {
+		display.WarningMessage(fmt.Sprintf("Session not saved: %v", err))
+	}
+}
@@ -0,0 +1,82 @@
+package conversation
+
+import (
+	"strconv"
+	"strings"
+)
+
+// previewKeys name the input shown while a tool call is streamed: what the
+// user most needs to see to stop a bad call, such as the Bash command.
+var previewKeys = map[string][]string{
+	"Bash":        {"command"},
+	"Read":        {"file_path"},
+	"Write":       {"file_path"},
+	"Edit":        {"file_path"},
+	"MultiEdit":   {"file_path"},
+	"EditLines":   {"file_path"},
+	"HttpRequest": {"url", "curl"},
+	"WebSocket":   {"url"},
+	"Grep":        {"pattern"},
+	"Glob":        {"pattern"},
+	"Stat":        {"path"},
+	"Archive":     {"archive_path"},
+}
+
+// previewInput returns the value of the tool's preview key in partial, the
+// tool input JSON received so far, even when the string is still open.
+func previewInput(toolName, partial string) (string, bool) {
+	for _, key := range previewKeys[toolName] {
+		if v, ok := partialString(partial, key); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// partialString decodes the string value of key in possibly truncated JSON.
+func partialString(buf, key string) (string, bool) {
+	i := strings.Index(buf, strconv.Quote(key))
+	if i < 0 {
+		return "", false
+	}
+	rest := strings.TrimLeft(buf[i+len(key)+2:], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return "", false
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return "", false
+	}
+	var b strings.Builder
+	for j := 1; j < len(rest); j++ {
+		c := rest[j]
+		switch {
+		case c == '"':
+			return b.String(), true
+		case c != '\\':
+			b.WriteByte(c)
+		case j+1 >= len(rest):
+			return b.String(), true
+		default:
+			j++
+			switch rest[j] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+			case 'u':
+				if j+4 >= len(rest) {
+					return b.String(), true
+				}
+				if r, err := strconv.ParseUint(rest[j+1:j+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+				}
+				j += 4
+			default:
+				b.WriteByte(rest[j])
+			}
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,46 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/project"
+)
+
+// profileSection tells the model what the project is built with and the
+// commands that build, test and lint it.
+func profileSection(p *project.Profile, cwd string) string {
+	var sb strings.Builder
+	sb.WriteString("\nProject profile:\n")
+	if p.Root != cwd {
+		fmt.Fprintf(&sb, "- Root: %s\n", p.Root)
+	}
+	fmt.Fprintf(&sb, "- Primary language: %s\n", p.Language)
+	for _, s := range p.Stacks {
+		fmt.Fprintf(&sb, "- %s", s.Manifest)
+		if s.Name != "" {
+			fmt.Fprintf(&sb, " %s", s.Name)
+		}
+		fmt.Fprintf(&sb, " (%s", s.Language)
+		if s.Version != "" {
+			fmt.Fprintf(&sb, " %s", s.Version)
+		}
+		if s.Tool != s.Language {
+			fmt.Fprintf(&sb, ", %s", s.Tool)
+		}
+		sb.WriteString(")")
+		var cmds []string
+		for _, c := range s.Commands() {
+			cmds = append(cmds, fmt.Sprintf("%s `%s`", c[0], c[1]))
+		}
+		if len(cmds) > 0 {
+			fmt.Fprintf(&sb, ": %s", strings.Join(cmds, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	if len(p.MakeTargets) > 0 {
+		fmt.Fprintf(&sb, "- Makefile targets: %s\n", strings.Join(p.MakeTargets, ", "))
+	}
+	sb.WriteString("Use these commands to build, test and lint, run from the root, unless the task says otherwise.\n")
+	return sb.String()
+}
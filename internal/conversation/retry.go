@@ -0,0 +1,57 @@
+package conversation
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Retry resends the request a turn failed on, e.g. after a dropped
+// connection or an overloaded API. A failed response is never added to
+// history, which still ends with what it answered: the prompt or the
+// results of the tools that ran before it. Only that request is sent
+// again; no tool runs twice.
+func (s *Session) Retry() error {
+	if !s.unanswered() {
+		return fmt.Errorf("nothing to retry: the last turn finished")
+	}
+	s.resetTurn()
+	if s.turnBefore == nil {
+		s.turnBefore = make(map[string]snapshot)
+	}
+	display.InfoMessage("Retrying the last request")
+	return s.finishTurn(s.runLoop())
+}
+
+// unanswered reports whether history ends with a user message the model
+// has not answered, which is where a failed turn leaves it.
+func (s *Session) unanswered() bool {
+	return len(s.messages) > 0 && s.messages[len(s.messages)-1].Role == "user"
+}
+
+// dropUserText removes text from the end of the trailing user message if
+// appendUserText put it there, so a retry does not send it twice.
+func (s *Session) dropUserText(text string) {
+	if !s.unanswered() {
+		return
+	}
+	last := &s.messages[len(s.messages)-1]
+	blocks, ok := last.Content.([]interface{})
+	if !ok || len(blocks) == 0 {
+		return
+	}
+	want := map[string]interface{}{"type": "text", "text": text}
+	if !reflect.DeepEqual(blocks[len(blocks)-1], want) {
+		return
+	}
+	blocks = blocks[:len(blocks)-1]
+	if len(blocks) == 1 {
+		// appendUserText turned a plain prompt into blocks; undo that too.
+		if b, ok := blocks[0].(map[string]interface{}); ok && b["type"] == "text" {
+			last.Content = b["text"]
+			return
+		}
+	}
+	last.Content = blocks
+}
@@ -0,0 +1,141 @@
+package conversation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/linediff"
+)
+
+const reviewContext = 3
+
+// stagedTools are the tools whose changes go through the review queue.
+var stagedTools = map[string]bool{
+	"Write": true, "Edit": true, "MultiEdit": true, "EditLines": true, "ApplyPatch": true,
+}
+
+// SetReviewChanges turns the review queue on or off. While it is on, file
+// changes made during a turn are staged in memory without a prompt per
+// call, and at the end of the turn each hunk is shown for the user to
+// apply or skip before anything is written.
+func (s *Session) SetReviewChanges(on bool) {
+	s.executor.SetStaging(on)
+}
+
+// ToggleReviewChanges flips the review queue and returns whether it is on.
+func (s *Session) ToggleReviewChanges() bool {
+	s.SetReviewChanges(!s.executor.Staging())
+	return s.executor.Staging()
+}
+
+// reviewNote tells the model during a turn that its changes are staged.
+func (s *Session) reviewNote() string {
+	if !s.executor.Staging() {
+		return ""
+	}
+	return "\n\nFile changes are staged for the user to review at the end of your turn. " +
+		"Read shows the staged content; Bash, Grep and Glob see the files on disk, so builds and tests do not include your changes yet.\n"
+}
+
+// reviewStaged walks the user through the staged changes hunk by hunk,
+// writes what was accepted and remembers what was skipped so the model
+// learns about it with the next prompt.
+func (s *Session) reviewStaged() {
+	changes := s.executor.StagedChanges()
+	if len(changes) == 0 {
+		return
+	}
+	var skipped []string
+	quit := false
+	for i, c := range changes {
+		rel := c.Path
+		if r, err := filepath.Rel(s.workDir, c.Path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+		if quit {
+			s.executor.DropStaged(c.Path)
+			skipped = append(skipped, rel+" (not written)")
+			continue
+		}
+
+		note := ""
+		switch {
+		case c.Created:
+			note = "new file"
+		case c.Deleted:
+			note = "deleted"
+		}
+		display.ReviewFile(rel, note, i+1, len(changes))
+
+		if c.Deleted {
+			switch display.ReviewPrompt("Delete this file?") {
+			case "y", "a":
+				if err := s.executor.ApplyStaged(c.Path, "", true); err != nil {
+					skipped = append(skipped, fmt.Sprintf("%s (delete failed: %v)", rel, err))
+				}
+			case "q":
+				quit = true
+				fallthrough
+			default:
+				s.executor.DropStaged(c.Path)
+				skipped = append(skipped, rel+" (not deleted)")
+			}
+			continue
+		}
+
+		old := linediff.Split(c.Old)
+		hunks := linediff.Hunks(linediff.Diff(old, linediff.Split(c.New)), reviewContext)
+		accept := make([]bool, len(hunks))
+		decided, all := false, false
+		for j, h := range hunks {
+			if decided {
+				accept[j] = all
+				continue
+			}
+			lines := make([]string, len(h.Lines))
+			for k, l := range h.Lines {
+				lines[k] = string(l.Kind) + l.Text
+			}
+			display.DiffHunk(c.Path, h.Header(), lines)
+			switch display.ReviewPrompt(fmt.Sprintf("Apply hunk %d/%d?", j+1, len(hunks))) {
+			case "y":
+				accept[j] = true
+			case "a":
+				accept[j], decided, all = true, true, true
+			case "d":
+				decided = true
+			case "q":
+				decided, quit = true, true
+			}
+		}
+
+		applied := 0
+		for _, ok := range accept {
+			if ok {
+				applied++
+			}
+		}
+		content := c.New
+		if applied < len(hunks) {
+			content = strings.Join(linediff.Apply(old, hunks, accept), "")
+		}
+		switch {
+		case len(hunks) > 0 && applied == 0:
+			s.executor.DropStaged(c.Path)
+			delete(s.modified, c.Path)
+			skipped = append(skipped, rel+" (not written)")
+		case s.executor.ApplyStaged(c.Path, content, false) != nil:
+			skipped = append(skipped, rel+" (write failed)")
+		case applied < len(hunks):
+			skipped = append(skipped, fmt.Sprintf("%s (%d of %d hunks skipped)", rel, len(hunks)-applied, len(hunks)))
+		}
+	}
+
+	if len(skipped) > 0 {
+		display.InfoMessage(fmt.Sprintf("Skipped changes: %s", strings.Join(skipped, ", ")))
+		s.pendingNote = "<review>The user reviewed your file changes and skipped some of them: " +
+			strings.Join(skipped, "; ") + ". Skipped changes were not written; read the files again before building on them.</review>"
+	}
+}
@@ -0,0 +1,113 @@
+package conversation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Sampling holds the request parameters that the config, flags and /set
+// can change. Zero values leave the defaults.
+type Sampling struct {
+	MaxTokens     int
+	Temperature   *float64
+	TopP          *float64
+	StopSequences []string
+}
+
+// SetSampling replaces the sampling parameters of later requests.
+func (s *Session) SetSampling(p Sampling) {
+	s.sampling = p
+}
+
+// applySampling copies the sampling parameters into req. The API rejects
+// temperature and top_p with extended thinking, so they wait until it is
+// off.
+func (s *Session) applySampling(req *client.MessagesRequest) {
+	req.MaxTokens = s.sampling.MaxTokens
+	req.StopSequences = s.sampling.StopSequences
+	if req.Thinking == nil {
+		req.Temperature = s.sampling.Temperature
+		req.TopP = s.sampling.TopP
+	}
+}
+
+// SetParam changes one sampling parameter for the rest of the session, for
+// /set: max_tokens, temperature, top_p or stop_sequences (comma-separated).
+// "default" restores the default.
+func (s *Session) SetParam(key, value string) error {
+	value = strings.TrimSpace(value)
+	reset := value == "default"
+	p := &s.sampling
+	switch key {
+	case "max_tokens":
+		if reset {
+			p.MaxTokens = 0
+			break
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("max_tokens must be a positive whole number")
+		}
+		p.MaxTokens = n
+	case "temperature", "top_p":
+		var v *float64
+		if !reset {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f < 0 || f > 1 {
+				return fmt.Errorf("%s must be between 0 and 1", key)
+			}
+			v = &f
+		}
+		if key == "temperature" {
+			p.Temperature = v
+		} else {
+			p.TopP = v
+		}
+	case "stop_sequences":
+		p.StopSequences = nil
+		if !reset {
+			for _, seq := range strings.Split(value, ",") {
+				if seq = strings.TrimSpace(seq); seq != "" {
+					p.StopSequences = append(p.StopSequences, seq)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unknown parameter %q; use max_tokens, temperature, top_p or stop_sequences", key)
+	}
+	if s.thinkingBudget > 0 && (key == "temperature" || key == "top_p") && !reset {
+		display.WarningMessage(key + " is not sent while thinking is on")
+	}
+	return nil
+}
+
+// ShowParams lists the sampling parameters in effect, for /set without
+// arguments.
+func (s *Session) ShowParams() {
+	p := s.sampling
+	maxTokens := fmt.Sprintf("%d (default)", client.DefaultMaxTokens)
+	if p.MaxTokens > 0 {
+		maxTokens = strconv.Itoa(p.MaxTokens)
+	}
+	stops := "none"
+	if len(p.StopSequences) > 0 {
+		quoted := make([]string, len(p.StopSequences))
+		for i, seq := range p.StopSequences {
+			quoted[i] = strconv.Quote(seq)
+		}
+		stops = strings.Join(quoted, ", ")
+	}
+	display.InfoMessage(fmt.Sprintf("max_tokens %s · temperature %s · top_p %s · stop_sequences %s",
+		maxTokens, formatParam(p.Temperature), formatParam(p.TopP), stops))
+}
+
+func formatParam(v *float64) string {
+	if v == nil {
+		return "default"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,167 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+)
+
+// scaffoldTools may be used before the user has approved the setup plan;
+// Bash is only added afterwards.
+var scaffoldTools = []string{"Write", "Glob", "Read"}
+
+const scaffoldPrompt = `Create a new project in the current directory, which is empty: %s
+
+Write every file the project needs (sources, build files, README, .gitignore) with the Write tool. You cannot run commands yet: do not try to install dependencies, initialize git or build. Keep the project minimal but runnable, and use current, widely used tools for the stack.`
+
+const scaffoldPlanInstruction = `List the shell commands needed to finish setting up the project you wrote (installing dependencies, initializing git, a first build or test run) by calling setup_plan. The user approves them before they run. Leave the list empty if nothing is needed.`
+
+var scaffoldPlanTool = client.ToolDefinition{
+	Name:        "setup_plan",
+	Description: "Propose the commands that finish setting up the scaffolded project.",
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"commands": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"command": map[string]string{"type": "string"},
+						"reason":  map[string]string{"type": "string"},
+					},
+					"required": []string{"command", "reason"},
+				},
+			},
+		},
+		"required": []string{"commands"},
+	},
+}
+
+// Scaffold creates a new project from a description in the working
+// directory, which must be empty. The model first writes files with only
+// Write, Glob and Read; the commands it then proposes run only once the
+// user approves them. A summary of the generated files ends the run.
+func (s *Session) Scaffold(description string) error {
+	if strings.TrimSpace(description) == "" {
+		return fmt.Errorf("describe the project to create")
+	}
+	if err := checkEmptyDir(s.workDir); err != nil {
+		return err
+	}
+	defer func() { s.allowedTools, s.confineTo = nil, "" }()
+	s.confineTo = s.workDir
+	s.setAllowedTools(scaffoldTools)
+	if err := s.SendMessage(fmt.Sprintf(scaffoldPrompt, description)); err != nil {
+		return err
+	}
+
+	raw, err := s.StructuredOutput(scaffoldPlanInstruction, scaffoldPlanTool)
+	if err != nil {
+		return fmt.Errorf("setup plan: %w", err)
+	}
+	var plan struct {
+		Commands []struct {
+			Command string `json:"command"`
+			Reason  string `json:"reason"`
+		} `json:"commands"`
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return fmt.Errorf("setup plan: %w", err)
+	}
+	if len(plan.Commands) > 0 {
+		rows := make([][2]string, len(plan.Commands))
+		var list []string
+		for i, c := range plan.Commands {
+			rows[i] = [2]string{c.Command, c.Reason}
+			list = append(list, "- "+c.Command)
+		}
+		display.ScaffoldPlan(rows)
+		if display.ConfirmPrompt(i18n.T("confirm.setup_plan")) {
+			s.setAllowedTools(append(append([]string(nil), scaffoldTools...), "Edit", "MultiEdit", "Bash"))
+			err := s.SendMessage("The user approved the setup plan. Run these commands with Bash, fixing the project if one fails:\n" +
+				strings.Join(list, "\n"))
+			if err != nil {
+				return err
+			}
+		} else {
+			display.InfoMessage("Setup plan skipped; the files are written but no commands were run")
+		}
+	}
+
+	files, err := scaffoldedFiles(s.workDir)
+	if err != nil {
+		return err
+	}
+	display.ScaffoldSummary(files)
+	return nil
+}
+
+// confined refuses path inputs outside the directory a run is confined
+// to, or returns "".
+func (s *Session) confined(input map[string]interface{}) string {
+	if s.confineTo == "" {
+		return ""
+	}
+	for _, k := range []string{"file_path", "path", "cwd"} {
+		p, _ := input[k].(string)
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(s.executor.WorkDir(), p)
+		}
+		if rel, err := filepath.Rel(s.confineTo, filepath.Clean(p)); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Sprintf("%s is outside %s; only files of the new project may be touched", p, s.confineTo)
+		}
+	}
+	return ""
+}
+
+// checkEmptyDir allows only a .git directory, so a clone of an empty
+// repository can be scaffolded.
+func checkEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() != ".git" {
+			return fmt.Errorf("%s is not empty; scaffold new projects into an empty directory", dir)
+		}
+	}
+	return nil
+}
+
+// scaffoldedFiles lists the files of the new project with their sizes,
+// leaving out dependency and VCS directories.
+func scaffoldedFiles(dir string) ([]display.FileSize, error) {
+	var files []display.FileSize
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "node_modules", "vendor", ".venv", "target":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, display.FileSize{Path: rel, Size: info.Size()})
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,103 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/workspace"
+)
+
+const maxListedPackages = 30
+
+// workspaceSection describes the monorepo and the active package so the model
+// keeps its searches and test runs scoped.
+func workspaceSection(ws *workspace.Workspace, cwd string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nMonorepo (%s) rooted at %s with %d packages", ws.Kind, ws.Root, len(ws.Packages))
+	if len(ws.Packages) > 0 {
+		listed := ws.Packages
+		if len(listed) > maxListedPackages {
+			listed = listed[:maxListedPackages]
+		}
+		fmt.Fprintf(&sb, ": %s", strings.Join(listed, ", "))
+		if len(ws.Packages) > maxListedPackages {
+			fmt.Fprintf(&sb, ", ... (%d more)", len(ws.Packages)-maxListedPackages)
+		}
+	}
+	sb.WriteString("\n")
+
+	if pkg := ws.PackageFor(cwd); pkg != "" {
+		fmt.Fprintf(&sb, "Active package: %s. Keep searches, edits and test runs inside it unless the task needs other packages.\n", pkg)
+		if cmd := ws.TestCommand(pkg); cmd != "" {
+			fmt.Fprintf(&sb, "Run this package's tests with: %s\n", cmd)
+		}
+	} else {
+		sb.WriteString("No package is active; prefer narrowing searches to the relevant package directory.\n")
+	}
+	return sb.String()
+}
+
+// Scope returns the directory tools currently operate in.
+func (s *Session) Scope() string {
+	return s.workDir
+}
+
+// AddDir adds a directory next to the working directory, for --add-dir and
+// /add-dir, e.g. the backend repository beside a frontend. Tools search it
+// and the model is told about it.
+func (s *Session) AddDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(s.workDir, dir)
+	}
+	added, err := s.executor.AddDir(dir)
+	if err != nil {
+		return err
+	}
+	s.system = s.composeSystem()
+	display.SuccessMessage("Added " + added)
+	return nil
+}
+
+// SetScope points tools and the system prompt at a monorepo package, given
+// by name, path relative to the workspace root, or directory. An empty
+// argument resets the scope to the workspace root.
+func (s *Session) SetScope(arg string) error {
+	ws := workspace.Detect(s.workDir)
+	var dir string
+	switch {
+	case arg == "" && ws != nil:
+		dir = ws.Root
+	case arg == "":
+		return fmt.Errorf("not inside a monorepo; give a directory to scope to")
+	case ws != nil:
+		resolved, ok := ws.Resolve(arg)
+		if !ok {
+			return fmt.Errorf("no package or directory %q in %s", arg, ws.Root)
+		}
+		dir = resolved
+	default:
+		dir = arg
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(s.workDir, dir)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("scope to %s: %w", arg, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("scope to %s: not a directory", arg)
+		}
+		dir = filepath.Clean(dir)
+	}
+
+	s.workDir = dir
+	s.executor.SetWorkDir(dir)
+	// The new scope may have a project memory of its own, or none.
+	s.memoryPath, s.memory = findMemory(dir)
+	s.SetSystemPrompt(BuildSystemPrompt(dir))
+	display.SuccessMessage(fmt.Sprintf("Scope: %s", dir))
+	return nil
+}
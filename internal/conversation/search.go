@@ -0,0 +1,205 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Search limits: how many hits are shown, and how many saved sessions are
+// read, newest first.
+const (
+	maxSearchHits     = 20
+	maxSearchSessions = 50
+	// excerptWidth is how much of a long line is shown around a match.
+	excerptWidth = 120
+)
+
+// searchEntry is a prompt, answer, tool call or tool result, lowercased
+// for matching.
+type searchEntry struct {
+	message int
+	label   string
+	text    string
+	lower   string
+}
+
+// indexMessages splits a conversation into the entries /search looks in.
+func indexMessages(messages []client.Message) []searchEntry {
+	var entries []searchEntry
+	toolNames := make(map[string]string)
+	for i, m := range messages {
+		for _, b := range messageBlocks(m) {
+			e := searchEntry{message: i + 1}
+			switch b.Type {
+			case "text":
+				e.label, e.text = "you", b.Text
+				if m.Role == "assistant" {
+					e.label = "assistant"
+				}
+			case "thinking":
+				e.label, e.text = "thinking", b.Thinking
+			case "tool_use":
+				toolNames[b.ID] = b.Name
+				e.label, e.text = b.Name+" call", compactJSON(b.Input)
+			case "tool_result":
+				e.label, e.text = toolNames[b.ToolUseID]+" result", resultText(b.Content)
+				if b.IsError {
+					e.label = toolNames[b.ToolUseID] + " error"
+				}
+			default:
+				continue
+			}
+			if strings.TrimSpace(e.text) == "" {
+				continue
+			}
+			e.lower = strings.ToLower(e.text)
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func compactJSON(raw json.RawMessage) string {
+	var v interface{}
+	if json.Unmarshal(raw, &v) != nil {
+		return string(raw)
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		// Inputs such as a command or a path read better bare.
+		var parts []string
+		for _, k := range []string{"command", "file_path", "path", "pattern", "query", "url", "content", "new_string"} {
+			if s, _ := m[k].(string); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "\n")
+		}
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// Search finds the entries of this conversation, and of the saved
+// sessions when a session store is configured, that contain every word of
+// query, for /search. Hits are shown newest first with the lines around
+// the match.
+func (s *Session) Search(query string) error {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return fmt.Errorf("usage: /search <query>")
+	}
+	var hits []display.SearchHit
+	total := 0
+	collect := func(session string, entries []searchEntry) {
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if !matchesAll(e.lower, terms) {
+				continue
+			}
+			if total++; len(hits) < maxSearchHits {
+				hits = append(hits, display.SearchHit{Session: session, Message: e.message, Label: e.label, Lines: excerpt(e.text, e.lower, terms)})
+			}
+		}
+	}
+	collect("", indexMessages(s.messages))
+
+	searched := 0
+	if s.store != nil {
+		ids, err := s.ListSessions()
+		if err != nil {
+			return err
+		}
+		for i := len(ids) - 1; i >= 0 && searched < maxSearchSessions; i-- {
+			if ids[i] == s.sessionID {
+				continue
+			}
+			entries, err := s.savedEntries(ids[i])
+			if err != nil {
+				display.WarningMessage(err.Error())
+				continue
+			}
+			searched++
+			collect(ids[i], entries)
+		}
+	}
+	display.SearchResults(query, hits, total, searched)
+	return nil
+}
+
+// savedEntries indexes a saved session once; saved sessions other than
+// the current one do not change.
+func (s *Session) savedEntries(id string) ([]searchEntry, error) {
+	if entries, ok := s.searchIndex[id]; ok {
+		return entries, nil
+	}
+	data, err := s.store.Get(id + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", id, err)
+	}
+	var doc savedSession
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse session %s: %w", id, err)
+	}
+	if s.searchIndex == nil {
+		s.searchIndex = make(map[string][]searchEntry)
+	}
+	entries := indexMessages(doc.Messages)
+	s.searchIndex[id] = entries
+	return entries, nil
+}
+
+func matchesAll(text string, terms []string) bool {
+	for _, t := range terms {
+		if !strings.Contains(text, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// excerpt returns the line with the first match and the lines either
+// side, long lines cut down to the part around the match.
+func excerpt(text, lower string, terms []string) []string {
+	lines := strings.Split(text, "\n")
+	lowerLines := strings.Split(lower, "\n")
+	at := 0
+	for i, l := range lowerLines {
+		if strings.Contains(l, terms[0]) {
+			at = i
+			break
+		}
+	}
+	var out []string
+	for i := at - 1; i <= at+1; i++ {
+		if i < 0 || i >= len(lines) || i >= len(lowerLines) {
+			continue
+		}
+		line := strings.TrimRight(lines[i], "\r")
+		if len(line) > excerptWidth {
+			start := 0
+			if i == at && len(lowerLines[i]) == len(lines[i]) {
+				if j := strings.Index(lowerLines[i], terms[0]); j > excerptWidth/3 {
+					start = j - excerptWidth/3
+				}
+			}
+			end := start + excerptWidth
+			if end > len(line) {
+				end = len(line)
+			}
+			line = strings.ToValidUTF8(line[start:end], "")
+			if start > 0 {
+				line = "…" + line
+			}
+			if end < len(lines[i]) {
+				line += "…"
+			}
+		}
+		out = append(out, line)
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/linediff"
+)
+
+var opinionTool = client.ToolDefinition{
+	Name:        "assessment",
+	Description: "Record a review of a proposed plan or code change.",
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"verdict": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"approve", "approve_with_changes", "reject"},
+				"description": "Whether the change should go ahead as it is, after changes, or not at all",
+			},
+			"summary":     map[string]string{"type": "string", "description": "The assessment in two or three sentences"},
+			"risks":       stringList("Concrete problems: bugs, data loss, security issues, missed requirements"),
+			"suggestions": stringList("Changes that would address the risks"),
+		},
+		"required": []string{"verdict", "summary", "risks", "suggestions"},
+	},
+}
+
+const opinionInstruction = `Review the %s below as a skeptical senior engineer would before approving it. Look for bugs, destructive or irreversible steps, security problems, and requirements it misses.
+Do not defend earlier decisions; judge it on its merits.%s Record your review by calling assessment.
+
+%s`
+
+const secondOpinionSystem = `You are reviewing work proposed by another AI coding assistant before the user approves it.
+You only see the user's request and the plan or diff; judge it on what is there and say what you cannot verify.`
+
+// maxOpinionDiff bounds the diff sent for review.
+const maxOpinionDiff = 60000
+
+// SecondOpinion has both the session's model and the configured second
+// model review the changes of the last turn, or the latest reply when
+// nothing changed, and shows their assessments side by side. The second
+// model only sees the user's first request and the subject. Its review
+// is passed to the session's model with the next prompt.
+func (s *Session) SecondOpinion(focus string) error {
+	if s.secondOpinion == nil {
+		return fmt.Errorf("no second model configured")
+	}
+	kind, subject := s.opinionSubject()
+	if subject == "" {
+		return fmt.Errorf("nothing to review yet: no changes and no reply")
+	}
+	api, model, err := s.secondOpinion()
+	if err != nil {
+		return err
+	}
+	focusNote := ""
+	if focus = strings.TrimSpace(focus); focus != "" {
+		focusNote = " Pay particular attention to: " + focus + "."
+	}
+	instruction := fmt.Sprintf(opinionInstruction, kind, focusNote, subject)
+
+	raw, err := s.StructuredOutput(instruction, opinionTool)
+	if err != nil {
+		return fmt.Errorf("second opinion: %w", err)
+	}
+	first, err := parseOpinion(raw, s.model)
+	if err != nil {
+		return err
+	}
+
+	prompt := instruction
+	if goal := s.firstPrompt(); goal != "" {
+		prompt = "The user asked:\n" + goal + "\n\n" + instruction
+	}
+	req := &client.MessagesRequest{
+		Model:      model,
+		System:     secondOpinionSystem,
+		Messages:   []client.Message{{Role: "user", Content: prompt}},
+		Tools:      []client.ToolDefinition{opinionTool},
+		ToolChoice: &client.ToolChoice{Type: "tool", Name: opinionTool.Name},
+	}
+	spinner := display.NewSpinner("Asking " + model + "...")
+	resp, err := api.SendMessageStream(req, nil)
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("second opinion from %s: %w", model, err)
+	}
+	if s.audit != nil {
+		s.audit.Usage(model, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+	}
+	var second *display.Opinion
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == opinionTool.Name {
+			if second, err = parseOpinion(block.Input, model); err != nil {
+				return err
+			}
+		}
+	}
+	if second == nil {
+		return fmt.Errorf("%s did not return an assessment", model)
+	}
+
+	display.SecondOpinion(kind, *first, *second)
+	note := fmt.Sprintf("<second_opinion>A second model (%s) reviewed the %s and said %s: %s", model, kind,
+		strings.ReplaceAll(second.Verdict, "_", " "), second.Summary)
+	if len(second.Risks) > 0 {
+		note += "\nRisks it raised:\n- " + strings.Join(second.Risks, "\n- ")
+	}
+	if s.pendingNote != "" {
+		s.pendingNote += "\n\n"
+	}
+	s.pendingNote += note + "</second_opinion>"
+	return nil
+}
+
+func parseOpinion(raw json.RawMessage, model string) (*display.Opinion, error) {
+	var o struct {
+		Verdict     string   `json:"verdict"`
+		Summary     string   `json:"summary"`
+		Risks       []string `json:"risks"`
+		Suggestions []string `json:"suggestions"`
+	}
+	if err := json.Unmarshal(raw, &o); err != nil {
+		return nil, fmt.Errorf("assessment from %s: %w", model, err)
+	}
+	return &display.Opinion{Model: model, Verdict: o.Verdict, Summary: o.Summary, Risks: o.Risks, Suggestions: o.Suggestions}, nil
+}
+
+// opinionSubject returns the diff of the last turn's changes, or else the
+// model's latest reply as the plan.
+func (s *Session) opinionSubject() (kind, subject string) {
+	if diff := s.turnDiff(); diff != "" {
+		if len(diff) > maxOpinionDiff {
+			diff = diff[:maxOpinionDiff] + "\n[diff truncated]\n"
+		}
+		return "diff", "```diff\n" + diff + "```"
+	}
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].Role != "assistant" {
+			continue
+		}
+		var text []string
+		for _, b := range messageBlocks(s.messages[i]) {
+			if b.Type == "text" && strings.TrimSpace(b.Text) != "" {
+				text = append(text, b.Text)
+			}
+		}
+		if len(text) > 0 {
+			return "plan", strings.Join(text, "\n\n")
+		}
+	}
+	return "", ""
+}
+
+// turnDiff renders the last turn's file changes as a unified diff.
+func (s *Session) turnDiff() string {
+	paths := make([]string, 0, len(s.turnBefore))
+	for p := range s.turnBefore {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		before := s.turnBefore[p]
+		data, err := os.ReadFile(p)
+		if err != nil && !before.existed {
+			continue
+		}
+		hunks := linediff.Hunks(linediff.Diff(linediff.Split(before.content), linediff.Split(string(data))), 3)
+		if len(hunks) == 0 {
+			continue
+		}
+		name := p
+		if rel, err := filepath.Rel(s.workDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+		for _, h := range hunks {
+			b.WriteString(h.Header() + "\n")
+			for _, l := range h.Lines {
+				b.WriteString(string(l.Kind) + strings.TrimSuffix(l.Text, "\n") + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// firstPrompt returns the text of the first user message.
+func (s *Session) firstPrompt() string {
+	for _, m := range s.messages {
+		if m.Role != "user" {
+			continue
+		}
+		for _, b := range messageBlocks(m) {
+			if b.Type == "text" && b.Text != "" {
+				return b.Text
+			}
+		}
+	}
+	return ""
+}
@@ -3,23 +3,155 @@ package conversation
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/rpay/apipod-cli/internal/audit"
 	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/config"
 	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/ignore"
+	"github.com/rpay/apipod-cli/internal/index"
+	"github.com/rpay/apipod-cli/internal/injection"
+	"github.com/rpay/apipod-cli/internal/input"
+	"github.com/rpay/apipod-cli/internal/presence"
+	"github.com/rpay/apipod-cli/internal/project"
+	"github.com/rpay/apipod-cli/internal/redact"
+	"github.com/rpay/apipod-cli/internal/replay"
+	"github.com/rpay/apipod-cli/internal/safety"
+	"github.com/rpay/apipod-cli/internal/semantic"
+	"github.com/rpay/apipod-cli/internal/sessionstore"
+	"github.com/rpay/apipod-cli/internal/telemetry"
 	"github.com/rpay/apipod-cli/internal/tools"
+	"github.com/rpay/apipod-cli/internal/workspace"
+	"github.com/rpay/apipod-cli/internal/worktree"
 )
 
-const maxToolIterations = 25
+// defaultMaxIterations is the number of model requests one prompt may make.
+const defaultMaxIterations = 25
 
 type Session struct {
 	client   *client.Client
 	executor *tools.Executor
 	model    string
-	messages []client.Message
-	system   string
+	// smallModel answers side questions such as explaining a command.
+	smallModel string
+	messages   []client.Message
+	system     string
+	workDir    string
+
+	// baseSystem is the built prompt before the configured override and
+	// additions in systemOverride and systemAppend are applied.
+	baseSystem     string
+	systemOverride string
+	systemAppend   []string
+	outputStyle    string
+	// memory is the project memory file at memoryPath, put in the system
+	// prompt.
+	memory     string
+	memoryPath string
+	// attached are the files sent with the next prompt.
+	attached []input.File
+	// searchIndex holds the saved sessions /search has read, by ID.
+	searchIndex map[string][]searchEntry
+
+	recorder *replay.Recorder
+	player   *replay.Player
+	stats    toolStats
+
+	toolDefsMode string
+	requests     int
+
+	redactor *redact.Redactor
+	usage    client.Usage
+	usageAt  map[int]client.Usage
+	modified map[string]bool
+	// lastModified is the file the latest file tool call changed.
+	lastModified string
+	// editor is the configured /open command; "" uses $VISUAL or $EDITOR.
+	editor string
+	// secondOpinion connects to the model /second-opinion consults.
+	secondOpinion func() (*client.Client, string, error)
+	// presence mirrors the output to watchers while pairing.
+	presence *presence.Broadcaster
+	audit    *audit.Logger
+	guard    *injection.Guard
+
+	risk *safety.Classifier
+
+	// allowedTools, when set, limits the tools of the running custom
+	// command, mapped to the patterns their calls must match, if any.
+	allowedTools map[string][]string
+	// confineTo, when set, keeps path inputs inside this directory.
+	confineTo string
+
+	// worktree is the git worktree the session runs in with --worktree.
+	worktree *worktree.Worktree
+
+	// An auto-approval window (/auto) lasts until autoUntil or for
+	// autoTurns more turns.
+	autoUntil time.Time
+	autoTurns int
+
+	maxIterations   int
+	maxContinues    int
+	turnContinues   int
+	turnBudget      float64
+	turnTokenBudget int
+	turnUsage       client.Usage
+	turnTools       int
+	stopConds       []StopCondition
+	stopMet         string
+	turnBefore      map[string]snapshot
+	lastContext     int
+
+	// events are the latest steps of the loop; turnLimit and turnDenials
+	// describe how the last turn went.
+	events      []Event
+	turnLimit   string
+	turnDenials int
+
+	thinkingBudget int
+	showThinking   bool
+	sampling       Sampling
+	lastThinking   string
+
+	countUnsupported bool
+
+	share *config.Share
+	// transport carries the proxy and TLS settings to the other requests
+	// made to the API, such as embeddings.
+	transport http.RoundTripper
+	hooks     config.Hooks
+
+	// pendingNote is added to the next prompt, e.g. which reviewed
+	// changes the user skipped.
+	pendingNote string
+	// steerer supplies instructions typed during a turn.
+	steerer Steerer
+	// listener and approver connect a front end other than the terminal.
+	listener Listener
+	approver Approver
+	// focus and notifications decide when to get the user's attention.
+	focus         FocusSource
+	notifications *config.Notifications
+	turnStarted   time.Time
+	// telemetry exports spans and metrics; turnSpan is the running turn's.
+	telemetry *telemetry.Exporter
+	turnSpan  *telemetry.Span
+
+	// outputs are the latest full tool results, for /expand.
+	outputs      []keptOutput
+	outputBytes  int
+	lastOutputID int
+
+	store     sessionstore.Store
+	sessionID string
+	created   time.Time
 }
 
 func NewSession(c *client.Client, model, workDir string) *Session {
@@ -28,35 +160,137 @@ func NewSession(c *client.Client, model, workDir string) *Session {
 		cwd = workDir
 	}
 
-	system := buildSystemPrompt(cwd)
+	system := BuildSystemPrompt(cwd)
 
-	return &Session{
+	redactor, _ := redact.New(nil)
+
+	s := &Session{
 		client:   c,
 		executor: tools.NewExecutor(cwd),
 		model:    model,
 		messages: []client.Message{},
 		system:   system,
+		workDir:  cwd,
+
+		baseSystem: system,
+
+		redactor: redactor,
+		usageAt:  make(map[int]client.Usage),
+		modified: make(map[string]bool),
+		risk:     safety.NewClassifier(nil),
+
+		maxIterations: defaultMaxIterations,
+		maxContinues:  defaultMaxContinues,
+	}
+	s.memoryPath, s.memory = findMemory(cwd)
+	s.system = s.composeSystem()
+	return s
+}
+
+// SetRedactor replaces the secret filter applied to tool results; nil
+// disables redaction.
+func (s *Session) SetRedactor(r *redact.Redactor) {
+	s.redactor = r
+}
+
+// SetInjectionGuard neutralizes instruction-like passages in the output of
+// tools that return untrusted content; nil turns the guard off.
+func (s *Session) SetInjectionGuard(g *injection.Guard) {
+	s.guard = g
+}
+
+// untrustedTools return content written by someone other than the user:
+// files, web pages, API responses and command output.
+var untrustedTools = map[string]bool{
+	"Read": true, "Grep": true, "HttpRequest": true, "WebSocket": true,
+	"Grpc": true, "Bash": true, "BashOutput": true, "WebFetch": true,
+	"GitHost": true,
+}
+
+// SetRecorder records every prompt, request, response and tool output of the
+// session so the run can be replayed later.
+func (s *Session) SetRecorder(r *replay.Recorder) {
+	s.recorder = r
+}
+
+// recording reports a failed write to the recording once and stops
+// recording, since a recording with gaps cannot be replayed.
+func (s *Session) recording(err error) {
+	if err == nil {
+		return
 	}
+	display.WarningMessage(fmt.Sprintf("Recording stopped: %v", err))
+	s.recorder = nil
+}
+
+func (s *Session) RecordingHeader() replay.Header {
+	return replay.Header{Model: s.model, WorkDir: s.workDir, System: s.system}
 }
 
-func buildSystemPrompt(cwd string) string {
+// Replay re-executes a recorded run: responses come from the recording instead
+// of the API, and tool calls are applied again with the decisions recorded
+// for them. A call the recording has no output for is confirmed as in a
+// live session. Tool outputs that differ from the recording are reported as
+// warnings.
+func (s *Session) Replay(p *replay.Player) error {
+	s.player = p
+	defer func() { s.player = nil }()
+
+	for _, prompt := range p.Prompts() {
+		if err := s.SendMessage(prompt); err != nil {
+			return err
+		}
+	}
+	if n := p.Remaining(); n > 0 {
+		return fmt.Errorf("replay diverged: %d recorded responses were not used", n)
+	}
+	return nil
+}
+
+// SetSystemPrompt replaces the system prompt, e.g. with one prepared ahead of
+// time by the warm-start daemon.
+func (s *Session) SetSystemPrompt(system string) {
+	s.baseSystem = system
+	s.system = s.composeSystem()
+}
+
+// SetModel changes the model used from the next request, as /model does.
+func (s *Session) SetModel(model string) {
+	s.model = model
+}
+
+func (s *Session) Model() string {
+	return s.model
+}
+
+func (s *Session) WorkDir() string {
+	return s.workDir
+}
+
+// systemInstructions opens the built-in system prompt; an override
+// replaces it and keeps the environment details that follow.
+const systemInstructions = "You are an agentic coding assistant running in the user's terminal via apipod-cli.\n" +
+	"You help with software engineering tasks: writing code, debugging, running commands, and explaining code.\n\n" +
+	"Guidelines:\n" +
+	"- Be concise and direct\n" +
+	"- Use tools to explore the codebase before making changes\n" +
+	"- Make minimal, surgical changes\n" +
+	"- Run tests/builds after changes when possible\n" +
+	"- Do not add unnecessary comments to code\n\n"
+
+func BuildSystemPrompt(cwd string) string {
 	var sb strings.Builder
-	sb.WriteString("You are an agentic coding assistant running in the user's terminal via apipod-cli.\n")
-	sb.WriteString("You help with software engineering tasks: writing code, debugging, running commands, and explaining code.\n\n")
-	sb.WriteString("Guidelines:\n")
-	sb.WriteString("- Be concise and direct\n")
-	sb.WriteString("- Use tools to explore the codebase before making changes\n")
-	sb.WriteString("- Make minimal, surgical changes\n")
-	sb.WriteString("- Run tests/builds after changes when possible\n")
-	sb.WriteString("- Do not add unnecessary comments to code\n\n")
+	sb.WriteString(systemInstructions)
 
 	sb.WriteString(fmt.Sprintf("Working directory: %s\n", cwd))
 	sb.WriteString(fmt.Sprintf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH))
+	sb.WriteString(fmt.Sprintf("Shell: %s (the Bash tool runs commands with it)\n", tools.DefaultShell().Name))
 
 	if info, err := os.ReadDir(cwd); err == nil {
+		ignored := ignore.New(cwd)
 		var files []string
 		for _, f := range info {
-			if !strings.HasPrefix(f.Name(), ".") {
+			if !strings.HasPrefix(f.Name(), ".") && !ignored.Ignored(f.Name(), f.IsDir()) {
 				files = append(files, f.Name())
 			}
 		}
@@ -65,78 +299,244 @@ func buildSystemPrompt(cwd string) string {
 		}
 	}
 
+	if p := project.Detect(cwd); p != nil {
+		sb.WriteString(profileSection(p, cwd))
+	}
+
+	if ws := workspace.Detect(cwd); ws != nil {
+		sb.WriteString(workspaceSection(ws, cwd))
+	}
+
+	if idx, err := index.Load(cwd); err == nil && len(idx.Files) > 0 {
+		sb.WriteString(fmt.Sprintf("\nProject map (%d files indexed):\n", len(idx.Files)))
+		sb.WriteString(idx.Summary(40))
+	}
+
 	return sb.String()
 }
 
 func (s *Session) SendMessage(userInput string) error {
-	s.messages = append(s.messages, client.Message{
-		Role:    "user",
-		Content: userInput,
-	})
+	if s.recorder != nil {
+		s.recording(s.recorder.Prompt(userInput))
+	}
+	if s.presence != nil {
+		s.presence.Annotate("› " + userInput)
+	}
+	if s.audit != nil {
+		prompt := userInput
+		if s.redactor != nil {
+			prompt, _ = s.redactor.Redact(prompt)
+		}
+		s.audit.Prompt(prompt)
+	}
+	s.resetTurn()
+	s.turnBefore = make(map[string]snapshot)
+
+	content, attached := input.ExpandMentions(userInput, s.workDir)
+	for _, a := range attached {
+		display.InfoMessage(fmt.Sprintf("📎 %s (%.1f KB)", a.Path, float64(a.Size)/1024))
+	}
+	// After a failed turn history ends with a user message the model never
+	// answered; the new prompt joins it so the roles keep alternating.
+	message := s.withAttachments(content)
+	restore := s.messages
+	if s.unanswered() {
+		restore = append([]client.Message(nil), s.messages...)
+		s.appendUserContent(message)
+	} else {
+		s.messages = append(s.messages, client.Message{
+			Role:    "user",
+			Content: message,
+		})
+	}
+	if s.pendingNote != "" {
+		s.appendUserText(s.pendingNote)
+	}
+	if err := s.checkContext(); err != nil {
+		s.messages = restore
+		return err
+	}
+	s.pendingNote = ""
+	s.attached = nil
 
-	return s.runLoop()
+	return s.finishTurn(s.runLoop())
+}
+
+func (s *Session) resetTurn() {
+	s.turnStarted = time.Now()
+	s.startTurnSpan()
+	s.turnUsage = client.Usage{}
+	s.turnTools = 0
+	s.stats.turn = len(s.stats.calls)
+	s.turnContinues = 0
+	s.stopMet = ""
+	s.turnLimit, s.turnDenials = "", 0
+}
+
+// finishTurn runs what follows every turn, whether it ended or failed.
+func (s *Session) finishTurn(err error) error {
+	s.reviewStaged()
+	s.stopHooks()
+	s.endAutoTurn()
+	s.announceTurn(err)
+	s.endTurnSpan(err)
+	if err == nil {
+		added, removed, files := s.TurnDiffStat()
+		display.TokenUsage(s.turnUsage.InputTokens, s.turnUsage.OutputTokens, s.turnTools, s.stats.turnDuration(), added, removed, files)
+		display.ContextMeter(s.lastContext, contextWindow, false)
+	}
+	s.autosave()
+	return err
 }
 
 func (s *Session) runLoop() error {
 	toolDefs := s.getToolDefinitions()
+	s.repairHistory()
 
-	for i := 0; i < maxToolIterations; i++ {
+	for i := 0; i < s.maxIterations; i++ {
 		req := &client.MessagesRequest{
 			Model:    s.model,
-			Messages: s.messages,
-			System:   s.system,
-			Tools:    toolDefs,
+			Messages: withNote(s.messages, s.budgetNote(i)),
+			System:   s.system + s.reviewNote(),
+			Tools:    s.toolDefinitionsFor(toolDefs),
+			Thinking: s.thinkingParam(),
+		}
+		s.applySampling(req)
+		// Once a limit is reached the model gets one last request without
+		// tools to summarize, rather than the loop stopping mid-task.
+		limit := s.limitReached(i)
+		wrapUp := wrapUpInstruction(limit)
+		if i > 0 && len(s.stopConds) > 0 {
+			if met := s.checkStopConditions(); met != "" {
+				s.stopMet = met
+				limit, wrapUp = "stop condition met: "+met, stopInstruction(met)
+			}
+		}
+		if limit != "" {
+			if s.stopMet == "" {
+				s.turnLimit = limit
+				s.recordEvent("limit", "", limit, false)
+			}
+			s.appendUserText(wrapUp)
+			req.Messages = withNote(s.messages, s.budgetNote(i))
+			req.ToolChoice = &client.ToolChoice{Type: "none"}
 		}
 
 		spinner := display.NewSpinner("Thinking...")
 		var textAccumulator strings.Builder
 		streaming := false
 
+		// Reasoning streams before the answer; it is shown once the answer
+		// or a tool call starts.
+		var thinking strings.Builder
+		thinkingShown := false
+
+		// The input of a tool call is previewed as it streams.
+		var preview *display.ToolPreview
+		var previewTool string
+		var partialInput strings.Builder
+		clearPreview := func() {
+			if preview != nil {
+				preview.Clear()
+				preview = nil
+			}
+		}
+		showThinking := func() {
+			if thinking.Len() > 0 && !thinkingShown {
+				thinkingShown = true
+				spinner.Stop()
+				s.lastThinking = thinking.String()
+				display.Thinking(s.lastThinking, s.showThinking)
+			}
+		}
+
 		cb := &client.StreamCallback{
+			OnRateLimitWait: func(left time.Duration) {
+				if left > 0 {
+					spinner.SetMessage(fmt.Sprintf("Waiting %s for the rate limit...", left.Round(time.Second)))
+				} else {
+					spinner.SetMessage("Thinking...")
+				}
+			},
+			OnThinking: func(text string) {
+				thinking.WriteString(text)
+				if s.listener != nil {
+					s.listener.Thinking(text)
+				}
+			},
 			OnText: func(text string) {
 				spinner.Stop()
 				if !streaming {
+					showThinking()
 					streaming = true
 				}
 				textAccumulator.WriteString(text)
 				// Show raw streaming text as it comes in
 				display.StreamingText(text)
+				if s.listener != nil {
+					s.listener.Text(text)
+				}
 			},
 			OnToolUseStart: func(id, name string) {
 				spinner.Stop()
+				showThinking()
+				clearPreview()
+				preview, previewTool = display.NewToolPreview(name), name
+				partialInput.Reset()
+			},
+			OnToolUseInput: func(partialJSON string) {
+				partialInput.WriteString(partialJSON)
+				if preview != nil {
+					if detail, ok := previewInput(previewTool, partialInput.String()); ok {
+						preview.Update(detail)
+					}
+				}
+			},
+			OnContentBlockStop: func(index int) {
+				clearPreview()
 			},
 			OnError: func(err error) {
 				spinner.Stop()
-				display.ErrorMessage(err.Error())
+				clearPreview()
+				display.APIError(err)
 			},
 		}
 
-		resp, err := s.client.SendMessageStream(req, cb)
+		resp, err := s.send(req, cb)
 		spinner.Stop()
+		clearPreview()
+		showThinking()
 
 		// If we streamed text, render it as formatted markdown
 		if streaming && textAccumulator.Len() > 0 {
 			// Clear the raw streamed text and replace with markdown
-			fmt.Print("\r\033[2K")
 			rawText := textAccumulator.String()
-			rawLines := strings.Count(rawText, "\n")
-			for i := 0; i < rawLines; i++ {
-				fmt.Print("\033[A\033[2K")
-			}
-			fmt.Print("\r")
+			display.ClearStreamed(rawText)
 			display.RenderMarkdown(rawText)
 		}
 
 		if err != nil {
+			s.recordEvent("api_error", "", err.Error(), true)
+			if limit != "" {
+				s.dropUserText(wrapUp)
+			}
+			if streaming {
+				display.InfoMessage("The partial response was not kept; /retry sends the request again")
+			} else {
+				display.InfoMessage("/retry sends the request again")
+			}
 			return fmt.Errorf("API error: %w", err)
 		}
+		s.addUsage(resp.Usage)
 
 		hasToolUse := false
 		var toolResults []interface{}
+		truncated := resp.StopReason == "max_tokens"
 
-		for _, block := range resp.Content {
+		for n, block := range resp.Content {
 			if block.Type == "tool_use" {
 				hasToolUse = true
+				s.turnTools++
 
 				var input map[string]interface{}
 				if err := json.Unmarshal(block.Input, &input); err != nil {
@@ -144,26 +544,159 @@ func (s *Session) runLoop() error {
 				}
 
 				display.ToolCallStart(block.Name, input)
+				if s.listener != nil {
+					s.listener.ToolCall(block.ID, block.Name, input)
+				}
+				if s.audit != nil {
+					s.audit.ToolCall(block.Name, block.ID, s.auditInput(input))
+				}
 
-				if needsConfirmation(block.Name, input) {
-					if !display.ConfirmPrompt(fmt.Sprintf("Allow %s?", block.Name)) {
-						toolResults = append(toolResults, map[string]interface{}{
-							"type":        "tool_result",
-							"tool_use_id": block.ID,
-							"content":     "User denied this operation",
-							"is_error":    true,
-						})
-						continue
-					}
+				// Only the last block can be cut off by the token limit.
+				if truncated && n == len(resp.Content)-1 {
+					s.recordTool(block.Name, tools.ToolResult{ToolUseID: block.ID, Content: truncatedToolMessage, IsError: true}, false)
+					display.ToolCallResult(truncatedToolMessage, true)
+					toolResults = append(toolResults, map[string]interface{}{
+						"type":        "tool_result",
+						"tool_use_id": block.ID,
+						"content":     truncatedToolMessage,
+						"is_error":    true,
+					})
+					continue
+				}
+
+				// The user asked to change course; the rest of this
+				// response's calls may no longer apply.
+				if s.steeringPending() {
+					s.recordTool(block.Name, tools.ToolResult{ToolUseID: block.ID, Content: steeredMessage, IsError: true}, false)
+					display.ToolCallResult(steeredMessage, true)
+					toolResults = append(toolResults, map[string]interface{}{
+						"type":        "tool_result",
+						"tool_use_id": block.ID,
+						"content":     steeredMessage,
+						"is_error":    true,
+					})
+					continue
+				}
+
+				msg := s.patternDenied(block.Name, input)
+				if !s.toolAllowed(block.Name) {
+					msg = fmt.Sprintf("Tool %s is not available in this context", block.Name)
+				}
+				if msg != "" {
+					s.recordTool(block.Name, tools.ToolResult{ToolUseID: block.ID, Content: msg, IsError: true}, false)
+					s.refusedEvent(block.Name, msg, false)
+					display.ToolCallResult(msg, true)
+					toolResults = append(toolResults, map[string]interface{}{
+						"type":        "tool_result",
+						"tool_use_id": block.ID,
+						"content":     msg,
+						"is_error":    true,
+					})
+					continue
+				}
+
+				if blocked := s.executor.Blocked(tools.ToolCall{ID: block.ID, Name: block.Name, Input: input}); blocked != nil {
+					s.recordTool(block.Name, *blocked, false)
+					s.refusedEvent(block.Name, blocked.Content, false)
+					s.recordMetric(ToolMetric{Name: block.Name, ToolUseID: block.ID, IsError: true})
+					display.ToolCallResult(blocked.Content, true)
+					toolResults = append(toolResults, map[string]interface{}{
+						"type":        "tool_result",
+						"tool_use_id": block.ID,
+						"content":     blocked.Content,
+						"is_error":    true,
+					})
+					continue
+				}
+
+				reason := s.confined(input)
+				if reason == "" {
+					reason = s.preToolHooks(block.Name, input)
+				}
+				if reason != "" {
+					s.recordTool(block.Name, tools.ToolResult{ToolUseID: block.ID, Content: reason, IsError: true}, false)
+					s.refusedEvent(block.Name, reason, false)
+					s.recordMetric(ToolMetric{Name: block.Name, ToolUseID: block.ID, IsError: true})
+					display.ToolCallResult(reason, true)
+					toolResults = append(toolResults, map[string]interface{}{
+						"type":        "tool_result",
+						"tool_use_id": block.ID,
+						"content":     reason,
+						"is_error":    true,
+					})
+					continue
 				}
 
+				denied := s.denied(block.Name, input, block.ID)
+				if s.audit != nil {
+					s.audit.Approval(block.Name, block.ID, !denied)
+				}
+				if denied {
+					s.recordTool(block.Name, tools.ToolResult{ToolUseID: block.ID, Content: deniedMessage, IsError: true}, true)
+					s.refusedEvent(block.Name, deniedMessage, true)
+					s.recordMetric(ToolMetric{Name: block.Name, ToolUseID: block.ID, IsError: true, Denied: true})
+					toolResults = append(toolResults, map[string]interface{}{
+						"type":        "tool_result",
+						"tool_use_id": block.ID,
+						"content":     deniedMessage,
+						"is_error":    true,
+					})
+					continue
+				}
+
+				var live *display.LiveOutput
+				if block.Name == "Bash" && !isBackground(input) {
+					live = display.NewLiveOutput()
+					s.executor.SetOutputHandler(live.Line)
+				}
+
+				s.snapshotBefore(block.Name, input)
+				started := time.Now()
 				result := s.executor.Execute(tools.ToolCall{
 					ID:    block.ID,
 					Name:  block.Name,
 					Input: input,
 				})
+				took := time.Since(started)
+				if !result.IsError {
+					s.trackModified(block.Name, input)
+				}
+				s.postToolHooks(block.Name, input, &result)
+				if redacted, n := s.redactor.Redact(result.Content); n > 0 {
+					result.Content = redacted
+					display.WarningMessage(fmt.Sprintf("Redacted %d secret(s) from %s output", n, block.Name))
+				}
+				if s.guard != nil && untrustedTools[block.Name] {
+					if guarded, findings := s.guard.Neutralize(block.Name, result.Content); len(findings) > 0 {
+						result.Content = guarded
+						display.WarningMessage(fmt.Sprintf("Possible prompt injection in %s output (%s); marked as untrusted",
+							block.Name, injection.Summary(findings)))
+					}
+				}
+				s.recordMetric(ToolMetric{
+					Name:        block.Name,
+					ToolUseID:   block.ID,
+					Duration:    took,
+					ExitCode:    result.ExitCode,
+					OutputBytes: len(result.Content),
+					IsError:     result.IsError,
+				})
+				s.recordTool(block.Name, result, false)
+				s.recordEvent("tool_call", block.Name, result.Content, result.IsError)
 
-				display.ToolCallResult(result.Content, result.IsError)
+				if live != nil {
+					s.executor.SetOutputHandler(nil)
+					live.Done(result.IsError)
+				}
+				id := s.keepOutput(block.Name, input, result.Content, result.IsError)
+				if path, _ := input["file_path"].(string); block.Name == "Read" && !result.IsError {
+					display.CodeResult(id, path, result.Content)
+				} else if live == nil || !live.Streamed() {
+					display.ExpandableResult(id, result.Content, result.IsError)
+				}
+				if live == nil {
+					display.ToolTiming(took, result.ExitCode, len(result.Content))
+				}
 
 				toolResults = append(toolResults, map[string]interface{}{
 					"type":        "tool_result",
@@ -183,22 +716,53 @@ func (s *Session) runLoop() error {
 					"type": "text",
 					"text": block.Text,
 				})
+			case "thinking":
+				// Thinking blocks go back unchanged, signature included, so
+				// the model can continue its reasoning across tool calls.
+				contentBlocks = append(contentBlocks, map[string]interface{}{
+					"type":      "thinking",
+					"thinking":  block.Thinking,
+					"signature": block.Signature,
+				})
+			case "redacted_thinking":
+				contentBlocks = append(contentBlocks, map[string]interface{}{
+					"type": "redacted_thinking",
+					"data": block.Data,
+				})
 			case "tool_use":
 				contentBlocks = append(contentBlocks, map[string]interface{}{
 					"type":  "tool_use",
 					"id":    block.ID,
 					"name":  block.Name,
-					"input": json.RawMessage(block.Input),
+					"input": completeInput(block.Input),
 				})
 			}
 		}
+		s.usageAt[len(s.messages)] = resp.Usage
 		s.messages = append(s.messages, client.Message{
 			Role:    "assistant",
 			Content: contentBlocks,
 		})
 
 		if !hasToolUse {
-			display.TokenUsage(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+			if limit != "" {
+				s.showStopped(limit)
+				break
+			}
+			if truncated && s.continueTruncated() {
+				continue
+			}
+			// The model thinks it is done; send it back if the run's
+			// goal does not hold yet.
+			if len(s.stopConds) > 0 {
+				met := s.checkStopConditions()
+				if met == "" {
+					s.messages = append(s.messages, client.Message{Role: "user", Content: notMetInstruction(s.stopConds)})
+					continue
+				}
+				s.stopMet = met
+				display.InfoMessage("Stop condition met: " + met)
+			}
 			break
 		}
 
@@ -207,35 +771,231 @@ func (s *Session) runLoop() error {
 			Role:    "user",
 			Content: toolResults,
 		})
+		if limit != "" {
+			s.showStopped(limit)
+			break
+		}
+		s.steer()
 	}
 
 	return nil
 }
 
+// showStopped reports why a turn ended early: a stop condition that held,
+// which is the goal, or a limit, which is not.
+func (s *Session) showStopped(limit string) {
+	if s.stopMet != "" {
+		display.InfoMessage("Stop condition met: " + s.stopMet)
+		return
+	}
+	display.WarningMessage("Stopped: " + limit)
+}
+
+func (s *Session) send(req *client.MessagesRequest, cb *client.StreamCallback) (*client.MessagesResponse, error) {
+	if s.recorder != nil {
+		s.recording(s.recorder.Request(req.Messages))
+	}
+
+	var resp *client.MessagesResponse
+	var err error
+	done := s.apiSpan(req)
+	if s.player != nil {
+		resp, err = s.player.NextResponse()
+		if err == nil && cb != nil && cb.OnText != nil {
+			for _, block := range resp.Content {
+				if block.Type == "text" && block.Text != "" {
+					cb.OnText(block.Text)
+				}
+			}
+		}
+	} else {
+		resp, err = s.client.SendMessageStream(req, cb)
+	}
+	done(resp, err)
+
+	if err == nil {
+		s.requests++
+		if s.recorder != nil {
+			s.recording(s.recorder.Response(resp))
+		}
+		if s.audit != nil {
+			s.audit.Usage(req.Model, resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		}
+	}
+	return resp, err
+}
+
+const deniedMessage = "User denied this operation"
+
+// denied asks for confirmation when needed. During replay the recorded
+// decision is reused instead of prompting; a call that was not recorded
+// is asked about like any other.
+func (s *Session) denied(toolName string, input map[string]interface{}, toolUseID string) bool {
+	if s.player != nil {
+		if out, ok := s.player.ToolOutput(toolUseID); ok {
+			return out.Denied
+		}
+	}
+	switch toolName {
+	case "Bash":
+		return s.bashDenied(input)
+	case "ApiDiff":
+		return s.apiDiffDenied(input)
+	}
+	if s.executor.Staging() && stagedTools[toolName] {
+		return false
+	}
+	if !needsConfirmation(toolName, input) || s.autoApprove(toolName, input) {
+		return false
+	}
+	return !s.confirm(toolName, input, i18n.T("confirm.allow_tool", toolName))
+}
+
+func (s *Session) recordTool(name string, result tools.ToolResult, denied bool) {
+	if s.listener != nil {
+		s.listener.ToolResult(result.ToolUseID, name, result.Content, result.IsError, denied)
+	}
+	if s.audit != nil {
+		s.audit.ToolResult(name, result.ToolUseID, result.Content, result.IsError, denied)
+	}
+	if s.player != nil {
+		if want, ok := s.player.ToolOutput(result.ToolUseID); !ok {
+			display.WarningMessage(fmt.Sprintf("replay: no recorded output for %s (%s)", name, result.ToolUseID))
+		} else if want.Content != result.Content || want.IsError != result.IsError {
+			display.WarningMessage(fmt.Sprintf("replay: %s output differs from recording (%s)", name, result.ToolUseID))
+		}
+	}
+	if s.recorder != nil {
+		s.recording(s.recorder.Tool(replay.ToolOutput{
+			ToolUseID: result.ToolUseID,
+			Name:      name,
+			Content:   result.Content,
+			IsError:   result.IsError,
+			Denied:    denied,
+		}))
+	}
+}
+
 func (s *Session) getToolDefinitions() []client.ToolDefinition {
 	raw := tools.GetToolDefinitions()
 	var defs []client.ToolDefinition
 	for _, r := range raw {
 		var def client.ToolDefinition
-		if err := json.Unmarshal(r, &def); err == nil {
+		if err := json.Unmarshal(r, &def); err == nil && s.toolAllowed(def.Name) {
 			defs = append(defs, def)
 		}
 	}
 	return defs
 }
 
+// RunningJobs returns how many background shells are still running.
+func (s *Session) RunningJobs() int {
+	n := 0
+	for _, sh := range s.executor.Shells() {
+		if sh.Running {
+			n++
+		}
+	}
+	return n
+}
+
+// MessageCount returns the number of messages in the conversation.
+func (s *Session) MessageCount() int {
+	return len(s.messages)
+}
+
+func (s *Session) ShowJobs() {
+	var rows []display.JobRow
+	for _, sh := range s.executor.Shells() {
+		end := time.Now()
+		if !sh.Running {
+			end = sh.Ended
+		}
+		rows = append(rows, display.JobRow{
+			ID:       sh.ID,
+			Command:  sh.Command,
+			Running:  sh.Running,
+			ExitCode: sh.ExitCode,
+			Runtime:  end.Sub(sh.Started),
+		})
+	}
+	display.Jobs(rows)
+}
+
+// EnableSemanticSearch offers the SemanticSearch tool using the configured
+// embedding provider; baseURL and apiKey are the fallbacks for "api".
+func (s *Session) EnableSemanticSearch(cfg *config.SemanticSearch, baseURL, apiKey string) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	var emb semantic.Embedder = semantic.LocalEmbedder{}
+	if cfg.Provider == "api" {
+		if cfg.BaseURL != "" {
+			baseURL = cfg.BaseURL
+		}
+		if cfg.APIKey != "" {
+			apiKey = cfg.APIKey
+		}
+		model := cfg.Model
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		api := semantic.NewAPIEmbedder(baseURL, apiKey, model)
+		if s.transport != nil {
+			api.SetTransport(s.transport)
+		}
+		emb = api
+	}
+	s.executor.SetSemanticSearch(semantic.New(s.workDir, emb))
+}
+
+// Close releases session resources, killing background shells, and
+// settles the worktree of a --worktree session.
+func (s *Session) Close() {
+	s.flushTelemetry()
+	s.executor.Close()
+	s.endWorktree()
+}
+
 func (s *Session) Clear() {
 	s.messages = nil
+	s.usageAt = make(map[int]client.Usage)
+	s.executor.ForgetReads()
 	display.SuccessMessage("Conversation cleared")
 }
 
+func isBackground(input map[string]interface{}) bool {
+	bg, _ := input["run_in_background"].(bool)
+	return bg
+}
+
+// Interrupt stops the foreground command the session is currently running.
+func (s *Session) Interrupt() bool {
+	return s.executor.Interrupt()
+}
+
 func needsConfirmation(toolName string, input map[string]interface{}) bool {
 	switch toolName {
 	case "Bash":
 		return true
 	case "Write":
 		return true
-	case "Edit", "MultiEdit":
+	case "Archive":
+		action, _ := input["action"].(string)
+		return action == "extract"
+	case "HttpRequest":
+		method, u := tools.HTTPRequestURL(input)
+		return !isLocalURL(u) && !isReadOnlyMethod(method)
+	case "WebSocket":
+		u, _ := input["url"].(string)
+		return !isLocalURL(u)
+	case "Grpc":
+		action, _ := input["action"].(string)
+		return action == "call"
+	case "GitHost":
+		action, _ := input["action"].(string)
+		return strings.HasPrefix(action, "create_")
+	case "Edit", "MultiEdit", "EditLines", "ApplyPatch":
 		return true
 	default:
 		return false
@@ -0,0 +1,103 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/redact"
+	"github.com/rpay/apipod-cli/internal/transcript"
+)
+
+const shareTimeout = 30 * time.Second
+
+// Share prepares the session for review by others as a self-contained HTML
+// page with credentials masked and the home directory shortened to ~. With
+// a share endpoint configured the page is uploaded and its link returned;
+// otherwise it is written to path (default apipod-share-<time>.html in the
+// working directory) and the path returned.
+func (s *Session) Share(path string) (string, error) {
+	t, err := s.sharedTranscript()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.WriteHTML(&buf); err != nil {
+		return "", fmt.Errorf("render share: %w", err)
+	}
+
+	if s.share != nil && s.share.URL != "" && path == "" {
+		return s.uploadShare(buf.Bytes())
+	}
+	if path == "" {
+		path = "apipod-share-" + time.Now().Format("20060102-150405") + ".html"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workDir, path)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return "", fmt.Errorf("write share: %w", err)
+	}
+	return path, nil
+}
+
+// sharedTranscript returns the transcript with secrets masked. Redaction
+// applies even when it is turned off for tool results, since the bundle
+// leaves the user's hands.
+func (s *Session) sharedTranscript() (*transcript.Transcript, error) {
+	r := s.redactor
+	if r == nil {
+		var err error
+		if r, err = redact.New(nil); err != nil {
+			return nil, err
+		}
+	}
+	home, _ := os.UserHomeDir()
+	t := s.Transcript()
+	t.Redact(func(text string) string {
+		text, _ = r.Redact(text)
+		if home != "" && home != string(filepath.Separator) {
+			text = strings.ReplaceAll(text, home, "~")
+		}
+		return text
+	})
+	return t, nil
+}
+
+func (s *Session) uploadShare(page []byte) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, s.share.URL, bytes.NewReader(page))
+	if err != nil {
+		return "", fmt.Errorf("share: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/html; charset=utf-8")
+	for k, v := range s.share.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := (&http.Client{Timeout: shareTimeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("upload share: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("upload share: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		URL string `json:"url"`
+	}
+	link := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &out) == nil {
+		link = out.URL
+	}
+	if link == "" {
+		return "", fmt.Errorf("upload share: %s returned no link", s.share.URL)
+	}
+	return link, nil
+}
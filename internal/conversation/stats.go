@@ -0,0 +1,134 @@
+package conversation
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// ToolStat aggregates the calls made to one tool during a session.
+type ToolStat struct {
+	Name        string        `json:"name"`
+	Calls       int           `json:"calls"`
+	Errors      int           `json:"errors"`
+	Denied      int           `json:"denied"`
+	Duration    time.Duration `json:"duration_ns"`
+	OutputBytes int           `json:"output_bytes"`
+}
+
+// ToolMetric is one tool call: how long it ran, the exit status of a
+// Bash command and the size of the output the model got. Calls that did
+// not run have no duration.
+type ToolMetric struct {
+	Name        string        `json:"name"`
+	ToolUseID   string        `json:"tool_use_id"`
+	Duration    time.Duration `json:"duration_ns"`
+	ExitCode    int           `json:"exit_code,omitempty"`
+	OutputBytes int           `json:"output_bytes"`
+	IsError     bool          `json:"is_error,omitempty"`
+	Denied      bool          `json:"denied,omitempty"`
+}
+
+// toolStats is the session's record of tool calls: each call, for
+// ToolMetrics and telemetry, and the totals per tool that /stats, /status,
+// the usage line and exported transcripts show.
+type toolStats struct {
+	calls  []ToolMetric
+	byName map[string]*ToolStat
+	// turn is where the calls of the current turn start.
+	turn int
+}
+
+func (t *toolStats) record(m ToolMetric) {
+	if t.byName == nil {
+		t.byName = make(map[string]*ToolStat)
+	}
+	st, ok := t.byName[m.Name]
+	if !ok {
+		st = &ToolStat{Name: m.Name}
+		t.byName[m.Name] = st
+	}
+	st.Calls++
+	st.Duration += m.Duration
+	st.OutputBytes += m.OutputBytes
+	if m.Denied {
+		st.Denied++
+	} else if m.IsError {
+		st.Errors++
+	}
+	t.calls = append(t.calls, m)
+}
+
+// turnDuration is the time the current turn's tools ran for.
+func (t *toolStats) turnDuration() time.Duration {
+	var d time.Duration
+	for _, m := range t.calls[t.turn:] {
+		d += m.Duration
+	}
+	return d
+}
+
+// recordMetric adds a tool call to the session's metrics.
+func (s *Session) recordMetric(m ToolMetric) {
+	s.stats.record(m)
+	s.toolSpan(m)
+}
+
+// ToolMetrics returns every tool call of the session, oldest first.
+func (s *Session) ToolMetrics() []ToolMetric {
+	return append([]ToolMetric(nil), s.stats.calls...)
+}
+
+// ToolStats returns per-tool counts and durations, busiest tool first.
+func (s *Session) ToolStats() []ToolStat {
+	out := make([]ToolStat, 0, len(s.stats.byName))
+	for _, st := range s.stats.byName {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Duration != out[j].Duration {
+			return out[i].Duration > out[j].Duration
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// ShowToolStats prints /stats: calls, time and output per tool.
+func (s *Session) ShowToolStats() {
+	var rows []display.ToolStatRow
+	for _, st := range s.ToolStats() {
+		rows = append(rows, display.ToolStatRow{
+			Name:     st.Name,
+			Calls:    st.Calls,
+			Errors:   st.Errors,
+			Denied:   st.Denied,
+			Duration: st.Duration,
+			Output:   st.OutputBytes,
+		})
+	}
+	display.ToolStats(rows)
+}
+
+// toolsStatus sums up the session's tool calls for /status.
+func (s *Session) toolsStatus() display.StatusRow {
+	stats := s.ToolStats()
+	if len(stats) == 0 {
+		return display.StatusRow{Name: "Tools", Value: "no calls yet"}
+	}
+	var calls, failed int
+	var total time.Duration
+	for _, st := range stats {
+		calls += st.Calls
+		failed += st.Errors
+		total += st.Duration
+	}
+	value := fmt.Sprintf("%d calls · %s", calls, total.Round(100*time.Millisecond))
+	if failed > 0 {
+		value += fmt.Sprintf(" · %d failed", failed)
+	}
+	value += fmt.Sprintf(" · most time in %s (%d calls, %s); /stats for all", stats[0].Name, stats[0].Calls, stats[0].Duration.Round(100*time.Millisecond))
+	return display.StatusRow{Name: "Tools", Value: value}
+}
@@ -0,0 +1,140 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// statusTimeout bounds each check that leaves the process.
+const statusTimeout = 5 * time.Second
+
+// Status checks what a session depends on, for /status and `apipod-cli
+// doctor`: the API and the credentials, the model, the project and its
+// settings. Checks that fail come with what to do about them.
+func (s *Session) Status() []display.StatusRow {
+	rows := []display.StatusRow{
+		{Name: "Version", Value: fmt.Sprintf("apipod-cli %s · %s/%s", display.Version(), runtime.GOOS, runtime.GOARCH)},
+	}
+	rows = append(rows, s.apiStatus()...)
+	model := s.model
+	if s.smallModel != "" {
+		model += " · small: " + s.smallModel
+	}
+	rows = append(rows,
+		display.StatusRow{Name: "Model", Value: model},
+		display.StatusRow{Name: "Directory", Value: s.workDir},
+		gitStatus(s.workDir),
+		settingsStatus(s.workDir),
+		s.hooksStatus(),
+		s.toolsStatus(),
+	)
+	if id := s.SessionID(); id != "" {
+		rows = append(rows, display.StatusRow{Name: "Session", Value: id})
+	}
+	tokens := s.lastContext
+	rows = append(rows, display.StatusRow{Name: "Context", Value: fmt.Sprintf("%dk of %dk tokens · %d messages", tokens/1000, contextWindow/1000, len(s.messages))})
+	return rows
+}
+
+// ShowStatus prints Status and reports whether every check passed.
+func (s *Session) ShowStatus() bool {
+	rows := s.Status()
+	display.StatusPanel(rows)
+	for _, r := range rows {
+		if r.Level == display.StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// apiStatus checks that the API answers and accepts the credentials,
+// timing the round trip.
+func (s *Session) apiStatus() []display.StatusRow {
+	if s.client == nil {
+		return []display.StatusRow{{Name: "API", Value: "not connected (replaying a recording)", Level: display.StatusWarn}}
+	}
+	api := display.StatusRow{Name: "API", Value: s.client.BaseURL()}
+	started := time.Now()
+	account, err := s.client.Whoami()
+	elapsed := time.Since(started).Round(time.Millisecond)
+	var netErr *url.Error
+	switch {
+	case err == nil:
+		api.Value += fmt.Sprintf(" · reachable in %s", elapsed)
+		return []display.StatusRow{api, {Name: "Auth", Value: fmt.Sprintf("signed in as %s (%s plan)", account.Username, account.Plan)}}
+	case errors.Is(err, client.ErrInvalidAPIKey), errors.Is(err, client.ErrLoginRequired):
+		api.Value += fmt.Sprintf(" · reachable in %s", elapsed)
+		return []display.StatusRow{api, {Name: "Auth", Value: err.Error(), Level: display.StatusFail,
+			Hint: "run `apipod-cli login`, or check api_key in the config and APIPOD_API_KEY"}}
+	case !errors.As(err, &netErr):
+		// The server answered, but not with an account, e.g. a proxy
+		// without the endpoint; requests may still work.
+		api.Value += fmt.Sprintf(" · reachable in %s", elapsed)
+		return []display.StatusRow{api, {Name: "Auth", Value: "could not be checked: " + err.Error(), Level: display.StatusWarn}}
+	}
+	api.Value += " · " + err.Error()
+	api.Level = display.StatusFail
+	api.Hint = "check base_url, your network and proxy settings; --verbose traces the request"
+	return []display.StatusRow{api}
+}
+
+// gitStatus names the checked-out branch.
+func gitStatus(dir string) display.StatusRow {
+	row := display.StatusRow{Name: "Git"}
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		row.Value, row.Level = "git is not installed", display.StatusWarn
+	case err != nil:
+		row.Value = "not a repository"
+	default:
+		row.Value = "branch " + strings.TrimSpace(string(out))
+		if dirty, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain").Output(); err == nil && len(dirty) > 0 {
+			row.Value += fmt.Sprintf(" · %d uncommitted change(s)", strings.Count(string(dirty), "\n"))
+		}
+	}
+	return row
+}
+
+// settingsStatus lists the project settings files in use.
+func settingsStatus(dir string) display.StatusRow {
+	var found []string
+	for _, p := range []string{config.SettingsPath(dir), config.LocalSettingsPath(dir)} {
+		if _, err := os.Stat(p); err == nil {
+			rel, _ := filepath.Rel(dir, p)
+			found = append(found, rel)
+		}
+	}
+	row := display.StatusRow{Name: "Settings", Value: "no project settings"}
+	if len(found) > 0 {
+		row.Value = strings.Join(found, ", ")
+	}
+	if _, err := config.LoadSettings(dir); err != nil {
+		row.Value, row.Level = err.Error(), display.StatusFail
+		row.Hint = "fix the file; the project settings are not applied"
+	}
+	return row
+}
+
+func (s *Session) hooksStatus() display.StatusRow {
+	pre, post, stop := len(s.hooks.PreToolUse), len(s.hooks.PostToolUse), len(s.hooks.Stop)
+	if pre+post+stop == 0 {
+		return display.StatusRow{Name: "Hooks", Value: "none"}
+	}
+	return display.StatusRow{Name: "Hooks", Value: fmt.Sprintf("%d (%d before tools, %d after, %d at stop)", pre+post+stop, pre, post, stop)}
+}
@@ -0,0 +1,51 @@
+package conversation
+
+import (
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Steerer supplies instructions the user types while a turn is running,
+// such as input.Reader.
+type Steerer interface {
+	// Steering reports whether instructions are waiting.
+	Steering() bool
+	// TakeSteering returns the waiting instructions and clears them.
+	TakeSteering() []string
+}
+
+// SetSteerer lets the user redirect a running turn: once st has
+// instructions, the tool calls left in the model's response are skipped
+// and the instructions are sent with the tool results, so the model
+// changes course without waiting for the turn to end.
+func (s *Session) SetSteerer(st Steerer) {
+	s.steerer = st
+}
+
+const steeredMessage = "Not run: the user interrupted with new instructions"
+
+func (s *Session) steeringPending() bool {
+	return s.steerer != nil && s.steerer.Steering()
+}
+
+// steer adds waiting instructions to the trailing tool results and
+// reports whether there were any.
+func (s *Session) steer() bool {
+	if s.steerer == nil {
+		return false
+	}
+	lines := s.steerer.TakeSteering()
+	if len(lines) == 0 {
+		return false
+	}
+	text := strings.Join(lines, "\n")
+	display.Steered(text)
+	if s.audit != nil {
+		s.audit.Prompt(text)
+	}
+	s.recordEvent("steering", "", text, false)
+	s.appendUserText("<user_interjection>The user interrupted the turn with new instructions:\n" + text +
+		"\nThey take priority over your plan so far; adjust course before continuing.</user_interjection>")
+	return true
+}
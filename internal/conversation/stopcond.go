@@ -0,0 +1,100 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stopCommandTimeout bounds a stop condition command, e.g. a test suite.
+const stopCommandTimeout = 10 * time.Minute
+
+// StopCondition ends a turn once it holds, so a headless run stops when
+// its goal is reached rather than when the model thinks it is. Exactly one
+// of Command (holds when it exits 0) and File (holds when it exists) is
+// set.
+type StopCondition struct {
+	Command string
+	File    string
+}
+
+// ParseStopCondition reads a condition as given to --stop-when:
+// "command:go test ./..." or "file:dist/app".
+func ParseStopCondition(spec string) (StopCondition, error) {
+	kind, arg, ok := strings.Cut(spec, ":")
+	arg = strings.TrimSpace(arg)
+	if !ok || arg == "" {
+		return StopCondition{}, fmt.Errorf("invalid stop condition %q (use command:CMD or file:PATH)", spec)
+	}
+	switch strings.TrimSpace(kind) {
+	case "command", "cmd":
+		return StopCondition{Command: arg}, nil
+	case "file":
+		return StopCondition{File: arg}, nil
+	default:
+		return StopCondition{}, fmt.Errorf("invalid stop condition %q (use command:CMD or file:PATH)", spec)
+	}
+}
+
+func (c StopCondition) String() string {
+	if c.File != "" {
+		return c.File + " exists"
+	}
+	return "`" + c.Command + "` exits 0"
+}
+
+// SetStopConditions sets the conditions checked after every round of tool
+// calls. The turn ends as soon as any holds, and the model is sent back to
+// work if it finishes before one does.
+func (s *Session) SetStopConditions(conds []StopCondition) {
+	s.stopConds = conds
+}
+
+// StopConditionMet returns the condition that ended the last turn, or ""
+// if none did.
+func (s *Session) StopConditionMet() string {
+	return s.stopMet
+}
+
+// checkStopConditions returns the first condition that holds, or "".
+func (s *Session) checkStopConditions() string {
+	for _, c := range s.stopConds {
+		if c.File != "" {
+			p := c.File
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(s.executor.WorkDir(), p)
+			}
+			if _, err := os.Stat(p); err == nil {
+				return c.String()
+			}
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), stopCommandTimeout)
+		cmd := s.executor.Shell().Command(ctx, c.Command)
+		cmd.Dir = s.executor.WorkDir()
+		cmd.Env = s.executor.Environ()
+		err := cmd.Run()
+		cancel()
+		if err == nil {
+			return c.String()
+		}
+	}
+	return ""
+}
+
+func stopInstruction(met string) string {
+	return "<stop_condition>The goal of this run is reached: " + met + ". Do not call any more tools. " +
+		"Summarize what you did.</stop_condition>"
+}
+
+func notMetInstruction(conds []StopCondition) string {
+	var names []string
+	for _, c := range conds {
+		names = append(names, c.String())
+	}
+	return "<stop_condition>The run is not done: it ends when " + strings.Join(names, " or ") +
+		", and that does not hold yet. Keep working towards it.</stop_condition>"
+}
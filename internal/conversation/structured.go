@@ -0,0 +1,112 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// StructuredOutput asks the model to answer instruction by calling tool,
+// forcing tool_choice so the reply always matches the tool's input schema.
+// The exchange is not added to the conversation history.
+func (s *Session) StructuredOutput(instruction string, tool client.ToolDefinition) (json.RawMessage, error) {
+	messages := append(append([]client.Message(nil), s.messages...), client.Message{
+		Role:    "user",
+		Content: instruction,
+	})
+	req := &client.MessagesRequest{
+		Model:      s.model,
+		Messages:   messages,
+		System:     s.system,
+		Tools:      []client.ToolDefinition{tool},
+		ToolChoice: &client.ToolChoice{Type: "tool", Name: tool.Name},
+	}
+
+	spinner := display.NewSpinner("Summarizing...")
+	resp, err := s.send(req, nil)
+	spinner.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("API error: %w", err)
+	}
+	s.addUsage(resp.Usage)
+
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == tool.Name {
+			return block.Input, nil
+		}
+	}
+	return nil, fmt.Errorf("model did not call %s", tool.Name)
+}
+
+func (s *Session) addUsage(u client.Usage) {
+	s.usage.InputTokens += u.InputTokens
+	s.usage.OutputTokens += u.OutputTokens
+	s.turnUsage.InputTokens += u.InputTokens
+	s.turnUsage.OutputTokens += u.OutputTokens
+	s.lastContext = u.InputTokens + u.OutputTokens
+}
+
+// Usage returns the tokens used by the session so far.
+func (s *Session) Usage() client.Usage {
+	return s.usage
+}
+
+// trackModified remembers files changed by successful file tools.
+func (s *Session) trackModified(toolName string, input map[string]interface{}) {
+	for _, p := range s.modifiedPaths(toolName, input) {
+		s.modified[p] = true
+		s.lastModified = p
+	}
+}
+
+// modifiedPaths returns the cleaned absolute paths a file tool call
+// changes.
+func (s *Session) modifiedPaths(toolName string, input map[string]interface{}) []string {
+	var paths []string
+	switch toolName {
+	case "Write", "Edit", "MultiEdit", "EditLines":
+		if p, ok := input["file_path"].(string); ok {
+			paths = append(paths, p)
+		}
+	case "ApplyPatch":
+		patch, _ := input["patch"].(string)
+		for _, line := range strings.Split(patch, "\n") {
+			if strings.HasPrefix(line, "+++ ") {
+				p := strings.TrimSpace(strings.TrimPrefix(line, "+++ "))
+				if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+					p = p[:tab]
+				}
+				p = strings.TrimPrefix(p, "b/")
+				if p != "/dev/null" {
+					paths = append(paths, p)
+				}
+			}
+		}
+	}
+	for i, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(s.executor.WorkDir(), p)
+		}
+		paths[i] = filepath.Clean(p)
+	}
+	return paths
+}
+
+// ModifiedFiles lists the files changed through file tools this session,
+// relative to the working directory where possible.
+func (s *Session) ModifiedFiles() []string {
+	var out []string
+	for p := range s.modified {
+		if rel, err := filepath.Rel(s.workDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,41 @@
+package conversation
+
+import "strings"
+
+// OverrideSystemPrompt replaces the built-in instructions of the system
+// prompt with text, as --system-prompt does; the environment details are
+// kept. An empty text restores the built-in instructions.
+func (s *Session) OverrideSystemPrompt(text string) {
+	s.systemOverride = strings.TrimSpace(text)
+	s.system = s.composeSystem()
+}
+
+// AppendSystemPrompt adds text to the end of the system prompt, as
+// --append-system-prompt does. Calls accumulate.
+func (s *Session) AppendSystemPrompt(text string) {
+	if text = strings.TrimSpace(text); text == "" {
+		return
+	}
+	s.systemAppend = append(s.systemAppend, text)
+	s.system = s.composeSystem()
+}
+
+func (s *Session) composeSystem() string {
+	system := s.baseSystem
+	if s.systemOverride != "" {
+		system = s.systemOverride + "\n\n" + strings.TrimPrefix(system, systemInstructions)
+	}
+	if section := memorySection(s.memoryPath, s.memory); section != "" {
+		system = strings.TrimRight(system, "\n") + "\n\n" + section
+	}
+	if dirs := s.executor.ExtraDirs(); len(dirs) > 0 {
+		system = strings.TrimRight(system, "\n") + "\n\nAdditional directories, searched by Glob and Grep along with the working directory (refer to their files by absolute path):\n- " + strings.Join(dirs, "\n- ") + "\n"
+	}
+	if style := outputStyles[s.outputStyle]; style != "" {
+		system = strings.TrimRight(system, "\n") + "\n\n" + style + "\n"
+	}
+	for _, text := range s.systemAppend {
+		system = strings.TrimRight(system, "\n") + "\n\n" + text + "\n"
+	}
+	return system
+}
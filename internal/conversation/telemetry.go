@@ -0,0 +1,113 @@
+package conversation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/telemetry"
+)
+
+// Spans follow the OpenTelemetry GenAI conventions where they apply: a
+// turn is the root of a trace, with a "chat <model>" span per API request
+// and an "execute_tool <name>" span per tool call under it.
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+func (s *Session) startTurnSpan() {
+	s.turnSpan = s.telemetry.Start("apipod.turn", nil, telemetry.KindInternal, s.turnStarted)
+	s.turnSpan.Set("gen_ai.request.model", s.model)
+	if s.sessionID != "" {
+		s.turnSpan.Set("session.id", s.sessionID)
+	}
+}
+
+func (s *Session) endTurnSpan(err error) {
+	if s.turnSpan == nil {
+		return
+	}
+	s.turnSpan.Set("apipod.turn.tool_calls", s.turnTools)
+	s.turnSpan.Set("gen_ai.usage.input_tokens", s.turnUsage.InputTokens)
+	s.turnSpan.Set("gen_ai.usage.output_tokens", s.turnUsage.OutputTokens)
+	if s.turnLimit != "" {
+		s.turnSpan.Set("apipod.turn.limit", s.turnLimit)
+	}
+	s.turnSpan.End(err)
+	s.turnSpan = nil
+	s.telemetry.Record("apipod.turn.duration", "s", time.Since(s.turnStarted).Seconds(),
+		"gen_ai.request.model", s.model, "outcome", outcome(err))
+}
+
+// apiSpan times an API request; call the returned function with its
+// outcome.
+func (s *Session) apiSpan(req *client.MessagesRequest) func(*client.MessagesResponse, error) {
+	if s.telemetry == nil {
+		return func(*client.MessagesResponse, error) {}
+	}
+	started := time.Now()
+	span := s.telemetry.Start("chat "+req.Model, s.turnSpan, telemetry.KindClient, started)
+	span.Set("gen_ai.operation.name", "chat")
+	span.Set("gen_ai.system", "anthropic")
+	span.Set("gen_ai.request.model", req.Model)
+	if req.MaxTokens > 0 {
+		span.Set("gen_ai.request.max_tokens", req.MaxTokens)
+	}
+	return func(resp *client.MessagesResponse, err error) {
+		if err == nil {
+			span.Set("gen_ai.response.model", resp.Model)
+			span.Set("gen_ai.response.finish_reasons", resp.StopReason)
+			span.Set("gen_ai.usage.input_tokens", resp.Usage.InputTokens)
+			span.Set("gen_ai.usage.output_tokens", resp.Usage.OutputTokens)
+			s.telemetry.Add("apipod.tokens", "{token}", int64(resp.Usage.InputTokens), "gen_ai.request.model", req.Model, "gen_ai.token.type", "input")
+			s.telemetry.Add("apipod.tokens", "{token}", int64(resp.Usage.OutputTokens), "gen_ai.request.model", req.Model, "gen_ai.token.type", "output")
+		}
+		span.End(err)
+		s.telemetry.Add("apipod.api.requests", "{request}", 1, "gen_ai.request.model", req.Model, "outcome", outcome(err))
+		s.telemetry.Record("apipod.api.duration", "s", time.Since(started).Seconds(), "gen_ai.request.model", req.Model)
+	}
+}
+
+// toolSpan reports a finished tool call, which ended just now.
+func (s *Session) toolSpan(m ToolMetric) {
+	if s.telemetry == nil {
+		return
+	}
+	result := "ok"
+	switch {
+	case m.Denied:
+		result = "denied"
+	case m.IsError:
+		result = "error"
+	}
+	span := s.telemetry.Start("execute_tool "+m.Name, s.turnSpan, telemetry.KindInternal, time.Now().Add(-m.Duration))
+	span.Set("gen_ai.operation.name", "execute_tool")
+	span.Set("gen_ai.tool.name", m.Name)
+	span.Set("gen_ai.tool.call.id", m.ToolUseID)
+	span.Set("apipod.tool.outcome", result)
+	span.Set("apipod.tool.output_bytes", m.OutputBytes)
+	if m.ExitCode != 0 {
+		span.Set("apipod.tool.exit_code", m.ExitCode)
+	}
+	var err error
+	if result != "ok" {
+		err = fmt.Errorf("%s %s", m.Name, result)
+	}
+	span.End(err)
+	s.telemetry.Add("apipod.tool.calls", "{call}", 1, "gen_ai.tool.name", m.Name, "outcome", result)
+	if m.Duration > 0 {
+		s.telemetry.Record("apipod.tool.duration", "s", m.Duration.Seconds(), "gen_ai.tool.name", m.Name)
+	}
+}
+
+// flushTelemetry sends what is left before the session ends.
+func (s *Session) flushTelemetry() {
+	if err := s.telemetry.Flush(); err != nil {
+		display.WarningMessage(err.Error())
+	}
+}
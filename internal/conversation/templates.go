@@ -0,0 +1,59 @@
+package conversation
+
+import (
+	"fmt"
+
+	"github.com/rpay/apipod-cli/internal/commands"
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/templates"
+	"github.com/rpay/apipod-cli/internal/tools"
+)
+
+// Templates returns the prompt templates of ~/.apipod/templates and the
+// project's .apipod/templates, warning about files that could not be read.
+func (s *Session) Templates() []*templates.Template {
+	list, errs := templates.Load(s.workDir)
+	for _, err := range errs {
+		display.WarningMessage(fmt.Sprintf("Skipped template %v", err))
+	}
+	return list
+}
+
+// StartTemplate prepares the session for `apipod-cli run`: the template's
+// model and tools apply for the rest of the session, and the prompt for
+// args is returned for the caller to send, interactively or headless.
+func (s *Session) StartTemplate(t *templates.Template, args []string) (string, error) {
+	prompt, err := t.Expand(args)
+	if err != nil {
+		return "", err
+	}
+	if len(t.AllowedTools) > 0 {
+		known := make(map[string]bool)
+		for _, name := range tools.ToolNames() {
+			known[name] = true
+		}
+		for _, entry := range t.AllowedTools {
+			if name, _ := commands.SplitTool(entry); !known[name] {
+				return "", fmt.Errorf("template %s allows unknown tool %q", t.Name, name)
+			}
+		}
+		s.setAllowedTools(t.AllowedTools)
+	}
+	if t.Model != "" {
+		s.SetModel(t.Model)
+	}
+	return prompt, nil
+}
+
+// ShowTemplates lists the templates `apipod-cli run` can start.
+func (s *Session) ShowTemplates() {
+	var rows []display.CommandRow
+	for _, t := range s.Templates() {
+		rows = append(rows, display.CommandRow{
+			Name:        t.Usage(),
+			Description: t.Description,
+			Source:      t.Source,
+		})
+	}
+	display.TemplateList(rows)
+}
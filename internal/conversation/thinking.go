@@ -0,0 +1,47 @@
+package conversation
+
+import (
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// minThinkingBudget is the smallest budget the API accepts.
+const minThinkingBudget = 1024
+
+// SetThinkingBudget enables extended thinking with up to tokens of
+// reasoning per request; zero turns it off.
+func (s *Session) SetThinkingBudget(tokens int) {
+	if tokens > 0 && tokens < minThinkingBudget {
+		tokens = minThinkingBudget
+	}
+	s.thinkingBudget = tokens
+}
+
+// SetShowThinking chooses whether reasoning is shown expanded or as a
+// one-line summary.
+func (s *Session) SetShowThinking(show bool) {
+	s.showThinking = show
+}
+
+// ToggleThinking flips between expanded and collapsed reasoning and
+// returns the new state.
+func (s *Session) ToggleThinking() bool {
+	s.showThinking = !s.showThinking
+	return s.showThinking
+}
+
+// ThinkingPanel renders the latest reasoning in the current display mode,
+// for /thinking and ctrl+o.
+func (s *Session) ThinkingPanel() string {
+	if s.lastThinking == "" {
+		return "  No reasoning to show"
+	}
+	return display.RenderThinking(s.lastThinking, s.showThinking)
+}
+
+func (s *Session) thinkingParam() *client.Thinking {
+	if s.thinkingBudget == 0 {
+		return nil
+	}
+	return &client.Thinking{Type: "enabled", BudgetTokens: s.thinkingBudget}
+}
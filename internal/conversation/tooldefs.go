@@ -0,0 +1,82 @@
+package conversation
+
+import (
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+)
+
+const (
+	ToolDefsFull  = "full"
+	ToolDefsCache = "cache"
+	ToolDefsSlim  = "slim"
+)
+
+// SetToolDefinitionMode selects how tool schemas are sent on each request.
+// Unknown modes fall back to sending the full definitions.
+func (s *Session) SetToolDefinitionMode(mode string) {
+	s.toolDefsMode = mode
+}
+
+// toolDefinitionsFor returns the tool definitions for the next request. The
+// first request of a session always carries the full descriptions so the
+// model learns each tool; afterwards "slim" mode sends only short variants.
+func (s *Session) toolDefinitionsFor(full []client.ToolDefinition) []client.ToolDefinition {
+	switch s.toolDefsMode {
+	case ToolDefsCache:
+		if len(full) == 0 {
+			return full
+		}
+		defs := append([]client.ToolDefinition(nil), full...)
+		defs[len(defs)-1].CacheControl = &client.CacheControl{Type: "ephemeral"}
+		return defs
+	case ToolDefsSlim:
+		if s.requests == 0 {
+			return full
+		}
+		defs := make([]client.ToolDefinition, len(full))
+		for i, d := range full {
+			defs[i] = client.ToolDefinition{
+				Name:        d.Name,
+				Description: firstSentence(d.Description),
+				InputSchema: stripDescriptions(d.InputSchema),
+			}
+		}
+		return defs
+	default:
+		return full
+	}
+}
+
+func firstSentence(s string) string {
+	if i := strings.Index(s, ". "); i >= 0 {
+		return s[:i+1]
+	}
+	return s
+}
+
+// stripDescriptions returns a copy of a JSON schema without property
+// descriptions; the property names and types are kept.
+func stripDescriptions(schema interface{}) interface{} {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if k == "description" {
+				if _, isString := val.(string); isString {
+					continue
+				}
+			}
+			out[k] = stripDescriptions(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = stripDescriptions(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,101 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/i18n"
+	"github.com/rpay/apipod-cli/internal/transfer"
+)
+
+var errTransferUnsupported = errors.New("your terminal does not support file transfers (OSC 1337: iTerm2 or WezTerm); use scp instead")
+
+// Download sends a file from the workspace to the user's local machine
+// through the terminal. Paths resolve like /open, so a bare name finds a
+// file changed this session.
+func (s *Session) Download(spec string) (string, error) {
+	if !transfer.Supported() {
+		return "", errTransferUnsupported
+	}
+	if strings.TrimSpace(spec) == "" {
+		spec = s.lastModified
+	}
+	if spec == "" {
+		return "", fmt.Errorf("usage: /download <path>")
+	}
+	path, err := s.resolveOpenPath(spec)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory; archive it first", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	err = s.withTerminal(func() error {
+		return transfer.Send(os.Stdout, filepath.Base(path), data)
+	})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Upload lets the user pick local files in their terminal and writes them
+// into the workspace: into dest when it is a directory (default the
+// working directory), or as dest when a single file is uploaded.
+// Existing files are only replaced after confirmation.
+func (s *Session) Upload(dest string) ([]string, error) {
+	if !transfer.Supported() {
+		return nil, errTransferUnsupported
+	}
+	if dest == "" {
+		dest = "."
+	}
+	dirSpec := strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(filepath.Separator))
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(s.executor.WorkDir(), dest)
+	}
+	var files []transfer.File
+	err := s.withTerminal(func() (err error) {
+		files, err = transfer.Receive()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	info, statErr := os.Stat(dest)
+	intoDir := statErr == nil && info.IsDir() || len(files) > 1 || dirSpec
+
+	var written []string
+	for _, f := range files {
+		target := dest
+		if intoDir {
+			target = filepath.Join(dest, f.Name)
+		}
+		if _, err := os.Stat(target); err == nil && !display.ConfirmPrompt(i18n.T("confirm.replace", target)) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, f.Data, 0o644); err != nil {
+			return written, fmt.Errorf("write %s: %w", target, err)
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
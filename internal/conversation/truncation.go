@@ -0,0 +1,51 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// defaultMaxContinues is how often one turn continues a response cut off
+// at the output token limit.
+const defaultMaxContinues = 3
+
+const continueInstruction = "Your last response was cut off at the output token limit. Continue exactly where it stopped, without repeating what you already wrote."
+
+// truncatedToolMessage answers a tool call whose input was cut off. It is
+// not run: a Write with half its content would silently truncate the file.
+const truncatedToolMessage = "This tool call was cut off at the output token limit, so its input is incomplete and it was not run. Make the change in smaller steps, e.g. write the first part of the file and add the rest with further edits."
+
+// SetMaxContinues sets how often one turn automatically continues a
+// response that hit the output token limit; zero restores the default and
+// a negative value turns continuing off.
+func (s *Session) SetMaxContinues(n int) {
+	if n == 0 {
+		n = defaultMaxContinues
+	}
+	s.maxContinues = max(n, 0)
+}
+
+// continueTruncated asks the model to go on after a text response that hit
+// the output token limit, and reports whether it did.
+func (s *Session) continueTruncated() bool {
+	if s.turnContinues >= s.maxContinues {
+		display.WarningMessage("The response was cut off at the output token limit")
+		return false
+	}
+	s.turnContinues++
+	display.InfoMessage(fmt.Sprintf("Response reached the output token limit; continuing (%d of %d)", s.turnContinues, s.maxContinues))
+	s.appendUserText(continueInstruction)
+	return true
+}
+
+// completeInput returns the input of a tool call as it goes back into
+// history; a call cut off mid-input has invalid JSON, which the API would
+// reject on every later request.
+func completeInput(input json.RawMessage) json.RawMessage {
+	if json.Valid(input) {
+		return input
+	}
+	return json.RawMessage("{}")
+}
@@ -0,0 +1,120 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/workflow"
+)
+
+// maxCheckOutput bounds the output of a failed success command passed
+// back to the model.
+const maxCheckOutput = 4000
+
+// WorkflowResult is the outcome of RunWorkflow, e.g. for the JSON result
+// of a headless run.
+type WorkflowResult struct {
+	Success bool                 `json:"success"`
+	Steps   []WorkflowStepResult `json:"steps"`
+}
+
+// WorkflowStepResult is the outcome of one step. Failure is why its last
+// check failed.
+type WorkflowStepResult struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Attempts int    `json:"attempts"`
+	Failure  string `json:"failure,omitempty"`
+}
+
+// RunWorkflow sends the steps of wf from index from on, one prompt each.
+// After each turn the step's artifacts and success command are checked;
+// a failing check goes back to the model until the step's attempts run
+// out, which ends the workflow. An error means a turn failed, e.g. the
+// API was unreachable.
+func (s *Session) RunWorkflow(wf *workflow.Workflow, from int) (*WorkflowResult, error) {
+	if wf.Model != "" {
+		s.SetModel(wf.Model)
+	}
+	res := &WorkflowResult{Steps: []WorkflowStepResult{}}
+	for i := from; i < len(wf.Steps); i++ {
+		step := wf.Steps[i]
+		display.WorkflowStep(i+1, len(wf.Steps), step.Name)
+		sr := WorkflowStepResult{Name: step.Name}
+		prompt := stepPrompt(step, i+1, len(wf.Steps))
+		for sr.Attempts < step.MaxAttempts {
+			sr.Attempts++
+			if err := s.SendMessage(prompt); err != nil {
+				sr.Failure = err.Error()
+				res.Steps = append(res.Steps, sr)
+				return res, err
+			}
+			if sr.Failure = s.checkStep(step); sr.Failure == "" {
+				sr.Passed = true
+				break
+			}
+			summary, _, _ := strings.Cut(sr.Failure, "\n")
+			display.WarningMessage(fmt.Sprintf("%s: check failed (attempt %d of %d): %s", step.Name, sr.Attempts, step.MaxAttempts, summary))
+			prompt = "<workflow_check>The step is not done yet: " + sr.Failure + "\nFix this and check again.</workflow_check>"
+		}
+		res.Steps = append(res.Steps, sr)
+		if !sr.Passed {
+			display.ErrorMessage(fmt.Sprintf("Workflow stopped at step %d (%s) after %d %s", i+1, step.Name, sr.Attempts, pluralize(sr.Attempts, "attempt", "attempts")))
+			return res, nil
+		}
+		display.SuccessMessage(fmt.Sprintf("Step %d/%d passed: %s", i+1, len(wf.Steps), step.Name))
+	}
+	res.Success = true
+	return res, nil
+}
+
+// stepPrompt tells the model what a step is and how it is checked.
+func stepPrompt(step workflow.Step, n, total int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<workflow_step>Step %d of %d: %s</workflow_step>\n\n%s", n, total, step.Name, step.Prompt)
+	if len(step.Artifacts) > 0 {
+		b.WriteString("\n\nWhen you are done these files must exist: " + strings.Join(step.Artifacts, ", ") + ".")
+	}
+	if step.Success != "" {
+		b.WriteString("\n\nThe step passes when `" + step.Success + "` exits 0; run it to check before you finish.")
+	}
+	return b.String()
+}
+
+// checkStep returns why step has not passed, or "" when it has.
+func (s *Session) checkStep(step workflow.Step) string {
+	var missing []string
+	for _, a := range step.Artifacts {
+		p := a
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(s.executor.WorkDir(), p)
+		}
+		if _, err := os.Stat(p); err != nil {
+			missing = append(missing, a)
+		}
+	}
+	if len(missing) > 0 {
+		return "missing " + strings.Join(missing, ", ")
+	}
+	if step.Success == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stopCommandTimeout)
+	defer cancel()
+	cmd := s.executor.Shell().Command(ctx, step.Success)
+	cmd.Dir = s.executor.WorkDir()
+	cmd.Env = s.executor.Environ()
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return ""
+	}
+	text := strings.TrimSpace(string(out))
+	if len(text) > maxCheckOutput {
+		text = "…" + text[len(text)-maxCheckOutput:]
+	}
+	return fmt.Sprintf("`%s` failed (%v):\n%s", step.Success, err, text)
+}
@@ -0,0 +1,71 @@
+package conversation
+
+import (
+	"fmt"
+
+	"github.com/rpay/apipod-cli/internal/display"
+	"github.com/rpay/apipod-cli/internal/worktree"
+)
+
+// EnableWorktree moves the session into a new git worktree on its own
+// branch, for --worktree: the model's edits and commands no longer touch
+// the user's working tree until Close offers to merge them.
+func (s *Session) EnableWorktree() error {
+	if worktree.Dirty(s.workDir) {
+		display.WarningMessage("Uncommitted changes in your working tree are not in the worktree")
+	}
+	w, err := worktree.Create(s.workDir)
+	if err != nil {
+		return fmt.Errorf("create worktree: %w", err)
+	}
+	s.worktree = w
+	s.workDir = w.Dir
+	s.executor.SetWorkDir(w.Dir)
+	s.SetSystemPrompt(BuildSystemPrompt(w.Dir))
+	display.SuccessMessage(fmt.Sprintf("Working in a worktree on branch %s", w.Branch))
+	return nil
+}
+
+// endWorktree commits what changed in the worktree and asks whether to
+// merge it into the user's working tree, keep the branch or discard it.
+// The worktree itself is removed either way.
+func (s *Session) endWorktree() {
+	w := s.worktree
+	if w == nil {
+		return
+	}
+	s.worktree = nil
+	stat, err := w.Commit()
+	if err != nil {
+		display.ErrorMessage(fmt.Sprintf("Worktree %s left in place: %v", w.Path, err))
+		return
+	}
+	if stat == "" {
+		if err := w.Discard(); err != nil {
+			display.ErrorMessage(err.Error())
+			return
+		}
+		display.InfoMessage("No changes in the worktree; removed it")
+		return
+	}
+	switch display.WorktreePrompt(w.Branch, stat) {
+	case "m":
+		err = w.Merge()
+		if err == nil {
+			display.SuccessMessage("Merged the changes into your working tree, uncommitted")
+		}
+	case "d":
+		err = w.Discard()
+		if err == nil {
+			display.SuccessMessage("Discarded the changes")
+		}
+	default:
+		err = w.Keep()
+		if err == nil {
+			display.SuccessMessage(fmt.Sprintf("Kept the changes on branch %s", w.Branch))
+		}
+	}
+	if err != nil {
+		display.ErrorMessage(fmt.Sprintf("%v; the changes are on branch %s", err, w.Branch))
+	}
+}
@@ -0,0 +1,282 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/conversation"
+	"github.com/rpay/apipod-cli/internal/index"
+)
+
+const (
+	SocketFile  = "daemon.sock"
+	IdleTimeout = 30 * time.Minute
+	dialTimeout = 200 * time.Millisecond
+	stateTTL    = 5 * time.Minute
+)
+
+// State is the pre-computed startup state handed to attaching sessions. It
+// holds nothing secret: clients read the config, and the credentials in
+// it, themselves.
+type State struct {
+	System   string    `json:"system"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+type request struct {
+	Method  string `json:"method"`
+	WorkDir string `json:"work_dir,omitempty"`
+}
+
+type response struct {
+	State  *State `json:"state,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SocketPath is the daemon socket, in a directory only the user can enter
+// so no one else can connect while it is being set up.
+func SocketPath() string {
+	return filepath.Join(filepath.Dir(config.ConfigPath()), "run", SocketFile)
+}
+
+// Server keeps config and per-directory session state warm between
+// invocations.
+type Server struct {
+	mu        sync.Mutex
+	states    map[string]*State
+	configMod time.Time
+	lastUsed  time.Time
+	started   time.Time
+	listener  net.Listener
+}
+
+func NewServer() *Server {
+	return &Server{states: make(map[string]*State)}
+}
+
+// Serve listens on the daemon socket until Shutdown is requested or the
+// daemon has been idle for IdleTimeout.
+func (s *Server) Serve() error {
+	path := SocketPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create socket dir: %w", err)
+	}
+	// MkdirAll leaves an existing directory's mode alone.
+	if err := os.Chmod(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create socket dir: %w", err)
+	}
+	if Running() {
+		return fmt.Errorf("daemon already running at %s", path)
+	}
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	defer os.Remove(path)
+	os.Chmod(path, 0600)
+
+	s.listener = ln
+	s.started = time.Now()
+	s.touch()
+	go s.reapWhenIdle()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	s.touch()
+
+	var req request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(response{Error: fmt.Sprintf("decode request: %v", err)})
+		return
+	}
+
+	var resp response
+	switch req.Method {
+	case "attach":
+		state, err := s.state(req.WorkDir)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.State = state
+		}
+	case "status":
+		s.mu.Lock()
+		resp.Status = fmt.Sprintf("running since %s, %d warm director(ies)", s.started.Format(time.RFC3339), len(s.states))
+		s.mu.Unlock()
+	case "shutdown":
+		resp.Status = "stopping"
+		json.NewEncoder(conn).Encode(resp)
+		s.close()
+		return
+	default:
+		resp.Error = fmt.Sprintf("unknown method: %s", req.Method)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// state returns the cached state for workDir, rebuilding it when the config
+// file changed or the entry is older than stateTTL. The rebuild runs
+// without the lock, so other directories and status requests are not held
+// up by a large project's index.
+func (s *Server) state(workDir string) (*State, error) {
+	if workDir == "" {
+		return nil, fmt.Errorf("missing work_dir")
+	}
+	var mod time.Time
+	if info, err := os.Stat(config.ConfigPath()); err == nil {
+		mod = info.ModTime()
+	}
+
+	s.mu.Lock()
+	if !mod.Equal(s.configMod) {
+		s.states = make(map[string]*State)
+		s.configMod = mod
+	}
+	st, ok := s.states[workDir]
+	s.mu.Unlock()
+	if ok && time.Since(st.LoadedAt) < stateTTL {
+		return st, nil
+	}
+
+	// Keep the project index fresh so the system prompt carries a current map.
+	index.Refresh(workDir)
+	st = &State{
+		System:   conversation.BuildSystemPrompt(workDir),
+		LoadedAt: time.Now(),
+	}
+	s.mu.Lock()
+	if mod.Equal(s.configMod) {
+		s.states[workDir] = st
+	}
+	s.mu.Unlock()
+	return st, nil
+}
+
+func (s *Server) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *Server) reapWhenIdle() {
+	for range time.Tick(time.Minute) {
+		s.mu.Lock()
+		idle := time.Since(s.lastUsed)
+		s.mu.Unlock()
+		if idle > IdleTimeout {
+			s.close()
+			return
+		}
+	}
+}
+
+func (s *Server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listener == nil
+}
+
+func call(req request) (*response, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// Attach fetches warm state for workDir. Callers fall back to loading
+// everything themselves when it returns an error.
+func Attach(workDir string) (*State, error) {
+	resp, err := call(request{Method: "attach", WorkDir: workDir})
+	if err != nil {
+		return nil, err
+	}
+	return resp.State, nil
+}
+
+func Running() bool {
+	_, err := call(request{Method: "status"})
+	return err == nil
+}
+
+func Status() (string, error) {
+	resp, err := call(request{Method: "status"})
+	if err != nil {
+		return "", fmt.Errorf("daemon not running")
+	}
+	return resp.Status, nil
+}
+
+func Stop() error {
+	_, err := call(request{Method: "shutdown"})
+	return err
+}
+
+// Spawn starts the daemon as a detached child running
+// "<executable> daemon serve".
+func Spawn() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+	cmd := exec.Command(exe, "daemon", "serve")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start daemon: %w", err)
+	}
+	go cmd.Wait()
+
+	for i := 0; i < 20; i++ {
+		if Running() {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("daemon did not come up at %s", SocketPath())
+}
@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/config"
+)
+
+func TestServeLocksDownSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes do not apply")
+	}
+	// A short home keeps the socket path within the unix limit.
+	home, err := os.MkdirTemp("/tmp", "apd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	t.Setenv("HOME", home)
+
+	const key = "apk_daemon_test_secret_123"
+	if err := os.MkdirAll(filepath.Dir(SocketPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config.ConfigPath(), []byte(`{"api_key": "`+key+`"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer()
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+	defer func() {
+		Stop()
+		<-done
+	}()
+	for deadline := time.Now().Add(5 * time.Second); !Running(); {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if info, err := os.Stat(filepath.Dir(SocketPath())); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("socket dir mode = %v, %v; want 0700", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(SocketPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	st, err := Attach(t.TempDir())
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if strings.Contains(st.System, key) {
+		t.Error("attach handed out the API key")
+	}
+}
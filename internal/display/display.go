@@ -1,6 +1,8 @@
 package display
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +13,8 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/term"
+
+	"github.com/rpay/apipod-cli/internal/i18n"
 )
 
 var (
@@ -33,54 +37,26 @@ var (
 	BrightWhite = "\033[97m"
 )
 
-// Lipgloss styles
+// Lipgloss styles, built from the active theme by applyTheme.
 var (
-	headerStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("63")).
-			Padding(0, 1).
-			Align(lipgloss.Center)
-
-	responseStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("240")).
-			Padding(0, 1)
-
-	toolStyle = lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color("241")).
-			BorderLeft(true).
-			BorderRight(false).
-			BorderTop(false).
-			BorderBottom(false).
-			PaddingLeft(1)
-
-	titleStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("63")).
-			Bold(true)
-
-	dimStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241"))
-
-	successStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("42")).
-			Bold(true)
-
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("196")).
-			Bold(true)
-
-	warnStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("214")).
-			Bold(true)
-
-	promptStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("63")).
-			Bold(true)
+	headerStyle   lipgloss.Style
+	responseStyle lipgloss.Style
+	toolStyle     lipgloss.Style
+	titleStyle    lipgloss.Style
+	dimStyle      lipgloss.Style
+	successStyle  lipgloss.Style
+	errorStyle    lipgloss.Style
+	warnStyle     lipgloss.Style
+	promptStyle   lipgloss.Style
+	accentStyle   lipgloss.Style
 )
 
+// terminal is the real stdout, which stays queryable while os.Stdout is
+// teed to pairing watchers.
+var terminal = os.Stdout
+
 func TermWidth() int {
-	w, _, err := term.GetSize(int(os.Stdout.Fd()))
+	w, _, err := term.GetSize(int(terminal.Fd()))
 	if err != nil || w <= 0 {
 		return 80
 	}
@@ -95,13 +71,28 @@ func contentWidth() int {
 	return w
 }
 
+// version is shown in the banner; SetVersion sets it from the build.
+var version = "0.1.0"
+
+func SetVersion(v string) {
+	if v != "" {
+		version = strings.TrimPrefix(v, "v")
+	}
+}
+
+// Version returns the version shown in the banner.
+func Version() string {
+	return version
+}
+
 func Banner(model, cwd string) {
 	w := contentWidth()
 	dir := filepath.Base(cwd)
 
-	title := titleStyle.Render("◆ apipod-cli") + " " + dimStyle.Render("v0.1.0")
+	title := titleStyle.Render("◆ apipod-cli") + " " + dimStyle.Render("v"+version)
 	info := dimStyle.Render(fmt.Sprintf("%s · %s", dir, model))
-	tip := dimStyle.Render("Type ") + lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Render("/help") + dimStyle.Render(" for commands")
+	before, after, _ := strings.Cut(i18n.T("banner.tip"), "%s")
+	tip := dimStyle.Render(before) + accentStyle.Render("/help") + dimStyle.Render(after)
 
 	content := title + "\n" + info + "\n" + tip
 
@@ -137,6 +128,18 @@ func ErrorMessage(msg string) {
 	fmt.Println(errorStyle.Render("  ✗ " + msg))
 }
 
+// APIError prints an error with what to do about it, when the error knows
+// (the typed API errors of the client package do).
+func APIError(err error) {
+	ErrorMessage(err.Error())
+	var h interface{ Hint() string }
+	if errors.As(err, &h) {
+		if hint := h.Hint(); hint != "" {
+			fmt.Println(dimStyle.Render("    " + hint))
+		}
+	}
+}
+
 func SuccessMessage(msg string) {
 	fmt.Println(successStyle.Render("  ✓ " + msg))
 }
@@ -150,6 +153,9 @@ type Spinner struct {
 	mu      sync.Mutex
 	stop    chan struct{}
 	stopped bool
+
+	// msgMu guards message, which SetMessage changes while run draws it.
+	msgMu   sync.Mutex
 	message string
 }
 
@@ -160,10 +166,20 @@ func NewSpinner(message string) *Spinner {
 		stop:    make(chan struct{}),
 		message: message,
 	}
-	go s.run()
+	if interactive {
+		go s.run()
+	}
 	return s
 }
 
+// SetMessage changes the text next to the spinner, e.g. to say what it is
+// waiting for.
+func (s *Spinner) SetMessage(message string) {
+	s.msgMu.Lock()
+	defer s.msgMu.Unlock()
+	s.message = message
+}
+
 func (s *Spinner) run() {
 	i := 0
 	for {
@@ -173,7 +189,10 @@ func (s *Spinner) run() {
 			return
 		default:
 			frame := spinnerFrames[i%len(spinnerFrames)]
-			fmt.Printf("\r  %s%s %s%s", BrightCyan, frame, s.message, Reset)
+			s.msgMu.Lock()
+			message := s.message
+			s.msgMu.Unlock()
+			fmt.Printf("\r\033[2K  %s%s %s%s", BrightCyan, frame, message, Reset)
 			i++
 			time.Sleep(80 * time.Millisecond)
 		}
@@ -186,16 +205,35 @@ func (s *Spinner) Stop() {
 	if !s.stopped {
 		s.stopped = true
 		close(s.stop)
-		time.Sleep(100 * time.Millisecond)
+		if interactive {
+			time.Sleep(100 * time.Millisecond)
+		}
 	}
 }
 
+// rawResponses prints responses as the model wrote them, for output that
+// is meant to be copied or piped, such as the json-only output style.
+var rawResponses bool
+
+// SetRawResponses turns markdown rendering of responses off or on.
+func SetRawResponses(on bool) {
+	rawResponses = on
+}
+
 // RenderMarkdown renders streamed text as markdown in a panel
 func RenderMarkdown(text string) {
+	if rawResponses {
+		fmt.Println(strings.TrimSpace(text))
+		return
+	}
 	w := contentWidth()
 
+	style := glamour.WithStandardStyle(markdownStyle())
+	if markdownStyle() == "auto" {
+		style = glamour.WithAutoStyle()
+	}
 	renderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
+		style,
 		glamour.WithWordWrap(w-6),
 	)
 	if err != nil {
@@ -233,6 +271,9 @@ func ToolCallStart(name string, input map[string]interface{}) {
 				}
 			}
 		}
+		if cwd, ok := input["cwd"].(string); ok && cwd != "" {
+			detail += " (in " + cwd + ")"
+		}
 	case "Read":
 		if fp, ok := input["file_path"].(string); ok {
 			detail = shortenPath(fp)
@@ -245,6 +286,69 @@ func ToolCallStart(name string, input map[string]interface{}) {
 		if fp, ok := input["file_path"].(string); ok {
 			detail = shortenPath(fp)
 		}
+	case "EditLines":
+		if fp, ok := input["file_path"].(string); ok {
+			start, _ := input["start_line"].(float64)
+			end, _ := input["end_line"].(float64)
+			detail = fmt.Sprintf("%s:%d-%d", shortenPath(fp), int(start), int(end))
+		}
+	case "ApplyPatch":
+		if patch, ok := input["patch"].(string); ok {
+			detail = fmt.Sprintf("%d file(s)", strings.Count("\n"+patch, "\n+++ "))
+		}
+	case "HttpRequest":
+		if u, ok := input["url"].(string); ok {
+			method, _ := input["method"].(string)
+			if method == "" {
+				method = "GET"
+			}
+			detail = strings.ToUpper(method) + " " + u
+		} else if _, ok := input["curl"].(string); ok {
+			detail = "curl"
+		}
+	case "ApiDiff":
+		action, _ := input["action"].(string)
+		collection, _ := input["collection"].(string)
+		detail = action + " " + shortenPath(collection)
+	case "WebSocket":
+		if u, ok := input["url"].(string); ok {
+			detail = u
+		}
+	case "Grpc":
+		action, _ := input["action"].(string)
+		if action == "" {
+			action = "list"
+		}
+		detail = action
+		if m, ok := input["method"].(string); ok && m != "" {
+			detail += " " + m
+		} else if sym, ok := input["symbol"].(string); ok && sym != "" {
+			detail += " " + sym
+		}
+	case "GitHost":
+		action, _ := input["action"].(string)
+		detail = action
+		if n, ok := input["number"].(float64); ok {
+			detail += fmt.Sprintf(" #%d", int(n))
+		} else if b, ok := input["branch"].(string); ok && b != "" {
+			detail += " " + b
+		} else if t, ok := input["title"].(string); ok && t != "" {
+			detail += " " + t
+		}
+	case "Stat":
+		if fp, ok := input["path"].(string); ok {
+			detail = shortenPath(fp)
+		} else if ps, ok := input["paths"].([]interface{}); ok {
+			detail = fmt.Sprintf("%d path(s)", len(ps))
+		}
+	case "Archive":
+		if fp, ok := input["archive_path"].(string); ok {
+			action, _ := input["action"].(string)
+			if action == "" {
+				action = "list"
+			}
+			detail = action + " " + shortenPath(fp)
+		}
 	case "Glob":
 		if p, ok := input["pattern"].(string); ok {
 			detail = p
@@ -253,6 +357,10 @@ func ToolCallStart(name string, input map[string]interface{}) {
 		if p, ok := input["pattern"].(string); ok {
 			detail = p
 		}
+	case "SemanticSearch":
+		if q, ok := input["query"].(string); ok {
+			detail = q
+		}
 	}
 
 	icon := toolIcon(name)
@@ -266,15 +374,23 @@ func ToolCallStart(name string, input map[string]interface{}) {
 
 func toolIcon(name string) string {
 	switch name {
-	case "Bash", "BashOutput", "KillBash":
+	case "Bash", "BashOutput", "KillBash", "ListShells":
 		return "❯"
-	case "Read":
+	case "Read", "Stat":
 		return "📄"
+	case "Archive":
+		return "📦"
+	case "HttpRequest", "ApiDiff":
+		return "🌐"
+	case "GitHost":
+		return "⎇"
+	case "WebSocket", "Grpc":
+		return "🔌"
 	case "Write":
 		return "✏️"
-	case "Edit", "MultiEdit":
+	case "Edit", "MultiEdit", "EditLines", "ApplyPatch":
 		return "✏️"
-	case "Glob", "Grep":
+	case "Glob", "Grep", "SemanticSearch":
 		return "🔍"
 	default:
 		return "⚡"
@@ -293,52 +409,550 @@ func shortenPath(path string) string {
 	return "./" + rel
 }
 
+// CollapsedLines is how much of a tool result is shown at first.
+const CollapsedLines = 15
+
 func ToolCallResult(content string, isError bool) {
+	ExpandableResult(0, content, isError)
+}
+
+// ExpandableResult is ToolCallResult for a result kept under id, whose
+// truncation note says how to see all of it.
+func ExpandableResult(id int, content string, isError bool) {
 	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
-	maxLines := 15
-	truncated := false
 	totalLines := len(lines)
-	if len(lines) > maxLines {
-		truncated = true
-		lines = lines[:maxLines]
+	if len(lines) > CollapsedLines {
+		lines = lines[:CollapsedLines]
+	}
+	fmt.Println(toolStyle.Render(plainLines(lines, isError) + moreNote(id, totalLines-len(lines))))
+}
+
+func plainLines(lines []string, isError bool) string {
+	if isError {
+		return errorStyle.Render(strings.Join(lines, "\n"))
+	}
+	return dimStyle.Render(strings.Join(lines, "\n"))
+}
+
+// moreNote says how many lines were left out, and with an id how to
+// expand them.
+func moreNote(id, more int) string {
+	if more <= 0 {
+		return ""
+	}
+	note := fmt.Sprintf("... %d more lines", more)
+	if id > 0 {
+		note += fmt.Sprintf(" · /expand %d or ctrl+r", id)
+	}
+	return "\n" + dimStyle.Render(note)
+}
+
+// ExpandedResult renders all of a kept tool result under a "#id title"
+// heading; Read results of path are highlighted as in CodeResult.
+func ExpandedResult(id int, title, path, content string, isError bool) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	body := ""
+	if path != "" && !isError {
+		if colored := codeLines(path, lines); colored != nil {
+			body = strings.Join(colored, "\n")
+		}
+	}
+	if body == "" {
+		body = plainLines(lines, isError)
+	}
+	heading := accentStyle.Render(fmt.Sprintf("#%d", id)) + " " + titleStyle.Render(title) +
+		dimStyle.Render(fmt.Sprintf(" · %d lines", len(lines)))
+	return "\n  " + heading + "\n" + toolStyle.Render(body)
+}
+
+// ToolPreview shows the input of a tool call while the model is still
+// writing it, on one line that is redrawn as it grows, so a bad command
+// can be seen (and interrupted) before it is complete.
+type ToolPreview struct {
+	name  string
+	shown bool
+}
+
+func NewToolPreview(name string) *ToolPreview {
+	return &ToolPreview{name: name}
+}
+
+// Update redraws the preview with the input so far; the end of a long
+// input, where the model is writing, is kept.
+func (p *ToolPreview) Update(detail string) {
+	if !interactive {
+		return
+	}
+	detail = strings.NewReplacer("\n", " ⏎ ", "\t", " ").Replace(detail)
+	label := "  ⋯ " + p.name + " "
+	if room := contentWidth() - lipgloss.Width(label) - 1; room > 0 {
+		if r := []rune(detail); len(r) > room {
+			detail = "…" + string(r[len(r)-room+1:])
+		}
+	}
+	fmt.Print("\r\033[2K" + dimStyle.Render(label) + toolStyle.Render(detail))
+	p.shown = true
+}
+
+// Clear removes the preview line.
+func (p *ToolPreview) Clear() {
+	if p.shown {
+		fmt.Print("\r\033[2K")
+		p.shown = false
+	}
+}
+
+// LiveOutput streams a running command's output under the tool header with
+// an elapsed-time line kept at the bottom.
+type LiveOutput struct {
+	mu      sync.Mutex
+	started time.Time
+	lines   int
+	done    chan struct{}
+}
+
+func NewLiveOutput() *LiveOutput {
+	l := &LiveOutput{started: time.Now(), done: make(chan struct{})}
+	if interactive {
+		l.drawStatus()
+		go l.tick()
+	}
+	return l
+}
+
+func (l *LiveOutput) tick() {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-t.C:
+			l.mu.Lock()
+			l.drawStatus()
+			l.mu.Unlock()
+		}
+	}
+}
+
+func (l *LiveOutput) drawStatus() {
+	elapsed := time.Since(l.started).Round(time.Second)
+	fmt.Printf("\r\033[2K  %s", dimStyle.Render(fmt.Sprintf("⏱ %s · %d lines", elapsed, l.lines)))
+}
+
+func (l *LiveOutput) Line(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines++
+	fmt.Printf("%s  %s %s\n", clearLine(), dimStyle.Render("│"), dimStyle.Render(line))
+	if interactive {
+		l.drawStatus()
 	}
+}
 
-	var resultText string
+// Done stops the elapsed-time line and prints a one-line summary.
+func (l *LiveOutput) Done(isError bool) {
+	close(l.done)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elapsed := time.Since(l.started).Round(100 * time.Millisecond)
+	summary := fmt.Sprintf("%s · %d lines", elapsed, l.lines)
+	fmt.Print(clearLine())
 	if isError {
-		resultText = errorStyle.Render(strings.Join(lines, "\n"))
+		fmt.Println(errorStyle.Render("  ✗ failed after " + summary))
 	} else {
-		resultText = dimStyle.Render(strings.Join(lines, "\n"))
+		fmt.Println(dimStyle.Render("  ✓ done in " + summary))
+	}
+}
+
+// ToolTiming follows a tool result with how long the call took, the exit
+// status of a failed command and the size of its output.
+func ToolTiming(d time.Duration, exitCode, size int) {
+	parts := []string{toolDuration(d)}
+	if exitCode != 0 {
+		parts = append(parts, fmt.Sprintf("exit %d", exitCode))
+	}
+	if size > 0 {
+		parts = append(parts, byteSize(size))
+	}
+	fmt.Println(dimStyle.Render("  · " + strings.Join(parts, " · ")))
+}
+
+func toolDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func byteSize(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
 	}
-	if truncated {
-		resultText += "\n" + dimStyle.Render(fmt.Sprintf("... %d more lines", totalLines-maxLines))
+}
+
+// Streamed reports whether any output was shown live.
+func (l *LiveOutput) Streamed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lines > 0
+}
+
+// RiskWarning shows a red panel explaining why a command is high risk.
+func RiskWarning(command string, reasons []string) {
+	w := contentWidth()
+	var b strings.Builder
+	b.WriteString(errorStyle.Render(i18n.T("risk.title")))
+	b.WriteString("\n" + command)
+	for _, r := range reasons {
+		b.WriteString("\n" + errorStyle.Render("• ") + r)
 	}
+	panel := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Error)).
+		Padding(0, 1).
+		Width(w - 4).
+		Render(b.String())
+	fmt.Println(panel)
+}
 
-	styled := toolStyle.Render(resultText)
-	fmt.Println(styled)
+// Thinking prints the model's reasoning, see RenderThinking.
+func Thinking(text string, expanded bool) {
+	fmt.Println(RenderThinking(text, expanded))
+}
+
+// RenderThinking returns reasoning as a dimmed panel when expanded, or as a
+// one-line summary when collapsed.
+func RenderThinking(text string, expanded bool) string {
+	text = strings.TrimSpace(text)
+	words := len(strings.Fields(text))
+	if !expanded {
+		return dimStyle.Render("  " + i18n.N("thinking.summary", words, words))
+	}
+	body := dimStyle.Italic(true).Render(text)
+	if text == "" {
+		body = dimStyle.Render(i18n.T("thinking.redacted"))
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Muted)).
+		Padding(0, 1).
+		Width(contentWidth() - 4).
+		Render(dimStyle.Render(i18n.T("thinking.title")) + "\n" + body)
+}
+
+// inputPause stops reading typed-ahead input while a prompt waits for an
+// answer; see SetInputPause.
+var inputPause func() (resume func())
+
+// SetInputPause registers fn, which the prompts below call before reading
+// from the terminal and whose result they call once answered, so input
+// queued in the background does not swallow the answer.
+func SetInputPause(fn func() (resume func())) {
+	inputPause = fn
+}
+
+func pauseInput() (resume func()) {
+	if inputPause == nil {
+		return func() {}
+	}
+	return inputPause()
+}
+
+// QueuedInput notes that a line typed during a turn was queued, e.g.
+// "1 message queued".
+func QueuedInput(n int) {
+	fmt.Println(dimStyle.Render("  " + i18n.N("input.queued", n, n)))
+}
+
+// SteeringQueued notes that a line typed after Esc will redirect the
+// running turn.
+func SteeringQueued() {
+	fmt.Println(dimStyle.Render("  " + i18n.T("input.steering")))
+}
+
+// Steered shows the instructions sent into the running turn.
+func Steered(text string) {
+	fmt.Println(accentStyle.Render("  ↪ ") + text)
 }
 
 func ConfirmPrompt(msg string) bool {
+	defer pauseInput()()
+	fmt.Printf("  %s %s ", warnStyle.Render("?"), msg)
+	fmt.Printf("%s ", dimStyle.Render(i18n.T("prompt.yes_no")))
+	var input string
+	fmt.Scanln(&input)
+	return i18n.IsYes(input)
+}
+
+// Answers to ExplainPrompt.
+const (
+	Deny = iota
+	Allow
+	Explain
+)
+
+// ExplainPrompt is ConfirmPrompt with an extra "e" answer asking for an
+// explanation first; offerExplain hides it once one was shown.
+func ExplainPrompt(msg string, offerExplain bool) int {
+	defer pauseInput()()
+	choices := i18n.T("prompt.yes_no_explain")
+	if !offerExplain {
+		choices = i18n.T("prompt.yes_no")
+	}
 	fmt.Printf("  %s %s ", warnStyle.Render("?"), msg)
-	fmt.Printf("%s ", dimStyle.Render("[y/N]"))
+	fmt.Printf("%s ", dimStyle.Render(choices))
 	var input string
 	fmt.Scanln(&input)
-	input = strings.TrimSpace(strings.ToLower(input))
-	return input == "y" || input == "yes"
+	if i18n.IsYes(input) {
+		return Allow
+	}
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "e", "explain":
+		if offerExplain {
+			return Explain
+		}
+	}
+	return Deny
+}
+
+// CommandExplanation shows what a command will do, as explained by the
+// model, in a panel above the prompt.
+func CommandExplanation(text string) {
+	panel := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Accent)).
+		Padding(0, 1).
+		Width(contentWidth() - 4).
+		Render(accentStyle.Render(i18n.T("explain.title")) + "\n" + strings.TrimSpace(text))
+	fmt.Println(panel)
+}
+
+// AutoApproved notes a call approved by an /auto window, e.g.
+// "auto-approved Edit (for 8m12s)".
+func AutoApproved(tool, remaining string) {
+	fmt.Println(dimStyle.Render("  " + i18n.T("auto.approved", tool, remaining)))
+}
+
+// SecretPrompt reads a line without echoing it. When stdin is not a
+// terminal, e.g. `echo $KEY | apipod-cli login --api-key -`, the first
+// line of input is read instead.
+func SecretPrompt(msg string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	defer pauseInput()()
+	fmt.Printf("  %s %s ", accentStyle.Render("?"), msg)
+	secret, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
 }
 
-func TokenUsage(input, output int) {
+// ReviewFile introduces one file of the review queue, e.g.
+// "✎ internal/api.go (2/3) · new file".
+func ReviewFile(path, note string, index, total int) {
+	info := fmt.Sprintf("(%d/%d)", index, total)
+	if note != "" {
+		info += " · " + note
+	}
+	fmt.Println()
+	fmt.Printf("  %s %s %s\n", accentStyle.Render("✎"), titleStyle.Render(path), dimStyle.Render(info))
+}
+
+// DiffHunk prints a hunk of path whose lines start with ' ', '-' or '+',
+// the code highlighted for the file's language when it is known.
+func DiffHunk(path, header string, lines []string) {
+	fmt.Println("  " + accentStyle.Render(header))
+	code := make([]string, len(lines))
+	for i, l := range lines {
+		l = strings.TrimRight(l, "\r\n")
+		if l != "" {
+			l = l[1:]
+		}
+		code[i] = l
+	}
+	colored := highlightLines(path, code)
+	for i, l := range lines {
+		l = strings.TrimRight(l, "\r\n")
+		if colored != nil && l != "" {
+			marker := dimStyle.Render(l[:1])
+			switch l[0] {
+			case '+':
+				marker = successStyle.Render("+")
+			case '-':
+				marker = errorStyle.Render("-")
+			}
+			fmt.Println("  " + marker + colored[i])
+			continue
+		}
+		switch {
+		case strings.HasPrefix(l, "+"):
+			fmt.Println("  " + successStyle.Render(l))
+		case strings.HasPrefix(l, "-"):
+			fmt.Println("  " + errorStyle.Render(l))
+		default:
+			fmt.Println("  " + dimStyle.Render(l))
+		}
+	}
+}
+
+// ReviewPrompt asks what to do with a hunk and returns "y" (apply), "n"
+// (skip), "a" (apply the rest of the file), "d" (skip the rest of the
+// file) or "q" (skip everything left). An empty answer skips.
+func ReviewPrompt(msg string) string {
+	defer pauseInput()()
+	for {
+		fmt.Printf("  %s %s %s ", warnStyle.Render("?"), msg, dimStyle.Render(i18n.T("review.choices")))
+		var input string
+		fmt.Scanln(&input)
+		input = strings.TrimSpace(strings.ToLower(input))
+		switch input {
+		case "y", "n", "a", "d", "q":
+			return input
+		case "":
+			return "n"
+		case "?":
+			fmt.Println(dimStyle.Render("    " + i18n.T("review.help")))
+		}
+	}
+}
+
+// CommitMessage shows a drafted commit message.
+func CommitMessage(message string) {
+	panel := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Accent)).
+		Padding(0, 1).
+		Width(contentWidth() - 4).
+		Render(accentStyle.Render(i18n.T("commit.title")) + "\n" + message)
+	fmt.Println(panel)
+}
+
+// CommitPrompt asks whether to commit with the drafted message: "y"
+// commits, "e" edits it first and "n", the default, cancels.
+func CommitPrompt() string {
+	defer pauseInput()()
+	fmt.Printf("  %s %s %s ", warnStyle.Render("?"), i18n.T("commit.confirm"), dimStyle.Render(i18n.T("commit.choices")))
+	var input string
+	fmt.Scanln(&input)
+	if i18n.IsYes(input) {
+		return "y"
+	}
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "e", "edit":
+		return "e"
+	}
+	return "n"
+}
+
+// WorktreePrompt asks what to do with the changes made in a worktree:
+// "m" merges them, "d" discards them and "k", the default, keeps the
+// branch.
+func WorktreePrompt(branch, stat string) string {
+	defer pauseInput()()
+	fmt.Println()
+	fmt.Println(accentStyle.Render("  " + i18n.T("worktree.changes", branch)))
+	for _, line := range strings.Split(stat, "\n") {
+		fmt.Println(dimStyle.Render("    " + strings.TrimSpace(line)))
+	}
+	for {
+		fmt.Printf("  %s %s %s ", warnStyle.Render("?"), i18n.T("worktree.confirm"), dimStyle.Render(i18n.T("worktree.choices")))
+		var input string
+		fmt.Scanln(&input)
+		switch strings.TrimSpace(strings.ToLower(input)) {
+		case "m", "merge":
+			return "m"
+		case "d", "discard":
+			return "d"
+		case "", "k", "keep":
+			return "k"
+		}
+	}
+}
+
+// BatchProgress shows how far a message batch has got, e.g.
+// "batch msgbatch_01… · 12 processing · 30 done · 1 failed".
+func BatchProgress(id string, processing, succeeded, errored int) {
+	info := fmt.Sprintf("⧗ batch %s · %d processing · %d done", id, processing, succeeded)
+	if errored > 0 {
+		info += fmt.Sprintf(" · %d failed", errored)
+	}
+	fmt.Println(dimStyle.Render("  " + info + " · " + time.Now().Format("15:04")))
+}
+
+// WorkflowStep introduces a step of a workflow, e.g. "▶ Step 2/4 · Wire
+// up the CLI".
+func WorkflowStep(n, total int, name string) {
+	fmt.Println()
+	fmt.Println(accentStyle.Render(fmt.Sprintf("  ▶ Step %d/%d ·", n, total)) + " " + titleStyle.Render(name))
+}
+
+// TokenUsage ends a turn with what it cost and what it changed, e.g.
+// "tokens: 48210 (46900 in, 1310 out) · ~$0.1603 · 7 tool calls (12.4s) ·
+// +120/−15 across 3 files".
+func TokenUsage(input, output, toolCalls int, toolTime time.Duration, added, removed, files int) {
 	total := input + output
-	cost := estimateCost(input, output)
-	var info string
+	cost := EstimateCost(input, output)
+	info := i18n.T("usage.tokens", total, input, output)
 	if cost > 0 {
-		info = fmt.Sprintf("↳ tokens: %d (%d in, %d out) · ~$%.4f", total, input, output, cost)
-	} else {
-		info = fmt.Sprintf("↳ tokens: %d (%d in, %d out)", total, input, output)
+		info += i18n.T("usage.cost", cost)
+	}
+	if toolCalls > 0 {
+		info += i18n.N("usage.tool_calls", toolCalls, toolCalls)
+		if toolTime > 0 {
+			info += i18n.T("usage.tool_time", toolDuration(toolTime))
+		}
+	}
+	if files > 0 {
+		info += i18n.N("usage.files", files, added, removed, files)
 	}
 	fmt.Println(dimStyle.Render("  " + info))
 }
 
-func estimateCost(input, output int) float64 {
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// ContextMeter shows how full the context window is, e.g.
+// "72k/200k · 36%", turning into a warning from 80%. Estimated counts are
+// marked with "~".
+func ContextMeter(used, window int, estimated bool) {
+	if window <= 0 {
+		return
+	}
+	pct := used * 100 / window
+	filled := min(pct/10, 10)
+	bar := strings.Repeat("▰", filled) + strings.Repeat("▱", 10-filled)
+	approx := ""
+	if estimated {
+		approx = "~"
+	}
+	info := fmt.Sprintf("%s %s%dk/%dk · %d%%", bar, approx, used/1000, window/1000, pct)
+	if pct >= 80 {
+		fmt.Println(warnStyle.Render("  " + info + i18n.T("context.nearly_full")))
+		return
+	}
+	fmt.Println(dimStyle.Render("  " + info))
+}
+
+// EstimateCost returns the approximate dollar cost of a token count.
+func EstimateCost(input, output int) float64 {
 	inCost := float64(input) / 1_000_000 * 3.0
 	outCost := float64(output) / 1_000_000 * 15.0
 	return inCost + outCost
@@ -346,7 +960,22 @@ func estimateCost(input, output int) float64 {
 
 // StreamingText prints text as it streams in (raw, before final markdown render)
 func StreamingText(text string) {
-	fmt.Print(text)
+	if interactive {
+		fmt.Print(text)
+	}
+}
+
+// ClearStreamed erases the raw text printed by StreamingText so it can be
+// replaced by the rendered markdown.
+func ClearStreamed(raw string) {
+	if !interactive {
+		return
+	}
+	fmt.Print("\r\033[2K")
+	for i := 0; i < strings.Count(raw, "\n"); i++ {
+		fmt.Print("\033[A\033[2K")
+	}
+	fmt.Print("\r")
 }
 
 func StreamingDone() {
@@ -354,9 +983,8 @@ func StreamingDone() {
 }
 
 func LoginInfo(username, plan string) {
-	content := successStyle.Render("✓ Authenticated successfully") + "\n\n" +
-		dimStyle.Render("Username") + "  " + username + "\n" +
-		dimStyle.Render("Plan") + "      " + plan
+	content := successStyle.Render(i18n.T("login.success")) + "\n\n" +
+		labels(username, plan)
 
 	box := responseStyle.Width(50).Render(content)
 	fmt.Println()
@@ -366,23 +994,24 @@ func LoginInfo(username, plan string) {
 
 func LogoutInfo() {
 	fmt.Println()
-	fmt.Println(successStyle.Render("  ✓ Logged out successfully"))
+	fmt.Println(successStyle.Render("  " + i18n.T("logout.success")))
 	fmt.Println()
 }
 
 func NotLoggedIn() {
 	fmt.Println()
-	fmt.Println(warnStyle.Render("  ⚠ Not authenticated"))
-	fmt.Println(dimStyle.Render("  Run ") + titleStyle.Render("apipod-cli login") + dimStyle.Render(" to connect your account."))
+	fmt.Println(warnStyle.Render("  " + i18n.T("login.required")))
+	before, after, _ := strings.Cut(i18n.T("login.required_run"), "%s")
+	fmt.Println(dimStyle.Render("  "+before) + titleStyle.Render("apipod-cli login") + dimStyle.Render(after))
 	fmt.Println()
 }
 
 func DeviceCodeDisplay(userCode, verificationURL string) {
-	content := lipgloss.NewStyle().Bold(true).Render("🔐 Device Authorization") + "\n\n" +
-		dimStyle.Render("Open in browser:") + "\n" +
-		lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("63")).Render(verificationURL) + "\n\n" +
-		dimStyle.Render("Enter this code:") + "\n" +
-		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("42")).Render("▶  "+userCode+"  ◀")
+	content := lipgloss.NewStyle().Bold(true).Render(i18n.T("device.title")) + "\n\n" +
+		dimStyle.Render(i18n.T("device.open")) + "\n" +
+		accentStyle.Bold(true).Underline(true).Render(verificationURL) + "\n\n" +
+		dimStyle.Render(i18n.T("device.code")) + "\n" +
+		successStyle.Render("▶  "+userCode+"  ◀")
 
 	box := headerStyle.Width(60).Render(content)
 	fmt.Println()
@@ -391,20 +1020,22 @@ func DeviceCodeDisplay(userCode, verificationURL string) {
 }
 
 func DeviceCodeWaiting() {
-	fmt.Printf("  %sWaiting for authorization%s", Dim, Reset)
+	fmt.Printf("  %s%s%s", Dim, i18n.T("device.waiting"), Reset)
 }
 
 func DeviceCodePolling() {
 	fmt.Print(".")
 }
 
+// UpdateNotice tells that a newer release is available.
+func UpdateNotice(latest string) {
+	fmt.Printf("  %s %s\n\n", accentStyle.Render("↑"),
+		dimStyle.Render(i18n.T("update.available", latest, version)))
+}
+
 func WhoamiDisplay(username, plan, baseURL, model, configPath string) {
-	content := lipgloss.NewStyle().Bold(true).Render("👤 Account Info") + "\n\n" +
-		dimStyle.Render("Username") + "  " + username + "\n" +
-		dimStyle.Render("Plan") + "      " + plan + "\n" +
-		dimStyle.Render("API URL") + "   " + baseURL + "\n" +
-		dimStyle.Render("Model") + "     " + model + "\n" +
-		dimStyle.Render("Config") + "    " + configPath
+	content := lipgloss.NewStyle().Bold(true).Render(i18n.T("whoami.title")) + "\n\n" +
+		labels(username, plan, baseURL, model, configPath)
 
 	box := responseStyle.Width(60).Render(content)
 	fmt.Println()
@@ -412,20 +1043,468 @@ func WhoamiDisplay(username, plan, baseURL, model, configPath string) {
 	fmt.Println()
 }
 
-func SlashHelp() {
-	commands := []struct{ cmd, desc string }{
-		{"/help", "Show this help"},
-		{"/clear", "Clear conversation history"},
-		{"/model [name]", "Show or change model"},
-		{"/compact", "Compact context (clear history)"},
-		{"/whoami", "Show current user info"},
-		{"/quit", "Exit the session"},
+// labels lays out account details as "Label  value" lines: username,
+// plan, API URL, model and config path, as many as values are given. The
+// values line up after the longest label in the selected language.
+func labels(values ...string) string {
+	names := []string{i18n.T("login.username"), i18n.T("login.plan"), i18n.T("whoami.api_url"), i18n.T("whoami.model"), i18n.T("whoami.config")}[:len(values)]
+	width := 0
+	for _, name := range names {
+		width = max(width, lipgloss.Width(name))
+	}
+	lines := make([]string, len(values))
+	for i, v := range values {
+		lines[i] = dimStyle.Render(names[i]) + strings.Repeat(" ", width-lipgloss.Width(names[i])+2) + v
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Levels of a StatusRow.
+const (
+	StatusOK = iota
+	StatusWarn
+	StatusFail
+)
+
+// StatusRow is one line of /status: a check, what it found and, when it
+// failed, what to do.
+type StatusRow struct {
+	Name  string
+	Value string
+	Level int
+	Hint  string
+}
+
+// StatusPanel shows the /status checks, marking warnings and failures.
+func StatusPanel(rows []StatusRow) {
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r.Name))
+	}
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().Bold(true).Render("🩺 Status") + "\n")
+	for _, r := range rows {
+		mark := successStyle.Render("✓")
+		switch r.Level {
+		case StatusWarn:
+			mark = warnStyle.Render("!")
+		case StatusFail:
+			mark = errorStyle.Render("✗")
+		}
+		fmt.Fprintf(&b, "\n%s %s  %s", mark, dimStyle.Render(fmt.Sprintf("%-*s", width, r.Name)), r.Value)
+		if r.Hint != "" {
+			fmt.Fprintf(&b, "\n  %s  %s", strings.Repeat(" ", width), dimStyle.Render("→ "+r.Hint))
+		}
+	}
+	fmt.Println()
+	fmt.Println(responseStyle.Width(contentWidth() - 2).Render(b.String()))
+	fmt.Println()
+}
+
+type ToolStatRow struct {
+	Name     string
+	Calls    int
+	Errors   int
+	Denied   int
+	Duration time.Duration
+	Output   int
+}
+
+func ToolStats(rows []ToolStatRow) {
+	fmt.Println()
+	if len(rows) == 0 {
+		fmt.Println(dimStyle.Render("  No tool calls yet"))
+		fmt.Println()
+		return
+	}
+	var calls, output int
+	var total time.Duration
+	for _, r := range rows {
+		calls += r.Calls
+		total += r.Duration
+		output += r.Output
+		line := fmt.Sprintf("%-12s %4d calls  %8s  %9s", r.Name, r.Calls, toolDuration(r.Duration), byteSize(r.Output))
+		if r.Errors > 0 {
+			line += fmt.Sprintf("  %d failed", r.Errors)
+		}
+		if r.Denied > 0 {
+			line += fmt.Sprintf("  %d denied", r.Denied)
+		}
+		fmt.Println("  " + line)
 	}
+	fmt.Println(dimStyle.Render(fmt.Sprintf("  %-12s %4d calls  %8s  %9s", "total", calls, toolDuration(total), byteSize(output))))
 	fmt.Println()
+}
+
+type JobRow struct {
+	ID       string
+	Command  string
+	Running  bool
+	ExitCode int
+	Runtime  time.Duration
+}
+
+func Jobs(rows []JobRow) {
+	fmt.Println()
+	if len(rows) == 0 {
+		fmt.Println(dimStyle.Render("  No background jobs"))
+		fmt.Println()
+		return
+	}
+	for _, r := range rows {
+		state := successStyle.Render("running")
+		if !r.Running {
+			if r.ExitCode == 0 {
+				state = dimStyle.Render("exited 0")
+			} else {
+				state = errorStyle.Render(fmt.Sprintf("exited %d", r.ExitCode))
+			}
+		}
+		cmd := strings.SplitN(r.Command, "\n", 2)[0]
+		fmt.Printf("  %s  %s  %s  %s\n", dimStyle.Render(r.ID), state, dimStyle.Render(r.Runtime.Round(time.Second).String()), cmd)
+	}
+	fmt.Println()
+}
+
+// Sessions lists saved session IDs, oldest first, marking the current one.
+// SearchHit is an entry /search found: a prompt, answer, tool call or tool
+// result, in the current session when Session is empty.
+type SearchHit struct {
+	Session string
+	Message int
+	Label   string
+	Lines   []string
+}
+
+// SearchResults shows the hits for query, newest first, with the words of
+// the query highlighted. total counts every match, also those not shown,
+// and sessions is how many saved sessions were searched.
+func SearchResults(query string, hits []SearchHit, total, sessions int) {
+	where := "this session"
+	if sessions > 0 {
+		where = fmt.Sprintf("this session and %d saved %s", sessions, plural(sessions, "session", "sessions"))
+	}
+	if total == 0 {
+		InfoMessage(fmt.Sprintf("No matches for %q in %s", query, where))
+		return
+	}
+	terms := strings.Fields(strings.ToLower(query))
+	fmt.Println()
+	summary := fmt.Sprintf("  %d %s for %q in %s", total, plural(total, "match", "matches"), query, where)
+	if total > len(hits) {
+		summary += fmt.Sprintf(", newest %d shown", len(hits))
+	}
+	fmt.Println(dimStyle.Render(summary))
+	for _, h := range hits {
+		session := "this session"
+		if h.Session != "" {
+			session = "session " + h.Session
+		}
+		fmt.Println()
+		fmt.Printf("  %s %s\n", accentStyle.Render(h.Label), dimStyle.Render(fmt.Sprintf("· %s · message %d", session, h.Message)))
+		for _, l := range h.Lines {
+			fmt.Println(dimStyle.Render("    │ ") + highlightTerms(l, terms))
+		}
+	}
+}
+
+// highlightTerms marks every case-insensitive occurrence of terms in line.
+func highlightTerms(line string, terms []string) string {
+	lower := strings.ToLower(line)
+	if len(lower) != len(line) {
+		return line
+	}
+	marked := make([]bool, len(line))
+	for _, t := range terms {
+		for i := 0; t != ""; {
+			j := strings.Index(lower[i:], t)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(t); k++ {
+				marked[k] = true
+			}
+			i += j + len(t)
+		}
+	}
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		j := i
+		for j < len(line) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(accentStyle.Render(line[i:j]))
+		} else {
+			b.WriteString(line[i:j])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// TabRow is an open session tab in /sessions.
+type TabRow struct {
+	Name     string
+	WorkDir  string
+	Model    string
+	Messages int
+	Jobs     int
+	Current  bool
+}
+
+// Tabs lists the open session tabs, marking the one in front.
+func Tabs(rows []TabRow) {
+	fmt.Println()
+	fmt.Println(dimStyle.Render("  Open tabs"))
+	for i, r := range rows {
+		mark, name := " ", fmt.Sprintf("%d %s", i+1, r.Name)
+		if r.Current {
+			mark, name = accentStyle.Render("●"), accentStyle.Render(name)
+		}
+		detail := fmt.Sprintf("%s · %s · %d %s", shortenPath(r.WorkDir), r.Model, r.Messages, plural(r.Messages, "message", "messages"))
+		if r.Jobs > 0 {
+			detail += fmt.Sprintf(" · %d %s", r.Jobs, plural(r.Jobs, "running job", "running jobs"))
+		}
+		fmt.Printf("  %s %s  %s\n", mark, name, dimStyle.Render(detail))
+	}
+}
+
+func Sessions(ids []string, current, location string) {
+	fmt.Println()
+	if len(ids) == 0 {
+		fmt.Println(dimStyle.Render("  No saved sessions in " + location))
+		fmt.Println()
+		return
+	}
+	fmt.Println(dimStyle.Render("  Saved sessions in " + location))
+	for _, id := range ids {
+		if id == current {
+			fmt.Printf("  %s %s\n", accentStyle.Render(id), dimStyle.Render("(current)"))
+		} else {
+			fmt.Println("  " + id)
+		}
+	}
+	fmt.Println()
+}
+
+// HandoffSummary shows the task taken over from a handoff document.
+func HandoffSummary(goal string, nextSteps, openQuestions []string) {
+	fmt.Println()
+	fmt.Println(dimStyle.Render("  Taking over: ") + goal)
+	for i, step := range nextSteps {
+		fmt.Printf("  %s %s\n", dimStyle.Render(fmt.Sprintf("%d.", i+1)), step)
+	}
+	if len(openQuestions) > 0 {
+		fmt.Println(dimStyle.Render(fmt.Sprintf("  %d open question(s) for you", len(openQuestions))))
+	}
+	fmt.Println()
+}
+
+// ScaffoldPlan shows the setup commands proposed for a new project, each
+// with why it is needed.
+func ScaffoldPlan(commands [][2]string) {
+	fmt.Println()
+	fmt.Println(titleStyle.Render("  Setup plan"))
 	for _, c := range commands {
+		fmt.Printf("  %s %s\n", accentStyle.Render("$"), c[0])
+		if c[1] != "" {
+			fmt.Println(dimStyle.Render("    " + c[1]))
+		}
+	}
+	fmt.Println()
+}
+
+// FileSize is a file of a generated project.
+type FileSize struct {
+	Path string
+	Size int64
+}
+
+// ScaffoldSummary lists the files of a new project.
+func ScaffoldSummary(files []FileSize) {
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	fmt.Println()
+	fmt.Println(titleStyle.Render(fmt.Sprintf("  Created %d %s (%.1f KB)", len(files), plural(len(files), "file", "files"), float64(total)/1024)))
+	for _, f := range files {
+		fmt.Printf("  %s %s\n", f.Path, dimStyle.Render(fmt.Sprintf("%.1f KB", float64(f.Size)/1024)))
+	}
+	fmt.Println()
+}
+
+// Opinion is one model's assessment of a plan or diff.
+type Opinion struct {
+	Model       string
+	Verdict     string
+	Summary     string
+	Risks       []string
+	Suggestions []string
+}
+
+// SecondOpinion shows two assessments side by side, or one above the
+// other on narrow terminals, and whether their verdicts agree.
+func SecondOpinion(subject string, a, b Opinion) {
+	w := contentWidth()
+	width := w - 4
+	wide := w >= 100
+	if wide {
+		width = (w - 5) / 2
+	}
+	left, right := opinionPanel(a, width), opinionPanel(b, width)
+	fmt.Println()
+	fmt.Println(titleStyle.Render("  Second opinion on " + subject))
+	if wide {
+		fmt.Println(lipgloss.JoinHorizontal(lipgloss.Top, left, " ", right))
+	} else {
+		fmt.Println(left)
+		fmt.Println(right)
+	}
+	if a.Verdict == b.Verdict {
+		fmt.Println(successStyle.Render("  ✓ Both models say " + verdictLabel(a.Verdict)))
+	} else {
+		fmt.Println(warnStyle.Render(fmt.Sprintf("  ⚠ The models disagree: %s vs %s", verdictLabel(a.Verdict), verdictLabel(b.Verdict))))
+	}
+	fmt.Println()
+}
+
+func verdictLabel(v string) string {
+	return strings.ReplaceAll(v, "_", " ")
+}
+
+func opinionPanel(o Opinion, width int) string {
+	style, color := successStyle, theme.Success
+	switch o.Verdict {
+	case "approve_with_changes":
+		style, color = warnStyle, theme.Warning
+	case "reject":
+		style, color = errorStyle, theme.Error
+	}
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(o.Model) + " " + style.Render(verdictLabel(o.Verdict)))
+	b.WriteString("\n" + o.Summary)
+	for _, section := range []struct {
+		title string
+		items []string
+	}{{"Risks", o.Risks}, {"Suggestions", o.Suggestions}} {
+		if len(section.items) == 0 {
+			continue
+		}
+		b.WriteString("\n\n" + dimStyle.Render(section.title))
+		for _, item := range section.items {
+			b.WriteString("\n• " + item)
+		}
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(color)).
+		Padding(0, 1).
+		Width(width).
+		Render(b.String())
+}
+
+// CurlCommands prints requests as copyable curl commands, numbered from
+// the oldest shown.
+func CurlCommands(cmds []string) {
+	fmt.Println()
+	if len(cmds) == 0 {
+		fmt.Println(dimStyle.Render("  No HTTP requests yet"))
+		fmt.Println()
+		return
+	}
+	for i, c := range cmds {
+		fmt.Println(dimStyle.Render(fmt.Sprintf("  # %d", i+1)))
+		fmt.Println(c)
+	}
+	fmt.Println()
+}
+
+// CommandRow is a custom slash command listed by SlashHelp.
+type CommandRow struct {
+	Name         string
+	ArgumentHint string
+	Description  string
+	Source       string
+}
+
+func SlashHelp(custom ...CommandRow) {
+	commands := []string{
+		"/help",
+		"/clear",
+		"/model [name]",
+		"/compact",
+		"/jobs",
+		"/stats",
+		"/scope [pkg]",
+		"/export [format]",
+		"/share",
+		"/new [name] [dir]",
+		"/switch [name|n]",
+		"/close [name]",
+		"/sessions",
+		"/search <query>",
+		"/resume [id]",
+		"/init",
+		"/handoff [file]",
+		"/import <file>",
+		"/open [file[:line]]",
+		"/download <path>",
+		"/pair [off]",
+		"/upload [path]",
+		"/theme [name]",
+		"/curl [n]",
+		"/status",
+		"/thinking",
+		"/add-dir <path>",
+		"/attach [path...]",
+		"/commit [note]",
+		"/output-style [name]",
+		"/set [param value]",
+		"/expand [n]",
+		"/retry",
+		"/review",
+		"/second-opinion [focus]",
+		"/auto [10m|N|off]",
+		"/whoami",
+		"/quit",
+	}
+	fmt.Println()
+	for _, cmd := range commands {
+		// Descriptions are looked up by the command's name, e.g. "help.set".
+		name := strings.TrimPrefix(strings.Fields(cmd)[0], "/")
 		fmt.Printf("  %s  %s\n",
-			lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Width(16).Render(c.cmd),
-			dimStyle.Render(c.desc))
+			accentStyle.Width(16).Render(cmd),
+			dimStyle.Render(i18n.T("help."+name)))
+	}
+	if len(custom) > 0 {
+		fmt.Println()
+		fmt.Println(dimStyle.Render("  " + i18n.T("help.custom")))
+		for _, c := range custom {
+			cmd := "/" + c.Name
+			if c.ArgumentHint != "" {
+				cmd += " " + c.ArgumentHint
+			}
+			fmt.Printf("  %s  %s\n",
+				accentStyle.Render(cmd),
+				dimStyle.Render(c.Description+" ("+c.Source+")"))
+		}
+	}
+	fmt.Println()
+}
+
+// TemplateList shows the prompt templates of `apipod-cli run`, with the
+// arguments each takes in Name.
+func TemplateList(rows []CommandRow) {
+	fmt.Println()
+	if len(rows) == 0 {
+		fmt.Println(dimStyle.Render("  No templates yet; add markdown or YAML files to ~/.apipod/templates or .apipod/templates"))
+		fmt.Println()
+		return
+	}
+	for _, r := range rows {
+		fmt.Printf("  %s  %s\n", accentStyle.Render(r.Name), dimStyle.Render(r.Description+" ("+r.Source+")"))
 	}
 	fmt.Println()
 }
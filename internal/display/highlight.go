@@ -0,0 +1,115 @@
+package display
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// maxHighlightBytes bounds the code highlighted at once; larger output is
+// shown plain.
+const maxHighlightBytes = 256 * 1024
+
+// highlightLines colors lines of code by the language of path, returning
+// one colored line per input line, or nil when the language is unknown or
+// colors are off. The lines are tokenized together, so strings and
+// comments spanning lines are colored correctly.
+func highlightLines(path string, lines []string) []string {
+	if !colorEnabled || len(lines) == 0 || theme.Syntax == "" {
+		return nil
+	}
+	code := strings.Join(lines, "\n")
+	if len(code) > maxHighlightBytes {
+		return nil
+	}
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		return nil
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code+"\n")
+	if err != nil {
+		return nil
+	}
+	formatter := formatters.TTY256
+	if lipgloss.ColorProfile() == termenv.TrueColor {
+		formatter = formatters.TTY16m
+	}
+	style := styles.Get(theme.Syntax)
+	out := make([]string, 0, len(lines))
+	for _, line := range chroma.SplitTokensIntoLines(tokens.Tokens()) {
+		for i := range line {
+			line[i].Value = strings.TrimRight(line[i].Value, "\n")
+		}
+		var b strings.Builder
+		if err := formatter.Format(&b, style, chroma.Literator(line...)); err != nil {
+			return nil
+		}
+		out = append(out, b.String())
+	}
+	if len(out) < len(lines) {
+		return nil
+	}
+	return out[:len(lines)]
+}
+
+// readGutter matches the line-number gutter of Read output: "   12│",
+// and "   12+│" or "     -│" for changed lines of a compact re-read.
+var readGutter = regexp.MustCompile(`^([ \d]{5}[ +-]?│)(.*)$`)
+
+// CodeResult shows the result of reading path like ExpandableResult, with
+// the code highlighted for its language and the gutter kept.
+func CodeResult(id int, path, content string) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	totalLines := len(lines)
+	if len(lines) > CollapsedLines {
+		lines = lines[:CollapsedLines]
+	}
+	colored := codeLines(path, lines)
+	if colored == nil {
+		ExpandableResult(id, content, false)
+		return
+	}
+	fmt.Println(toolStyle.Render(strings.Join(colored, "\n") + moreNote(id, totalLines-len(lines))))
+}
+
+// codeLines renders lines of Read output for path: the code after each
+// gutter highlighted, other lines dim. It returns nil when the code cannot
+// be highlighted.
+func codeLines(path string, lines []string) []string {
+	var gutters, code []string
+	var index []int
+	for i, l := range lines {
+		if m := readGutter.FindStringSubmatch(l); m != nil {
+			gutters = append(gutters, m[1])
+			code = append(code, m[2])
+			index = append(index, i)
+		}
+	}
+	colored := highlightLines(path, code)
+	if colored == nil {
+		return nil
+	}
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = dimStyle.Render(l)
+	}
+	for j, i := range index {
+		gutter := dimStyle.Render(gutters[j])
+		switch {
+		case strings.HasSuffix(gutters[j], "+│"):
+			gutter = successStyle.Render(gutters[j])
+		case strings.HasSuffix(gutters[j], "-│"):
+			gutter = errorStyle.Render(gutters[j])
+		}
+		out[i] = gutter + colored[j]
+	}
+	return out
+}
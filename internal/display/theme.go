@@ -0,0 +1,197 @@
+package display
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+// Theme names the colors used across the UI. Values are lipgloss colors:
+// ANSI 256 numbers ("63") or hex ("#5A56E0").
+type Theme struct {
+	Name    string
+	Accent  string
+	Border  string
+	Panel   string
+	Muted   string
+	Success string
+	Error   string
+	Warning string
+	// Markdown is the glamour style for responses; "auto" picks dark or
+	// light from the terminal background.
+	Markdown string
+	// Syntax is the chroma style for code in tool output, "" for none.
+	Syntax string
+}
+
+var themes = map[string]Theme{
+	"dark": {
+		Name: "dark", Accent: "63", Border: "241", Panel: "240", Muted: "241",
+		Success: "42", Error: "196", Warning: "214", Markdown: "auto", Syntax: "monokai",
+	},
+	"light": {
+		Name: "light", Accent: "#5A56E0", Border: "#A8A8A8", Panel: "#BCBCBC", Muted: "#6C6C6C",
+		Success: "#1A7F37", Error: "#CF222E", Warning: "#9A6700", Markdown: "light", Syntax: "github",
+	},
+	"high-contrast": {
+		Name: "high-contrast", Accent: "14", Border: "15", Panel: "15", Muted: "252",
+		Success: "10", Error: "9", Warning: "11", Markdown: "dark", Syntax: "hr_high_contrast",
+	},
+}
+
+var (
+	theme = themes["dark"]
+	// interactive is false when stdout is not a terminal; cursor movement
+	// and animations are skipped so piped output stays readable.
+	interactive  = term.IsTerminal(int(os.Stdout.Fd()))
+	colorEnabled = true
+)
+
+func init() {
+	if os.Getenv("NO_COLOR") != "" || !interactive {
+		DisableColor()
+	}
+	applyTheme()
+}
+
+// ThemeNames lists the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentTheme returns the active theme name.
+func CurrentTheme() string {
+	return theme.Name
+}
+
+// SetTheme switches to a built-in theme ("" keeps the current one) and
+// applies color overrides keyed by accent, border, panel, muted, success,
+// error or warning, and the code style keyed by syntax (a chroma style
+// name, or "none").
+func SetTheme(name string, overrides map[string]string) error {
+	t := theme
+	if name != "" {
+		base, ok := themes[name]
+		if !ok {
+			return fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+		}
+		t = base
+	}
+	for key, color := range overrides {
+		switch strings.ToLower(key) {
+		case "accent":
+			t.Accent = color
+		case "border":
+			t.Border = color
+		case "panel":
+			t.Panel = color
+		case "muted":
+			t.Muted = color
+		case "success":
+			t.Success = color
+		case "error":
+			t.Error = color
+		case "warning":
+			t.Warning = color
+		case "syntax":
+			if color == "none" {
+				t.Syntax = ""
+			} else if _, ok := styles.Registry[color]; !ok {
+				return fmt.Errorf("unknown syntax style %q", color)
+			} else {
+				t.Syntax = color
+			}
+		default:
+			return fmt.Errorf("unknown theme color %q", key)
+		}
+	}
+	theme = t
+	applyTheme()
+	return nil
+}
+
+// DisableColor switches to plain text output, as for NO_COLOR or
+// --no-color.
+func DisableColor() {
+	colorEnabled = false
+	lipgloss.SetColorProfile(termenv.Ascii)
+	Reset, Bold, Dim, Italic, Underline = "", "", "", "", ""
+	Cyan, Green, Yellow, Red, Blue, Magenta, Gray = "", "", "", "", "", "", ""
+	BgGray, BgDarkGray, White, BrightCyan, BrightWhite = "", "", "", "", ""
+}
+
+func applyTheme() {
+	headerStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Accent)).
+		Padding(0, 1).
+		Align(lipgloss.Center)
+
+	responseStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Panel)).
+		Padding(0, 1)
+
+	toolStyle = lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color(theme.Border)).
+		BorderLeft(true).
+		BorderRight(false).
+		BorderTop(false).
+		BorderBottom(false).
+		PaddingLeft(1)
+
+	titleStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Accent)).
+		Bold(true)
+
+	dimStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Muted))
+
+	successStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Success)).
+		Bold(true)
+
+	errorStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Error)).
+		Bold(true)
+
+	warnStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Warning)).
+		Bold(true)
+
+	promptStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Accent)).
+		Bold(true)
+
+	accentStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Accent))
+}
+
+// markdownStyle returns the glamour style name for the active theme.
+func markdownStyle() string {
+	if !colorEnabled {
+		return "notty"
+	}
+	return theme.Markdown
+}
+
+// clearLine returns the sequence that erases the current terminal line, or
+// nothing when output is not a terminal.
+func clearLine() string {
+	if !interactive {
+		return ""
+	}
+	return "\r\033[2K"
+}
@@ -0,0 +1,55 @@
+// Package doctext extracts the plain text of documents the file tools
+// cannot show as they are: PDFs, Word documents and PowerPoint
+// presentations. It needs no external programs; layout, images and
+// formatting are dropped.
+package doctext
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Kind names a document format.
+type Kind string
+
+const (
+	KindPDF  Kind = "PDF"
+	KindDocx Kind = "Word document"
+	KindPptx Kind = "PowerPoint presentation"
+)
+
+// Detect returns the kind of document at path from its extension and
+// first bytes, or "" for anything else.
+func Detect(path string, head []byte) Kind {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return KindPDF
+	case !bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return ""
+	case ext == ".docx" || ext == ".docm":
+		return KindDocx
+	case ext == ".pptx" || ext == ".pptm":
+		return KindPptx
+	}
+	return ""
+}
+
+// Pages returns the text of a document of the given kind split into pages:
+// the pages of a PDF, the slides of a presentation, or a single page for a
+// Word document, which has no fixed pages.
+func Pages(kind Kind, data []byte) ([]string, error) {
+	switch kind {
+	case KindPDF:
+		return PDF(data)
+	case KindPptx:
+		return Pptx(data)
+	default:
+		text, err := Docx(data)
+		if err != nil {
+			return nil, err
+		}
+		return []string{text}, nil
+	}
+}
@@ -0,0 +1,136 @@
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds one XML part of an Office document.
+const maxPartSize = 64 << 20
+
+// Docx returns the text of a Word document, one line per paragraph and
+// table rows with their cells separated by " | ".
+func Docx(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("open docx: %w", err)
+	}
+	part, err := readPart(zr, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	return officeText(part)
+}
+
+// Pptx returns the text of each slide of a PowerPoint presentation.
+func Pptx(data []byte) ([]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open pptx: %w", err)
+	}
+	var slides []string
+	for _, f := range zr.File {
+		if path.Dir(f.Name) == "ppt/slides" && strings.HasSuffix(f.Name, ".xml") {
+			slides = append(slides, f.Name)
+		}
+	}
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("no slides found")
+	}
+	// slide10.xml comes after slide9.xml.
+	sort.Slice(slides, func(i, j int) bool { return slideNumber(slides[i]) < slideNumber(slides[j]) })
+	out := make([]string, len(slides))
+	for i, name := range slides {
+		part, err := readPart(zr, name)
+		if err != nil {
+			return nil, err
+		}
+		if out[i], err = officeText(part); err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+	}
+	return out, nil
+}
+
+func slideNumber(name string) int {
+	base := strings.TrimSuffix(path.Base(name), ".xml")
+	n, _ := strconv.Atoi(strings.TrimLeft(base, "slide"))
+	return n
+}
+
+func readPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxPartSize))
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+// officeText collects the text runs of WordprocessingML or DrawingML: t
+// elements, with p ending a line and tab and br as whitespace. Inside a
+// table the cells of a row are joined on one line.
+func officeText(part []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(part))
+	var b bytes.Buffer
+	inText, tables := false, 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			case "tbl":
+				tables++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if tables > 0 {
+					b.WriteByte(' ')
+				} else {
+					b.WriteByte('\n')
+				}
+			case "tc":
+				b.WriteString("| ")
+			case "tr":
+				b.Truncate(len(bytes.TrimRight(b.Bytes(), "| ")))
+				b.WriteByte('\n')
+			case "tbl":
+				tables--
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return tidy(b.String()), nil
+}
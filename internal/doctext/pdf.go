@@ -0,0 +1,765 @@
+package doctext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrEncrypted is returned for password-protected PDFs.
+var ErrEncrypted = errors.New("encrypted PDFs are not supported")
+
+// maxStreamSize bounds a decompressed stream.
+const maxStreamSize = 64 << 20
+
+type pdfObject struct {
+	value  interface{}
+	stream []byte
+}
+
+type pdfFile struct {
+	objects map[int]*pdfObject
+}
+
+var objHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// PDF returns the text of each page of a PDF. Only FlateDecode streams are
+// read; text in fonts without a ToUnicode map is decoded as Latin-1.
+func PDF(data []byte) ([]string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return nil, fmt.Errorf("not a PDF")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, ErrEncrypted
+	}
+	f := &pdfFile{objects: make(map[int]*pdfObject)}
+	f.scan(data)
+	f.expandObjectStreams()
+
+	pages := f.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found")
+	}
+	out := make([]string, len(pages))
+	for i, p := range pages {
+		out[i] = f.pageText(p)
+	}
+	return out, nil
+}
+
+// scan reads every "N G obj ... endobj" in file order, so objects of
+// incremental updates replace the originals.
+func (f *pdfFile) scan(data []byte) {
+	pos := 0
+	for pos < len(data) {
+		loc := objHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			return
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+		l := &lexer{b: data, i: start}
+		value, err := l.value()
+		if err != nil {
+			pos = start
+			continue
+		}
+		obj := &pdfObject{value: value}
+		end := l.i
+		l.skipSpace()
+		if bytes.HasPrefix(data[l.i:], []byte("stream")) {
+			s := l.i + len("stream")
+			if s < len(data) && data[s] == '\r' {
+				s++
+			}
+			if s < len(data) && data[s] == '\n' {
+				s++
+			}
+			e := -1
+			if n, ok := dictGet(value, "Length").(float64); ok && s+int(n) <= len(data) {
+				e = s + int(n)
+			}
+			if e < 0 {
+				if i := bytes.Index(data[s:], []byte("endstream")); i >= 0 {
+					e = s + i
+				} else {
+					e = len(data)
+				}
+			}
+			obj.stream = data[s:e]
+			end = e
+		}
+		f.objects[num] = obj
+		if i := bytes.Index(data[end:], []byte("endobj")); i >= 0 {
+			pos = end + i + len("endobj")
+		} else {
+			pos = end
+		}
+	}
+}
+
+// expandObjectStreams adds the objects compressed into object streams.
+func (f *pdfFile) expandObjectStreams() {
+	nums := make([]int, 0, len(f.objects))
+	for n := range f.objects {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		obj := f.objects[n]
+		if dictGet(obj.value, "Type") != name("ObjStm") {
+			continue
+		}
+		data, err := f.decode(obj)
+		if err != nil {
+			continue
+		}
+		count, _ := dictGet(obj.value, "N").(float64)
+		first, _ := dictGet(obj.value, "First").(float64)
+		if int(first) > len(data) {
+			continue
+		}
+		header := &lexer{b: data[:int(first)]}
+		for i := 0; i < int(count); i++ {
+			numV, err1 := header.value()
+			offV, err2 := header.value()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			num, _ := numV.(float64)
+			off, _ := offV.(float64)
+			if _, exists := f.objects[int(num)]; exists {
+				continue
+			}
+			l := &lexer{b: data, i: int(first) + int(off)}
+			if v, err := l.value(); err == nil {
+				f.objects[int(num)] = &pdfObject{value: v}
+			}
+		}
+	}
+}
+
+func (f *pdfFile) resolve(v interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+		obj := f.objects[int(r)]
+		if obj == nil {
+			return nil
+		}
+		v = obj.value
+	}
+	return nil
+}
+
+func (f *pdfFile) streamOf(v interface{}) *pdfObject {
+	if r, ok := v.(ref); ok {
+		return f.objects[int(r)]
+	}
+	return nil
+}
+
+// decode returns the stream data with its filters applied.
+func (f *pdfFile) decode(obj *pdfObject) ([]byte, error) {
+	if obj == nil {
+		return nil, fmt.Errorf("missing stream")
+	}
+	var filters []interface{}
+	switch v := f.resolve(dictGet(obj.value, "Filter")).(type) {
+	case name:
+		filters = []interface{}{v}
+	case []interface{}:
+		filters = v
+	}
+	data := obj.stream
+	for _, flt := range filters {
+		switch flt {
+		case name("FlateDecode"), name("Fl"):
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			// Truncated streams are common; keep what was inflated.
+			out, _ := io.ReadAll(io.LimitReader(r, maxStreamSize))
+			data = out
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", flt)
+		}
+	}
+	return data, nil
+}
+
+type page struct {
+	dict      interface{}
+	resources interface{}
+}
+
+// pages walks the page tree from the catalog, falling back to every page
+// object in number order.
+func (f *pdfFile) pages() []page {
+	var out []page
+	seen := make(map[interface{}]bool)
+	var walk func(node interface{}, resources interface{}, depth int)
+	walk = func(node interface{}, resources interface{}, depth int) {
+		if depth > 64 || seen[node] {
+			return
+		}
+		if r, ok := node.(ref); ok {
+			seen[r] = true
+		}
+		d := f.resolve(node)
+		if res := dictGet(d, "Resources"); res != nil {
+			resources = res
+		}
+		switch dictGet(d, "Type") {
+		case name("Pages"):
+			kids, _ := f.resolve(dictGet(d, "Kids")).([]interface{})
+			for _, k := range kids {
+				walk(k, resources, depth+1)
+			}
+		case name("Page"):
+			out = append(out, page{dict: d, resources: resources})
+		}
+	}
+	for _, obj := range f.objects {
+		if dictGet(obj.value, "Type") == name("Catalog") {
+			walk(dictGet(obj.value, "Pages"), nil, 0)
+			break
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	nums := make([]int, 0, len(f.objects))
+	for n, obj := range f.objects {
+		if dictGet(obj.value, "Type") == name("Page") {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		d := f.objects[n].value
+		out = append(out, page{dict: d, resources: dictGet(d, "Resources")})
+	}
+	return out
+}
+
+// font maps the codes of a font to text.
+type font struct {
+	width int
+	cmap  map[int]string
+}
+
+func (f *pdfFile) fonts(resources interface{}) map[string]*font {
+	fonts := make(map[string]*font)
+	dict, _ := f.resolve(dictGet(f.resolve(resources), "Font")).(map[string]interface{})
+	for key, v := range dict {
+		fnt := &font{width: 1}
+		fd := f.resolve(v)
+		if data, err := f.decode(f.streamOf(dictGet(fd, "ToUnicode"))); err == nil {
+			fnt.width, fnt.cmap = parseCMap(data)
+		} else if dictGet(fd, "Subtype") == name("Type0") {
+			fnt.width = 2
+		}
+		fonts[key] = fnt
+	}
+	return fonts
+}
+
+func (fnt *font) decode(s []byte) string {
+	if fnt == nil {
+		fnt = &font{width: 1}
+	}
+	var b strings.Builder
+	for i := 0; i+fnt.width <= len(s); i += fnt.width {
+		code := 0
+		for _, c := range s[i : i+fnt.width] {
+			code = code<<8 | int(c)
+		}
+		if t, ok := fnt.cmap[code]; ok {
+			b.WriteString(t)
+		} else if fnt.width == 1 {
+			b.WriteRune(rune(code))
+		}
+	}
+	return b.String()
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap and
+// its code width in bytes.
+func parseCMap(data []byte) (int, map[int]string) {
+	m := make(map[int]string)
+	width := 0
+	l := &lexer{b: data}
+	var stack []interface{}
+	for {
+		v, err := l.value()
+		if err != nil {
+			break
+		}
+		k, isKw := v.(keyword)
+		if !isKw {
+			stack = append(stack, v)
+			continue
+		}
+		switch k {
+		case "endcodespacerange":
+			if len(stack) >= 1 {
+				if s, ok := stack[0].(pdfString); ok && width == 0 {
+					width = len(s)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(stack); i += 2 {
+				src, ok1 := stack[i].(pdfString)
+				dst, ok2 := stack[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[codeOf(src)] = utf16String(dst)
+					if width == 0 {
+						width = len(src)
+					}
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(stack); i += 3 {
+				lo, ok1 := stack[i].(pdfString)
+				hi, ok2 := stack[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				if width == 0 {
+					width = len(lo)
+				}
+				start, end := codeOf(lo), codeOf(hi)
+				if end-start > 0xFFFF {
+					continue
+				}
+				switch dst := stack[i+2].(type) {
+				case pdfString:
+					units := utf16.Decode(utf16Units(dst))
+					for c := start; c <= end && len(units) > 0; c++ {
+						r := append([]rune(nil), units...)
+						r[len(r)-1] += rune(c - start)
+						m[c] = string(r)
+					}
+				case []interface{}:
+					for j, d := range dst {
+						if s, ok := d.(pdfString); ok && start+j <= end {
+							m[start+j] = utf16String(s)
+						}
+					}
+				}
+			}
+		}
+		stack = stack[:0]
+	}
+	if width == 0 {
+		width = 1
+	}
+	return width, m
+}
+
+func codeOf(s []byte) int {
+	c := 0
+	for _, b := range s {
+		c = c<<8 | int(b)
+	}
+	return c
+}
+
+func utf16Units(s []byte) []uint16 {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return units
+}
+
+func utf16String(s []byte) string {
+	return string(utf16.Decode(utf16Units(s)))
+}
+
+// pageText runs the text operators of a page's content streams.
+func (f *pdfFile) pageText(p page) string {
+	var content []byte
+	switch c := dictGet(p.dict, "Contents").(type) {
+	case ref:
+		content, _ = f.decode(f.streamOf(c))
+	case []interface{}:
+		for _, part := range c {
+			if data, err := f.decode(f.streamOf(part)); err == nil {
+				content = append(append(content, data...), '\n')
+			}
+		}
+	}
+	fonts := f.fonts(p.resources)
+
+	var b strings.Builder
+	var cur *font
+	var operands []interface{}
+	lastY, haveY := 0.0, false
+	newline := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	l := &lexer{b: content}
+	for {
+		v, err := l.value()
+		if err != nil {
+			break
+		}
+		op, isOp := v.(keyword)
+		if !isOp {
+			operands = append(operands, v)
+			continue
+		}
+		switch op {
+		case "Tf":
+			if len(operands) >= 1 {
+				if n, ok := operands[0].(name); ok {
+					cur = fonts[string(n)]
+				}
+			}
+		case "Tj":
+			if s, ok := last(operands).(pdfString); ok {
+				b.WriteString(cur.decode(s))
+			}
+		case "'", "\"":
+			newline()
+			if s, ok := last(operands).(pdfString); ok {
+				b.WriteString(cur.decode(s))
+			}
+		case "TJ":
+			arr, _ := last(operands).([]interface{})
+			for _, item := range arr {
+				switch it := item.(type) {
+				case pdfString:
+					b.WriteString(cur.decode(it))
+				case float64:
+					if it < -200 {
+						b.WriteByte(' ')
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[1].(float64); ty != 0 {
+					newline()
+				} else if tx, _ := operands[0].(float64); tx > 0 {
+					b.WriteByte(' ')
+				}
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[5].(float64)
+				if haveY && y != lastY {
+					newline()
+				}
+				lastY, haveY = y, true
+			}
+		case "ET":
+			b.WriteByte(' ')
+		case "ID":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+	return tidy(b.String())
+}
+
+func last(vs []interface{}) interface{} {
+	if len(vs) == 0 {
+		return nil
+	}
+	return vs[len(vs)-1]
+}
+
+var blankRuns = regexp.MustCompile(`\n{3,}`)
+
+// tidy trims trailing spaces and collapses runs of blank lines.
+func tidy(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.Join(strings.Fields(line), " "), " ")
+	}
+	return strings.TrimSpace(blankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func dictGet(v interface{}, key string) interface{} {
+	d, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return d[key]
+}
+
+type (
+	name      string
+	keyword   string
+	ref       int
+	pdfString []byte
+)
+
+// lexer reads PDF values: numbers, strings, names, arrays, dictionaries,
+// references and bare keywords (operators).
+type lexer struct {
+	b []byte
+	i int
+}
+
+var errEOF = errors.New("end of data")
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.i < len(l.b) {
+		c := l.b[l.i]
+		if c == '%' {
+			for l.i < len(l.b) && l.b[l.i] != '\n' && l.b[l.i] != '\r' {
+				l.i++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		l.i++
+	}
+}
+
+// value reads one value, folding "N G R" into a reference.
+func (l *lexer) value() (interface{}, error) {
+	v, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return v, nil
+	}
+	save := l.i
+	if g, err := l.token(); err == nil {
+		if _, ok := g.(float64); ok {
+			if r, err := l.token(); err == nil && r == keyword("R") {
+				return ref(int(n)), nil
+			}
+		}
+	}
+	l.i = save
+	return n, nil
+}
+
+func (l *lexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.i >= len(l.b) {
+		return nil, errEOF
+	}
+	c := l.b[l.i]
+	switch {
+	case c == '/':
+		l.i++
+		start := l.i
+		for l.i < len(l.b) && !isSpace(l.b[l.i]) && !isDelim(l.b[l.i]) {
+			l.i++
+		}
+		return name(unescapeName(l.b[start:l.i])), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.i+1 < len(l.b) && l.b[l.i+1] == '<':
+		l.i += 2
+		d := make(map[string]interface{})
+		for {
+			l.skipSpace()
+			if l.i+1 < len(l.b) && l.b[l.i] == '>' && l.b[l.i+1] == '>' {
+				l.i += 2
+				return d, nil
+			}
+			k, err := l.token()
+			if err != nil {
+				return d, nil
+			}
+			v, err := l.value()
+			if err != nil {
+				return d, nil
+			}
+			if kn, ok := k.(name); ok {
+				d[string(kn)] = v
+			}
+		}
+	case c == '<':
+		l.i++
+		start := l.i
+		for l.i < len(l.b) && l.b[l.i] != '>' {
+			l.i++
+		}
+		hex := l.b[start:l.i]
+		l.i++
+		return decodeHex(hex), nil
+	case c == '[':
+		l.i++
+		var arr []interface{}
+		for {
+			l.skipSpace()
+			if l.i >= len(l.b) {
+				return arr, nil
+			}
+			if l.b[l.i] == ']' {
+				l.i++
+				return arr, nil
+			}
+			v, err := l.value()
+			if err != nil {
+				return arr, nil
+			}
+			arr = append(arr, v)
+		}
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		l.i++
+		return keyword(string(c)), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		start := l.i
+		l.i++
+		for l.i < len(l.b) && (l.b[l.i] == '.' || (l.b[l.i] >= '0' && l.b[l.i] <= '9')) {
+			l.i++
+		}
+		n, err := strconv.ParseFloat(string(l.b[start:l.i]), 64)
+		if err != nil {
+			return keyword(string(l.b[start:l.i])), nil
+		}
+		return n, nil
+	default:
+		start := l.i
+		for l.i < len(l.b) && !isSpace(l.b[l.i]) && !isDelim(l.b[l.i]) {
+			l.i++
+		}
+		if l.i == start {
+			l.i++
+		}
+		return keyword(string(l.b[start:l.i])), nil
+	}
+}
+
+func (l *lexer) literalString() pdfString {
+	l.i++ // (
+	var out []byte
+	depth := 1
+	for l.i < len(l.b) {
+		c := l.b[l.i]
+		l.i++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.i >= len(l.b) {
+				return out
+			}
+			e := l.b[l.i]
+			l.i++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.i < len(l.b) && l.b[l.i] == '\n' {
+					l.i++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for k := 0; k < 2 && l.i < len(l.b) && l.b[l.i] >= '0' && l.b[l.i] <= '7'; k++ {
+						v = v*8 + int(l.b[l.i]-'0')
+						l.i++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// skipInlineImage skips the data of an inline image up to EI.
+func (l *lexer) skipInlineImage() {
+	for l.i+2 < len(l.b) {
+		if isSpace(l.b[l.i]) && l.b[l.i+1] == 'E' && l.b[l.i+2] == 'I' && (l.i+3 >= len(l.b) || isSpace(l.b[l.i+3])) {
+			l.i += 3
+			return
+		}
+		l.i++
+	}
+	l.i = len(l.b)
+}
+
+func decodeHex(h []byte) pdfString {
+	var digits []byte
+	for _, c := range h {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+func unescapeName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,105 @@
+// Package editor opens files in the user's editor at a given line.
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// FromEnv returns $VISUAL, then $EDITOR, then the platform default.
+func FromEnv() string {
+	if e := os.Getenv("VISUAL"); e != "" {
+		return e
+	}
+	if e := os.Getenv("EDITOR"); e != "" {
+		return e
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// Resolve returns the configured editor command, or FromEnv when none is
+// configured.
+func Resolve(configured string) string {
+	if strings.TrimSpace(configured) != "" {
+		return configured
+	}
+	return FromEnv()
+}
+
+// gui lists editors that open their own window; they are started in the
+// background so the terminal stays usable.
+var gui = map[string]bool{
+	"code": true, "code-insiders": true, "codium": true, "cursor": true, "windsurf": true,
+	"subl": true, "sublime_text": true, "zed": true, "mate": true, "gedit": true, "kate": true,
+	"idea": true, "goland": true, "pycharm": true, "webstorm": true, "clion": true,
+	"phpstorm": true, "rubymine": true, "rider": true, "fleet": true,
+	"gvim": true, "mvim": true, "notepad": true, "notepad++": true, "open": true, "xdg-open": true,
+}
+
+func baseName(command string) string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(command)), ".exe")
+}
+
+// IsGUI reports whether an editor command opens its own window.
+func IsGUI(command string) bool {
+	fields := strings.Fields(command)
+	return len(fields) > 0 && gui[baseName(fields[0])]
+}
+
+// Args returns the arguments after the editor command that open path at
+// line; line 0 opens the file without positioning.
+func Args(command, path string, line int) []string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || line <= 0 {
+		return []string{path}
+	}
+	n := strconv.Itoa(line)
+	switch baseName(fields[0]) {
+	case "code", "code-insiders", "codium", "cursor", "windsurf":
+		return []string{"-g", path + ":" + n}
+	case "subl", "sublime_text", "zed", "fleet":
+		return []string{path + ":" + n}
+	case "idea", "goland", "pycharm", "webstorm", "clion", "phpstorm", "rubymine", "rider":
+		return []string{"--line", n, path}
+	case "mate", "kate":
+		return []string{"-l", n, path}
+	case "notepad++":
+		return []string{"-n" + n, path}
+	case "notepad", "open", "xdg-open":
+		return []string{path}
+	default:
+		// vi, vim, nvim, nano, emacs, micro, hx, kak, gedit, gvim and
+		// most other editors take +LINE.
+		return []string{"+" + n, path}
+	}
+}
+
+// Open opens path at line in the editor command. Terminal editors take over
+// the terminal until they exit; GUI editors are started and left running.
+func Open(command, path string, line int) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return fmt.Errorf("no editor configured")
+	}
+	cmd := exec.Command(fields[0], append(fields[1:], Args(command, path, line)...)...)
+	if IsGUI(command) {
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("start %s: %w", fields[0], err)
+		}
+		go cmd.Wait()
+		return nil
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run %s: %w", fields[0], err)
+	}
+	return nil
+}
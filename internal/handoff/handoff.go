@@ -0,0 +1,104 @@
+// Package handoff defines the task state document one session writes so
+// that another, possibly on a different machine or model, can pick up the
+// work without the original conversation.
+package handoff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Version is the format version written; Read rejects newer documents.
+const Version = 1
+
+// File is a file the work touched.
+type File struct {
+	Path   string `json:"path"`
+	Status string `json:"status"` // "modified", "created", "deleted" or "read"
+	Note   string `json:"note,omitempty"`
+}
+
+// Source records where a document came from.
+type Source struct {
+	Model   string    `json:"model,omitempty"`
+	WorkDir string    `json:"work_dir,omitempty"`
+	Session string    `json:"session,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Document is the state of a task at the point it is handed over.
+type Document struct {
+	Version       int      `json:"version"`
+	Goal          string   `json:"goal"`
+	Constraints   []string `json:"constraints"`
+	Progress      []string `json:"progress"`
+	NextSteps     []string `json:"next_steps"`
+	OpenQuestions []string `json:"open_questions"`
+	Files         []File   `json:"files"`
+	Source        Source   `json:"source"`
+}
+
+// Read parses a document and checks that it can be used.
+func Read(r io.Reader) (*Document, error) {
+	var d Document
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("parse handoff: %w", err)
+	}
+	if d.Version > Version {
+		return nil, fmt.Errorf("handoff version %d is newer than this apipod-cli supports (%d)", d.Version, Version)
+	}
+	if strings.TrimSpace(d.Goal) == "" {
+		return nil, fmt.Errorf("handoff has no goal")
+	}
+	return &d, nil
+}
+
+// Write stores d as indented JSON.
+func (d *Document) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// Prompt renders d as the briefing given to the session taking over.
+func (d *Document) Prompt() string {
+	var sb strings.Builder
+	sb.WriteString("<handoff>\nYou are continuing a task another session started")
+	if d.Source.Model != "" {
+		fmt.Fprintf(&sb, " with %s", d.Source.Model)
+	}
+	sb.WriteString(". Its state when it was handed over:\n\n")
+	fmt.Fprintf(&sb, "Goal: %s\n", d.Goal)
+	list := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n%s:\n", title)
+		for _, it := range items {
+			fmt.Fprintf(&sb, "- %s\n", it)
+		}
+	}
+	list("Constraints", d.Constraints)
+	list("Done so far", d.Progress)
+	list("Next steps", d.NextSteps)
+	list("Open questions", d.OpenQuestions)
+	if len(d.Files) > 0 {
+		sb.WriteString("\nFiles:\n")
+		for _, f := range d.Files {
+			fmt.Fprintf(&sb, "- %s (%s)", f.Path, f.Status)
+			if f.Note != "" {
+				fmt.Fprintf(&sb, ": %s", f.Note)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if d.Source.WorkDir != "" {
+		fmt.Fprintf(&sb, "\nThe work was done in %s.", d.Source.WorkDir)
+	}
+	sb.WriteString("\nRead the files listed before changing them; they may have moved on since. " +
+		"Respect the constraints, and ask the user about open questions rather than guessing.\n</handoff>")
+	return sb.String()
+}
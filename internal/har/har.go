@@ -0,0 +1,264 @@
+// Package har records HTTP exchanges in the HAR 1.2 format read by browser
+// devtools and most HTTP debugging tools.
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const Version = "1.2"
+
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         Request   `json:"request"`
+	Response        Response  `json:"response"`
+	Cache           struct{}  `json:"cache"`
+	Timings         Timings   `json:"timings"`
+	Comment         string    `json:"comment,omitempty"`
+}
+
+type Request struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	HTTPVersion string    `json:"httpVersion"`
+	Cookies     []NV      `json:"cookies"`
+	Headers     []NV      `json:"headers"`
+	QueryString []NV      `json:"queryString"`
+	PostData    *PostData `json:"postData,omitempty"`
+	HeadersSize int       `json:"headersSize"`
+	BodySize    int       `json:"bodySize"`
+}
+
+type Response struct {
+	Status      int     `json:"status"`
+	StatusText  string  `json:"statusText"`
+	HTTPVersion string  `json:"httpVersion"`
+	Cookies     []NV    `json:"cookies"`
+	Headers     []NV    `json:"headers"`
+	Content     Content `json:"content"`
+	RedirectURL string  `json:"redirectURL"`
+	HeadersSize int     `json:"headersSize"`
+	BodySize    int     `json:"bodySize"`
+}
+
+type NV struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// sensitiveHeaders have their values masked so a capture can be shared.
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+const masked = "[REDACTED]"
+
+// Recorder keeps a session's exchanges and rewrites the HAR file after each
+// one, so the file is complete even if the session ends abruptly.
+type Recorder struct {
+	mu   sync.Mutex
+	path string
+	log  Log
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	r := &Recorder{
+		path: path,
+		log: Log{
+			Version: Version,
+			Creator: Creator{Name: "apipod-cli", Version: "dev"},
+			Entries: []Entry{},
+		},
+	}
+	if err := r.flush(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Exchange is one request/response pair as the HTTP tools see it. Resp is
+// nil when the request failed; Err then describes why.
+type Exchange struct {
+	Req      *http.Request
+	ReqBody  string
+	Resp     *http.Response
+	RespBody []byte
+	// Truncated is set when RespBody is a prefix of the real body.
+	Truncated bool
+	Started   time.Time
+	Elapsed   time.Duration
+	Err       error
+	Comment   string
+}
+
+// Add appends an exchange and rewrites the file.
+func (r *Recorder) Add(x Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log.Entries = append(r.log.Entries, entry(x))
+	return r.flush()
+}
+
+func (r *Recorder) flush() error {
+	data, err := json.MarshalIndent(struct {
+		Log Log `json:"log"`
+	}{r.log}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode har: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0600); err != nil {
+		return fmt.Errorf("write har: %w", err)
+	}
+	return nil
+}
+
+func entry(x Exchange) Entry {
+	ms := float64(x.Elapsed.Microseconds()) / 1000
+	e := Entry{
+		StartedDateTime: x.Started,
+		Time:            ms,
+		Timings:         Timings{Wait: ms},
+		Comment:         x.Comment,
+	}
+
+	req := x.Req
+	e.Request = Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []NV{},
+		Headers:     headers(req.Header),
+		QueryString: query(req.URL),
+		HeadersSize: -1,
+		BodySize:    len(x.ReqBody),
+	}
+	if req.Host != "" && req.Host != req.URL.Host {
+		e.Request.Headers = append(e.Request.Headers, NV{"Host", req.Host})
+	}
+	if x.ReqBody != "" {
+		e.Request.PostData = &PostData{MimeType: req.Header.Get("Content-Type"), Text: x.ReqBody}
+	}
+
+	if x.Resp == nil {
+		e.Response = Response{
+			Cookies:     []NV{},
+			Headers:     []NV{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		if x.Err != nil {
+			e.Response.StatusText = x.Err.Error()
+			e.Response.Content.Comment = "request failed: " + x.Err.Error()
+		}
+		return e
+	}
+
+	resp := x.Resp
+	e.Response = Response{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Cookies:     []NV{},
+		Headers:     headers(resp.Header),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(x.RespBody),
+		Content: Content{
+			Size:     len(x.RespBody),
+			MimeType: resp.Header.Get("Content-Type"),
+		},
+	}
+	if utf8.Valid(x.RespBody) {
+		e.Response.Content.Text = string(x.RespBody)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(x.RespBody)
+		e.Response.Content.Encoding = "base64"
+	}
+	if x.Truncated {
+		e.Response.Content.Comment = "body truncated"
+	}
+	return e
+}
+
+func headers(h http.Header) []NV {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	out := []NV{}
+	for _, k := range names {
+		for _, v := range h[k] {
+			if sensitiveHeaders[strings.ToLower(k)] {
+				v = masked
+			}
+			out = append(out, NV{k, v})
+		}
+	}
+	return out
+}
+
+func query(u *url.URL) []NV {
+	out := []NV{}
+	q := u.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range q[k] {
+			out = append(out, NV{k, v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package headless
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/conversation"
+)
+
+// Failure kinds, so automation can decide whether and how to retry.
+const (
+	// FailureAuth: the credentials were rejected. Retrying will not help.
+	FailureAuth = "auth"
+	// FailureBudget: the cost, token or iteration limit ended the run
+	// first. Retry with a larger budget or a narrower task.
+	FailureBudget = "budget"
+	// FailureToolDenied: tool calls the task needed were denied or
+	// blocked. Retry with different permissions.
+	FailureToolDenied = "tool_denied"
+	// FailureGaveUp: the model stopped without completing the task.
+	FailureGaveUp = "model_gave_up"
+	// FailureTestsFailing: the change was made but tests or builds the
+	// model ran still fail.
+	FailureTestsFailing = "tests_still_failing"
+	// FailureContextOverflow: the conversation no longer fits the
+	// model's context window.
+	FailureContextOverflow = "context_overflow"
+	// FailureAPI: the API failed; rate limits, overload and server
+	// errors are worth retrying after a wait.
+	FailureAPI = "api_error"
+	// FailureInput: a file attached with --file is missing, binary or
+	// too large. Retrying will not help.
+	FailureInput = "invalid_input"
+)
+
+// failureEvents is how many of the session's last events a failure
+// carries.
+const failureEvents = 20
+
+// Failure explains why a headless run did not succeed.
+type Failure struct {
+	Kind      string `json:"kind"`
+	Retryable bool   `json:"retryable"`
+	Reason    string `json:"reason"`
+	// LastEvents are the final steps of the agent loop, oldest first.
+	LastEvents []conversation.Event `json:"last_events"`
+}
+
+// classifyError explains a run that ended with err.
+func classifyError(err error) *Failure {
+	var auth *client.AuthError
+	var tooLong *client.ContextTooLongError
+	var rateLimit *client.RateLimitError
+	var overloaded *client.OverloadedError
+	var apiErr *client.APIError
+	switch {
+	case errors.As(err, &auth), errors.Is(err, client.ErrLoginRequired), errors.Is(err, client.ErrInvalidAPIKey):
+		return &Failure{Kind: FailureAuth, Reason: err.Error()}
+	case errors.As(err, &tooLong):
+		return &Failure{Kind: FailureContextOverflow, Reason: err.Error()}
+	case errors.As(err, &rateLimit), errors.As(err, &overloaded):
+		return &Failure{Kind: FailureAPI, Retryable: true, Reason: err.Error()}
+	case errors.As(err, &apiErr):
+		return &Failure{Kind: FailureAPI, Retryable: apiErr.Status == 0 || apiErr.Status >= 500, Reason: err.Error()}
+	}
+	// Connection failures and other transport errors.
+	return &Failure{Kind: FailureAPI, Retryable: true, Reason: err.Error()}
+}
+
+// classifyOutcome explains a run that finished without success.
+func classifyOutcome(s *conversation.Session, res *Result) *Failure {
+	if limit := s.TurnLimit(); limit != "" {
+		return &Failure{Kind: FailureBudget, Retryable: true, Reason: "stopped early: " + limit}
+	}
+	var failing []string
+	for _, t := range res.TestsRun {
+		if !t.Passed {
+			failing = append(failing, t.Command)
+		}
+	}
+	if len(failing) > 0 {
+		return &Failure{Kind: FailureTestsFailing, Retryable: true,
+			Reason: "still failing: " + strings.Join(failing, ", ")}
+	}
+	if n := s.TurnDenials(); n > 0 {
+		return &Failure{Kind: FailureToolDenied, Reason: fmt.Sprintf("%d tool call(s) were denied or blocked", n)}
+	}
+	reason := "the model reported the task as not done"
+	if res.Summary != "" {
+		reason = res.Summary
+	}
+	return &Failure{Kind: FailureGaveUp, Retryable: true, Reason: reason}
+}
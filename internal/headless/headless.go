@@ -0,0 +1,145 @@
+package headless
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/conversation"
+)
+
+type TestRun struct {
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Result is the machine-readable outcome of a headless run. Pipelines can
+// gate on Success without parsing the model's prose.
+type Result struct {
+	Success      bool      `json:"success"`
+	Summary      string    `json:"summary"`
+	FilesChanged []string  `json:"files_changed"`
+	TestsRun     []TestRun `json:"tests_run"`
+	FollowUps    []string  `json:"follow_ups"`
+	Usage        Usage     `json:"usage"`
+	Error        string    `json:"error,omitempty"`
+
+	// StopCondition is the --stop-when condition that ended the run, if
+	// one did.
+	StopCondition string `json:"stop_condition,omitempty"`
+
+	// Failure classifies why the run did not succeed; it is nil on
+	// success.
+	Failure *Failure `json:"failure,omitempty"`
+}
+
+func (r *Result) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+const reportInstruction = `The task is finished. Report the outcome by calling final_report.
+Set success to true only if the requested change is complete and every test or build you ran afterwards passed.
+List each test/build command you ran with whether its final run passed. Do not invent commands you did not run.`
+
+var reportTool = client.ToolDefinition{
+	Name:        "final_report",
+	Description: "Report the final, machine-readable outcome of the task.",
+	InputSchema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"success": map[string]interface{}{"type": "boolean", "description": "Whether the task was completed and verified"},
+			"summary": map[string]interface{}{"type": "string", "description": "One or two sentences on what was done"},
+			"files_changed": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]string{"type": "string"},
+			},
+			"tests_run": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"command": map[string]string{"type": "string"},
+						"passed":  map[string]string{"type": "boolean"},
+						"summary": map[string]string{"type": "string"},
+					},
+					"required": []string{"command", "passed"},
+				},
+			},
+			"follow_ups": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]string{"type": "string"},
+				"description": "Suggested next steps or remaining issues",
+			},
+		},
+		"required": []string{"success", "summary", "files_changed", "tests_run", "follow_ups"},
+	},
+}
+
+// Run sends prompt, with files attached as --file does, through the agent
+// loop and then asks the model for a final_report. Files actually modified
+// through tools are always included in FilesChanged, whatever the model
+// reports.
+func Run(s *conversation.Session, prompt string, files ...string) *Result {
+	res := &Result{FilesChanged: []string{}, TestsRun: []TestRun{}, FollowUps: []string{}}
+	defer func() {
+		u := s.Usage()
+		res.Usage = Usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens}
+		res.FilesChanged = mergeFiles(res.FilesChanged, s.ModifiedFiles())
+		if res.Failure != nil {
+			res.Failure.LastEvents = s.RecentEvents(failureEvents)
+		}
+	}()
+
+	if len(files) > 0 {
+		if err := s.Attach(files...); err != nil {
+			res.Error = err.Error()
+			res.Failure = &Failure{Kind: FailureInput, Reason: err.Error()}
+			return res
+		}
+	}
+	err := s.SendMessage(prompt)
+	res.StopCondition = s.StopConditionMet()
+	if err != nil {
+		res.Error = err.Error()
+		res.Failure = classifyError(err)
+		return res
+	}
+
+	raw, err := s.StructuredOutput(reportInstruction, reportTool)
+	if err != nil {
+		res.Error = fmt.Sprintf("final report: %v", err)
+		res.Failure = classifyError(err)
+		return res
+	}
+	if err := json.Unmarshal(raw, res); err != nil {
+		res.Success = false
+		res.Error = fmt.Sprintf("final report: %v", err)
+	}
+	if !res.Success {
+		res.Failure = classifyOutcome(s, res)
+	}
+	return res
+}
+
+func mergeFiles(reported, tracked []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, list := range [][]string{tracked, reported} {
+		for _, f := range list {
+			if !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
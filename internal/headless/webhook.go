@@ -0,0 +1,129 @@
+package headless
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/conversation"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+const (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+)
+
+type Diffstat struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Files   int `json:"files"`
+}
+
+// Notification is the JSON body posted to the webhook when a headless run
+// ends. Event is "run.completed" or "run.failed"; Status is "success",
+// "failure" (the task was not achieved) or "error" (the run itself broke).
+type Notification struct {
+	Event         string    `json:"event"`
+	Status        string    `json:"status"`
+	Session       string    `json:"session"`
+	WorkDir       string    `json:"work_dir"`
+	Model         string    `json:"model"`
+	Summary       string    `json:"summary,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	StopCondition string    `json:"stop_condition,omitempty"`
+	FailureKind   string    `json:"failure_kind,omitempty"`
+	CostUSD       float64   `json:"cost_usd"`
+	Usage         Usage     `json:"usage"`
+	Diffstat      Diffstat  `json:"diffstat"`
+	FilesChanged  []string  `json:"files_changed"`
+	Transcript    string    `json:"transcript,omitempty"`
+	Started       time.Time `json:"started"`
+	Finished      time.Time `json:"finished"`
+	DurationMS    int64     `json:"duration_ms"`
+}
+
+// NewNotification summarizes a finished run that started at started.
+func NewNotification(s *conversation.Session, res *Result, started time.Time) *Notification {
+	n := &Notification{
+		Event:         "run.completed",
+		Status:        "success",
+		Session:       s.SessionID(),
+		WorkDir:       s.WorkDir(),
+		Model:         s.Model(),
+		Summary:       res.Summary,
+		Error:         res.Error,
+		StopCondition: res.StopCondition,
+		CostUSD:       display.EstimateCost(res.Usage.InputTokens, res.Usage.OutputTokens),
+		Usage:         res.Usage,
+		FilesChanged:  res.FilesChanged,
+		Transcript:    s.TranscriptLocation(),
+		Started:       started.UTC(),
+		Finished:      time.Now().UTC(),
+	}
+	n.DurationMS = n.Finished.Sub(n.Started).Milliseconds()
+	n.Diffstat.Added, n.Diffstat.Removed, n.Diffstat.Files = s.TurnDiffStat()
+	if res.Failure != nil {
+		n.FailureKind = res.Failure.Kind
+	}
+	switch {
+	case res.Error != "":
+		n.Event, n.Status = "run.failed", "error"
+	case !res.Success:
+		n.Status = "failure"
+	}
+	return n
+}
+
+// Notify posts n to the webhook, retrying failed deliveries a few times.
+// Only a final failure is returned; it should not change the run's exit
+// status.
+func Notify(hook *config.Webhook, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("encode notification: %w", err)
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	for attempt := 1; ; attempt++ {
+		err = deliver(client, hook, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * 2 * time.Second)
+	}
+}
+
+func deliver(client *http.Client, hook *config.Webhook, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "apipod-cli")
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Apipod-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("webhook: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package httpreq
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// collectionEntry is one request in a JSON collection file.
+type collectionEntry struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+	Curl    string            `json:"curl"`
+}
+
+// LoadCollection reads a list of requests from a HAR file (.har) or a JSON
+// file holding an array of {name, method, url, headers, body} or {name,
+// curl} objects, optionally wrapped as {"requests": [...]}. A JSON body
+// that is not a string is sent as its JSON encoding.
+func LoadCollection(path string) ([]Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read collection: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		return harRequests(data)
+	}
+
+	var entries []collectionEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var wrapped struct {
+			Requests []collectionEntry `json:"requests"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil || wrapped.Requests == nil {
+			return nil, fmt.Errorf("parse collection: %w", err)
+		}
+		entries = wrapped.Requests
+	}
+
+	reqs := make([]Request, 0, len(entries))
+	for i, e := range entries {
+		var r *Request
+		if e.Curl != "" {
+			if r, err = ParseCurl(e.Curl); err != nil {
+				return nil, fmt.Errorf("request %d: %w", i+1, err)
+			}
+		} else {
+			r = &Request{Method: strings.ToUpper(e.Method), URL: e.URL, Headers: SortedHeaders(e.Headers)}
+			if r.Method == "" {
+				r.Method = "GET"
+			}
+			if len(e.Body) > 0 && string(e.Body) != "null" {
+				var s string
+				if json.Unmarshal(e.Body, &s) == nil {
+					r.Body = s
+				} else {
+					r.Body = string(e.Body)
+				}
+			}
+		}
+		if r.URL == "" {
+			return nil, fmt.Errorf("request %d has no url", i+1)
+		}
+		r.Name = e.Name
+		if r.Name == "" {
+			r.Name = r.Method + " " + r.URL
+		}
+		reqs = append(reqs, *r)
+	}
+	return reqs, nil
+}
+
+func harRequests(data []byte) ([]Request, error) {
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					Method  string `json:"method"`
+					URL     string `json:"url"`
+					Headers []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"headers"`
+					PostData *struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse har: %w", err)
+	}
+	var reqs []Request
+	for _, e := range doc.Log.Entries {
+		r := Request{Method: e.Request.Method, URL: e.Request.URL}
+		for _, h := range e.Request.Headers {
+			// Pseudo-headers from HTTP/2 captures and values that
+			// the transport sets itself are not replayed.
+			switch strings.ToLower(h.Name) {
+			case "content-length", "host", "connection", "accept-encoding":
+				continue
+			}
+			if strings.HasPrefix(h.Name, ":") {
+				continue
+			}
+			r.Headers = append(r.Headers, Header{h.Name, h.Value})
+		}
+		if e.Request.PostData != nil {
+			r.Body = e.Request.PostData.Text
+		}
+		r.Name = r.Method + " " + r.URL
+		reqs = append(reqs, r)
+	}
+	return reqs, nil
+}
@@ -0,0 +1,384 @@
+package httpreq
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Request is an HTTP request as the HttpRequest tool makes it.
+type Request struct {
+	// Name labels requests loaded from a collection.
+	Name   string
+	Method string
+	URL    string
+	// Headers keeps the order they were given in.
+	Headers  []Header
+	Body     string
+	Insecure bool
+	// NoFollow disables following redirects.
+	NoFollow bool
+}
+
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Header returns the first value of the named header.
+func (r *Request) Header(name string) string {
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// SetHeader replaces all values of the named header.
+func (r *Request) SetHeader(name, value string) {
+	out := r.Headers[:0]
+	for _, h := range r.Headers {
+		if !strings.EqualFold(h.Name, name) {
+			out = append(out, h)
+		}
+	}
+	r.Headers = append(out, Header{name, value})
+}
+
+// SortedHeaders returns headers from a map in a stable order.
+func SortedHeaders(m map[string]string) []Header {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]Header, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, Header{k, m[k]})
+	}
+	return out
+}
+
+// Curl renders the request as a curl command line.
+func (r *Request) Curl() string {
+	parts := []string{"curl"}
+	method := strings.ToUpper(r.Method)
+	switch {
+	case method == "HEAD":
+		parts = append(parts, "-I")
+	case method == "" || method == "GET":
+	case method == "POST" && r.Body != "":
+	default:
+		parts = append(parts, "-X", method)
+	}
+	if !r.NoFollow {
+		parts = append(parts, "-L")
+	}
+	if r.Insecure {
+		parts = append(parts, "-k")
+	}
+	parts = append(parts, shellQuote(r.URL))
+	for _, h := range r.Headers {
+		parts = append(parts, "-H", shellQuote(h.Name+": "+h.Value))
+	}
+	if r.Body != "" {
+		parts = append(parts, "--data-raw", shellQuote(r.Body))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ParseCurl turns a curl command line, as pasted from docs or a browser's
+// "copy as cURL", into a Request. Output-only flags are ignored; flags that
+// change what is sent but are not supported return an error.
+func ParseCurl(command string) (*Request, error) {
+	args, err := splitWords(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("not a curl command")
+	}
+
+	r := &Request{NoFollow: true}
+	var data []string
+	var query []string
+	explicitMethod, getData := false, false
+
+	value := func(i *int, flag string) (string, error) {
+		if *i+1 >= len(args) {
+			return "", fmt.Errorf("%s needs a value", flag)
+		}
+		*i++
+		return args[*i], nil
+	}
+
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		flag, attached := a, ""
+		// -XPOST, -H'...' and --flag=value forms.
+		if strings.HasPrefix(a, "--") {
+			if eq := strings.IndexByte(a, '='); eq > 0 {
+				flag, attached = a[:eq], a[eq+1:]
+			}
+		} else if strings.HasPrefix(a, "-") && len(a) > 2 && strings.ContainsRune("XHdubAeo", rune(a[1])) {
+			flag, attached = a[:2], a[2:]
+		}
+		arg := func() (string, error) {
+			if attached != "" {
+				return attached, nil
+			}
+			return value(&i, flag)
+		}
+
+		switch flag {
+		case "-X", "--request":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			r.Method, explicitMethod = strings.ToUpper(v), true
+		case "-H", "--header":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			name, val, ok := strings.Cut(v, ":")
+			if !ok {
+				return nil, fmt.Errorf("malformed header %q", v)
+			}
+			r.Headers = append(r.Headers, Header{strings.TrimSpace(name), strings.TrimSpace(val)})
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(v, "@") && flag != "--data-raw" {
+				return nil, fmt.Errorf("%s with a file (%s) is not supported; paste the body instead", flag, v)
+			}
+			data = append(data, v)
+		case "--data-urlencode":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			if name, val, ok := strings.Cut(v, "="); ok {
+				data = append(data, name+"="+url.QueryEscape(val))
+			} else {
+				data = append(data, url.QueryEscape(v))
+			}
+		case "--json":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, v)
+			if r.Header("Content-Type") == "" {
+				r.Headers = append(r.Headers, Header{"Content-Type", "application/json"})
+			}
+			if r.Header("Accept") == "" {
+				r.Headers = append(r.Headers, Header{"Accept", "application/json"})
+			}
+		case "-u", "--user":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			r.Headers = append(r.Headers, Header{"Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(v))})
+		case "-A", "--user-agent":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			r.Headers = append(r.Headers, Header{"User-Agent", v})
+		case "-e", "--referer":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			r.Headers = append(r.Headers, Header{"Referer", v})
+		case "-b", "--cookie":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			r.Headers = append(r.Headers, Header{"Cookie", v})
+		case "--url":
+			v, err := arg()
+			if err != nil {
+				return nil, err
+			}
+			r.URL = v
+		case "-k", "--insecure":
+			r.Insecure = true
+		case "-L", "--location":
+			r.NoFollow = false
+		case "-I", "--head":
+			r.Method, explicitMethod = "HEAD", true
+		case "-G", "--get":
+			getData = true
+		case "-o", "--output", "-w", "--write-out", "--connect-timeout", "-m", "--max-time", "--retry":
+			if attached == "" {
+				i++
+			}
+		case "--compressed", "-s", "--silent", "-S", "--show-error", "-v", "--verbose", "-i", "--include", "-f", "--fail", "--http1.1", "--http2":
+		default:
+			if strings.HasPrefix(a, "-") && len(a) > 1 {
+				if isIgnorableShortFlags(a) {
+					if strings.ContainsRune(a, 'L') {
+						r.NoFollow = false
+					}
+					if strings.ContainsRune(a, 'k') {
+						r.Insecure = true
+					}
+					continue
+				}
+				return nil, fmt.Errorf("unsupported curl option %s", a)
+			}
+			if r.URL != "" {
+				return nil, fmt.Errorf("multiple URLs are not supported")
+			}
+			r.URL = a
+		}
+	}
+
+	if r.URL == "" {
+		return nil, fmt.Errorf("no URL in curl command")
+	}
+	if !strings.Contains(r.URL, "://") {
+		r.URL = "http://" + r.URL
+	}
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if getData {
+			query = append(query, joined)
+		} else {
+			r.Body = joined
+			if !explicitMethod {
+				r.Method = "POST"
+			}
+			if r.Header("Content-Type") == "" {
+				r.Headers = append(r.Headers, Header{"Content-Type", "application/x-www-form-urlencoded"})
+			}
+		}
+	}
+	if len(query) > 0 {
+		sep := "?"
+		if strings.Contains(r.URL, "?") {
+			sep = "&"
+		}
+		r.URL += sep + strings.Join(query, "&")
+	}
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+	return r, nil
+}
+
+// isIgnorableShortFlags accepts bundles such as -sSL made only of flags
+// that take no value.
+func isIgnorableShortFlags(a string) bool {
+	if strings.HasPrefix(a, "--") {
+		return false
+	}
+	for _, c := range a[1:] {
+		if !strings.ContainsRune("sSLkvif", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitWords splits a POSIX shell command line, handling quotes, escapes
+// and backslash-newline continuations.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			if s[i] == '\n' || (s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n') {
+				if s[i] == '\r' {
+					i++
+				}
+				continue
+			}
+			cur.WriteByte(s[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			// ANSI-C quoting as produced by browsers' "copy as cURL".
+			i += 2
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						cur.WriteByte('\n')
+					case 't':
+						cur.WriteByte('\t')
+					case 'r':
+						cur.WriteByte('\r')
+					default:
+						cur.WriteByte(s[i])
+					}
+					continue
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated $' quote")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
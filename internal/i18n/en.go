@@ -0,0 +1,120 @@
+package i18n
+
+// english is the source catalog: every message ID the CLI uses, and the
+// text shown when a locale has no translation for one. Translations keep
+// the format verbs (%s, %d) in the same order and the answer letters of
+// prompts, which are read as they are.
+var english = Catalog{
+	// Words accepted as "yes" besides y and yes, comma-separated.
+	"answer.yes": "y,yes",
+
+	"banner.tip": "Type %s for commands",
+
+	"prompt.yes_no":         "[y/N]",
+	"prompt.yes_no_explain": "[y/N/e=explain]",
+
+	"confirm.allow_tool":     "Allow %s?",
+	"confirm.high_risk":      "Run this high-risk command?",
+	"confirm.trust_hooks":    "Trust these hooks?",
+	"confirm.setup_plan":     "Run the setup plan?",
+	"confirm.replace":        "Replace %s?",
+	"confirm.write_memory":   "Write %s?",
+	"confirm.replay.one":     "Replay %d request, %d of them remote or not read-only?",
+	"confirm.replay.other":   "Replay %d requests, %d of them remote or not read-only?",
+	"notify.confirm":         "Waiting for you: %s",
+	"notify.done":            "Finished after %s",
+	"notify.failed":          "Stopped after %s: %v",
+	"explain.title":          "ⓘ What this command does",
+	"explain.failed":         "Could not explain the command: %v",
+	"risk.title":             "⚠ High-risk command",
+	"auto.approved":          "⏵ auto-approved %s (%s)",
+	"review.choices":         "[y,n,a,d,q,?]",
+	"review.help":            "y apply · n skip · a apply rest of file · d skip rest of file · q skip everything left",
+	"commit.title":           "✎ Commit message",
+	"commit.confirm":         "Commit all changes with this message?",
+	"commit.choices":         "[y/e=edit/N]",
+	"worktree.changes":       "⎇ Changes on %s",
+	"worktree.confirm":       "Merge them into your working tree?",
+	"worktree.choices":       "[m=merge/K=keep branch/d=discard]",
+	"thinking.summary.one":   "💭 Thought for %d word · /thinking or ctrl+o to expand",
+	"thinking.summary.other": "💭 Thought for %d words · /thinking or ctrl+o to expand",
+	"thinking.title":         "💭 Thinking",
+	"thinking.redacted":      "(reasoning redacted by the provider)",
+
+	"input.queued.one":   "⧗ %d message queued · sent when this turn ends",
+	"input.queued.other": "⧗ %d messages queued · sent when this turn ends",
+	"input.steering":     "↪ steering · the turn changes course after the current tool",
+
+	"usage.tokens":           "↳ tokens: %d (%d in, %d out)",
+	"usage.cost":             " · ~$%.4f",
+	"usage.tool_calls.one":   " · %d tool call",
+	"usage.tool_calls.other": " · %d tool calls",
+	"usage.tool_time":        " (%s)",
+	"usage.files.one":        " · +%d/−%d across %d file",
+	"usage.files.other":      " · +%d/−%d across %d files",
+	"context.nearly_full":    " · context nearly full, consider /compact",
+
+	"login.success":      "✓ Authenticated successfully",
+	"login.username":     "Username",
+	"login.plan":         "Plan",
+	"logout.success":     "✓ Logged out successfully",
+	"login.required":     "⚠ Not authenticated",
+	"login.required_run": "Run %s to connect your account.",
+	"device.title":       "🔐 Device Authorization",
+	"device.open":        "Open in browser:",
+	"device.code":        "Enter this code:",
+	"device.waiting":     "Waiting for authorization",
+	"update.available":   "apipod-cli %s is available (you have %s) · run `apipod-cli update`",
+	"whoami.title":       "👤 Account Info",
+	"whoami.api_url":     "API URL",
+	"whoami.model":       "Model",
+	"whoami.config":      "Config",
+
+	"error.server":           "The API had a server error; try again in a moment.",
+	"error.permission":       "Your account cannot use this model or feature; check your plan or pick another model with /model.",
+	"error.auth":             "Check your API key, or run `apipod-cli login` to sign in again.",
+	"error.rate_limit_after": "Rate limit reached; try again in %s.",
+	"error.rate_limit":       "Rate limit reached; wait a moment and try again.",
+	"error.overloaded":       "The API is overloaded; try again shortly, or switch to another model with /model.",
+	"error.context_too_long": "Context too long — run /compact to summarize the conversation, or /clear to start over.",
+
+	"help.custom":         "Custom commands",
+	"help.help":           "Show this help",
+	"help.clear":          "Clear conversation history",
+	"help.model":          "Show or change model",
+	"help.compact":        "Compact context (clear history)",
+	"help.jobs":           "List background shells",
+	"help.stats":          "Show calls, time and output per tool",
+	"help.scope":          "Scope tools to a monorepo package",
+	"help.export":         "Export transcript (md, json, html)",
+	"help.share":          "Share a redacted HTML copy of the session",
+	"help.new":            "Open a session tab, optionally in another directory",
+	"help.switch":         "Switch to a session tab (default the next)",
+	"help.close":          "Close a session tab and its background shells",
+	"help.sessions":       "List open tabs and saved sessions",
+	"help.search":         "Find earlier answers, tool output and decisions",
+	"help.resume":         "Continue a saved session",
+	"help.init":           "Scan the repository and write an APIPOD.md project memory",
+	"help.handoff":        "Write the task state for another session",
+	"help.import":         "Continue from a handoff file",
+	"help.open":           "Open a file in your editor (default the last changed)",
+	"help.download":       "Save a workspace file on your local machine",
+	"help.pair":           "Let teammates watch this session read-only",
+	"help.upload":         "Copy local files into the workspace",
+	"help.theme":          "Show or change the color theme",
+	"help.curl":           "Show HTTP requests as curl commands",
+	"help.status":         "Check the API, login, model, project and hooks",
+	"help.thinking":       "Expand or collapse model reasoning",
+	"help.add-dir":        "Search and edit another directory too",
+	"help.attach":         "Attach files or directories to the next message",
+	"help.commit":         "Draft a commit message for all changes and commit",
+	"help.output-style":   "Show or change the response style for this project",
+	"help.set":            "Show or change max_tokens, temperature, top_p, stop_sequences",
+	"help.expand":         "Show all of a cut-short tool result (also ctrl+r)",
+	"help.retry":          "Send the request of a failed turn again",
+	"help.review":         "Review file changes before they are written",
+	"help.second-opinion": "Have a second model review the plan or diff",
+	"help.auto":           "Auto-approve low-risk tools for a while or N turns",
+	"help.whoami":         "Show current user info",
+	"help.quit":           "Exit the session",
+}
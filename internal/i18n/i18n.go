@@ -0,0 +1,177 @@
+// Package i18n translates the messages the CLI shows. Messages are looked
+// up by ID in the catalog of the selected locale and fall back to English,
+// so a partial translation is still usable.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Catalog maps message IDs to text. Text is a fmt format when the message
+// takes arguments. A message that depends on a count has an ID ending in
+// ".one" for one and ".other" for any other count; see N.
+type Catalog map[string]string
+
+var (
+	mu       sync.RWMutex
+	catalogs = map[string]Catalog{"en": english}
+	locale   = "en"
+	active   = english
+)
+
+// Register adds messages for locale, e.g. "de" or "pt_BR", over those it
+// already has. Translations are plugged in this way, from code or with
+// LoadDir.
+func Register(name string, c Catalog) {
+	name = normalize(name)
+	mu.Lock()
+	defer mu.Unlock()
+	merged := make(Catalog, len(catalogs[name])+len(c))
+	for id, text := range catalogs[name] {
+		merged[id] = text
+	}
+	for id, text := range c {
+		merged[id] = text
+	}
+	catalogs[name] = merged
+	if name == locale {
+		active = merged
+	}
+}
+
+// LoadDir registers every <locale>.json file in dir, each a JSON object of
+// message IDs to text. A missing dir is not an error.
+func LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read translations: %w", err)
+		}
+		var c Catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		Register(strings.TrimSuffix(filepath.Base(path), ".json"), c)
+	}
+	return nil
+}
+
+// SetLocale selects the locale messages are shown in; "" picks it from
+// the environment (see Detect). A region without its own catalog uses the
+// language's, e.g. "de_AT" uses "de", and a locale without any uses
+// English. It returns the locale selected.
+func SetLocale(name string) string {
+	if name == "" {
+		name = Detect()
+	}
+	name = normalize(name)
+	mu.Lock()
+	defer mu.Unlock()
+	locale, active = "en", english
+	for _, candidate := range []string{name, strings.SplitN(name, "_", 2)[0]} {
+		if c, ok := catalogs[candidate]; ok {
+			locale, active = candidate, c
+			break
+		}
+	}
+	return locale
+}
+
+// Locale returns the locale selected with SetLocale.
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return locale
+}
+
+// Locales returns the locales that have a catalog.
+func Locales() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(catalogs))
+	for name := range catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect returns the locale of the environment from LC_ALL, LC_MESSAGES
+// or LANG, the first one set, e.g. "de_DE" for "de_DE.UTF-8".
+func Detect() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			return normalize(v)
+		}
+	}
+	return "en"
+}
+
+// normalize turns "de-DE.UTF-8@euro" into "de_DE"; the C and POSIX
+// locales are English.
+func normalize(name string) string {
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ReplaceAll(name, "-", "_")
+	if name == "" || name == "C" || name == "POSIX" {
+		return "en"
+	}
+	lang, region, _ := strings.Cut(name, "_")
+	if region != "" {
+		return strings.ToLower(lang) + "_" + strings.ToUpper(region)
+	}
+	return strings.ToLower(lang)
+}
+
+// T returns message id in the selected locale, formatted with args. An
+// ID missing from every catalog is returned as it is, so it shows up.
+func T(id string, args ...interface{}) string {
+	mu.RLock()
+	text, ok := active[id]
+	if !ok {
+		text, ok = english[id]
+	}
+	mu.RUnlock()
+	if !ok {
+		text = id
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// N returns the form of message id for count n: id+".one" when n is 1 and
+// id+".other" otherwise, formatted with args.
+func N(id string, n int, args ...interface{}) string {
+	if n == 1 {
+		return T(id+".one", args...)
+	}
+	return T(id+".other", args...)
+}
+
+// IsYes reports whether answer means yes: English "y" or "yes", or one of
+// the comma-separated words of the locale's "answer.yes".
+func IsYes(answer string) bool {
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer == "y" || answer == "yes" {
+		return true
+	}
+	for _, word := range strings.Split(T("answer.yes"), ",") {
+		if word = strings.TrimSpace(word); word != "" && answer == word {
+			return true
+		}
+	}
+	return false
+}
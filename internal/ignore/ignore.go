@@ -0,0 +1,248 @@
+// Package ignore decides which project files the file tools and the project
+// map skip: dependency and build directories by default, plus what
+// .gitignore files and .apipodignore exclude, with gitignore syntax.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// File is the project's own ignore file. Its rules apply after those of
+// .gitignore, so "!vendor" in it brings back a directory skipped by default.
+const File = ".apipodignore"
+
+// defaults are skipped in every project.
+var defaults = []string{
+	".git/", ".hg/", ".svn/", ".apipod/",
+	"node_modules/", "vendor/", "dist/", "build/", "target/",
+	"__pycache__/", ".venv/", ".idea/",
+}
+
+// DefaultDirs are the directory names skipped by default, for tools such as
+// grep that take a list of names.
+func DefaultDirs() []string {
+	dirs := make([]string, len(defaults))
+	for i, d := range defaults {
+		dirs[i] = strings.TrimSuffix(d, "/")
+	}
+	return dirs
+}
+
+type rule struct {
+	// base is the slash-separated directory of the file the rule is from,
+	// "" for the root.
+	base     string
+	pattern  []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher answers whether paths under a root are ignored. .gitignore files
+// in subdirectories are read the first time a path below them is checked.
+type Matcher struct {
+	root  string
+	first []rule
+	last  []rule
+
+	mu     sync.Mutex
+	nested map[string][]rule
+}
+
+// New returns the matcher for root, reading .git/info/exclude, the root
+// .gitignore and .apipodignore.
+func New(root string) *Matcher {
+	m := &Matcher{root: root, nested: make(map[string][]rule)}
+	for _, line := range defaults {
+		m.first = append(m.first, parse("", line)...)
+	}
+	m.first = append(m.first, readRules(filepath.Join(root, ".git", "info", "exclude"), "")...)
+	m.nested[""] = readRules(filepath.Join(root, ".gitignore"), "")
+	m.last = readRules(filepath.Join(root, File), "")
+	return m
+}
+
+// Root is the directory paths are relative to.
+func (m *Matcher) Root() string {
+	return m.root
+}
+
+// Ignored reports whether path, absolute or relative to the root, is
+// ignored, either itself or through one of its parent directories. Paths
+// outside the root are never ignored.
+func (m *Matcher) Ignored(p string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	rel := p
+	if filepath.IsAbs(p) {
+		var err error
+		if rel, err = filepath.Rel(m.root, p); err != nil {
+			return false
+		}
+	}
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		dir := i < len(parts) || isDir
+		if m.match(parts[:i], dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skip is for filepath.WalkDir: it reports whether the entry at p should
+// be left out, and returns filepath.SkipDir for ignored directories. The
+// walk root itself is never skipped.
+func (m *Matcher) Skip(p string, isDir bool) (bool, error) {
+	if m == nil || filepath.Clean(p) == filepath.Clean(m.root) {
+		return false, nil
+	}
+	rel, err := filepath.Rel(m.root, p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false, nil
+	}
+	// Parents were checked when the walk entered them.
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if !m.match(parts, isDir) {
+		return false, nil
+	}
+	if isDir {
+		return true, filepath.SkipDir
+	}
+	return true, nil
+}
+
+// match applies the rules to one path, the last matching rule deciding.
+func (m *Matcher) match(parts []string, isDir bool) bool {
+	ignored := false
+	apply := func(rules []rule) {
+		for _, r := range rules {
+			if r.matches(parts, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	apply(m.first)
+	// .gitignore files from the root down to the path's directory.
+	for i := 0; i < len(parts); i++ {
+		apply(m.dirRules(strings.Join(parts[:i], "/")))
+	}
+	apply(m.last)
+	return ignored
+}
+
+func (m *Matcher) dirRules(dir string) []rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rules, ok := m.nested[dir]
+	if !ok {
+		rules = readRules(filepath.Join(m.root, filepath.FromSlash(dir), ".gitignore"), dir)
+		m.nested[dir] = rules
+	}
+	return rules
+}
+
+func readRules(file, base string) []rule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []rule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rules = append(rules, parse(base, scanner.Text())...)
+	}
+	return rules
+}
+
+// parse turns one gitignore line into a rule; blank lines and comments
+// yield none.
+func parse(base, line string) []rule {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A slash anywhere but at the end ties the pattern to the file's
+	// directory; otherwise it matches a name at any depth.
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+	r.pattern = strings.Split(line, "/")
+	return []rule{r}
+}
+
+func (r rule) matches(parts []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		baseParts := strings.Split(r.base, "/")
+		if len(parts) <= len(baseParts) {
+			return false
+		}
+		for i, b := range baseParts {
+			if parts[i] != b {
+				return false
+			}
+		}
+		parts = parts[len(baseParts):]
+	}
+	if !r.anchored {
+		ok, _ := path.Match(r.pattern[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(r.pattern, parts)
+}
+
+// matchSegments matches path segments against pattern segments, where
+// "**" spans any number of directories.
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
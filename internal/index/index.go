@@ -0,0 +1,352 @@
+package index
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/ignore"
+)
+
+const (
+	File = "files.json"
+
+	maxSymbolFileSize = 512 * 1024
+	maxSymbolsPerFile = 50
+)
+
+type Entry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Lang    string    `json:"lang,omitempty"`
+	Symbols []string  `json:"symbols,omitempty"`
+}
+
+type Index struct {
+	Root      string    `json:"root"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Files     []Entry   `json:"files"`
+}
+
+// CacheDir is where the index of root and other caches derived from it
+// are kept: under ~/.apipod/index, outside the project, so indexing
+// never changes the worktree.
+func CacheDir(root string) string {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	sum := sha256.Sum256([]byte(abs))
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".apipod", "index", filepath.Base(abs)+"-"+hex.EncodeToString(sum[:6]))
+}
+
+func indexPath(root string) string {
+	return filepath.Join(CacheDir(root), File)
+}
+
+func Load(root string) (*Index, error) {
+	data, err := os.ReadFile(indexPath(root))
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parse index: %w", err)
+	}
+	idx.Root = root
+	return &idx, nil
+}
+
+func (idx *Index) Save() error {
+	p := indexPath(idx.Root)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("create index dir: %w", err)
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("marshal index: %w", err)
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Refresh loads the saved index for root (if any) and brings it up to date.
+// Files whose size and modification time are unchanged keep their symbols,
+// so only new or modified files are re-parsed. Hidden directories and
+// ignored files (see package ignore) are left out.
+func Refresh(root string) (*Index, error) {
+	prev := map[string]Entry{}
+	if old, err := Load(root); err == nil {
+		for _, e := range old.Files {
+			prev[e.Path] = e
+		}
+	}
+
+	idx := &Index{Root: root, UpdatedAt: time.Now()}
+	ignored := ignore.New(root)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if skip, err := ignored.Skip(p, d.IsDir()); skip {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if old, ok := prev[rel]; ok && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			idx.Files = append(idx.Files, old)
+			return nil
+		}
+		e := Entry{Path: rel, Size: info.Size(), ModTime: info.ModTime(), Lang: Language(rel)}
+		if e.Lang != "" && e.Size <= maxSymbolFileSize {
+			e.Symbols = extractSymbols(p, e.Lang)
+		}
+		idx.Files = append(idx.Files, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(idx.Files, func(i, j int) bool { return idx.Files[i].Path < idx.Files[j].Path })
+	return idx, idx.Save()
+}
+
+var languages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "javascript",
+	".mjs": "javascript", ".ts": "typescript", ".tsx": "typescript",
+	".rs": "rust", ".java": "java", ".kt": "kotlin", ".rb": "ruby",
+	".php": "php", ".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp",
+	".hpp": "cpp", ".cs": "csharp", ".swift": "swift", ".scala": "scala",
+	".sh": "shell", ".sql": "sql", ".proto": "protobuf",
+}
+
+func Language(p string) string {
+	return languages[strings.ToLower(path.Ext(p))]
+}
+
+var symbolPatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^(?:func(?:\s+\([^)]*\))?|type)\s+([A-Za-z_]\w*)`),
+	"python":     regexp.MustCompile(`^\s*(?:async\s+)?(?:def|class)\s+([A-Za-z_]\w*)`),
+	"javascript": regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?(?:function\*?|class|const|let)\s+([A-Za-z_$][\w$]*)`),
+	"typescript": regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:abstract\s+)?(?:async\s+)?(?:function\*?|class|interface|type|enum|const)\s+([A-Za-z_$][\w$]*)`),
+	"rust":       regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:fn|struct|enum|trait|type|mod)\s+([A-Za-z_]\w*)`),
+	"java":       regexp.MustCompile(`^\s*(?:public|protected|private)?\s*(?:static\s+)?(?:final\s+)?(?:abstract\s+)?(?:class|interface|enum|record)\s+([A-Za-z_]\w*)`),
+	"kotlin":     regexp.MustCompile(`^\s*(?:\w+\s+)*(?:fun|class|object|interface)\s+([A-Za-z_]\w*)`),
+	"ruby":       regexp.MustCompile(`^\s*(?:def|class|module)\s+([A-Za-z_][\w.]*)`),
+	"php":        regexp.MustCompile(`^\s*(?:\w+\s+)*(?:function|class|interface|trait)\s+([A-Za-z_]\w*)`),
+	"csharp":     regexp.MustCompile(`^\s*(?:\w+\s+)*(?:class|interface|struct|enum|record)\s+([A-Za-z_]\w*)`),
+	"swift":      regexp.MustCompile(`^\s*(?:\w+\s+)*(?:func|class|struct|enum|protocol)\s+([A-Za-z_]\w*)`),
+}
+
+func extractSymbols(p, lang string) []string {
+	re := symbolPatterns[lang]
+	if re == nil {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var symbols []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() && len(symbols) < maxSymbolsPerFile {
+		if m := re.FindStringSubmatch(scanner.Text()); m != nil {
+			symbols = append(symbols, m[1])
+		}
+	}
+	return symbols
+}
+
+// Match returns indexed paths matching a glob pattern relative to the root.
+// Unlike filepath.Glob, "**" matches any number of directories.
+func (idx *Index) Match(pattern string) []string {
+	pattern = filepath.ToSlash(pattern)
+	var out []string
+	for _, e := range idx.Files {
+		if MatchGlob(pattern, e.Path) {
+			out = append(out, e.Path)
+		}
+	}
+	return out
+}
+
+// MatchGlob reports whether a slash-separated path matches pattern, where
+// "**" spans directories and other segments use path.Match syntax.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// Fuzzy ranks indexed paths against query, subsequence-matching characters
+// in order and preferring matches in the file name and contiguous runs.
+func (idx *Index) Fuzzy(query string, limit int) []string {
+	type scored struct {
+		path  string
+		score int
+	}
+	var results []scored
+	q := strings.ToLower(query)
+	for _, e := range idx.Files {
+		if s, ok := FuzzyScore(q, e.Path); ok {
+			results = append(results, scored{e.Path, s})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].path < results[j].path
+	})
+	var out []string
+	for i := 0; i < len(results) && i < limit; i++ {
+		out = append(out, results[i].path)
+	}
+	return out
+}
+
+// FuzzyScore scores how well a lower-cased query matches candidate.
+func FuzzyScore(query, candidate string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	lower := strings.ToLower(candidate)
+	base := strings.LastIndex(lower, "/") + 1
+	score, qi, run := 0, 0, 0
+	for i := 0; i < len(lower) && qi < len(query); i++ {
+		if lower[i] != query[qi] {
+			run = 0
+			continue
+		}
+		qi++
+		run++
+		score += run * 2
+		if i >= base {
+			score += 3
+		}
+		if i == 0 || lower[i-1] == '/' || lower[i-1] == '_' || lower[i-1] == '-' || lower[i-1] == '.' {
+			score += 5
+		}
+	}
+	if qi < len(query) {
+		return 0, false
+	}
+	return score - len(candidate)/10, true
+}
+
+// Summary renders a compact map of the project: directories with their file
+// counts, dominant language and a few symbol names.
+func (idx *Index) Summary(maxDirs int) string {
+	type dirInfo struct {
+		files   int
+		langs   map[string]int
+		symbols []string
+	}
+	dirs := map[string]*dirInfo{}
+	for _, e := range idx.Files {
+		d := path.Dir(e.Path)
+		info, ok := dirs[d]
+		if !ok {
+			info = &dirInfo{langs: map[string]int{}}
+			dirs[d] = info
+		}
+		info.files++
+		if e.Lang != "" {
+			info.langs[e.Lang]++
+		}
+		for _, s := range e.Symbols {
+			if len(info.symbols) < 6 && len(s) > 0 && s[0] >= 'A' && s[0] <= 'Z' {
+				info.symbols = append(info.symbols, s)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(dirs))
+	for d := range dirs {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for i, d := range names {
+		if i == maxDirs {
+			fmt.Fprintf(&sb, "... %d more directories\n", len(names)-maxDirs)
+			break
+		}
+		info := dirs[d]
+		lang, best := "", 0
+		for l, n := range info.langs {
+			if n > best || (n == best && l < lang) {
+				lang, best = l, n
+			}
+		}
+		fmt.Fprintf(&sb, "%s/ (%d files", d, info.files)
+		if lang != "" {
+			fmt.Fprintf(&sb, ", %s", lang)
+		}
+		sb.WriteString(")")
+		if len(info.symbols) > 0 {
+			fmt.Fprintf(&sb, ": %s", strings.Join(info.symbols, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
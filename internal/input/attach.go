@@ -0,0 +1,167 @@
+package input
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/ignore"
+)
+
+// Limits on files attached with --file and /attach. Images and PDFs are
+// sent as they are, so they may be larger than text files.
+const (
+	maxAttachText  = 256 * 1024
+	maxAttachMedia = 5 * 1024 * 1024
+	maxAttachTotal = 8 * 1024 * 1024
+	maxAttachFiles = 100
+)
+
+// mediaTypes are the images and documents sent as content blocks of
+// their own rather than as text.
+var mediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+}
+
+// File is a file attached to a prompt, with the content block it is sent
+// as.
+type File struct {
+	Path  string
+	Size  int
+	Block map[string]interface{}
+}
+
+// Attach reads paths, relative to workDir, to send with the next prompt,
+// as --file and /attach do. A directory attaches the text files under it
+// that are not ignored, skipping binary and oversized ones; a file named
+// directly must fit the limits or the whole call fails.
+func Attach(paths []string, workDir string) ([]File, error) {
+	var files []File
+	total := 0
+	add := func(f File) error {
+		if len(files) == maxAttachFiles {
+			return fmt.Errorf("too many files; attach at most %d at once", maxAttachFiles)
+		}
+		if total += f.Size; total > maxAttachTotal {
+			return fmt.Errorf("the attachments are larger than %d MB together", maxAttachTotal>>20)
+		}
+		files = append(files, f)
+		return nil
+	}
+	for _, p := range paths {
+		abs := p
+		if strings.HasPrefix(abs, "~/") {
+			home, _ := os.UserHomeDir()
+			abs = filepath.Join(home, abs[2:])
+		} else if !filepath.IsAbs(abs) {
+			abs = filepath.Join(workDir, abs)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("attach %s: %w", p, err)
+		}
+		if !info.IsDir() {
+			f, err := readAttachment(abs, workDir)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var names []string
+		ignored := ignore.New(abs)
+		filepath.WalkDir(abs, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if skip, err := ignored.Skip(path, d.IsDir()); skip {
+				return err
+			}
+			if d.IsDir() && path != abs && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if d.Type().IsRegular() {
+				names = append(names, path)
+			}
+			return nil
+		})
+		sort.Strings(names)
+		for _, name := range names {
+			f, err := readAttachment(name, workDir)
+			if err != nil || f.Block["type"] != "text" {
+				continue
+			}
+			if err := add(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+func readAttachment(path, workDir string) (File, error) {
+	rel, err := filepath.Rel(workDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return File{}, fmt.Errorf("attach %s: %w", rel, err)
+	}
+	media := mediaTypes[strings.ToLower(filepath.Ext(path))]
+	limit := maxAttachText
+	if media != "" {
+		limit = maxAttachMedia
+	}
+	if info.Size() > int64(limit) {
+		return File{}, fmt.Errorf("%s is larger than %d KB", rel, limit/1024)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, fmt.Errorf("attach %s: %w", rel, err)
+	}
+
+	f := File{Path: rel, Size: len(data)}
+	switch {
+	case media == "application/pdf":
+		f.Block = map[string]interface{}{
+			"type":   "document",
+			"title":  rel,
+			"source": map[string]string{"type": "base64", "media_type": media, "data": base64.StdEncoding.EncodeToString(data)},
+		}
+	case media != "":
+		// The extension can lie; the API rejects an image whose type is
+		// wrong.
+		if sniffed := http.DetectContentType(data); strings.HasPrefix(sniffed, "image/") {
+			media = sniffed
+		}
+		f.Block = map[string]interface{}{
+			"type":   "image",
+			"source": map[string]string{"type": "base64", "media_type": media, "data": base64.StdEncoding.EncodeToString(data)},
+		}
+	case bytes.IndexByte(data, 0) >= 0:
+		return File{}, fmt.Errorf("%s is a binary file", rel)
+	default:
+		text := fmt.Sprintf("<file path=%q>\n%s", rel, data)
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			text += "\n"
+		}
+		f.Block = map[string]interface{}{"type": "text", "text": text + "</file>"}
+	}
+	return f, nil
+}
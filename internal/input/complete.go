@@ -0,0 +1,74 @@
+package input
+
+import (
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/index"
+)
+
+// Completer completes @mentions with fuzzy-matched project paths.
+type Completer struct {
+	root string
+	idx  *index.Index
+	// last remembers the previous completion so repeated Tab presses cycle
+	// through the candidates.
+	last       string
+	candidates []string
+	next       int
+}
+
+func NewCompleter(root string) *Completer {
+	return &Completer{root: root}
+}
+
+func (c *Completer) index() *index.Index {
+	if c.idx == nil {
+		idx, err := index.Load(c.root)
+		if err != nil {
+			idx, err = index.Refresh(c.root)
+		}
+		if err != nil {
+			return nil
+		}
+		c.idx = idx
+	}
+	return c.idx
+}
+
+// Candidates returns up to limit paths matching query, best first.
+func (c *Completer) Candidates(query string, limit int) []string {
+	idx := c.index()
+	if idx == nil {
+		return nil
+	}
+	return idx.Fuzzy(query, limit)
+}
+
+// AutoComplete has the signature of term.Terminal's AutoCompleteCallback.
+// Tab on an @word replaces it with the best matching path; pressing Tab
+// again cycles through the next candidates.
+func (c *Completer) AutoComplete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	start := strings.LastIndexAny(line[:pos], " \t") + 1
+	word := line[start:pos]
+	if !strings.HasPrefix(word, "@") {
+		return "", 0, false
+	}
+	query := word[1:]
+
+	if word == c.last && len(c.candidates) > 1 {
+		c.next = (c.next + 1) % len(c.candidates)
+	} else {
+		c.candidates = c.Candidates(query, 10)
+		c.next = 0
+	}
+	if len(c.candidates) == 0 {
+		return "", 0, false
+	}
+
+	c.last = "@" + c.candidates[c.next]
+	newLine := line[:start] + c.last + line[pos:]
+	return newLine, start + len(c.last), true
+}
@@ -0,0 +1,55 @@
+package input
+
+import (
+	"os"
+	"strings"
+)
+
+// Terminal focus reporting: while it is on, the terminal sends focusIn
+// and focusOut as its window gains and loses focus.
+const (
+	focusOn  = "\033[?1004h"
+	focusOff = "\033[?1004l"
+	focusIn  = "\033[I"
+	focusOut = "\033[O"
+)
+
+// Focused reports whether the terminal window has focus while a turn
+// runs, and whether that is known at all: focus is only tracked while
+// typed lines are queued, and only by terminals that report it. A turn
+// starts, and a confirmation ends, with the user at the keyboard, so the
+// window counts as focused until the terminal says otherwise.
+func (r *Reader) Focused() (focused, known bool) {
+	if r.terminal == nil || !canPoll {
+		return false, false
+	}
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	return !r.unfocused, true
+}
+
+func (r *Reader) trackFocus(on bool) {
+	if on {
+		r.queueMu.Lock()
+		r.unfocused = false
+		r.queueMu.Unlock()
+		os.Stdout.WriteString(focusOn)
+	} else {
+		os.Stdout.WriteString(focusOff)
+	}
+}
+
+// takeFocusEvents removes focus reports from input read while queueing
+// and records the last one.
+func (r *Reader) takeFocusEvents(b []byte) []byte {
+	s := string(b)
+	in, out := strings.LastIndex(s, focusIn), strings.LastIndex(s, focusOut)
+	if in < 0 && out < 0 {
+		return b
+	}
+	r.queueMu.Lock()
+	r.unfocused = out > in
+	r.queueMu.Unlock()
+	s = strings.ReplaceAll(strings.ReplaceAll(s, focusIn, ""), focusOut, "")
+	return []byte(s)
+}
@@ -0,0 +1,73 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxMentionSize caps the size of a file attached with @path.
+const maxMentionSize = 256 * 1024
+
+var mentionRe = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// Attachment is a file pulled into a prompt by an @mention.
+type Attachment struct {
+	Path string
+	Size int
+}
+
+// ExpandMentions finds @path references to files under workDir and returns
+// the prompt preceded by a <file> context block per file. Mentions that
+// don't name a readable text file (e.g. @decorators) are left alone.
+func ExpandMentions(text, workDir string) (string, []Attachment) {
+	var blocks strings.Builder
+	var attached []Attachment
+	seen := make(map[string]bool)
+
+	for _, m := range mentionRe.FindAllStringSubmatch(text, -1) {
+		rel, data, ok := resolveMention(m[2], workDir)
+		if !ok || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		fmt.Fprintf(&blocks, "<file path=%q>\n%s", rel, data)
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			blocks.WriteString("\n")
+		}
+		blocks.WriteString("</file>\n\n")
+		attached = append(attached, Attachment{Path: rel, Size: len(data)})
+	}
+	if len(attached) == 0 {
+		return text, nil
+	}
+	return blocks.String() + text, attached
+}
+
+// resolveMention reads the file a mention refers to, retrying without
+// trailing punctuation so "see @main.go." still works.
+func resolveMention(ref, workDir string) (string, []byte, bool) {
+	for _, cand := range []string{ref, strings.TrimRight(ref, ".,;:!?)]}'\"")} {
+		p := cand
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(workDir, p)
+		}
+		info, err := os.Stat(p)
+		if err != nil || info.IsDir() || info.Size() > maxMentionSize {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+		rel, err := filepath.Rel(workDir, p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = p
+		}
+		return filepath.ToSlash(rel), data, true
+	}
+	return "", nil, false
+}
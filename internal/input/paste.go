@@ -0,0 +1,138 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Bracketed paste: while it is on, the terminal wraps pasted text in
+// pasteStart and pasteEnd, so a paste can be told apart from typing.
+const (
+	pasteOn    = "\033[?2004h"
+	pasteOff   = "\033[?2004l"
+	pasteStart = "\033[200~"
+	pasteEnd   = "\033[201~"
+)
+
+// pasteReader sits between stdin and the line editor. A paste of several
+// lines reaches the editor as a placeholder such as "[pasted 84 lines]",
+// so its newlines do not submit the line; Expand puts the text back once
+// the line is done. A one-line paste is passed on as typed text.
+type pasteReader struct {
+	in io.Reader
+
+	// pending is input not yet handed to the editor.
+	pending []byte
+	// pasting holds the text of a paste whose end has not arrived yet.
+	pasting *bytes.Buffer
+	// held is the start of a marker the last read ended in.
+	held []byte
+
+	mu sync.Mutex
+	// pastes maps placeholders to the text they stand for. They are kept
+	// for the whole session so lines recalled from history expand too.
+	pastes map[string]string
+}
+
+func newPasteReader(in io.Reader) *pasteReader {
+	return &pasteReader{in: in, pastes: make(map[string]string)}
+}
+
+func (p *pasteReader) Read(b []byte) (int, error) {
+	for len(p.pending) == 0 {
+		buf := make([]byte, len(b))
+		n, err := p.in.Read(buf)
+		p.pending = p.filter(buf[:n])
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// filter takes the paste markers out of data, with the text between them
+// collected until the end marker arrives. A marker split across reads is
+// kept for the next one.
+func (p *pasteReader) filter(data []byte) []byte {
+	var out []byte
+	data = append(p.held, data...)
+	p.held = nil
+	for len(data) > 0 {
+		if p.pasting != nil {
+			i := bytes.Index(data, []byte(pasteEnd))
+			if i < 0 {
+				keep := partialMarker(data, pasteEnd)
+				p.pasting.Write(data[:len(data)-keep])
+				p.held = append([]byte(nil), data[len(data)-keep:]...)
+				return out
+			}
+			p.pasting.Write(data[:i])
+			out = append(out, p.paste(p.pasting.String())...)
+			p.pasting = nil
+			data = data[i+len(pasteEnd):]
+			continue
+		}
+		i := bytes.Index(data, []byte(pasteStart))
+		if i < 0 {
+			keep := partialMarker(data, pasteStart)
+			out = append(out, data[:len(data)-keep]...)
+			p.held = append([]byte(nil), data[len(data)-keep:]...)
+			return out
+		}
+		out = append(out, data[:i]...)
+		p.pasting = new(bytes.Buffer)
+		data = data[i+len(pasteStart):]
+	}
+	return out
+}
+
+// partialMarker returns how many bytes at the end of data could be the
+// start of marker.
+func partialMarker(data []byte, marker string) int {
+	for n := len(marker) - 1; n > 0; n-- {
+		if len(data) >= n && bytes.HasSuffix(data, []byte(marker[:n])) {
+			return n
+		}
+	}
+	return 0
+}
+
+// paste returns what the editor gets for a paste of text.
+func (p *pasteReader) paste(text string) []byte {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	text = strings.TrimRight(text, "\n")
+	if !strings.Contains(text, "\n") {
+		// Tabs would start completion.
+		return []byte(strings.ReplaceAll(text, "\t", "    "))
+	}
+	lines := strings.Count(text, "\n") + 1
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	placeholder := fmt.Sprintf("[pasted %d lines]", lines)
+	for n := 2; ; n++ {
+		if _, taken := p.pastes[placeholder]; !taken {
+			break
+		}
+		placeholder = fmt.Sprintf("[pasted %d lines #%d]", lines, n)
+	}
+	p.pastes[placeholder] = text
+	return []byte(placeholder)
+}
+
+// Expand replaces the placeholders in line with the text pasted.
+func (p *pasteReader) Expand(line string) string {
+	if !strings.Contains(line, "[pasted ") {
+		return line
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for placeholder, text := range p.pastes {
+		line = strings.ReplaceAll(line, placeholder, text)
+	}
+	return line
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package input
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const canPoll = true
+
+// readable waits up to timeout for input on fd.
+func readable(fd int, timeout time.Duration) bool {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout/time.Millisecond))
+	return err == nil && n > 0 && fds[0].Revents&unix.POLLIN != 0
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package input
+
+import "time"
+
+// The console cannot be polled without taking the input, so lines are not
+// queued on Windows.
+const canPoll = false
+
+func readable(fd int, timeout time.Duration) bool {
+	return false
+}
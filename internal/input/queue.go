@@ -0,0 +1,164 @@
+package input
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// queuePoll is how often the queue checks for typed input, and so how
+// long StopQueue may wait.
+const queuePoll = 100 * time.Millisecond
+
+// StartQueue collects lines typed while the agent works on a turn; call it
+// when a turn starts. The terminal stays in its normal line mode, so
+// typing echoes as usual and a line is queued when Enter is pressed.
+// ReadLine returns queued lines before reading new ones. A line that
+// starts with Esc steers the turn instead (see Steering); Esc alone turns
+// the lines already queued into steering. It has no effect without a
+// terminal, where input is buffered anyway.
+func (r *Reader) StartQueue() {
+	if r.terminal == nil || !canPoll || r.stopQueue != nil {
+		return
+	}
+	r.stopQueue, r.queueDone = make(chan struct{}), make(chan struct{})
+	r.trackFocus(true)
+	go r.collect(r.stopQueue, r.queueDone)
+}
+
+// StopQueue stops collecting typed lines; they stay queued.
+func (r *Reader) StopQueue() {
+	if r.stopQueue == nil {
+		return
+	}
+	close(r.stopQueue)
+	<-r.queueDone
+	r.trackFocus(false)
+	r.stopQueue, r.queueDone = nil, nil
+}
+
+// Queued returns the number of lines waiting to be sent.
+func (r *Reader) Queued() int {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	return len(r.steering) + len(r.queued)
+}
+
+// ClearQueue drops the queued lines and returns how many there were.
+func (r *Reader) ClearQueue() int {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	n := len(r.steering) + len(r.queued)
+	r.steering, r.queued = nil, nil
+	return n
+}
+
+// Steering reports whether lines typed after Esc are waiting to redirect
+// the running turn.
+func (r *Reader) Steering() bool {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	return len(r.steering) > 0
+}
+
+// TakeSteering returns the steering lines and clears them. Lines the turn
+// did not take are returned by ReadLine as the next prompt instead.
+func (r *Reader) TakeSteering() []string {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	lines := r.steering
+	r.steering = nil
+	return lines
+}
+
+func (r *Reader) nextQueued() (string, bool) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	if len(r.steering) > 0 {
+		line := strings.Join(r.steering, "\n")
+		r.steering = nil
+		return line, true
+	}
+	if len(r.queued) == 0 {
+		return "", false
+	}
+	line := r.queued[0]
+	r.queued = r.queued[1:]
+	return line, true
+}
+
+// pauseQueue stops collecting while a prompt reads its answer from the
+// terminal, and returns the function that resumes it.
+func (r *Reader) pauseQueue() func() {
+	if r.stopQueue == nil {
+		return func() {}
+	}
+	r.StopQueue()
+	return r.StartQueue
+}
+
+// collect reads stdin only when input is waiting, so once stopped it has
+// not taken any bytes meant for the prompt or a confirmation.
+func (r *Reader) collect(stop, done chan struct{}) {
+	defer close(done)
+	var partial []byte
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-stop:
+			if line := strings.TrimSpace(string(partial)); line != "" {
+				r.enqueue(line)
+			}
+			return
+		default:
+		}
+		if !readable(r.fd, queuePoll) {
+			continue
+		}
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		partial = r.takeFocusEvents(append(partial, buf[:n]...))
+		for {
+			i := strings.IndexByte(string(partial), '\n')
+			if i < 0 {
+				break
+			}
+			line := strings.TrimRight(string(partial[:i]), "\r")
+			partial = partial[i+1:]
+			if strings.HasPrefix(line, "\x1b") {
+				r.steerWith(strings.TrimSpace(strings.TrimLeft(line, "\x1b")))
+			} else if strings.TrimSpace(line) != "" {
+				r.enqueue(line)
+			}
+		}
+	}
+}
+
+// steerWith makes line, or the lines queued so far when it is empty,
+// steering for the running turn.
+func (r *Reader) steerWith(line string) {
+	r.queueMu.Lock()
+	if line != "" {
+		r.steering = append(r.steering, line)
+	} else {
+		r.steering = append(r.steering, r.queued...)
+		r.queued = nil
+	}
+	steering := len(r.steering) > 0
+	r.queueMu.Unlock()
+	if steering {
+		display.SteeringQueued()
+	}
+}
+
+func (r *Reader) enqueue(line string) {
+	r.queueMu.Lock()
+	r.queued = append(r.queued, line)
+	n := len(r.queued)
+	r.queueMu.Unlock()
+	display.QueuedInput(n)
+}
@@ -0,0 +1,123 @@
+package input
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Reader reads prompt lines with line editing, history and @mention
+// completion when stdin is a terminal, and plain lines otherwise.
+type Reader struct {
+	fd        int
+	terminal  *term.Terminal
+	paste     *pasteReader
+	plain     *bufio.Reader
+	completer *Completer
+	bindings  map[rune]func() string
+	prompt    string
+
+	// Lines typed during a turn; see StartQueue.
+	queueMu   sync.Mutex
+	queued    []string
+	steering  []string
+	stopQueue chan struct{}
+	queueDone chan struct{}
+	// unfocused is set once the terminal reports losing focus.
+	unfocused bool
+}
+
+// Keys with a binding in the prompt: KeyCtrlO toggles the reasoning
+// display and KeyCtrlR expands the last cut-short tool result.
+const (
+	KeyCtrlO rune = 0x0f
+	KeyCtrlR rune = 0x12
+)
+
+func NewReader(prompt string, completer *Completer) *Reader {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return &Reader{fd: fd, plain: bufio.NewReader(os.Stdin)}
+	}
+	paste := newPasteReader(os.Stdin)
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{paste, os.Stdout}, prompt)
+	r := &Reader{fd: fd, terminal: t, paste: paste, completer: completer, bindings: make(map[rune]func() string), prompt: prompt}
+	t.AutoCompleteCallback = r.handleKey
+	display.SetInputPause(r.pauseQueue)
+	return r
+}
+
+// Bind runs fn when key is pressed while a line is being edited and prints
+// what it returns above the prompt. It has no effect without a terminal.
+func (r *Reader) Bind(key rune, fn func() string) {
+	if r.terminal != nil {
+		r.bindings[key] = fn
+	}
+}
+
+func (r *Reader) handleKey(line string, pos int, key rune) (string, int, bool) {
+	if fn, ok := r.bindings[key]; ok {
+		if out := fn(); out != "" {
+			// The terminal is locked while it handles a key; the write
+			// goes through once it is back to waiting for input, and
+			// redraws the prompt and the line being edited.
+			go r.terminal.Write([]byte(out + "\n"))
+		}
+		return line, pos, true
+	}
+	if r.completer != nil {
+		return r.completer.AutoComplete(line, pos, key)
+	}
+	return "", 0, false
+}
+
+// ReadLine returns the next line without its newline: the oldest line
+// queued during the last turn, or else one read from the terminal. The
+// terminal is only in raw mode while reading, so tool output prints
+// normally. Text pasted while reading is part of the line, newlines and
+// all, and shows as a "[pasted N lines]" placeholder until it is sent.
+func (r *Reader) ReadLine() (string, error) {
+	if r.terminal == nil {
+		line, err := r.plain.ReadString('\n')
+		if line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	r.StopQueue()
+	if line, ok := r.nextQueued(); ok {
+		os.Stdout.WriteString(r.prompt + line + "\n")
+		return line, nil
+	}
+
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(r.fd, state)
+	os.Stdout.WriteString(pasteOn)
+	defer os.Stdout.WriteString(pasteOff)
+	if w, h, err := term.GetSize(r.fd); err == nil {
+		r.terminal.SetSize(w, h)
+	}
+	line, err := r.terminal.ReadLine()
+	return r.paste.Expand(line), err
+}
+
+// SetPrompt changes the prompt shown by the next ReadLine.
+func (r *Reader) SetPrompt(prompt string) {
+	if r.terminal != nil {
+		r.terminal.SetPrompt(prompt)
+		r.prompt = prompt
+	}
+}
@@ -0,0 +1,253 @@
+// Package jsondiff compares decoded JSON values structurally, so key order
+// and formatting do not count as changes.
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Change is one difference at a path such as $.items[2].price.
+type Change struct {
+	Path string
+	Kind Kind
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, brief(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, brief(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s → %s", c.Path, brief(c.Old), brief(c.New))
+	}
+}
+
+// Diff returns the changes from a to b, skipping paths that match an
+// ignore pattern. Patterns are paths where * matches any one key or index,
+// e.g. $.data[*].updated_at; a leading "$" is optional.
+func Diff(a, b interface{}, ignore []string) []Change {
+	d := &differ{ignore: compile(ignore)}
+	d.walk("$", a, b)
+	return d.changes
+}
+
+type differ struct {
+	ignore  [][]string
+	changes []Change
+}
+
+func (d *differ) walk(path string, a, b interface{}) {
+	if d.ignored(path) {
+		return
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			d.add(path, Changed, a, b)
+			return
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + key(k)
+			old, inA := av[k]
+			nw, inB := bv[k]
+			switch {
+			case !inB:
+				if !d.ignored(p) {
+					d.add(p, Removed, old, nil)
+				}
+			case !inA:
+				if !d.ignored(p) {
+					d.add(p, Added, nil, nw)
+				}
+			default:
+				d.walk(p, old, nw)
+			}
+		}
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			d.add(path, Changed, a, b)
+			return
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(bv):
+				if !d.ignored(p) {
+					d.add(p, Removed, av[i], nil)
+				}
+			case i >= len(av):
+				if !d.ignored(p) {
+					d.add(p, Added, nil, bv[i])
+				}
+			default:
+				d.walk(p, av[i], bv[i])
+			}
+		}
+	default:
+		if !scalarEqual(a, b) {
+			d.add(path, Changed, a, b)
+		}
+	}
+}
+
+func (d *differ) add(path string, kind Kind, old, nw interface{}) {
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, Old: old, New: nw})
+}
+
+func scalarEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if av == bv {
+			return true
+		}
+		af, errA := av.Float64()
+		bf, errB := bv.Float64()
+		return errA == nil && errB == nil && af == bf
+	default:
+		return a == b
+	}
+}
+
+func key(k string) string {
+	for i, r := range k {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return "[" + strconv.Quote(k) + "]"
+		}
+	}
+	if k == "" {
+		return `[""]`
+	}
+	return "." + k
+}
+
+// compile splits patterns into segments: keys, [n] indexes and *.
+func compile(patterns []string) [][]string {
+	var out [][]string
+	for _, p := range patterns {
+		p = strings.TrimPrefix(strings.TrimSpace(p), "$")
+		if p == "" {
+			continue
+		}
+		out = append(out, segments(p))
+	}
+	return out
+}
+
+func segments(p string) []string {
+	var segs []string
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, "."):
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			segs = append(segs, p[:end])
+			p = p[end:]
+		case strings.HasPrefix(p, "["):
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return append(segs, p[1:])
+			}
+			seg := p[1:end]
+			if uq, err := strconv.Unquote(seg); err == nil {
+				seg = uq
+			}
+			segs = append(segs, seg)
+			p = p[end+1:]
+		default:
+			// A bare first key, as in "data.id".
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			segs = append(segs, p[:end])
+			p = p[end:]
+		}
+	}
+	return segs
+}
+
+// ignored reports whether path matches a pattern exactly or lies beneath
+// one.
+func (d *differ) ignored(path string) bool {
+	if len(d.ignore) == 0 {
+		return false
+	}
+	segs := segments(strings.TrimPrefix(path, "$"))
+	for _, pat := range d.ignore {
+		if len(pat) > len(segs) {
+			continue
+		}
+		match := true
+		for i, p := range pat {
+			if p != "*" && p != segs[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// brief renders a value on one line, shortened for reports.
+func brief(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
+
+// Parse decodes JSON keeping numbers exact so large integers and IDs
+// compare reliably.
+func Parse(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,25 @@
+// Package keychain stores secrets in the operating system's credential
+// store: the macOS Keychain, the Windows Credential Manager or a Secret
+// Service such as GNOME Keyring through libsecret's secret-tool.
+package keychain
+
+import "errors"
+
+// Service is the name entries are stored under.
+const Service = "apipod-cli"
+
+var ErrNotFound = errors.New("not found in keychain")
+
+// Store is a credential backend.
+type Store interface {
+	Name() string
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+// Default returns the platform's credential store, or nil when none is
+// usable, e.g. on a headless Linux machine without a Secret Service.
+func Default() Store {
+	return platformStore()
+}
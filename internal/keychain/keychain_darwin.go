@@ -0,0 +1,47 @@
+//go:build darwin
+
+package keychain
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type macKeychain struct{}
+
+func platformStore() Store {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil
+	}
+	return macKeychain{}
+}
+
+func (macKeychain) Name() string { return "macOS Keychain" }
+
+func (macKeychain) Get(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", Service, "-a", account, "-w").Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 44 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("read keychain: %w", err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (macKeychain) Set(account, secret string) error {
+	// -U updates an existing entry instead of failing.
+	if out, err := exec.Command("security", "add-generic-password", "-U", "-s", Service, "-a", account, "-w", secret).CombinedOutput(); err != nil {
+		return fmt.Errorf("write keychain: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (macKeychain) Delete(account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", Service, "-a", account).Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 44 {
+		return nil
+	}
+	return err
+}
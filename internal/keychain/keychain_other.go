@@ -0,0 +1,57 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// secretService talks to a Secret Service (GNOME Keyring, KWallet) through
+// libsecret's secret-tool.
+type secretService struct{}
+
+func platformStore() Store {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	return secretService{}
+}
+
+func (secretService) Name() string { return "Secret Service" }
+
+func (secretService) Get(account string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", Service, "account", account)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// lookup exits 1 without output when nothing matches.
+		if stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("read keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func (secretService) Set(account, secret string) error {
+	// The secret goes through stdin so it never shows up in the process
+	// list.
+	cmd := exec.Command("secret-tool", "store", "--label", Service+" ("+account+")", "service", Service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("write keychain: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (secretService) Delete(account string) error {
+	return exec.Command("secret-tool", "clear", "service", Service, "account", account).Run()
+}
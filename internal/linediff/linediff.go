@@ -0,0 +1,209 @@
+// Package linediff computes line diffs and groups them into hunks that can
+// be accepted or rejected one by one.
+package linediff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind byte
+
+const (
+	Equal  Kind = ' '
+	Delete Kind = '-'
+	Insert Kind = '+'
+)
+
+// Line is one line of an edit script.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// maxEdits bounds the search; beyond it the differing middle is reported as
+// one replacement, which is still correct but less precise.
+const maxEdits = 2000
+
+// Diff returns the edit script turning a into b, using Myers' algorithm on
+// the part between the common prefix and suffix.
+func Diff(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out := make([]Line, 0, len(a)+len(b)-prefix-suffix)
+	for _, t := range a[:prefix] {
+		out = append(out, Line{Equal, t})
+	}
+	out = append(out, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, t := range a[len(a)-suffix:] {
+		out = append(out, Line{Equal, t})
+	}
+	return out
+}
+
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEdits)
+	off := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] holds v[-d-1..d+1] as it was before step d.
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[off+k-1] < v[off+k+1] {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	out := make([]Line, 0, n+m)
+	for _, t := range a {
+		out = append(out, Line{Delete, t})
+	}
+	for _, t := range b {
+		out = append(out, Line{Insert, t})
+	}
+	return out
+}
+
+func backtrack(a, b []string, trace [][]int) []Line {
+	var rev []Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, Line{Equal, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				rev = append(rev, Line{Insert, b[prevY]})
+			} else {
+				rev = append(rev, Line{Delete, a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev
+}
+
+// Hunk is a run of changes with surrounding context. OldStart and NewStart
+// are 0-based indexes of its first line in the old and new text.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Header returns the unified diff header, e.g. "@@ -12,7 +12,8 @@".
+func (h Hunk) Header() string {
+	oldStart, newStart := h.OldStart+1, h.NewStart+1
+	if h.OldLines == 0 {
+		oldStart--
+	}
+	if h.NewLines == 0 {
+		newStart--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, h.OldLines, newStart, h.NewLines)
+}
+
+// Hunks groups an edit script into hunks with up to context unchanged lines
+// around each change; changes separated by at most twice that share a hunk.
+func Hunks(script []Line, context int) []Hunk {
+	oldAt := make([]int, len(script)+1)
+	newAt := make([]int, len(script)+1)
+	for i, l := range script {
+		oldAt[i+1], newAt[i+1] = oldAt[i], newAt[i]
+		if l.Kind != Insert {
+			oldAt[i+1]++
+		}
+		if l.Kind != Delete {
+			newAt[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(script); i++ {
+		if script[i].Kind == Equal {
+			continue
+		}
+		start := max(i-context, 0)
+		end := i + 1
+		for j := i + 1; j < len(script); j++ {
+			if script[j].Kind != Equal {
+				end = j + 1
+			} else if j-end+1 > 2*context {
+				break
+			}
+		}
+		stop := min(end+context, len(script))
+		hunks = append(hunks, Hunk{
+			OldStart: oldAt[start],
+			OldLines: oldAt[stop] - oldAt[start],
+			NewStart: newAt[start],
+			NewLines: newAt[stop] - newAt[start],
+			Lines:    script[start:stop],
+		})
+		i = end - 1
+	}
+	return hunks
+}
+
+// Apply rebuilds the new text from a, taking the changes of accepted hunks
+// and keeping the original lines of the rejected ones.
+func Apply(a []string, hunks []Hunk, accept []bool) []string {
+	out := make([]string, 0, len(a))
+	pos := 0
+	for i, h := range hunks {
+		out = append(out, a[pos:h.OldStart]...)
+		for _, l := range h.Lines {
+			if l.Kind == Equal || l.Kind == Delete && !accept[i] || l.Kind == Insert && accept[i] {
+				out = append(out, l.Text)
+			}
+		}
+		pos = h.OldStart + h.OldLines
+	}
+	return append(out, a[pos:]...)
+}
+
+// Split breaks text into lines that keep their line endings, so joining
+// them restores the text exactly, a missing final newline included.
+func Split(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
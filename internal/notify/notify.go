@@ -0,0 +1,64 @@
+// Package notify gets the user's attention when apipod-cli is in the
+// background: with a desktop notification (osascript on macOS,
+// notify-send on Linux, a toast on Windows) or the terminal bell.
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Methods of getting attention.
+const (
+	// Auto sends a desktop notification, or rings the bell when none can
+	// be sent.
+	Auto    = "auto"
+	Desktop = "desktop"
+	Bell    = "bell"
+	Off     = "off"
+)
+
+// Title heads every desktop notification.
+const Title = "apipod-cli"
+
+// Send shows message with method. It does not wait for the notification
+// to be dismissed.
+func Send(method, message string) error {
+	switch method {
+	case Off:
+		return nil
+	case Bell, "":
+		return ring()
+	case Desktop:
+		return desktop(Title, message)
+	case Auto:
+		if err := desktop(Title, message); err != nil {
+			return ring()
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown notification method %q", method)
+	}
+}
+
+// ring writes the bell to stderr, which is still the terminal while
+// stdout is mirrored elsewhere.
+func ring() error {
+	_, err := os.Stderr.WriteString("\a")
+	return err
+}
+
+// start runs a notifier without waiting for it.
+func start(name string, args ...string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	go cmd.Wait()
+	return nil
+}
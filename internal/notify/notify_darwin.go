@@ -0,0 +1,16 @@
+//go:build darwin
+
+package notify
+
+import "strings"
+
+func desktop(title, message string) error {
+	script := "display notification " + appleString(message) + " with title " + appleString(title)
+	return start("osascript", "-e", script)
+}
+
+// appleString quotes s as an AppleScript string literal.
+func appleString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\r", " ").Replace(s)
+	return `"` + s + `"`
+}
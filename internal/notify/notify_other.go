@@ -0,0 +1,15 @@
+//go:build !darwin && !windows
+
+package notify
+
+import (
+	"fmt"
+	"os"
+)
+
+func desktop(title, message string) error {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return fmt.Errorf("no graphical session")
+	}
+	return start("notify-send", "--app-name="+title, title, message)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package notify
+
+import (
+	"os"
+	"os/exec"
+)
+
+// toastScript shows a toast through the Windows Runtime. The text comes
+// from the environment so it needs no quoting.
+const toastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:APIPOD_NOTIFY_TITLE)) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode($env:APIPOD_NOTIFY_MESSAGE)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('apipod-cli').Show($toast)`
+
+func desktop(title, message string) error {
+	path, err := exec.LookPath("powershell")
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "APIPOD_NOTIFY_TITLE="+title, "APIPOD_NOTIFY_MESSAGE="+message)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
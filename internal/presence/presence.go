@@ -0,0 +1,272 @@
+// Package presence mirrors a session's terminal output to teammates who
+// attach to it read-only from another terminal on the same machine.
+package presence
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/config"
+)
+
+const (
+	socketSuffix = ".sock"
+	// backlogSize is how much recent output a new watcher is shown first.
+	backlogSize = 64 << 10
+	// watcherQueue chunks may wait for a slow watcher before it is dropped,
+	// so a stalled watcher never holds up the session.
+	watcherQueue = 1024
+	writeTimeout = 5 * time.Second
+	dialTimeout  = 200 * time.Millisecond
+)
+
+// Dir holds one socket per shared session.
+func Dir() string {
+	return filepath.Join(filepath.Dir(config.ConfigPath()), "presence")
+}
+
+func socketPath(id string) string {
+	return filepath.Join(Dir(), id+socketSuffix)
+}
+
+// Broadcaster tees everything written to os.Stdout to the terminal and to
+// every attached watcher.
+type Broadcaster struct {
+	id   string
+	ln   net.Listener
+	term *os.File
+	pr   *os.File
+	pw   *os.File
+	done chan struct{}
+
+	mu       sync.Mutex
+	watchers map[net.Conn]chan []byte
+	backlog  []byte
+}
+
+// Share starts mirroring the output of session id. Until Close, os.Stdout
+// is a pipe; code that hands the terminal to another program must Pause.
+func Share(id string) (*Broadcaster, error) {
+	if err := os.MkdirAll(Dir(), 0o700); err != nil {
+		return nil, fmt.Errorf("create presence dir: %w", err)
+	}
+	path := socketPath(id)
+	os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	os.Chmod(path, 0o600)
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	b := &Broadcaster{
+		id:       id,
+		ln:       ln,
+		term:     os.Stdout,
+		pr:       pr,
+		pw:       pw,
+		done:     make(chan struct{}),
+		watchers: make(map[net.Conn]chan []byte),
+	}
+	os.Stdout = pw
+	go b.pump()
+	go b.accept()
+	return b, nil
+}
+
+// pump copies the session's output to the terminal and the watchers.
+func (b *Broadcaster) pump() {
+	defer close(b.done)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := b.pr.Read(buf)
+		if n > 0 {
+			b.term.Write(buf[:n])
+			b.broadcast(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (b *Broadcaster) broadcast(p []byte) {
+	chunk := append([]byte(nil), p...)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.backlog = append(b.backlog, chunk...)
+	if len(b.backlog) > backlogSize {
+		b.backlog = append([]byte(nil), b.backlog[len(b.backlog)-backlogSize:]...)
+	}
+	for conn, ch := range b.watchers {
+		select {
+		case ch <- chunk:
+		default:
+			delete(b.watchers, conn)
+			close(ch)
+		}
+	}
+}
+
+func (b *Broadcaster) accept() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		ch := make(chan []byte, watcherQueue)
+		b.mu.Lock()
+		backlog := append([]byte(nil), b.backlog...)
+		b.watchers[conn] = ch
+		b.mu.Unlock()
+		go b.serve(conn, ch, backlog)
+	}
+}
+
+func (b *Broadcaster) serve(conn net.Conn, ch chan []byte, backlog []byte) {
+	defer conn.Close()
+	write := func(p []byte) bool {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		_, err := conn.Write(p)
+		return err == nil
+	}
+	if !write(backlog) {
+		b.drop(conn)
+		return
+	}
+	for p := range ch {
+		if !write(p) {
+			b.drop(conn)
+			return
+		}
+	}
+}
+
+func (b *Broadcaster) drop(conn net.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ch, ok := b.watchers[conn]; ok {
+		delete(b.watchers, conn)
+		close(ch)
+	}
+}
+
+// ID is the session being shared.
+func (b *Broadcaster) ID() string {
+	return b.id
+}
+
+// Watchers returns how many teammates are attached.
+func (b *Broadcaster) Watchers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.watchers)
+}
+
+// Annotate shows text to the watchers only, e.g. the prompt the driver
+// typed, which the terminal already shows.
+func (b *Broadcaster) Annotate(text string) {
+	b.broadcast([]byte(text + "\n"))
+}
+
+// Pause gives os.Stdout back to the terminal, for an editor or another
+// program that needs it, and returns the function that resumes sharing.
+// Output while paused is not mirrored.
+func (b *Broadcaster) Pause() func() {
+	os.Stdout = b.term
+	return func() { os.Stdout = b.pw }
+}
+
+// Close stops sharing, disconnects the watchers and restores os.Stdout.
+func (b *Broadcaster) Close() error {
+	os.Stdout = b.term
+	b.pw.Close()
+	<-b.done
+	b.pr.Close()
+	err := b.ln.Close()
+	os.Remove(socketPath(b.id))
+	b.mu.Lock()
+	for conn, ch := range b.watchers {
+		delete(b.watchers, conn)
+		close(ch)
+	}
+	b.mu.Unlock()
+	return err
+}
+
+// Active lists the IDs of the sessions being shared right now. Sockets
+// left behind by sessions that crashed are removed.
+func Active() ([]string, error) {
+	entries, err := os.ReadDir(Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), socketSuffix)
+		if !ok {
+			continue
+		}
+		conn, err := net.DialTimeout("unix", socketPath(id), dialTimeout)
+		if err != nil {
+			os.Remove(socketPath(id))
+			continue
+		}
+		conn.Close()
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Resolve finds the shared session an ID or unique ID prefix refers to;
+// "" matches the only shared session.
+func Resolve(id string) (string, error) {
+	ids, err := Active()
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, candidate := range ids {
+		if strings.HasPrefix(candidate, id) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(ids) == 0:
+		return "", fmt.Errorf("no session is being shared; start one with /pair")
+	case len(matches) == 0:
+		return "", fmt.Errorf("no shared session %q (shared: %s)", id, strings.Join(ids, ", "))
+	default:
+		return "", fmt.Errorf("several sessions are shared (%s); give an ID", strings.Join(matches, ", "))
+	}
+}
+
+// Attach copies the output of a shared session to out until the session
+// stops sharing. Nothing is sent back: watching is read-only.
+func Attach(id string, out io.Writer) error {
+	conn, err := net.DialTimeout("unix", socketPath(id), dialTimeout)
+	if err != nil {
+		return fmt.Errorf("attach to %s: %w", id, err)
+	}
+	defer conn.Close()
+	if _, err := io.Copy(out, conn); err != nil {
+		return fmt.Errorf("attach to %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+// Package project works out what kind of project a directory holds: its
+// language, toolchain and the commands that build, test and lint it, so
+// the model runs the right ones on the first try.
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/ignore"
+	"github.com/rpay/apipod-cli/internal/index"
+)
+
+// maxCounted bounds the files looked at to find the primary language.
+const maxCounted = 5000
+
+// Stack is a toolchain found through its manifest.
+type Stack struct {
+	Manifest string
+	// Name is the module or package name the manifest declares.
+	Name     string
+	Language string
+	// Tool is the package manager or build tool, e.g. pnpm or poetry.
+	Tool string
+	// Version is the language or toolchain version the manifest asks
+	// for, when it says.
+	Version string
+	Build   string
+	Test    string
+	Lint    string
+}
+
+// Profile describes a project.
+type Profile struct {
+	Root string
+	// Language is the primary language, the one most source files are in.
+	Language string
+	Stacks   []Stack
+	// MakeTargets are the usual targets the Makefile defines.
+	MakeTargets []string
+}
+
+// Detect walks up from dir to the first directory with a manifest, not
+// past the repository root, and returns nil when there is none.
+func Detect(dir string) *Profile {
+	dir, _ = filepath.Abs(dir)
+	for {
+		if p := detectAt(dir); p != nil {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func detectAt(dir string) *Profile {
+	p := &Profile{Root: dir}
+	for _, detect := range []func(string) *Stack{goStack, rustStack, nodeStack, pythonStack} {
+		if s := detect(dir); s != nil {
+			p.Stacks = append(p.Stacks, *s)
+		}
+	}
+	if len(p.Stacks) == 0 {
+		return nil
+	}
+	p.MakeTargets = makeTargets(filepath.Join(dir, "Makefile"))
+	p.Language = primaryLanguage(dir)
+	if p.Language == "" {
+		p.Language = p.Stacks[0].Language
+	}
+	// The primary language's toolchain comes first.
+	sort.SliceStable(p.Stacks, func(i, j int) bool {
+		return p.Stacks[i].Language == p.Language && p.Stacks[j].Language != p.Language
+	})
+	return p
+}
+
+// Commands returns the stack's commands as "build", "test" and "lint"
+// pairs, leaving out those it has none for.
+func (s Stack) Commands() [][2]string {
+	var out [][2]string
+	for _, c := range [][2]string{{"build", s.Build}, {"test", s.Test}, {"lint", s.Lint}} {
+		if c[1] != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+var (
+	goModule  = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	goVersion = regexp.MustCompile(`(?m)^go\s+(\S+)`)
+)
+
+func goStack(dir string) *Stack {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	s := &Stack{Manifest: "go.mod", Language: "go", Tool: "go", Build: "go build ./...", Test: "go test ./...", Lint: "go vet ./..."}
+	if m := goModule.FindSubmatch(data); m != nil {
+		s.Name = string(m[1])
+	}
+	if m := goVersion.FindSubmatch(data); m != nil {
+		s.Version = string(m[1])
+	}
+	if exists(dir, ".golangci.yml", ".golangci.yaml", ".golangci.toml") {
+		s.Lint = "golangci-lint run"
+	}
+	return s
+}
+
+var (
+	rustVersion = regexp.MustCompile(`(?m)^rust-version\s*=\s*"([^"]+)"`)
+	// tomlName is the first name key, the package's in Cargo.toml and the
+	// project's in pyproject.toml.
+	tomlName = regexp.MustCompile(`(?m)^name\s*=\s*"([^"]+)"`)
+)
+
+func rustStack(dir string) *Stack {
+	data, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return nil
+	}
+	s := &Stack{Manifest: "Cargo.toml", Language: "rust", Tool: "cargo", Build: "cargo build", Test: "cargo test", Lint: "cargo clippy"}
+	if m := rustVersion.FindSubmatch(data); m != nil {
+		s.Version = string(m[1])
+	}
+	if m := tomlName.FindSubmatch(data); m != nil {
+		s.Name = string(m[1])
+	}
+	return s
+}
+
+// npmNoTest is the test script npm init writes, which only fails.
+const npmNoTest = `echo "Error: no test specified" && exit 1`
+
+func nodeStack(dir string) *Stack {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Name            string            `json:"name"`
+		PackageManager  string            `json:"packageManager"`
+		Scripts         map[string]string `json:"scripts"`
+		Engines         map[string]string `json:"engines"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	json.Unmarshal(data, &pkg)
+
+	tool := "npm"
+	switch {
+	case pkg.PackageManager != "":
+		tool, _, _ = strings.Cut(pkg.PackageManager, "@")
+	case exists(dir, "pnpm-lock.yaml"):
+		tool = "pnpm"
+	case exists(dir, "yarn.lock"):
+		tool = "yarn"
+	case exists(dir, "bun.lockb", "bun.lock"):
+		tool = "bun"
+	}
+	s := &Stack{Manifest: "package.json", Name: pkg.Name, Language: "javascript", Tool: tool, Version: pkg.Engines["node"]}
+	if _, ok := pkg.DevDependencies["typescript"]; ok || exists(dir, "tsconfig.json") {
+		s.Language = "typescript"
+	} else if _, ok := pkg.Dependencies["typescript"]; ok {
+		s.Language = "typescript"
+	}
+	run := func(script string) string {
+		if script == "test" && tool != "bun" {
+			return tool + " test"
+		}
+		return tool + " run " + script
+	}
+	if t := pkg.Scripts["test"]; t != "" && t != npmNoTest {
+		s.Test = run("test")
+	}
+	if pkg.Scripts["build"] != "" {
+		s.Build = run("build")
+	}
+	if pkg.Scripts["lint"] != "" {
+		s.Lint = run("lint")
+	}
+	return s
+}
+
+var pythonVersion = regexp.MustCompile(`(?m)^requires-python\s*=\s*"([^"]+)"`)
+
+func pythonStack(dir string) *Stack {
+	data, err := os.ReadFile(filepath.Join(dir, "pyproject.toml"))
+	if err != nil {
+		return nil
+	}
+	text := string(data)
+	s := &Stack{Manifest: "pyproject.toml", Language: "python", Tool: "pip"}
+	if m := pythonVersion.FindStringSubmatch(text); m != nil {
+		s.Version = m[1]
+	}
+	if m := tomlName.FindStringSubmatch(text); m != nil {
+		s.Name = m[1]
+	}
+	prefix := ""
+	switch {
+	case exists(dir, "uv.lock"):
+		s.Tool, prefix = "uv", "uv run "
+	case strings.Contains(text, "[tool.poetry]") || exists(dir, "poetry.lock"):
+		s.Tool, prefix = "poetry", "poetry run "
+		s.Build = "poetry build"
+	case strings.Contains(text, "[tool.hatch"):
+		s.Tool, prefix = "hatch", "hatch run "
+	}
+	if strings.Contains(text, "pytest") || exists(dir, "pytest.ini", "conftest.py") {
+		s.Test = prefix + "pytest"
+	} else if exists(dir, "tests") {
+		s.Test = prefix + "python -m unittest"
+	}
+	switch {
+	case strings.Contains(text, "ruff") || exists(dir, "ruff.toml", ".ruff.toml"):
+		s.Lint = prefix + "ruff check ."
+	case strings.Contains(text, "flake8") || exists(dir, ".flake8"):
+		s.Lint = prefix + "flake8"
+	}
+	return s
+}
+
+var makeTarget = regexp.MustCompile(`^([A-Za-z][\w-]*)\s*:([^=]|$)`)
+
+// usualTargets are the Makefile targets worth naming.
+var usualTargets = map[string]bool{
+	"build": true, "test": true, "check": true, "lint": true, "fmt": true,
+	"format": true, "run": true, "install": true, "generate": true, "ci": true,
+}
+
+func makeTargets(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var targets []string
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if m := makeTarget.FindStringSubmatch(sc.Text()); m != nil && usualTargets[m[1]] && !seen[m[1]] {
+			seen[m[1]] = true
+			targets = append(targets, m[1])
+		}
+	}
+	return targets
+}
+
+var errEnough = errors.New("enough files")
+
+// primaryLanguage is the language most source files under root are in,
+// leaving out ignored files and counting at most maxCounted.
+func primaryLanguage(root string) string {
+	counts := make(map[string]int)
+	seen := 0
+	ignored := ignore.New(root)
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if skip, err := ignored.Skip(p, d.IsDir()); skip {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if lang := index.Language(p); lang != "" && lang != "shell" && lang != "sql" && lang != "protobuf" {
+			counts[lang]++
+		}
+		if seen++; seen >= maxCounted {
+			return errEnough
+		}
+		return nil
+	})
+	lang, best := "", 0
+	for l, n := range counts {
+		if n > best || (n == best && l < lang) {
+			lang, best = l, n
+		}
+	}
+	return lang
+}
+
+func exists(dir string, names ...string) bool {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	// group, when non-zero, masks only that submatch so surrounding context
+	// such as the variable name stays readable.
+	group int
+	// keep, when set, leaves masked text it matches alone.
+	keep *regexp.Regexp
+}
+
+var builtin = []rule{
+	{name: "private-key", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{name: "aws-access-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-key", re: regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`), group: 1},
+	{name: "github-token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{name: "gitlab-token", re: regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}\b`)},
+	{name: "slack-token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{name: "stripe-key", re: regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{20,}\b`)},
+	{name: "google-api-key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{name: "anthropic-key", re: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_\-]{20,}\b`)},
+	{name: "openai-key", re: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_\-]{32,}\b`)},
+	{name: "apipod-key", re: regexp.MustCompile(`\bapk_[A-Za-z0-9_\-]{16,}\b`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}\b`)},
+	{name: "bearer-token", re: regexp.MustCompile(`(?i)\b(?:authorization:\s*)?bearer\s+([A-Za-z0-9._~+/\-]{20,}=*)`), group: 1},
+	{name: "url-credentials", re: regexp.MustCompile(`\b[a-z][a-z0-9+.\-]*://[^/\s:@]+:([^/\s@]+)@`), group: 1},
+	// env-secret matches KEY=value assignments as in .env files and shell
+	// scripts, where the key ends like a credential's name and the value is
+	// a literal rather than a ${VAR} or process.env reference.
+	{name: "env-secret", re: regexp.MustCompile(`(?m)^[ \t]*(?:export[ \t]+)?[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_KEY|APIKEY|PRIVATE_KEY|ACCESS_KEY|SECRET_KEY|CREDENTIALS)=["']?([^\s"'#$]{6,})["']?[ \t]*(?:#.*)?$`), group: 1,
+		keep: regexp.MustCompile(`^(?:process\.env|os\.environ|os\.Getenv|ENV\[)`)},
+}
+
+// Marker starts every mask Redact puts in text.
+const Marker = "[REDACTED:"
+
+// Redactor masks credentials in text before it leaves the machine or is
+// persisted.
+type Redactor struct {
+	rules []rule
+}
+
+// New returns a Redactor with the built-in rules plus custom regular
+// expressions; a custom pattern with a capture group masks only the group.
+func New(custom []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule(nil), builtin...)}
+	for i, p := range custom {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %d: %w", i+1, err)
+		}
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1
+		}
+		r.rules = append(r.rules, rule{name: "custom", re: re, group: group})
+	}
+	return r, nil
+}
+
+// Redact returns s with secrets replaced by [REDACTED:<kind>] and the number
+// of replacements made.
+func (r *Redactor) Redact(s string) (string, int) {
+	if r == nil || s == "" {
+		return s, 0
+	}
+	count := 0
+	for _, rl := range r.rules {
+		mask := Marker + rl.name + "]"
+		if rl.group == 0 {
+			s = rl.re.ReplaceAllStringFunc(s, func(string) string {
+				count++
+				return mask
+			})
+			continue
+		}
+		s = replaceGroup(s, rl, mask, &count)
+	}
+	return s, count
+}
+
+func replaceGroup(s string, rl rule, mask string, count *int) string {
+	matches := rl.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*rl.group], m[2*rl.group+1]
+		if start < 0 || strings.HasPrefix(s[start:end], Marker) || (rl.keep != nil && rl.keep.MatchString(s[start:end])) {
+			continue
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, mask...)
+		last = end
+		*count++
+	}
+	out = append(out, s[last:]...)
+	return string(out)
+}
@@ -0,0 +1,179 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/client"
+)
+
+const FormatVersion = 1
+
+// Header describes the run a recording was captured from.
+type Header struct {
+	Version   int       `json:"version"`
+	Model     string    `json:"model"`
+	WorkDir   string    `json:"work_dir"`
+	System    string    `json:"system"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ToolOutput struct {
+	ToolUseID string `json:"tool_use_id"`
+	Name      string `json:"name"`
+	Content   string `json:"content"`
+	IsError   bool   `json:"is_error,omitempty"`
+	Denied    bool   `json:"denied,omitempty"`
+}
+
+// Entry is one line of a recording file.
+type Entry struct {
+	Type     string                   `json:"type"`
+	Header   *Header                  `json:"header,omitempty"`
+	Prompt   string                   `json:"prompt,omitempty"`
+	Messages []client.Message         `json:"messages,omitempty"`
+	Response *client.MessagesResponse `json:"response,omitempty"`
+	Tool     *ToolOutput              `json:"tool,omitempty"`
+}
+
+// Recorder appends everything needed to re-execute a run to a JSONL file.
+type Recorder struct {
+	mu   sync.Mutex
+	f    *os.File
+	enc  *json.Encoder
+	sent int
+}
+
+func NewRecorder(path string, header Header) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+	r := &Recorder{f: f, enc: json.NewEncoder(f)}
+	header.Version = FormatVersion
+	if header.CreatedAt.IsZero() {
+		header.CreatedAt = time.Now().UTC()
+	}
+	if err := r.write(Entry{Type: "header", Header: &header}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) write(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) Prompt(text string) error {
+	return r.write(Entry{Type: "prompt", Prompt: text})
+}
+
+// Request records the messages added to the history since the previous request.
+func (r *Recorder) Request(messages []client.Message) error {
+	r.mu.Lock()
+	start := r.sent
+	if start > len(messages) {
+		start = 0
+	}
+	r.sent = len(messages)
+	r.mu.Unlock()
+	return r.write(Entry{Type: "request", Messages: messages[start:]})
+}
+
+func (r *Recorder) Response(resp *client.MessagesResponse) error {
+	return r.write(Entry{Type: "response", Response: resp})
+}
+
+func (r *Recorder) Tool(out ToolOutput) error {
+	return r.write(Entry{Type: "tool", Tool: &out})
+}
+
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
+
+// Player serves a recording back in order so a run can be verified without
+// calling the API.
+type Player struct {
+	Header    Header
+	prompts   []string
+	responses []*client.MessagesResponse
+	tools     map[string]ToolOutput
+	next      int
+}
+
+func Load(path string) (*Player, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+
+	p := &Player{tools: make(map[string]ToolOutput)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		switch e.Type {
+		case "header":
+			if e.Header != nil {
+				p.Header = *e.Header
+			}
+		case "prompt":
+			p.prompts = append(p.prompts, e.Prompt)
+		case "response":
+			if e.Response != nil {
+				p.responses = append(p.responses, e.Response)
+			}
+		case "tool":
+			if e.Tool != nil {
+				p.tools[e.Tool.ToolUseID] = *e.Tool
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	if p.Header.Version > FormatVersion {
+		return nil, fmt.Errorf("recording version %d is newer than supported (%d)", p.Header.Version, FormatVersion)
+	}
+	return p, nil
+}
+
+func (p *Player) Prompts() []string {
+	return p.prompts
+}
+
+func (p *Player) NextResponse() (*client.MessagesResponse, error) {
+	if p.next >= len(p.responses) {
+		return nil, fmt.Errorf("recording exhausted after %d responses", len(p.responses))
+	}
+	resp := p.responses[p.next]
+	p.next++
+	return resp, nil
+}
+
+func (p *Player) ToolOutput(toolUseID string) (ToolOutput, bool) {
+	out, ok := p.tools[toolUseID]
+	return out, ok
+}
+
+// Remaining reports how many recorded responses were not consumed.
+func (p *Player) Remaining() int {
+	return len(p.responses) - p.next
+}
@@ -0,0 +1,199 @@
+package safety
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Level is the approval class of a shell command.
+type Level int
+
+const (
+	// Safe commands only read state and run without confirmation.
+	Safe Level = iota
+	// Normal commands get the regular confirmation prompt.
+	Normal
+	// Dangerous commands get a high-risk warning before the prompt.
+	Dangerous
+)
+
+// Assessment is the result of classifying a command.
+type Assessment struct {
+	Level   Level
+	Reasons []string
+}
+
+// DefaultSafe lists read-only commands that are auto-approved. An entry
+// matches a command whose leading words equal it, whatever arguments follow.
+// Builds and test runners are left out: they run code from the repository
+// (tests, build scripts, Makefiles, package.json scripts), which the model
+// may just have written.
+var DefaultSafe = []string{
+	"ls", "pwd", "cat", "head", "tail", "wc", "echo", "which", "file", "stat",
+	"tree", "du", "df", "grep", "rg", "diff", "sort", "uniq",
+	"git status", "git diff", "git log", "git show", "git blame",
+	"go vet", "go list", "go version", "go env",
+}
+
+type pattern struct {
+	re     *regexp.Regexp
+	reason string
+}
+
+var dangerous = []pattern{
+	{regexp.MustCompile(`(^|[\s;&|(])rm\s+(-[a-zA-Z]*[rR]|--recursive)`), "recursive delete (rm -r)"},
+	{regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(ba|z|da|k)?sh\b`), "pipes a download into a shell"},
+	{regexp.MustCompile(`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(python[0-9.]*|perl|ruby|node)\b`), "pipes a download into an interpreter"},
+	{regexp.MustCompile(`\bgit\s+push\b.*(\s--force\b|\s-f\b|\s\+\S)`), "force push"},
+	{regexp.MustCompile(`\bgit\s+reset\s+--hard\b`), "discards uncommitted changes (git reset --hard)"},
+	{regexp.MustCompile(`\bgit\s+clean\s+-[a-zA-Z]*f`), "deletes untracked files (git clean -f)"},
+	{regexp.MustCompile(`(^|[\s;&|(])(sudo|doas)\s`), "runs with elevated privileges"},
+	{regexp.MustCompile(`\bmkfs(\.\w+)?\b|\bdd\s+.*\bof=/dev/`), "writes to a raw device or filesystem"},
+	{regexp.MustCompile(`>\s*/dev/(sd|nvme|disk|hd)`), "writes to a raw device or filesystem"},
+	{regexp.MustCompile(`\bchmod\s+(-R\s+)?0?777\b`), "makes files world-writable"},
+	{regexp.MustCompile(`\b(shutdown|reboot|halt|poweroff)\b`), "shuts down or reboots the machine"},
+	{regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}`), "fork bomb"},
+	{regexp.MustCompile(`(?i)\b(drop\s+(table|database)|truncate\s+table)\b`), "destroys database data"},
+}
+
+// Classifier sorts shell commands into approval levels.
+type Classifier struct {
+	safe [][]string
+}
+
+// NewClassifier returns a Classifier auto-approving DefaultSafe plus extra.
+func NewClassifier(extra []string) *Classifier {
+	c := &Classifier{}
+	for _, s := range append(append([]string(nil), DefaultSafe...), extra...) {
+		if words := strings.Fields(s); len(words) > 0 {
+			c.safe = append(c.safe, words)
+		}
+	}
+	return c
+}
+
+// Classify reports whether command is safe, normal or dangerous. A command
+// is safe only when every segment of a pipeline or list is on the safe list
+// and it neither redirects output, substitutes other commands nor leaves the
+// shell to expand globs, quotes, ~ or variables into paths the checker
+// cannot see.
+func (c *Classifier) Classify(command string) Assessment {
+	var reasons []string
+	for _, p := range dangerous {
+		if p.re.MatchString(command) && !contains(reasons, p.reason) {
+			reasons = append(reasons, p.reason)
+		}
+	}
+	if len(reasons) > 0 {
+		return Assessment{Level: Dangerous, Reasons: reasons}
+	}
+	if c.allSafe(command) && !expands(command) {
+		return Assessment{Level: Safe}
+	}
+	return Assessment{Level: Normal}
+}
+
+// Allows reports whether every segment of command starts with one of
+// prefixes, without redirection or substitution, matched as the safe list
+// is. It enforces tool patterns such as Bash(git:*).
+func Allows(prefixes []string, command string) bool {
+	c := &Classifier{}
+	for _, p := range prefixes {
+		if words := strings.Fields(p); len(words) > 0 {
+			c.safe = append(c.safe, words)
+		}
+	}
+	return c.allSafe(command)
+}
+
+// expands reports whether the shell would rewrite part of command before
+// running it: globs, braces, quoting, escapes, ~ and variables.
+func expands(command string) bool {
+	command = strings.ReplaceAll(command, "2>&1", "")
+	return strings.ContainsAny(command, "*?[{'\"\\~$")
+}
+
+func (c *Classifier) allSafe(command string) bool {
+	if strings.TrimSpace(command) == "" {
+		return false
+	}
+	// Merging stderr into stdout is the one redirection that writes nothing.
+	command = strings.ReplaceAll(command, "2>&1", "")
+	if strings.ContainsAny(command, "`>") || strings.Contains(command, "$(") {
+		return false
+	}
+	segments := strings.FieldsFunc(command, func(r rune) bool {
+		return r == ';' || r == '&' || r == '|' || r == '\n'
+	})
+	for _, seg := range segments {
+		if !c.safeSegment(strings.Fields(seg)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Classifier) safeSegment(words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	for _, w := range words {
+		if writingFlag(w) {
+			return false
+		}
+	}
+	for _, s := range c.safe {
+		if len(words) >= len(s) && equalWords(words[:len(s)], s) {
+			return true
+		}
+	}
+	return false
+}
+
+// writingFlag reports whether a flag makes an otherwise read-only command
+// write a file or run another program, e.g. sort -o FILE, git diff
+// --output=FILE, find -exec, rg --pre CMD or go vet -vettool=CMD.
+func writingFlag(w string) bool {
+	name, _, _ := strings.Cut(w, "=")
+	switch name {
+	case "-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls",
+		"-toolexec", "--toolexec", "--ext-diff", "-trace", "--trace",
+		"--pre", "--pre-glob", "-vettool", "--vettool", "--compress-program":
+		return true
+	}
+	switch {
+	case strings.HasPrefix(name, "--output"):
+		return true
+	case strings.HasPrefix(name, "-o") && !strings.HasPrefix(name, "--"):
+		// -o FILE, -oFILE and go test -outputdir.
+		return true
+	case strings.HasSuffix(name, "profile"):
+		// go test -coverprofile=FILE and the other profiles.
+		return true
+	case shortOutputBundle.MatchString(w):
+		// sort -uo FILE.
+		return true
+	}
+	return false
+}
+
+// shortOutputBundle matches short flags combined with -o, such as -uo.
+var shortOutputBundle = regexp.MustCompile(`^-[A-Za-z]{1,3}o$`)
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package semantic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder turns texts into vectors of a fixed dimension.
+type Embedder interface {
+	Name() string
+	Embed(texts []string) ([][]float32, error)
+}
+
+const localDims = 512
+
+// LocalEmbedder is a dependency-free fallback: a hashed bag of identifier
+// sub-words. It captures lexical rather than true semantic similarity but
+// works offline and needs no model.
+type LocalEmbedder struct{}
+
+func (LocalEmbedder) Name() string { return "local-hash-v1" }
+
+func (LocalEmbedder) Embed(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		vec := make([]float32, localDims)
+		for _, tok := range tokenize(t) {
+			h := fnv.New32a()
+			h.Write([]byte(tok))
+			sum := h.Sum32()
+			sign := float32(1)
+			if sum&1 == 1 {
+				sign = -1
+			}
+			vec[(sum>>1)%localDims] += sign
+		}
+		out[i] = normalize(vec)
+	}
+	return out, nil
+}
+
+// tokenize splits text into lower-cased words, also breaking camelCase and
+// snake_case identifiers into their parts.
+func tokenize(text string) []string {
+	var tokens []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 1 {
+			tokens = append(tokens, strings.ToLower(string(cur)))
+		}
+		cur = cur[:0]
+	}
+	var prev rune
+	for _, r := range text {
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			cur = append(cur, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur = append(cur, r)
+		default:
+			flush()
+		}
+		prev = r
+	}
+	flush()
+	return tokens
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+	return v
+}
+
+// APIEmbedder calls an OpenAI-compatible /v1/embeddings endpoint.
+type APIEmbedder struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func NewAPIEmbedder(baseURL, apiKey, model string) *APIEmbedder {
+	return &APIEmbedder{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// SetTransport routes the embedding requests through rt, e.g. one with
+// the proxy and CA settings of the API client.
+func (a *APIEmbedder) SetTransport(rt http.RoundTripper) {
+	a.httpClient.Transport = rt
+}
+
+func (a *APIEmbedder) Name() string { return "api:" + a.model }
+
+func (a *APIEmbedder) Embed(texts []string) ([][]float32, error) {
+	const batchSize = 64
+	var out [][]float32
+	for start := 0; start < len(texts); start += batchSize {
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		vecs, err := a.embedBatch(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+func (a *APIEmbedder) embedBatch(texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": a.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequest("POST", a.baseURL+"/v1/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set("x-api-key", a.apiKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embeddings error (status %d): %s", resp.StatusCode, string(errBody))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(result.Data), len(texts))
+	}
+	out := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index >= 0 && d.Index < len(out) {
+			out[d.Index] = normalize(d.Embedding)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,242 @@
+package semantic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/index"
+)
+
+const (
+	StoreFile = "embeddings.json"
+
+	chunkLines     = 40
+	chunkOverlap   = 10
+	maxFileSize    = 256 * 1024
+	maxSnippetRows = 12
+)
+
+type Chunk struct {
+	Path    string    `json:"path"`
+	Start   int       `json:"start"`
+	End     int       `json:"end"`
+	ModTime time.Time `json:"mod_time"`
+	Vector  []float32 `json:"vector"`
+}
+
+type store struct {
+	Embedder string  `json:"embedder"`
+	Chunks   []Chunk `json:"chunks"`
+}
+
+type Result struct {
+	Path    string
+	Start   int
+	End     int
+	Score   float32
+	Snippet string
+}
+
+// Engine maintains chunk embeddings for the files in the project index and
+// answers nearest-neighbour queries against them.
+type Engine struct {
+	root     string
+	embedder Embedder
+
+	mu     sync.Mutex
+	chunks []Chunk
+	loaded bool
+}
+
+func New(root string, embedder Embedder) *Engine {
+	return &Engine{root: root, embedder: embedder}
+}
+
+func (e *Engine) storePath() string {
+	return filepath.Join(index.CacheDir(e.root), StoreFile)
+}
+
+func (e *Engine) load() {
+	if e.loaded {
+		return
+	}
+	e.loaded = true
+	data, err := os.ReadFile(e.storePath())
+	if err != nil {
+		return
+	}
+	var st store
+	if json.Unmarshal(data, &st) == nil && st.Embedder == e.embedder.Name() {
+		e.chunks = st.Chunks
+	}
+}
+
+func (e *Engine) save() error {
+	data, err := json.Marshal(store{Embedder: e.embedder.Name(), Chunks: e.chunks})
+	if err != nil {
+		return fmt.Errorf("marshal embeddings: %w", err)
+	}
+	return os.WriteFile(e.storePath(), data, 0644)
+}
+
+// Update embeds files that are new or changed since the last update and drops
+// chunks of deleted files.
+func (e *Engine) Update() error {
+	idx, err := index.Refresh(e.root)
+	if err != nil {
+		return fmt.Errorf("index files: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.load()
+
+	current := map[string]time.Time{}
+	for _, c := range e.chunks {
+		current[c.Path] = c.ModTime
+	}
+
+	var kept []Chunk
+	stale := map[string]bool{}
+	live := map[string]bool{}
+	for _, f := range idx.Files {
+		if f.Lang == "" || f.Size > maxFileSize {
+			continue
+		}
+		live[f.Path] = true
+		if mod, ok := current[f.Path]; !ok || !mod.Equal(f.ModTime) {
+			stale[f.Path] = true
+		}
+	}
+	for _, c := range e.chunks {
+		if live[c.Path] && !stale[c.Path] {
+			kept = append(kept, c)
+		}
+	}
+
+	var pending []Chunk
+	var texts []string
+	for _, f := range idx.Files {
+		if !stale[f.Path] {
+			continue
+		}
+		lines, err := readLines(filepath.Join(e.root, filepath.FromSlash(f.Path)))
+		if err != nil {
+			continue
+		}
+		for start := 0; start < len(lines); start += chunkLines - chunkOverlap {
+			end := start + chunkLines
+			if end > len(lines) {
+				end = len(lines)
+			}
+			text := f.Path + "\n" + strings.Join(lines[start:end], "\n")
+			pending = append(pending, Chunk{Path: f.Path, Start: start + 1, End: end, ModTime: f.ModTime})
+			texts = append(texts, text)
+			if end == len(lines) {
+				break
+			}
+		}
+	}
+
+	if len(texts) > 0 {
+		vecs, err := e.embedder.Embed(texts)
+		if err != nil {
+			return fmt.Errorf("embed: %w", err)
+		}
+		for i := range pending {
+			pending[i].Vector = vecs[i]
+		}
+	}
+
+	e.chunks = append(kept, pending...)
+	if len(pending) > 0 || len(kept) != len(current) {
+		return e.save()
+	}
+	return nil
+}
+
+// Search returns the k chunks most similar to query, at most one per file.
+func (e *Engine) Search(query string, k int) ([]Result, error) {
+	if err := e.Update(); err != nil {
+		return nil, err
+	}
+	vecs, err := e.embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	q := vecs[0]
+
+	e.mu.Lock()
+	scored := make([]Result, 0, len(e.chunks))
+	for _, c := range e.chunks {
+		scored = append(scored, Result{Path: c.Path, Start: c.Start, End: c.End, Score: dot(q, c.Vector)})
+	}
+	e.mu.Unlock()
+
+	sort.Slice(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	var results []Result
+	seen := map[string]bool{}
+	for _, r := range scored {
+		if len(results) == k {
+			break
+		}
+		if seen[r.Path] || r.Score <= 0 {
+			continue
+		}
+		seen[r.Path] = true
+		r.Snippet = snippet(filepath.Join(e.root, filepath.FromSlash(r.Path)), r.Start, r.End)
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func readLines(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func snippet(p string, start, end int) string {
+	lines, err := readLines(p)
+	if err != nil || start > len(lines) {
+		return ""
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if end-start+1 > maxSnippetRows {
+		end = start + maxSnippetRows - 1
+	}
+	var sb strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&sb, "%5d│%s\n", i, lines[i-1])
+	}
+	return sb.String()
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/conversation"
+)
+
+// conn is one client connection and the sessions it started.
+type conn struct {
+	srv *Server
+	ws  *websocket.Conn
+	// done is closed when the connection ends, denying waiting approvals.
+	done chan struct{}
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is an agent session and the turn it may be running.
+type session struct {
+	id   string
+	conn *conn
+	s    *conversation.Session
+
+	mu        sync.Mutex
+	busy      bool
+	closing   bool
+	nextID    int
+	approvals map[string]chan bool
+}
+
+func (s *Server) serveConn(ws *websocket.Conn) {
+	ws.MaxPayloadBytes = maxMessage
+	c := &conn{srv: s, ws: ws, done: make(chan struct{}), sessions: make(map[string]*session)}
+	defer c.close()
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			return
+		}
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.send(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParse, Message: err.Error()}})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			c.reply(req.ID, nil, &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+		c.handle(req)
+	}
+}
+
+// close ends the connection's sessions: waiting approvals are denied,
+// running commands are interrupted and each session closes once its turn
+// is over.
+func (c *conn) close() {
+	close(c.done)
+	c.ws.Close()
+	c.mu.Lock()
+	sessions := c.sessions
+	c.sessions = nil
+	c.mu.Unlock()
+	for _, sess := range sessions {
+		sess.end()
+	}
+}
+
+func (c *conn) send(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	websocket.Message.Send(c.ws, string(data))
+}
+
+// reply answers a request; notifications, which have no id, get none.
+func (c *conn) reply(id json.RawMessage, result interface{}, rerr *rpcError) {
+	if len(id) == 0 {
+		return
+	}
+	if rerr == nil && result == nil {
+		result = struct{}{}
+	}
+	c.send(response{JSONRPC: "2.0", ID: id, Result: result, Error: rerr})
+}
+
+func (c *conn) notify(sessionID, typ string, fields map[string]interface{}) {
+	params := map[string]interface{}{"session_id": sessionID, "type": typ}
+	for k, v := range fields {
+		params[k] = v
+	}
+	c.send(notification{JSONRPC: "2.0", Method: "session/update", Params: params})
+}
+
+func (c *conn) handle(req request) {
+	var p struct {
+		SessionID  string `json:"session_id"`
+		WorkDir    string `json:"work_dir"`
+		Model      string `json:"model"`
+		Text       string `json:"text"`
+		ApprovalID string `json:"approval_id"`
+		Allow      bool   `json:"allow"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			c.reply(req.ID, nil, invalidParams("decode params: %v", err))
+			return
+		}
+	}
+
+	if req.Method == "session/start" {
+		// Starting may wait for an approval, which this loop must be free
+		// to read.
+		go func() {
+			result, rerr := c.start(p.WorkDir, p.Model)
+			c.reply(req.ID, result, rerr)
+		}()
+		return
+	}
+
+	var sess *session
+	switch req.Method {
+	case "session/send", "session/interrupt", "session/close", "tool/approve":
+		c.mu.Lock()
+		sess = c.sessions[p.SessionID]
+		c.mu.Unlock()
+		if sess == nil {
+			c.reply(req.ID, nil, invalidParams("unknown session %q", p.SessionID))
+			return
+		}
+	default:
+		c.reply(req.ID, nil, &rpcError{Code: codeNoMethod, Message: fmt.Sprintf("unknown method: %s", req.Method)})
+		return
+	}
+
+	switch req.Method {
+	case "session/send":
+		if p.Text == "" {
+			c.reply(req.ID, nil, invalidParams("missing text"))
+			return
+		}
+		if err := sess.send(req.ID, p.Text); err != nil {
+			c.reply(req.ID, nil, failed(err))
+		}
+	case "session/interrupt":
+		c.reply(req.ID, map[string]bool{"interrupted": sess.interrupt()}, nil)
+	case "session/close":
+		c.mu.Lock()
+		delete(c.sessions, sess.id)
+		c.mu.Unlock()
+		sess.end()
+		c.reply(req.ID, nil, nil)
+	case "tool/approve":
+		if !sess.answer(p.ApprovalID, p.Allow) {
+			c.reply(req.ID, nil, invalidParams("no approval %q is waiting", p.ApprovalID))
+			return
+		}
+		c.reply(req.ID, nil, nil)
+	}
+}
+
+func (c *conn) start(workDir, model string) (interface{}, *rpcError) {
+	if workDir == "" {
+		return nil, invalidParams("missing work_dir")
+	}
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, invalidParams("work_dir: %v", err)
+	}
+	cs, err := c.srv.newSession(workDir, model)
+	if err != nil {
+		return nil, failed(err)
+	}
+	sess := &session{id: c.srv.newSessionID(), conn: c, s: cs, approvals: make(map[string]chan bool)}
+	cs.SetApprover(sess.approve)
+	cs.SetListener(sess)
+
+	// The session is reachable before the config is applied, which may
+	// ask through tool/approve whether to trust the project's hooks.
+	c.mu.Lock()
+	if c.sessions == nil {
+		c.mu.Unlock()
+		cs.Close()
+		return nil, failed(fmt.Errorf("connection closed"))
+	}
+	c.sessions[sess.id] = sess
+	c.mu.Unlock()
+
+	if err := cs.ApplyConfig(c.srv.cfg); err != nil {
+		c.mu.Lock()
+		delete(c.sessions, sess.id)
+		c.mu.Unlock()
+		sess.end()
+		return nil, failed(err)
+	}
+	cs.SetReviewChanges(false)
+	return map[string]string{"session_id": sess.id, "model": cs.Model(), "work_dir": cs.WorkDir()}, nil
+}
+
+// send runs a turn in the background and answers the request id when it
+// ends. A session runs one turn at a time.
+func (sess *session) send(id json.RawMessage, text string) error {
+	sess.mu.Lock()
+	if sess.busy {
+		sess.mu.Unlock()
+		return fmt.Errorf("session %s is already running a turn", sess.id)
+	}
+	sess.busy = true
+	sess.mu.Unlock()
+
+	go func() {
+		err := sess.s.SendMessage(text)
+
+		sess.mu.Lock()
+		sess.busy = false
+		closing := sess.closing
+		sess.mu.Unlock()
+		if closing {
+			sess.s.Close()
+		}
+
+		if err != nil {
+			sess.conn.reply(id, nil, failed(err))
+			return
+		}
+		sess.conn.reply(id, struct {
+			Usage client.Usage `json:"usage"`
+			Limit string       `json:"limit,omitempty"`
+		}{sess.s.Usage(), sess.s.TurnLimit()}, nil)
+	}()
+	return nil
+}
+
+// interrupt denies the approvals waiting and stops the running command.
+func (sess *session) interrupt() bool {
+	sess.mu.Lock()
+	denied := len(sess.approvals) > 0
+	for id, ch := range sess.approvals {
+		ch <- false
+		delete(sess.approvals, id)
+	}
+	sess.mu.Unlock()
+	return sess.s.Interrupt() || denied
+}
+
+// end closes the session now, or after the turn it is running.
+func (sess *session) end() {
+	sess.interrupt()
+	sess.mu.Lock()
+	busy := sess.busy
+	sess.closing = true
+	sess.mu.Unlock()
+	if !busy {
+		sess.s.Close()
+	}
+}
+
+// approve asks the client and waits for tool/approve; the answer is no
+// once the session is interrupted or the connection ends.
+func (sess *session) approve(a conversation.Approval) bool {
+	sess.mu.Lock()
+	if sess.closing {
+		sess.mu.Unlock()
+		return false
+	}
+	sess.nextID++
+	id := fmt.Sprintf("a%d", sess.nextID)
+	ch := make(chan bool, 1)
+	sess.approvals[id] = ch
+	sess.mu.Unlock()
+
+	sess.conn.notify(sess.id, "approval_request", map[string]interface{}{
+		"approval_id": id,
+		"tool":        a.Tool,
+		"input":       a.Input,
+		"question":    a.Question,
+	})
+	select {
+	case allow := <-ch:
+		return allow
+	case <-sess.conn.done:
+		return false
+	}
+}
+
+// answer delivers the client's decision on approval id.
+func (sess *session) answer(id string, allow bool) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	ch, ok := sess.approvals[id]
+	if !ok {
+		return false
+	}
+	delete(sess.approvals, id)
+	ch <- allow
+	return true
+}
+
+func (sess *session) Text(text string) {
+	sess.conn.notify(sess.id, "text", map[string]interface{}{"text": text})
+}
+
+func (sess *session) Thinking(text string) {
+	sess.conn.notify(sess.id, "thinking", map[string]interface{}{"text": text})
+}
+
+func (sess *session) ToolCall(id, name string, input map[string]interface{}) {
+	sess.conn.notify(sess.id, "tool_call", map[string]interface{}{"tool_use_id": id, "name": name, "input": input})
+}
+
+func (sess *session) ToolResult(id, name, content string, isError, denied bool) {
+	sess.conn.notify(sess.id, "tool_result", map[string]interface{}{
+		"tool_use_id": id,
+		"name":        name,
+		"content":     content,
+		"is_error":    isError,
+		"denied":      denied,
+	})
+}
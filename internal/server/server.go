@@ -0,0 +1,241 @@
+// Package server exposes the agent loop to editors and other front ends
+// over JSON-RPC 2.0 on a local WebSocket, for apipod-cli serve.
+//
+// A client connects to ws://127.0.0.1:<port>/rpc with the server's token,
+// either as "Authorization: Bearer <token>" or as ?token=<token>, and
+// calls:
+//
+//	session/start      {work_dir, model}          → {session_id, model, work_dir}
+//	session/send       {session_id, text}         → {usage, limit} once the turn ends
+//	session/interrupt  {session_id}               → {interrupted}
+//	session/close      {session_id}               → {}
+//	tool/approve       {session_id, approval_id, allow} → {}
+//
+// While a turn runs the server sends session/update notifications whose
+// type is "text", "thinking", "tool_call", "tool_result" or
+// "approval_request". A tool call waiting for approval blocks until
+// tool/approve answers it; sessions end with their connection.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/rpay/apipod-cli/internal/client"
+	"github.com/rpay/apipod-cli/internal/config"
+	"github.com/rpay/apipod-cli/internal/conversation"
+)
+
+const (
+	// DefaultAddr is where apipod-cli serve listens without --port.
+	DefaultAddr = "127.0.0.1:7483"
+	// InfoFile, next to the config, tells editor plugins where the
+	// running server listens and which token it expects.
+	InfoFile = "serve.json"
+
+	// maxMessage bounds a single JSON-RPC message.
+	maxMessage = 8 << 20
+)
+
+// JSON-RPC error codes.
+const (
+	codeParse          = -32700
+	codeInvalidRequest = -32600
+	codeNoMethod       = -32601
+	codeInvalidParams  = -32602
+	codeFailed         = -32000
+)
+
+// Info is the content of InfoFile.
+type Info struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+	PID   int    `json:"pid"`
+}
+
+// InfoPath returns where the running server describes itself.
+func InfoPath() string {
+	return filepath.Join(filepath.Dir(config.ConfigPath()), InfoFile)
+}
+
+// Server hands out agent sessions to WebSocket clients.
+type Server struct {
+	cfg   *config.Config
+	token string
+
+	mu     sync.Mutex
+	nextID int
+	http   *http.Server
+}
+
+// New returns a server creating its sessions from cfg, with a fresh
+// random token.
+func New(cfg *config.Config) (*Server, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	return &Server{cfg: cfg, token: hex.EncodeToString(b)}, nil
+}
+
+// Token is the secret clients must present.
+func (s *Server) Token() string {
+	return s.token
+}
+
+// ListenAndServe serves on addr, which must be a loopback address, and
+// writes InfoFile until Shutdown is called. ready, when not nil, is
+// called with the WebSocket URL once the server is listening.
+func (s *Server) ListenAndServe(addr string, ready func(url string)) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("parse address: %w", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("refusing to listen on %s: the server runs tools on this machine, so it only listens on loopback addresses", host)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	url := "ws://" + ln.Addr().String() + "/rpc"
+	if err := writeInfo(Info{URL: url, Token: s.token, PID: os.Getpid()}); err != nil {
+		ln.Close()
+		return err
+	}
+	defer os.Remove(InfoPath())
+
+	mux := http.NewServeMux()
+	mux.Handle("/rpc", websocket.Server{
+		Handshake: s.handshake,
+		Handler:   s.serveConn,
+	})
+	srv := &http.Server{Handler: mux}
+	s.mu.Lock()
+	s.http = srv
+	s.mu.Unlock()
+
+	if ready != nil {
+		ready(url)
+	}
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and closes the open ones.
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	srv := s.http
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Close()
+}
+
+func writeInfo(info Info) error {
+	path := InfoPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// handshake rejects clients without the token. Browsers cannot set
+// headers on WebSocket requests, so the query parameter is accepted too.
+func (s *Server) handshake(_ *websocket.Config, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+func (s *Server) newSessionID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return fmt.Sprintf("s%d", s.nextID)
+}
+
+// newSession connects a session for workDir the way the CLI does, with
+// the review queue off since reviewing needs the terminal.
+func (s *Server) newSession(workDir, model string) (*conversation.Session, error) {
+	info, err := os.Stat(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("work_dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("work_dir: %s is not a directory", workDir)
+	}
+	c, err := client.NewWithOptions(s.cfg.BaseURL, s.cfg.APIKey, s.cfg.ClientOptions())
+	if err != nil {
+		return nil, err
+	}
+	if model == "" {
+		model = s.cfg.Model
+	}
+	return conversation.NewSession(c, model, workDir), nil
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func invalidParams(format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+func failed(err error) *rpcError {
+	return &rpcError{Code: codeFailed, Message: err.Error()}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/rpay/apipod-cli/internal/config"
+)
+
+func TestHandshake(t *testing.T) {
+	s, err := New(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		query  string
+		header string
+		ok     bool
+	}{
+		{"bearer", "", "Bearer " + s.Token(), true},
+		{"query", "?token=" + s.Token(), "", true},
+		{"missing", "", "", false},
+		{"wrong bearer", "", "Bearer " + strings.Repeat("0", len(s.Token())), false},
+		{"wrong query", "?token=nope", "", false},
+		{"prefix of the token", "?token=" + s.Token()[:10], "", false},
+		{"not a bearer header", "", s.Token(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/rpc"+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if err := s.handshake(nil, r); (err == nil) != tt.ok {
+				t.Errorf("handshake error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWrongTokenIsRefused(t *testing.T) {
+	s, err := New(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(websocket.Server{
+		Handshake: s.handshake,
+		Handler:   func(ws *websocket.Conn) { ws.Close() },
+	})
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	if ws, err := websocket.Dial(url+"?token=wrong", "", ts.URL); err == nil {
+		ws.Close()
+		t.Error("dial with a wrong token succeeded")
+	}
+	ws, err := websocket.Dial(url+"?token="+s.Token(), "", ts.URL)
+	if err != nil {
+		t.Fatalf("dial with the token: %v", err)
+	}
+	ws.Close()
+}
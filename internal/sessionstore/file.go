@@ -0,0 +1,62 @@
+package sessionstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileStore keeps each session as a file in Dir.
+type FileStore struct {
+	Dir string
+}
+
+func (f *FileStore) Location() string { return f.Dir }
+
+// Put writes through a temporary file so a crash never leaves a truncated
+// session behind.
+func (f *FileStore) Put(name string, data []byte) error {
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(f.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(f.Dir, name))
+}
+
+func (f *FileStore) Get(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(f.Dir, filepath.Base(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (f *FileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(f.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".json" {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,174 @@
+package sessionstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gcsAPI = "https://storage.googleapis.com"
+
+// GCSStore keeps sessions in a Google Cloud Storage bucket through the
+// JSON API. The access token comes from GOOGLE_OAUTH_ACCESS_TOKEN, the GCE
+// metadata server (Cloud Build, GKE, Compute Engine) or `gcloud auth
+// print-access-token`, in that order.
+type GCSStore struct {
+	bucket, prefix string
+	client         *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newGCS(bucket, prefix string) *GCSStore {
+	return &GCSStore{bucket: bucket, prefix: prefix, client: &http.Client{Timeout: requestTimeout}}
+}
+
+func (g *GCSStore) Location() string {
+	return "gs://" + objectKey(g.bucket, g.prefix)
+}
+
+func (g *GCSStore) Put(name string, data []byte) error {
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		gcsAPI, url.PathEscape(g.bucket), url.QueryEscape(objectKey(g.prefix, name)))
+	resp, err := g.do(http.MethodPost, u, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (g *GCSStore) Get(name string) ([]byte, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media",
+		gcsAPI, url.PathEscape(g.bucket), url.PathEscape(objectKey(g.prefix, name)))
+	resp, err := g.do(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (g *GCSStore) List() ([]string, error) {
+	prefix := ""
+	if g.prefix != "" {
+		prefix = g.prefix + "/"
+	}
+	var names []string
+	pageToken := ""
+	for {
+		q := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		resp, err := g.do(http.MethodGet, fmt.Sprintf("%s/storage/v1/b/%s/o?%s", gcsAPI, url.PathEscape(g.bucket), q.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		var out struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list sessions: %w", err)
+		}
+		for _, it := range out.Items {
+			name := strings.TrimPrefix(it.Name, prefix)
+			if !strings.Contains(name, "/") && strings.HasSuffix(name, ".json") {
+				names = append(names, name)
+			}
+		}
+		if out.NextPageToken == "" {
+			break
+		}
+		pageToken = out.NextPageToken
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (g *GCSStore) do(method, u string, body []byte) (*http.Response, error) {
+	token, err := g.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gcs %s: %w", method, err)
+	}
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet && strings.Contains(u, "alt=media") {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("gcs %s: %s: %s", method, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (g *GCSStore) accessToken() (string, error) {
+	if t := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); t != "" {
+		return t, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+	if t, ttl, err := metadataToken(); err == nil {
+		g.token, g.expires = t, time.Now().Add(ttl-time.Minute)
+		return t, nil
+	}
+	out, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("gcs session store: no credentials (set GOOGLE_OAUTH_ACCESS_TOKEN, run on GCP or log in with gcloud)")
+	}
+	g.token, g.expires = strings.TrimSpace(string(out)), time.Now().Add(30*time.Minute)
+	return g.token, nil
+}
+
+func metadataToken() (string, time.Duration, error) {
+	req, _ := http.NewRequest(http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := (&http.Client{Timeout: 2 * time.Second}).Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("metadata server: %s", resp.Status)
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", 0, err
+	}
+	return out.AccessToken, time.Duration(out.ExpiresIn) * time.Second, nil
+}
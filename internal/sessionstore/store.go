@@ -0,0 +1,76 @@
+// Package sessionstore persists saved sessions on the local filesystem or
+// in object storage (S3 or GCS), so runners without a persistent disk can
+// resume a session started elsewhere.
+package sessionstore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// Store keeps session documents by name.
+type Store interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+	// List returns the stored names in ascending order.
+	List() ([]string, error)
+	// Location describes the store for messages, e.g. "s3://bucket/prefix".
+	Location() string
+}
+
+// Open returns the store for a location: a directory (~ is expanded),
+// file:///dir, s3://bucket/prefix or gs://bucket/prefix.
+func Open(location string) (Store, error) {
+	if !strings.Contains(location, "://") {
+		return fileStore(location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("session store %q: %w", location, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "file":
+		return fileStore(u.Path)
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("session store %q: missing bucket", location)
+		}
+		return newS3(u.Host, prefix)
+	case "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("session store %q: missing bucket", location)
+		}
+		return newGCS(u.Host, prefix), nil
+	default:
+		return nil, fmt.Errorf("session store %q: unsupported scheme %s (use a path, s3:// or gs://)", location, u.Scheme)
+	}
+}
+
+func fileStore(dir string) (Store, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("session store: empty path")
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// objectKey joins a prefix and a name into an object key.
+func objectKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
@@ -0,0 +1,181 @@
+// Package tabs keeps several named sessions open in one process, for
+// /new, /switch, /close and /sessions. Each tab has its own conversation,
+// executor and working directory, so background shells started in one
+// keep running while another is in front. Only the tab in front runs
+// turns.
+package tabs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/conversation"
+	"github.com/rpay/apipod-cli/internal/display"
+)
+
+// Factory creates the session of a new tab in workDir, set up like the
+// first one.
+type Factory func(workDir string) (*conversation.Session, error)
+
+// Tab is a named session.
+type Tab struct {
+	Name    string
+	Session *conversation.Session
+}
+
+// Manager holds the open tabs and knows which one is in front.
+type Manager struct {
+	tabs    []*Tab
+	current int
+	factory Factory
+}
+
+// New opens the first tab, "main", on session.
+func New(session *conversation.Session, factory Factory) *Manager {
+	return &Manager{tabs: []*Tab{{Name: "main", Session: session}}, factory: factory}
+}
+
+// Current returns the tab in front.
+func (m *Manager) Current() *Tab {
+	return m.tabs[m.current]
+}
+
+// Tabs returns the open tabs in the order they were opened.
+func (m *Manager) Tabs() []*Tab {
+	return append([]*Tab(nil), m.tabs...)
+}
+
+// Open starts a tab and brings it to the front. An empty name picks the
+// next free "tabN"; dir defaults to the working directory of the tab in
+// front, and a relative dir is resolved against it.
+func (m *Manager) Open(name, dir string) (*Tab, error) {
+	if name == "" {
+		for i := len(m.tabs) + 1; ; i++ {
+			name = fmt.Sprintf("tab%d", i)
+			if m.find(name) < 0 {
+				break
+			}
+		}
+	}
+	if strings.ContainsAny(name, " \t") {
+		return nil, fmt.Errorf("tab names cannot contain spaces")
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return nil, fmt.Errorf("tab names cannot be numbers, which /switch reads as positions")
+	}
+	if m.find(name) >= 0 {
+		return nil, fmt.Errorf("a tab named %s is already open", name)
+	}
+
+	base := m.Current().Session.WorkDir()
+	switch {
+	case dir == "":
+		dir = base
+	case strings.HasPrefix(dir, "~/"):
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[2:])
+	case !filepath.IsAbs(dir):
+		dir = filepath.Join(base, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	session, err := m.factory(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open tab: %w", err)
+	}
+	t := &Tab{Name: name, Session: session}
+	m.tabs = append(m.tabs, t)
+	m.current = len(m.tabs) - 1
+	return t, nil
+}
+
+// Switch brings a tab to the front by name or by its position from 1;
+// an empty ref goes to the next tab.
+func (m *Manager) Switch(ref string) (*Tab, error) {
+	i, err := m.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	m.current = i
+	return m.tabs[i], nil
+}
+
+// Close ends a tab, the one in front when ref is empty, and its
+// background shells. The last tab cannot be closed.
+func (m *Manager) Close(ref string) (*Tab, error) {
+	i := m.current
+	if ref != "" {
+		var err error
+		if i, err = m.resolve(ref); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.tabs) == 1 {
+		return nil, fmt.Errorf("%s is the only tab; use /quit to leave", m.tabs[0].Name)
+	}
+	t := m.tabs[i]
+	t.Session.Close()
+	m.tabs = append(m.tabs[:i], m.tabs[i+1:]...)
+	if m.current > i || m.current == len(m.tabs) {
+		m.current--
+	}
+	return t, nil
+}
+
+// CloseAll ends every tab, when the CLI exits.
+func (m *Manager) CloseAll() {
+	for _, t := range m.tabs {
+		t.Session.Close()
+	}
+}
+
+// Show prints /sessions: the open tabs and, when a session store is
+// configured, the saved sessions.
+func (m *Manager) Show() error {
+	rows := make([]display.TabRow, len(m.tabs))
+	for i, t := range m.tabs {
+		rows[i] = display.TabRow{
+			Name:     t.Name,
+			WorkDir:  t.Session.WorkDir(),
+			Model:    t.Session.Model(),
+			Messages: t.Session.MessageCount(),
+			Jobs:     t.Session.RunningJobs(),
+			Current:  i == m.current,
+		}
+	}
+	display.Tabs(rows)
+	if _, err := m.Current().Session.ListSessions(); err != nil {
+		return nil
+	}
+	return m.Current().Session.ShowSessions()
+}
+
+func (m *Manager) find(name string) int {
+	for i, t := range m.tabs {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Manager) resolve(ref string) (int, error) {
+	if ref == "" {
+		return (m.current + 1) % len(m.tabs), nil
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(m.tabs) {
+			return 0, fmt.Errorf("no tab %d; %d open", n, len(m.tabs))
+		}
+		return n - 1, nil
+	}
+	if i := m.find(ref); i >= 0 {
+		return i, nil
+	}
+	return 0, fmt.Errorf("no tab named %s", ref)
+}
@@ -0,0 +1,192 @@
+package telemetry
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The OTLP/JSON payloads. Times and 64-bit integers are decimal strings
+// and IDs are hex, as the JSON encoding of OTLP requires.
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value value  `json:"value"`
+}
+
+type value struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func attributes(m map[string]interface{}) []attribute {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]attribute, 0, len(keys))
+	for _, k := range keys {
+		var v value
+		switch x := m[k].(type) {
+		case string:
+			v.StringValue = &x
+		case bool:
+			v.BoolValue = &x
+		case int:
+			s := strconv.Itoa(x)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(x, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &x
+		default:
+			continue
+		}
+		out = append(out, attribute{Key: k, Value: v})
+	}
+	return out
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type spanData struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []attribute `json:"attributes,omitempty"`
+	Status       *status     `json:"status,omitempty"`
+}
+
+func (e *Exporter) scope() scope {
+	return scope{Name: e.opts.ServiceName, Version: e.opts.Version}
+}
+
+func (e *Exporter) traces(spans []spanData) interface{} {
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": resource{Attributes: e.resource},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": e.scope(),
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// durationBounds are the histogram buckets, in seconds.
+var durationBounds = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+type counter struct {
+	name  string
+	unit  string
+	attrs map[string]interface{}
+	value int64
+}
+
+type histogram struct {
+	name    string
+	unit    string
+	attrs   map[string]interface{}
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// aggregationCumulative reports totals since the exporter started.
+const aggregationCumulative = 2
+
+// metrics snapshots the counters and histograms; e.mu must be held.
+func (e *Exporter) metrics(now time.Time) []interface{} {
+	start, ts := nanos(e.started), nanos(now)
+	sums := make(map[string]map[string]interface{})
+	hists := make(map[string]map[string]interface{})
+	var names []string
+
+	for _, c := range e.counters {
+		m, ok := sums[c.name]
+		if !ok {
+			m = map[string]interface{}{"name": c.name, "unit": c.unit, "sum": map[string]interface{}{
+				"aggregationTemporality": aggregationCumulative,
+				"isMonotonic":            true,
+				"dataPoints":             []interface{}{},
+			}}
+			sums[c.name] = m
+			names = append(names, c.name)
+		}
+		sum := m["sum"].(map[string]interface{})
+		sum["dataPoints"] = append(sum["dataPoints"].([]interface{}), map[string]interface{}{
+			"attributes":        attributes(c.attrs),
+			"startTimeUnixNano": start,
+			"timeUnixNano":      ts,
+			"asInt":             strconv.FormatInt(c.value, 10),
+		})
+	}
+	for _, h := range e.histograms {
+		m, ok := hists[h.name]
+		if !ok {
+			m = map[string]interface{}{"name": h.name, "unit": h.unit, "histogram": map[string]interface{}{
+				"aggregationTemporality": aggregationCumulative,
+				"dataPoints":             []interface{}{},
+			}}
+			hists[h.name] = m
+			names = append(names, h.name)
+		}
+		counts := make([]string, len(h.buckets))
+		for i, n := range h.buckets {
+			counts[i] = strconv.FormatUint(n, 10)
+		}
+		hist := m["histogram"].(map[string]interface{})
+		hist["dataPoints"] = append(hist["dataPoints"].([]interface{}), map[string]interface{}{
+			"attributes":        attributes(h.attrs),
+			"startTimeUnixNano": start,
+			"timeUnixNano":      ts,
+			"count":             strconv.FormatUint(h.count, 10),
+			"sum":               h.sum,
+			"bucketCounts":      counts,
+			"explicitBounds":    durationBounds,
+		})
+	}
+
+	sort.Strings(names)
+	out := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		if m, ok := sums[name]; ok {
+			out = append(out, m)
+		} else {
+			out = append(out, hists[name])
+		}
+	}
+	return out
+}
+
+func (e *Exporter) resourceMetrics(metrics []interface{}) interface{} {
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": resource{Attributes: e.resource},
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   e.scope(),
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
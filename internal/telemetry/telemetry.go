@@ -0,0 +1,291 @@
+// Package telemetry exports traces and metrics over OTLP/HTTP with JSON
+// encoding, so any OpenTelemetry collector can receive them without the
+// SDK. Spans and counters are kept in memory and sent every interval and
+// on Flush; a nil *Exporter records nothing.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInterval is how often telemetry is exported.
+	DefaultInterval = 30 * time.Second
+	// maxSpans bounds the spans waiting for export; older ones are
+	// dropped when the collector is unreachable.
+	maxSpans      = 4096
+	exportTimeout = 10 * time.Second
+)
+
+// SpanKind is the OTLP span kind.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindClient   SpanKind = 3
+)
+
+// Options configure an Exporter.
+type Options struct {
+	// Endpoint is the collector's base URL, e.g. http://localhost:4318;
+	// /v1/traces and /v1/metrics are appended.
+	Endpoint string
+	Headers  map[string]string
+	// ServiceName and Version identify the CLI; Attributes are added to
+	// the resource, e.g. {"team": "payments"}.
+	ServiceName string
+	Version     string
+	Attributes  map[string]string
+	Interval    time.Duration
+	Transport   http.RoundTripper
+}
+
+// Exporter buffers spans and aggregates metrics until they are exported.
+type Exporter struct {
+	opts     Options
+	client   *http.Client
+	resource []attribute
+	started  time.Time
+
+	mu         sync.Mutex
+	spans      []spanData
+	counters   map[string]*counter
+	histograms map[string]*histogram
+
+	// exportMu keeps exports in order.
+	exportMu sync.Mutex
+}
+
+// New starts an exporter sending to opts.Endpoint, or returns nil when no
+// endpoint is set.
+func New(opts Options) *Exporter {
+	if opts.Endpoint == "" {
+		return nil
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "apipod-cli"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	opts.Endpoint = strings.TrimRight(opts.Endpoint, "/")
+	res := map[string]interface{}{
+		"service.name":    opts.ServiceName,
+		"service.version": opts.Version,
+		"os.type":         runtime.GOOS,
+		"host.arch":       runtime.GOARCH,
+	}
+	for k, v := range opts.Attributes {
+		res[k] = v
+	}
+	e := &Exporter{
+		opts:       opts,
+		client:     &http.Client{Timeout: exportTimeout, Transport: opts.Transport},
+		resource:   attributes(res),
+		started:    time.Now(),
+		counters:   make(map[string]*counter),
+		histograms: make(map[string]*histogram),
+	}
+	go e.loop()
+	return e
+}
+
+func (e *Exporter) loop() {
+	for range time.Tick(e.opts.Interval) {
+		e.Flush()
+	}
+}
+
+// Span is an operation being timed. Its methods do nothing on a nil Span.
+type Span struct {
+	e        *Exporter
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs map[string]interface{}
+}
+
+// Start begins a span at start, as a child of parent or, without one, as
+// the root of a new trace.
+func (e *Exporter) Start(name string, parent *Span, kind SpanKind, start time.Time) *Span {
+	if e == nil {
+		return nil
+	}
+	sp := &Span{e: e, spanID: randomHex(8), name: name, kind: kind, start: start, attrs: make(map[string]interface{})}
+	if parent != nil {
+		sp.traceID, sp.parentID = parent.traceID, parent.spanID
+	} else {
+		sp.traceID = randomHex(16)
+	}
+	return sp
+}
+
+// Set adds an attribute: a string, bool, int, int64 or float64.
+func (sp *Span) Set(key string, value interface{}) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	sp.attrs[key] = value
+	sp.mu.Unlock()
+}
+
+// End finishes the span now, marking it failed when err is not nil, and
+// queues it for export.
+func (sp *Span) End(err error) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	d := spanData{
+		TraceID:      sp.traceID,
+		SpanID:       sp.spanID,
+		ParentSpanID: sp.parentID,
+		Name:         sp.name,
+		Kind:         int(sp.kind),
+		Start:        nanos(sp.start),
+		End:          nanos(time.Now()),
+		Attributes:   attributes(sp.attrs),
+	}
+	sp.mu.Unlock()
+	if err != nil {
+		d.Status = &status{Code: 2, Message: err.Error()}
+	}
+	e := sp.e
+	e.mu.Lock()
+	e.spans = append(e.spans, d)
+	if len(e.spans) > maxSpans {
+		e.spans = e.spans[len(e.spans)-maxSpans:]
+	}
+	e.mu.Unlock()
+}
+
+// Add increases the counter name by n. attrs are key, value pairs.
+func (e *Exporter) Add(name, unit string, n int64, attrs ...string) {
+	if e == nil || n == 0 {
+		return
+	}
+	key := seriesKey(name, attrs)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.counters[key]
+	if !ok {
+		c = &counter{name: name, unit: unit, attrs: pairs(attrs)}
+		e.counters[key] = c
+	}
+	c.value += n
+}
+
+// Record adds a duration of v seconds to the histogram name. attrs are
+// key, value pairs.
+func (e *Exporter) Record(name, unit string, v float64, attrs ...string) {
+	if e == nil {
+		return
+	}
+	key := seriesKey(name, attrs)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h, ok := e.histograms[key]
+	if !ok {
+		h = &histogram{name: name, unit: unit, attrs: pairs(attrs), buckets: make([]uint64, len(durationBounds)+1)}
+		e.histograms[key] = h
+	}
+	h.count++
+	h.sum += v
+	h.buckets[sort.SearchFloat64s(durationBounds, v)]++
+}
+
+// Flush exports the spans queued and the current metric values.
+func (e *Exporter) Flush() error {
+	if e == nil {
+		return nil
+	}
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	metrics := e.metrics(time.Now())
+	e.mu.Unlock()
+
+	var errs []string
+	if len(spans) > 0 {
+		if err := e.post("/v1/traces", e.traces(spans)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(metrics) > 0 {
+		if err := e.post("/v1/metrics", e.resourceMetrics(metrics)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("export telemetry: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (e *Exporter) post(path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.opts.Endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func seriesKey(name string, attrs []string) string {
+	return name + "\x00" + strings.Join(attrs, "\x00")
+}
+
+func pairs(attrs []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		m[attrs[i]] = attrs[i+1]
+	}
+	return m
+}
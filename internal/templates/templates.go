@@ -0,0 +1,339 @@
+// Package templates loads reusable prompts for `apipod-cli run`. A
+// template is a markdown file with frontmatter, or a YAML file, declaring
+// its arguments and optionally the model and tools to use; the prompt
+// refers to arguments as {{name}}.
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/config"
+)
+
+// Template is a saved prompt.
+type Template struct {
+	Name        string
+	Description string
+	// Model and AllowedTools, when set, replace the session's for the run.
+	Model        string
+	AllowedTools []string
+	Args         []Arg
+	// Headless runs the template like `apipod-cli "prompt"` unless the
+	// command line asks for a session.
+	Headless bool
+	Prompt   string
+	// Source is "user" (~/.apipod/templates) or "project"
+	// (<workdir>/.apipod/templates).
+	Source string
+	Path   string
+}
+
+// Arg is an argument of a template, declared as "name" (required),
+// "name=default" or "name=a|b|c" (one of the choices, the first by
+// default).
+type Arg struct {
+	Name     string
+	Default  string
+	Required bool
+	Choices  []string
+}
+
+// placeholder matches {{name}} in a prompt.
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// Load returns the user and project templates sorted by name, and the
+// files that could not be read. A project template overrides a user
+// template of the same name; files in subdirectories are namespaced, so
+// docs/api.md becomes docs:api.
+func Load(workDir string) ([]*Template, []error) {
+	byName := make(map[string]*Template)
+	var errs []error
+	home, _ := os.UserHomeDir()
+	dirs := []struct{ dir, source string }{
+		{filepath.Join(home, config.ConfigDir, "templates"), "user"},
+		{filepath.Join(workDir, config.ConfigDir, "templates"), "project"},
+	}
+	for _, d := range dirs {
+		filepath.WalkDir(d.dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			ext := filepath.Ext(path)
+			if ext != ".md" && ext != ".yaml" && ext != ".yml" {
+				return nil
+			}
+			rel, _ := filepath.Rel(d.dir, path)
+			name := strings.ReplaceAll(filepath.ToSlash(strings.TrimSuffix(rel, ext)), "/", ":")
+			t, err := parseFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+				return nil
+			}
+			t.Name, t.Source, t.Path = name, d.source, path
+			byName[name] = t
+			return nil
+		})
+	}
+	list := make([]*Template, 0, len(byName))
+	for _, t := range byName {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, errs
+}
+
+// Find returns the template called name.
+func Find(list []*Template, name string) *Template {
+	for _, t := range list {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Usage shows how to call the template, e.g.
+// "release-notes <version> [since=HEAD~20] [format=markdown|html]".
+func (t *Template) Usage() string {
+	parts := []string{t.Name}
+	for _, a := range t.Args {
+		switch {
+		case a.Required:
+			parts = append(parts, "<"+a.Name+">")
+		case len(a.Choices) > 0:
+			parts = append(parts, "["+a.Name+"="+strings.Join(a.Choices, "|")+"]")
+		default:
+			parts = append(parts, "["+a.Name+"="+a.Default+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Expand returns the prompt for args, given in order or as name=value.
+// Missing required arguments, unknown names, extra arguments and values
+// outside an argument's choices are errors.
+func (t *Template) Expand(args []string) (string, error) {
+	values := make(map[string]string)
+	next := 0
+	for _, arg := range args {
+		if key, value, ok := strings.Cut(arg, "="); ok && t.arg(key) != nil {
+			values[key] = value
+			continue
+		}
+		for next < len(t.Args) && values[t.Args[next].Name] != "" {
+			next++
+		}
+		if next >= len(t.Args) {
+			return "", fmt.Errorf("too many arguments; usage: %s", t.Usage())
+		}
+		values[t.Args[next].Name] = arg
+		next++
+	}
+	for _, a := range t.Args {
+		v, ok := values[a.Name]
+		if !ok || v == "" {
+			if a.Required {
+				return "", fmt.Errorf("missing argument %s; usage: %s", a.Name, t.Usage())
+			}
+			values[a.Name] = a.Default
+			continue
+		}
+		if len(a.Choices) > 0 && !contains(a.Choices, v) {
+			return "", fmt.Errorf("%s must be one of %s, not %q", a.Name, strings.Join(a.Choices, ", "), v)
+		}
+	}
+	return placeholder.ReplaceAllStringFunc(t.Prompt, func(m string) string {
+		return values[placeholder.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+func (t *Template) arg(name string) *Arg {
+	for i := range t.Args {
+		if t.Args[i].Name == name {
+			return &t.Args[i]
+		}
+	}
+	return nil
+}
+
+func parseFile(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var meta map[string]string
+	var prompt string
+	if filepath.Ext(path) == ".md" {
+		var front string
+		front, prompt = splitFrontmatter(text)
+		meta, err = parseYAML(front)
+	} else {
+		meta, err = parseYAML(text)
+		prompt = meta["prompt"]
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &Template{
+		Description:  meta["description"],
+		Model:        meta["model"],
+		AllowedTools: parseList(meta["allowed-tools"]),
+		Headless:     meta["headless"] == "true",
+		Prompt:       strings.TrimSpace(prompt),
+	}
+	if t.Prompt == "" {
+		return nil, fmt.Errorf("the template has no prompt")
+	}
+	// Defaults may contain spaces, so arguments are split at commas only.
+	for _, spec := range strings.Split(strings.Trim(meta["arguments"], "[]"), ",") {
+		if spec = strings.Trim(strings.TrimSpace(spec), `"'`); spec == "" {
+			continue
+		}
+		name, def, hasDefault := strings.Cut(spec, "=")
+		name = strings.TrimSpace(name)
+		a := Arg{Name: name, Default: def, Required: !hasDefault}
+		if strings.Contains(def, "|") {
+			a.Choices = strings.Split(def, "|")
+			a.Default = a.Choices[0]
+		}
+		if !placeholder.MatchString("{{" + name + "}}") {
+			return nil, fmt.Errorf("invalid argument name %q", name)
+		}
+		if t.arg(name) != nil {
+			return nil, fmt.Errorf("argument %s is declared twice", name)
+		}
+		t.Args = append(t.Args, a)
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(t.Prompt, -1) {
+		if t.arg(m[1]) == nil {
+			return nil, fmt.Errorf("the prompt uses {{%s}}, which is not in arguments", m[1])
+		}
+	}
+	if t.Description == "" {
+		line, _, _ := strings.Cut(t.Prompt, "\n")
+		t.Description = strings.TrimLeft(line, "# ")
+	}
+	return t, nil
+}
+
+// splitFrontmatter separates a leading "---" block from the body.
+func splitFrontmatter(s string) (string, string) {
+	if !strings.HasPrefix(s, "---\n") {
+		return "", s
+	}
+	rest := s[4:]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return "", s
+	}
+	body := rest[end+4:]
+	if i := strings.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = ""
+	}
+	return rest[:end], body
+}
+
+// parseYAML reads the flat YAML of a template: "key: value" lines, lists
+// of "- item" lines, which are joined with commas, and "|" or ">" block
+// text.
+func parseYAML(text string) (map[string]string, error) {
+	meta := make(map[string]string)
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", i+1)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		// The indented lines that follow belong to the key.
+		var block []string
+		for i+1 < len(lines) && (strings.TrimSpace(lines[i+1]) == "" || strings.HasPrefix(lines[i+1], " ") || strings.HasPrefix(lines[i+1], "\t") || strings.HasPrefix(lines[i+1], "- ")) {
+			i++
+			block = append(block, lines[i])
+		}
+		switch {
+		case value == "|" || value == "|-" || value == ">" || value == ">-":
+			text := dedent(block)
+			if value[0] == '>' {
+				text = strings.Join(strings.Fields(text), " ")
+			}
+			meta[key] = text
+		case value == "" && len(block) > 0:
+			var items []string
+			for _, l := range block {
+				if item, ok := strings.CutPrefix(strings.TrimSpace(l), "- "); ok {
+					items = append(items, strings.Trim(strings.TrimSpace(item), `"'`))
+				}
+			}
+			meta[key] = strings.Join(items, ", ")
+		default:
+			meta[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return meta, nil
+}
+
+// dedent removes the indentation of the first non-empty line from lines.
+func dedent(lines []string) string {
+	indent := ""
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			indent = l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+			break
+		}
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, indent)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// parseList accepts "A, B", "[A, B]" or "A B". Tool patterns such as
+// Bash(git:*) are kept whole, spaces inside the parentheses included.
+func parseList(s string) []string {
+	var out []string
+	var cur strings.Builder
+	depth := 0
+	flush := func() {
+		if f := strings.Trim(cur.String(), `"'`); f != "" {
+			out = append(out, f)
+		}
+		cur.Reset()
+	}
+	for _, r := range strings.Trim(strings.TrimSpace(s), "[]") {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case (r == ',' || r == ' ') && depth == 0:
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	flush()
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rpay/apipod-cli/internal/httpreq"
+	"github.com/rpay/apipod-cli/internal/jsondiff"
+)
+
+const (
+	apiDiffMaxBody    = 1 << 20
+	apiDiffMaxChanges = 30
+)
+
+// apiBaseline is the file written by ApiDiff snapshot.
+type apiBaseline struct {
+	Created    time.Time     `json:"created"`
+	Collection string        `json:"collection"`
+	Responses  []apiResponse `json:"responses"`
+}
+
+type apiResponse struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	Body   string `json:"body"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (e *Executor) executeAPIDiff(call ToolCall) ToolResult {
+	action, _ := call.Input["action"].(string)
+	reqs, err := e.APIDiffRequests(call.Input)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	collection, _ := call.Input["collection"].(string)
+	baseline, _ := call.Input["baseline"].(string)
+	if baseline == "" {
+		stem := strings.TrimSuffix(filepath.Base(collection), filepath.Ext(collection))
+		baseline = filepath.Join(".apipod", "apidiff", stem+".json")
+	}
+	baselinePath := e.resolvePath(baseline)
+
+	timeout := httpDefaultTimeout
+	if v, ok := call.Input["timeout"].(float64); ok && v > 0 {
+		timeout = min(time.Duration(v*float64(time.Second)), httpMaxTimeout)
+	}
+	var ignore []string
+	if raw, ok := call.Input["ignore"].([]interface{}); ok {
+		for _, p := range raw {
+			if s, ok := p.(string); ok {
+				ignore = append(ignore, s)
+			}
+		}
+	}
+
+	switch action {
+	case "snapshot":
+		out, err := e.snapshotAPI(reqs, collection, baselinePath, baseline, timeout)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+		}
+		return ToolResult{ToolUseID: call.ID, Content: out}
+	case "compare":
+		out, err := e.compareAPI(reqs, baselinePath, ignore, timeout)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+		}
+		return ToolResult{ToolUseID: call.ID, Content: out}
+	default:
+		return ToolResult{ToolUseID: call.ID, Content: "action must be snapshot or compare", IsError: true}
+	}
+}
+
+// APIDiffRequests loads the collection of an ApiDiff call with its
+// base_url and headers overrides applied.
+func (e *Executor) APIDiffRequests(input map[string]interface{}) ([]httpreq.Request, error) {
+	collection, _ := input["collection"].(string)
+	if collection == "" {
+		return nil, fmt.Errorf("missing required parameter: collection")
+	}
+	reqs, err := httpreq.LoadCollection(e.resolvePath(collection))
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("collection %s has no requests", collection)
+	}
+
+	var base *url.URL
+	if b, _ := input["base_url"].(string); b != "" {
+		if base, err = url.Parse(b); err != nil || base.Host == "" {
+			return nil, fmt.Errorf("invalid base_url %q", b)
+		}
+	}
+	headers := map[string]string{}
+	if raw, ok := input["headers"].(map[string]interface{}); ok {
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				headers[k] = s
+			}
+		}
+	}
+	for i := range reqs {
+		if base != nil {
+			u, err := url.Parse(reqs[i].URL)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", reqs[i].Name, err)
+			}
+			u.Scheme, u.Host = base.Scheme, base.Host
+			u.Path = strings.TrimSuffix(base.Path, "/") + u.Path
+			reqs[i].URL = u.String()
+		}
+		for _, h := range httpreq.SortedHeaders(headers) {
+			reqs[i].SetHeader(h.Name, h.Value)
+		}
+	}
+	return reqs, nil
+}
+
+func (e *Executor) replayAPI(req httpreq.Request, timeout time.Duration) apiResponse {
+	out := apiResponse{Name: req.Name, Method: req.Method, URL: req.URL}
+	httpReq, err := newHTTPRequest(&req)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	e.recordHTTP(req)
+	resp, err := newHTTPClient(&req, timeout).Do(httpReq)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, apiDiffMaxBody))
+	if err != nil {
+		out.Error = err.Error()
+	}
+	out.Status = resp.StatusCode
+	out.Body = string(body)
+	return out
+}
+
+func (e *Executor) snapshotAPI(reqs []httpreq.Request, collection, path, shown string, timeout time.Duration) (string, error) {
+	b := apiBaseline{Created: time.Now().UTC(), Collection: collection}
+	var sb strings.Builder
+	failed := 0
+	for _, r := range reqs {
+		resp := e.replayAPI(r, timeout)
+		b.Responses = append(b.Responses, resp)
+		if resp.Error != "" {
+			failed++
+			fmt.Fprintf(&sb, "✗ %s: %s\n", r.Name, resp.Error)
+		} else {
+			fmt.Fprintf(&sb, "  %s: %d (%s)\n", r.Name, resp.Status, formatSize(int64(len(resp.Body))))
+		}
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, defaultFileMode); err != nil {
+		return "", fmt.Errorf("write baseline: %w", err)
+	}
+	fmt.Fprintf(&sb, "\nSaved baseline of %d response(s) to %s", len(b.Responses), shown)
+	if failed > 0 {
+		fmt.Fprintf(&sb, "; %d request(s) failed and will be compared as failures", failed)
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+func (e *Executor) compareAPI(reqs []httpreq.Request, path string, ignore []string, timeout time.Duration) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read baseline (run action snapshot first): %w", err)
+	}
+	var b apiBaseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return "", fmt.Errorf("parse baseline: %w", err)
+	}
+	byName := make(map[string]apiResponse, len(b.Responses))
+	for _, r := range b.Responses {
+		byName[r.Name] = r
+	}
+
+	var sb strings.Builder
+	changed, missing := 0, 0
+	for _, r := range reqs {
+		before, ok := byName[r.Name]
+		if !ok {
+			missing++
+			fmt.Fprintf(&sb, "? %s: not in baseline\n", r.Name)
+			continue
+		}
+		after := e.replayAPI(r, timeout)
+		diffs := compareResponses(before, after, ignore)
+		if len(diffs) == 0 {
+			fmt.Fprintf(&sb, "✓ %s\n", r.Name)
+			continue
+		}
+		changed++
+		fmt.Fprintf(&sb, "✗ %s\n", r.Name)
+		for i, d := range diffs {
+			if i == apiDiffMaxChanges {
+				fmt.Fprintf(&sb, "    ... and %d more\n", len(diffs)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "    %s\n", d)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n%d of %d response(s) changed since the baseline of %s", changed, len(reqs)-missing, b.Created.Local().Format("2006-01-02 15:04"))
+	if missing > 0 {
+		fmt.Fprintf(&sb, "; %d request(s) not in baseline", missing)
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+// compareResponses lists status and body differences; JSON bodies are
+// compared structurally, anything else byte for byte.
+func compareResponses(before, after apiResponse, ignore []string) []string {
+	var out []string
+	if before.Error != after.Error {
+		out = append(out, fmt.Sprintf("error: %q → %q", before.Error, after.Error))
+	}
+	if before.Status != after.Status {
+		out = append(out, fmt.Sprintf("status: %d → %d", before.Status, after.Status))
+	}
+	if before.Body == after.Body {
+		return out
+	}
+	a, errA := jsondiff.Parse([]byte(before.Body))
+	b, errB := jsondiff.Parse([]byte(after.Body))
+	if errA != nil || errB != nil {
+		return append(out, fmt.Sprintf("body: non-JSON content differs (%s → %s)",
+			formatSize(int64(len(before.Body))), formatSize(int64(len(after.Body)))))
+	}
+	for _, c := range jsondiff.Diff(a, b, ignore) {
+		out = append(out, c.String())
+	}
+	return out
+}
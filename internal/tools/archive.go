@@ -0,0 +1,353 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// archiveMaxExtract caps the total bytes written by one extraction.
+	archiveMaxExtract = 100 << 20
+	// archiveMaxRead caps the bytes shown by the read action.
+	archiveMaxRead   = 256 << 10
+	archiveMaxListed = 500
+)
+
+type archiveMember struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	dir     bool
+	regular bool
+	open    func() (io.ReadCloser, error)
+}
+
+func (e *Executor) executeArchive(call ToolCall) ToolResult {
+	action, _ := call.Input["action"].(string)
+	archivePath, _ := call.Input["archive_path"].(string)
+	if archivePath == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: archive_path", IsError: true}
+	}
+	var members []string
+	if raw, ok := call.Input["members"].([]interface{}); ok {
+		for _, m := range raw {
+			if s, ok := m.(string); ok && s != "" {
+				members = append(members, s)
+			}
+		}
+	}
+
+	resolved := e.resolvePath(archivePath)
+	var out string
+	err := walkArchive(resolved, func(entries []archiveMember) error {
+		var err error
+		switch action {
+		case "", "list":
+			out = listArchive(entries)
+		case "read":
+			out, err = readArchiveMembers(entries, members)
+		case "extract":
+			dest, _ := call.Input["destination"].(string)
+			out, err = e.extractArchive(entries, members, dest)
+		default:
+			err = fmt.Errorf("unknown action %q (use list, read or extract)", action)
+		}
+		return err
+	})
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	return ToolResult{ToolUseID: call.ID, Content: out}
+}
+
+// walkArchive opens a zip (or jar/whl), tar or tar.gz/tgz file and passes
+// its members to fn while the archive is still open.
+func walkArchive(p string, fn func([]archiveMember) error) error {
+	lower := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".jar") || strings.HasSuffix(lower, ".whl"):
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		var entries []archiveMember
+		for _, f := range zr.File {
+			f := f
+			entries = append(entries, archiveMember{
+				name:    f.Name,
+				size:    int64(f.UncompressedSize64),
+				mode:    f.Mode(),
+				modTime: f.Modified,
+				dir:     f.FileInfo().IsDir(),
+				regular: f.Mode().IsRegular(),
+				open:    func() (io.ReadCloser, error) { return f.Open() },
+			})
+		}
+		return fn(entries)
+
+	case strings.HasSuffix(lower, ".tar"), strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return walkTar(p, strings.HasSuffix(lower, "gz"), fn)
+
+	default:
+		return fmt.Errorf("unsupported archive type: %s (zip, jar, whl, tar, tar.gz, tgz)", filepath.Base(p))
+	}
+}
+
+// walkTar reads a tar stream twice: once to collect the headers, and again
+// per member read, since tar has no random access.
+func walkTar(p string, gz bool, fn func([]archiveMember) error) error {
+	openStream := func() (*tar.Reader, io.Closer, error) {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		var r io.Reader = f
+		if gz {
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+			r = zr
+		}
+		return tar.NewReader(r), f, nil
+	}
+
+	tr, closer, err := openStream()
+	if err != nil {
+		return err
+	}
+	var entries []archiveMember
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			closer.Close()
+			return fmt.Errorf("read tar: %w", err)
+		}
+		name := h.Name
+		entries = append(entries, archiveMember{
+			name:    name,
+			size:    h.Size,
+			mode:    h.FileInfo().Mode(),
+			modTime: h.ModTime,
+			dir:     h.Typeflag == tar.TypeDir,
+			regular: h.Typeflag == tar.TypeReg,
+			open: func() (io.ReadCloser, error) {
+				tr, c, err := openStream()
+				if err != nil {
+					return nil, err
+				}
+				for {
+					h, err := tr.Next()
+					if err != nil {
+						c.Close()
+						return nil, fmt.Errorf("member %s: %w", name, err)
+					}
+					if h.Name == name {
+						return readCloser{tr, c}, nil
+					}
+				}
+			},
+		})
+	}
+	closer.Close()
+	return fn(entries)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func listArchive(entries []archiveMember) string {
+	var sb strings.Builder
+	var total int64
+	for i, m := range entries {
+		total += m.size
+		if i >= archiveMaxListed {
+			continue
+		}
+		kind := " "
+		if m.dir {
+			kind = "d"
+		} else if !m.regular {
+			kind = "l"
+		}
+		fmt.Fprintf(&sb, "%s %10s  %s  %s\n", kind, formatSize(m.size), m.modTime.Format("2006-01-02 15:04"), m.name)
+	}
+	if len(entries) > archiveMaxListed {
+		fmt.Fprintf(&sb, "... and %d more members\n", len(entries)-archiveMaxListed)
+	}
+	fmt.Fprintf(&sb, "\n%d members, %s uncompressed\n", len(entries), formatSize(total))
+	return sb.String()
+}
+
+func selectMembers(entries []archiveMember, names []string) ([]archiveMember, error) {
+	if len(names) == 0 {
+		return entries, nil
+	}
+	var out []archiveMember
+	for _, n := range names {
+		found := false
+		for _, m := range entries {
+			if m.name == n || strings.HasPrefix(m.name, strings.TrimSuffix(n, "/")+"/") {
+				out = append(out, m)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("member not found: %s", n)
+		}
+	}
+	return out, nil
+}
+
+func readArchiveMembers(entries []archiveMember, names []string) (string, error) {
+	if len(names) == 0 {
+		return "", fmt.Errorf("read needs members")
+	}
+	selected, err := selectMembers(entries, names)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	budget := int64(archiveMaxRead)
+	for _, m := range selected {
+		if m.dir || !m.regular {
+			continue
+		}
+		fmt.Fprintf(&sb, "==> %s (%s) <==\n", m.name, formatSize(m.size))
+		if budget <= 0 {
+			sb.WriteString("[output limit reached]\n")
+			break
+		}
+		rc, err := m.open()
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, budget))
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", m.name, err)
+		}
+		if isBinary(data[:min(len(data), binarySniffLen)]) {
+			sb.WriteString("[binary member; contents not shown]\n\n")
+			continue
+		}
+		budget -= int64(len(data))
+		sb.WriteString(strings.TrimRight(string(data), "\n"))
+		if int64(len(data)) < m.size {
+			fmt.Fprintf(&sb, "\n[truncated; %s more]", formatSize(m.size-int64(len(data))))
+		}
+		sb.WriteString("\n\n")
+	}
+	return sb.String(), nil
+}
+
+// extractArchive writes the selected members under destination, which must
+// stay inside the working directory. Absolute names, ".." components and
+// links are refused so an archive cannot write outside the destination.
+func (e *Executor) extractArchive(entries []archiveMember, names []string, destination string) (string, error) {
+	if destination == "" {
+		return "", fmt.Errorf("extract needs a destination directory")
+	}
+	dest := e.resolvePath(destination)
+	if !withinDir(e.workDir, dest) {
+		return "", fmt.Errorf("destination must be inside the working directory")
+	}
+	selected, err := selectMembers(entries, names)
+	if err != nil {
+		return "", err
+	}
+
+	var total int64
+	for _, m := range selected {
+		total += m.size
+	}
+	if total > archiveMaxExtract {
+		return "", fmt.Errorf("extraction would write %s (limit %s); select fewer members", formatSize(total), formatSize(archiveMaxExtract))
+	}
+
+	written, skipped := 0, 0
+	for _, m := range selected {
+		clean := path.Clean("/" + m.name)[1:]
+		if clean == "" || path.IsAbs(m.name) || hasDotDot(m.name) {
+			skipped++
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(clean))
+		if m.dir {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if !m.regular {
+			skipped++
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return "", err
+		}
+		if err := extractMember(m, target); err != nil {
+			return "", err
+		}
+		written++
+	}
+
+	msg := fmt.Sprintf("Extracted %d file(s) (%s) to %s", written, formatSize(total), destination)
+	if skipped > 0 {
+		msg += fmt.Sprintf("; skipped %d link(s) or unsafe path(s)", skipped)
+	}
+	return msg, nil
+}
+
+func extractMember(m archiveMember, target string) error {
+	rc, err := m.open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	mode := m.mode.Perm()
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	// The size was checked from the headers; the limit guards against
+	// headers that understate the real content.
+	if _, err := io.Copy(f, io.LimitReader(rc, m.size)); err != nil {
+		f.Close()
+		return fmt.Errorf("extract %s: %w", m.name, err)
+	}
+	return f.Close()
+}
+
+func hasDotDot(name string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func withinDir(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDenyPaths are credential stores and browser profiles no tool may
+// touch, whatever the user approves. "~" is the home directory.
+var DefaultDenyPaths = []string{
+	"~/.ssh",
+	"~/.aws",
+	"~/.gnupg",
+	"~/.azure",
+	"~/.config/gcloud",
+	"~/.kube/config",
+	"~/.docker/config.json",
+	"~/.netrc",
+	"~/.git-credentials",
+	"~/.apipod/config.json",
+	"~/.apipod/trusted_hooks.json",
+	"~/.mozilla",
+	"~/.config/google-chrome",
+	"~/.config/chromium",
+	"~/.config/BraveSoftware",
+	"~/Library/Application Support/Google/Chrome",
+	"~/Library/Application Support/Firefox",
+	"~/Library/Application Support/BraveSoftware",
+	"~/Library/Safari",
+	"~/Library/Keychains",
+	"~/AppData/Local/Google/Chrome/User Data",
+	"~/AppData/Local/Microsoft/Edge/User Data",
+	"~/AppData/Roaming/Mozilla/Firefox",
+}
+
+// pathInputs are the tool inputs that name files or directories.
+var pathInputs = []string{"file_path", "path", "archive_path", "destination", "collection", "baseline", "cwd"}
+
+// SetDenyPaths replaces the deny list with the defaults plus extra.
+func (e *Executor) SetDenyPaths(extra []string) {
+	e.deny = expandDenyPaths(append(append([]string{}, DefaultDenyPaths...), extra...))
+}
+
+func expandDenyPaths(paths []string) []string {
+	home, _ := os.UserHomeDir()
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			if home == "" {
+				continue
+			}
+			p = filepath.Join(home, p[1:])
+		}
+		out = append(out, filepath.Clean(filepath.FromSlash(p)))
+	}
+	return out
+}
+
+// deniedPath returns the deny entry covering p, following symlinks so a
+// link inside the project cannot reach a denied location.
+func (e *Executor) deniedPath(p string) (string, bool) {
+	abs := filepath.Clean(e.resolvePath(p))
+	candidates := []string{abs}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		candidates = append(candidates, real)
+	} else if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		candidates = append(candidates, filepath.Join(dir, filepath.Base(abs)))
+	}
+	for _, d := range e.deny {
+		for _, c := range candidates {
+			if withinDir(d, c) {
+				return d, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Blocked refuses calls whose path inputs, or Bash commands that mention a
+// path, fall under the deny list. Execute checks it too; callers use it to
+// skip the confirmation prompt for calls that would be refused anyway.
+func (e *Executor) Blocked(call ToolCall) *ToolResult {
+	var paths []string
+	for _, k := range pathInputs {
+		if p, ok := call.Input[k].(string); ok && p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if raw, ok := call.Input["paths"].([]interface{}); ok {
+		for _, p := range raw {
+			if s, ok := p.(string); ok && s != "" {
+				paths = append(paths, s)
+			}
+		}
+	}
+	if call.Name == "ApplyPatch" {
+		patch, _ := call.Input["patch"].(string)
+		paths = append(paths, patchPaths(patch)...)
+	}
+	for _, p := range paths {
+		if d, ok := e.deniedPath(p); ok {
+			return deniedResult(call, p, d)
+		}
+	}
+	if call.Name == "Bash" {
+		command, _ := call.Input["command"].(string)
+		if d, ok := e.commandMentionsDenied(command); ok {
+			return deniedResult(call, d, d)
+		}
+	}
+	return nil
+}
+
+// patchPaths returns the files a unified diff reads or writes: the paths
+// of its ---/+++ headers and of git's rename and copy lines.
+func patchPaths(patch string) []string {
+	var paths []string
+	for _, line := range strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n") {
+		var p string
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			p = diffPath(line[4:])
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "copy from "):
+			_, p, _ = strings.Cut(line, " from ")
+		case strings.HasPrefix(line, "rename to "), strings.HasPrefix(line, "copy to "):
+			_, p, _ = strings.Cut(line, " to ")
+		}
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+func deniedResult(call ToolCall, p, entry string) *ToolResult {
+	return &ToolResult{
+		ToolUseID: call.ID,
+		Content: fmt.Sprintf("Access to %s is blocked: %s is on the sensitive-path deny list. "+
+			"Do not try to reach it another way; ask the user if the task really needs it.", p, entry),
+		IsError: true,
+	}
+}
+
+// commandMentionsDenied catches the common spellings of a denied path in a
+// shell command: absolute, ~/ and $HOME/. It cannot see paths built at run
+// time, so it backs up rather than replaces the confirmation prompt.
+func (e *Executor) commandMentionsDenied(command string) (string, bool) {
+	if command == "" {
+		return "", false
+	}
+	home, _ := os.UserHomeDir()
+	normalized := filepath.ToSlash(command)
+	for _, d := range e.deny {
+		forms := []string{filepath.ToSlash(d)}
+		if home != "" {
+			if rel, err := filepath.Rel(home, d); err == nil && !strings.HasPrefix(rel, "..") {
+				rel = filepath.ToSlash(rel)
+				forms = append(forms, "~/"+rel, "$HOME/"+rel, "${HOME}/"+rel)
+			}
+		}
+		for _, f := range forms {
+			if mentionsPath(normalized, f) {
+				return d, true
+			}
+		}
+	}
+	return "", false
+}
+
+// BashConfined reports whether a Bash call stays inside the project roots:
+// its cwd and every argument, read as a path with symlinks followed,
+// resolve within a root, and it sets no environment variables.
+// Auto-approved commands must pass it, since commandMentionsDenied only
+// sees the paths a command spells out.
+func (e *Executor) BashConfined(input map[string]interface{}) bool {
+	if vars, ok := input["env"].(map[string]interface{}); ok && len(vars) > 0 {
+		return false
+	}
+	dir := e.workDir
+	if cwd, _ := input["cwd"].(string); cwd != "" {
+		dir = e.resolvePath(cwd)
+		if !e.insideRoots(dir) {
+			return false
+		}
+	}
+	command, _ := input["command"].(string)
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return r == ';' || r == '&' || r == '|' || r == '\n' || r == ' ' || r == '\t'
+	})
+	for _, w := range words {
+		if strings.HasPrefix(w, "-") {
+			_, v, ok := strings.Cut(w, "=")
+			if !ok {
+				continue
+			}
+			w = v
+		}
+		// Words that name no file, such as search patterns, resolve to a
+		// path under dir and pass.
+		p := filepath.FromSlash(w)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if !e.insideRoots(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// insideRoots reports whether p, with symlinks resolved, lies within the
+// working directory or an added directory.
+func (e *Executor) insideRoots(p string) bool {
+	p = filepath.Clean(p)
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		p = real
+	}
+	for _, root := range e.Roots() {
+		if real, err := filepath.EvalSymlinks(root); err == nil {
+			root = real
+		}
+		if withinDir(root, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionsPath reports whether p occurs in s as a whole path or a prefix
+// of one, so ~/.ssh matches ~/.ssh/id_rsa but not ~/.sshrc.
+func mentionsPath(s, p string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], p)
+		if j < 0 {
+			return false
+		}
+		end := i + j + len(p)
+		if end == len(s) || strings.ContainsRune("/ \t\n'\";|&)", rune(s[end])) {
+			return true
+		}
+		i = end
+	}
+}
+
+// filterDeniedLines drops output lines that start with a denied path, as
+// produced by Grep when a search covers one.
+func (e *Executor) filterDeniedLines(lines []string) []string {
+	if len(e.deny) == 0 {
+		return lines
+	}
+	kept := lines[:0]
+	for _, line := range lines {
+		denied := false
+		for _, d := range e.deny {
+			if line == d || strings.HasPrefix(line, d+string(filepath.Separator)) || strings.HasPrefix(line, d+":") {
+				denied = true
+				break
+			}
+		}
+		if !denied {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
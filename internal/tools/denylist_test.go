@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlocked(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	e := NewExecutor(t.TempDir())
+
+	tests := []struct {
+		name  string
+		call  ToolCall
+		block bool
+	}{
+		{"read key", ToolCall{Name: "Read", Input: map[string]interface{}{"file_path": filepath.Join(home, ".ssh", "id_rsa")}}, true},
+		{"read dir", ToolCall{Name: "Glob", Input: map[string]interface{}{"path": filepath.Join(home, ".aws")}}, true},
+		{"trusted hooks", ToolCall{Name: "Write", Input: map[string]interface{}{"file_path": filepath.Join(home, ".apipod", "trusted_hooks.json")}}, true},
+		{"paths list", ToolCall{Name: "Archive", Input: map[string]interface{}{"paths": []interface{}{"a.txt", filepath.Join(home, ".netrc")}}}, true},
+		{"project file", ToolCall{Name: "Read", Input: map[string]interface{}{"file_path": "main.go"}}, false},
+		{"similar name", ToolCall{Name: "Read", Input: map[string]interface{}{"file_path": filepath.Join(home, ".sshrc")}}, false},
+		{"bash tilde", ToolCall{Name: "Bash", Input: map[string]interface{}{"command": "cat ~/.ssh/id_rsa"}}, true},
+		{"bash home var", ToolCall{Name: "Bash", Input: map[string]interface{}{"command": "tar czf x.tgz ${HOME}/.aws"}}, true},
+		{"bash absolute", ToolCall{Name: "Bash", Input: map[string]interface{}{"command": "cp " + filepath.Join(home, ".netrc") + " ."}}, true},
+		{"bash other", ToolCall{Name: "Bash", Input: map[string]interface{}{"command": "cat ~/.sshrc; ls"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.Blocked(tt.call) != nil; got != tt.block {
+				t.Errorf("Blocked = %v, want %v", got, tt.block)
+			}
+		})
+	}
+}
+
+func TestBlockedFollowsSymlinks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(filepath.Join(home, ".ssh"), filepath.Join(dir, "keys")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	e := NewExecutor(dir)
+	if e.Blocked(ToolCall{Name: "Read", Input: map[string]interface{}{"file_path": "keys/id_rsa"}}) == nil {
+		t.Error("Read through a symlink into ~/.ssh was not blocked")
+	}
+}
+
+func TestBashConfined(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	link := filepath.Join(dir, "out")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+	e := NewExecutor(dir)
+
+	tests := []struct {
+		input map[string]interface{}
+		want  bool
+	}{
+		{map[string]interface{}{"command": "ls -la src"}, true},
+		{map[string]interface{}{"command": "grep -rn TODO . | sort"}, true},
+		{map[string]interface{}{"command": "cat " + filepath.Join(dir, "go.mod")}, true},
+		{map[string]interface{}{"command": "cat /etc/passwd"}, false},
+		{map[string]interface{}{"command": "cat ../secret"}, false},
+		{map[string]interface{}{"command": "ls out/"}, false},
+		{map[string]interface{}{"command": "diff --from-file=/etc/hosts a"}, false},
+		{map[string]interface{}{"command": "ls", "cwd": outside}, false},
+		{map[string]interface{}{"command": "ls", "cwd": "src"}, true},
+		{map[string]interface{}{"command": "ls", "env": map[string]interface{}{"PATH": "."}}, false},
+	}
+	for _, tt := range tests {
+		if got := e.BashConfined(tt.input); got != tt.want {
+			t.Errorf("BashConfined(%v) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/doctext"
+)
+
+// maxDocumentSize bounds the documents Read extracts text from.
+const maxDocumentSize = 100 << 20
+
+// readDocument shows the extracted text of a PDF or Office document, a
+// page marker before each page, numbered and paged by offset and limit
+// like the lines of any file. The text is read-only, so no range_hash is
+// given.
+func readDocument(call ToolCall, reader *bufio.Reader, size int64, kind doctext.Kind, offset, limit int) ToolResult {
+	if size > maxDocumentSize {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("%s is too large to extract text from (%s, limit %s)", kind, formatSize(size), formatSize(maxDocumentSize)), IsError: true}
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	pages, err := doctext.Pages(kind, data)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Could not extract text from %s (%s): %v", kind, formatSize(size), err), IsError: true}
+	}
+
+	unit := "page"
+	if kind == doctext.KindPptx {
+		unit = "slide"
+	}
+	var lines []string
+	empty := true
+	for i, page := range pages {
+		if len(pages) > 1 {
+			lines = append(lines, fmt.Sprintf("--- %s %d ---", unit, i+1))
+		}
+		if page != "" {
+			lines = append(lines, strings.Split(page, "\n")...)
+			empty = false
+		}
+	}
+	if empty {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("%s (%s, %d %s(s)) has no extractable text; it may be scanned images", kind, formatSize(size), len(pages), unit)}
+	}
+	if offset >= len(lines) {
+		return ToolResult{ToolUseID: call.ID, Content: "Offset beyond file length", IsError: true}
+	}
+
+	var sb strings.Builder
+	if kind == doctext.KindDocx {
+		fmt.Fprintf(&sb, "[%s; extracted text, %d lines]\n", kind, len(lines))
+	} else {
+		fmt.Fprintf(&sb, "[%s, %d %s(s); extracted text, %d lines]\n", kind, len(pages), unit, len(lines))
+	}
+	end, truncatedLines := offset, 0
+	for ; end < len(lines) && end-offset < limit && sb.Len() < readMaxBytes; end++ {
+		line := lines[end]
+		if len(line) > readMaxLineLen {
+			line = fmt.Sprintf("%s... [line truncated, %d more chars]", truncateUTF8(line, readMaxLineLen), len(line)-readMaxLineLen)
+			truncatedLines++
+		}
+		fmt.Fprintf(&sb, "%5d│%s\n", end+1, line)
+	}
+	if end < len(lines) {
+		fmt.Fprintf(&sb, "\n[Showing lines %d-%d of %d. Use offset/limit to read further.]\n", offset+1, end, len(lines))
+	}
+	if truncatedLines > 0 {
+		fmt.Fprintf(&sb, "[%d long line(s) truncated to %d chars]\n", truncatedLines, readMaxLineLen)
+	}
+	return ToolResult{ToolUseID: call.ID, Content: sb.String()}
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// rangeHash identifies the content of a line range so EditLines can refuse
+// to edit lines that changed since they were read. Lines are joined with LF
+// and compared without CR.
+func rangeHash(lines []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:4])
+}
+
+func (e *Executor) executeEditLines(call ToolCall) ToolResult {
+	filePath, _ := call.Input["file_path"].(string)
+	startF, okStart := call.Input["start_line"].(float64)
+	endF, okEnd := call.Input["end_line"].(float64)
+	hash, _ := call.Input["range_hash"].(string)
+	newContent, _ := call.Input["new_content"].(string)
+
+	if filePath == "" || !okStart || !okEnd {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameters", IsError: true}
+	}
+	start, end := int(startF), int(endF)
+
+	resolved := e.resolvePath(filePath)
+	content, format, err := e.readWithFormat(resolved)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	// end_line = start_line-1 inserts before start_line without replacing.
+	if start < 1 || end < start-1 || end > len(lines) || start > len(lines)+1 {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Invalid range %d-%d: file has %d lines", start, end, len(lines)), IsError: true}
+	}
+	old := lines[start-1 : end]
+	if len(old) > 0 && hash != rangeHash(old) {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Lines %d-%d do not match range_hash %q; the file changed or the hash is for a different range. Read the file again with offset=%d limit=%d to get the current hash.", start, end, hash, start, end-start+1), IsError: true}
+	}
+
+	var replacement []string
+	if newContent = normalizeNewlines(newContent); newContent != "" {
+		replacement = strings.Split(strings.TrimSuffix(newContent, "\n"), "\n")
+	}
+
+	updated := make([]string, 0, len(lines)-len(old)+len(replacement))
+	updated = append(updated, lines[:start-1]...)
+	updated = append(updated, replacement...)
+	updated = append(updated, lines[end:]...)
+
+	text := strings.Join(updated, "\n")
+	if len(updated) > 0 {
+		text += "\n"
+	}
+	if err := e.writeWithFormat(resolved, text, format); err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+
+	action := fmt.Sprintf("replaced lines %d-%d with %d line(s)", start, end, len(replacement))
+	if len(old) == 0 {
+		action = fmt.Sprintf("inserted %d line(s) before line %d", len(replacement), start)
+	}
+	if len(replacement) == 0 {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Edited: %s (%s)", filePath, action)}
+	}
+	newEnd := start + len(replacement) - 1
+	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Edited: %s (%s; lines %d-%d now hash %s)", filePath, action, start, newEnd, rangeHash(replacement))}
+}
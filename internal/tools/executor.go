@@ -1,31 +1,85 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rpay/apipod-cli/internal/doctext"
+	"github.com/rpay/apipod-cli/internal/har"
+	"github.com/rpay/apipod-cli/internal/httpreq"
+	"github.com/rpay/apipod-cli/internal/ignore"
+	"github.com/rpay/apipod-cli/internal/index"
+	"github.com/rpay/apipod-cli/internal/redact"
+	"github.com/rpay/apipod-cli/internal/semantic"
 )
 
 type Executor struct {
 	workDir  string
 	bgShells map[string]*bgShell
 	bgMu     sync.Mutex
-}
 
-type bgShell struct {
-	cmd    *exec.Cmd
-	output strings.Builder
-	mu     sync.Mutex
+	semantic *semantic.Engine
+	grpc     *GrpcTarget
+	shell    Shell
+
+	// env holds the session's variables from the config and settings,
+	// added to the process environment of every command it starts.
+	env map[string]string
+
+	// extraDirs are further roots next to workDir; see AddDir.
+	extraDirs []string
+
+	// githubToken and gitlabToken authenticate the GitHost tool.
+	githubToken string
+	gitlabToken string
+
+	httpLog []httpreq.Request
+	httpMu  sync.Mutex
+	har     *har.Recorder
+
+	disabled     map[string]bool
+	allowed      map[string]bool
+	toolDefaults map[string]map[string]interface{}
+	deny         []string
+
+	// staging holds file changes in staged instead of writing them; see
+	// stage.go.
+	staging bool
+	staged  map[string]*stagedFile
+
+	// compactRereads and copies implement SetCompactRereads; see
+	// workingcopy.go.
+	compactRereads bool
+	copies         map[string]*workingCopy
+	copiesMu       sync.Mutex
+
+	fgMu       sync.Mutex
+	fgCancel   context.CancelFunc
+	onOutputFn func(line string)
 }
 
 func NewExecutor(workDir string) *Executor {
 	return &Executor{
 		workDir:  workDir,
+		shell:    defaultShell(),
 		bgShells: make(map[string]*bgShell),
+		deny:     expandDenyPaths(DefaultDenyPaths),
 	}
 }
 
@@ -39,38 +93,219 @@ type ToolResult struct {
 	ToolUseID string `json:"tool_use_id"`
 	Content   string `json:"content"`
 	IsError   bool   `json:"is_error,omitempty"`
+	// ExitCode is a foreground Bash command's exit status; it is not sent
+	// to the model, which reads the output instead.
+	ExitCode int `json:"-"`
 }
 
 func (e *Executor) Execute(call ToolCall) ToolResult {
+	if e.disabled[call.Name] || e.allowed != nil && !e.allowed[call.Name] {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Tool %s is not allowed in this session", call.Name), IsError: true}
+	}
+	e.applyDefaults(call)
+	if blocked := e.Blocked(call); blocked != nil {
+		return *blocked
+	}
 	switch call.Name {
 	case "Bash":
 		return e.executeBash(call)
 	case "Read":
 		return e.executeRead(call)
-	case "Write":
-		return e.executeWrite(call)
-	case "Edit":
-		return e.executeEdit(call)
-	case "MultiEdit":
-		return e.executeMultiEdit(call)
+	case "Write", "Edit", "MultiEdit", "EditLines":
+		var result ToolResult
+		switch call.Name {
+		case "Write":
+			result = e.executeWrite(call)
+		case "Edit":
+			result = e.executeEdit(call)
+		case "MultiEdit":
+			result = e.executeMultiEdit(call)
+		default:
+			result = e.executeEditLines(call)
+		}
+		if path, _ := call.Input["file_path"].(string); !result.IsError && path != "" {
+			e.rememberEdit(call.Name, e.resolvePath(path))
+		}
+		return result
+	case "ApplyPatch":
+		e.ForgetReads()
+		return e.executeApplyPatch(call)
+	case "Archive":
+		return e.executeArchive(call)
+	case "Stat":
+		return e.executeStat(call)
+	case "HttpRequest":
+		return e.executeHTTPRequest(call)
+	case "ApiDiff":
+		return e.executeAPIDiff(call)
+	case "WebSocket":
+		return e.executeWebSocket(call)
+	case "Grpc":
+		return e.executeGrpc(call)
+	case "GitHost":
+		return e.executeGitHost(call)
 	case "Glob":
 		return e.executeGlob(call)
 	case "Grep":
 		return e.executeGrep(call)
+	case "SemanticSearch":
+		return e.executeSemanticSearch(call)
 	case "BashOutput":
 		return e.executeBashOutput(call)
 	case "KillBash":
 		return e.executeKillBash(call)
+	case "ListShells":
+		return e.executeListShells(call)
 	default:
 		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Unknown tool: %s", call.Name), IsError: true}
 	}
 }
 
+// SetWorkDir changes the directory relative paths and commands resolve
+// against, e.g. when the session is scoped to a monorepo package.
+func (e *Executor) SetWorkDir(dir string) {
+	e.workDir = dir
+}
+
+func (e *Executor) WorkDir() string {
+	return e.workDir
+}
+
+// SetToolSettings disables tools, limits them to allowed when it is not
+// nil and sets default inputs per tool, as read from the project settings.
+func (e *Executor) SetToolSettings(disabled, allowed []string, defaults map[string]map[string]interface{}) {
+	e.disabled = make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		e.disabled[name] = true
+	}
+	e.allowed = nil
+	if allowed != nil {
+		e.allowed = make(map[string]bool, len(allowed))
+		for _, name := range allowed {
+			e.allowed[name] = true
+		}
+	}
+	e.toolDefaults = defaults
+}
+
+// applyDefaults fills inputs the model did not set from the tool defaults.
+func (e *Executor) applyDefaults(call ToolCall) {
+	if call.Input == nil {
+		return
+	}
+	for k, v := range e.toolDefaults[call.Name] {
+		if _, ok := call.Input[k]; !ok {
+			call.Input[k] = v
+		}
+	}
+}
+
+// ToolEnabled reports whether a tool should be offered to the model.
+// Optional tools are only enabled once configured.
+func (e *Executor) ToolEnabled(name string) bool {
+	if e.disabled[name] || e.allowed != nil && !e.allowed[name] {
+		return false
+	}
+	switch name {
+	case "SemanticSearch":
+		return e.semantic != nil
+	case "Grpc":
+		return e.grpc != nil
+	default:
+		return true
+	}
+}
+
 func (e *Executor) resolvePath(p string) string {
+	p = filepath.FromSlash(p)
 	if filepath.IsAbs(p) {
 		return p
 	}
-	return filepath.Join(e.workDir, p)
+	resolved := filepath.Join(e.workDir, p)
+	if len(e.extraDirs) == 0 {
+		return resolved
+	}
+	// A relative path missing from the working directory may name a file
+	// in an added directory; new files still go to the working directory.
+	if _, err := os.Stat(resolved); err != nil {
+		for _, dir := range e.extraDirs {
+			if _, err := os.Stat(filepath.Join(dir, p)); err == nil {
+				return filepath.Join(dir, p)
+			}
+		}
+	}
+	return resolved
+}
+
+// ErrRootExists is returned by AddDir for a directory that is already a
+// root.
+var ErrRootExists = errors.New("already a root of this session")
+
+// AddDir makes dir a further root of the session: Glob and Grep search
+// it, and relative paths that exist only there resolve into it.
+func (e *Executor) AddDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	for _, root := range e.Roots() {
+		if root == dir {
+			return "", fmt.Errorf("%s: %w", dir, ErrRootExists)
+		}
+	}
+	e.extraDirs = append(e.extraDirs, dir)
+	return dir, nil
+}
+
+// ExtraDirs returns the directories added with AddDir.
+func (e *Executor) ExtraDirs() []string {
+	return e.extraDirs
+}
+
+// SetEnv sets the variables given to the commands the executor and its
+// hooks run, on top of the process environment. The process itself is
+// left alone, so sessions sharing it keep their own settings.
+func (e *Executor) SetEnv(vars map[string]string) {
+	e.env = vars
+}
+
+// Environ returns the environment for a command the session runs: the
+// process environment followed by the session's variables, which win.
+func (e *Executor) Environ() []string {
+	env := os.Environ()
+	keys := make([]string, 0, len(e.env))
+	for k := range e.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+e.env[k])
+	}
+	return env
+}
+
+// Roots returns the working directory followed by the added directories.
+func (e *Executor) Roots() []string {
+	return append([]string{e.workDir}, e.extraDirs...)
+}
+
+// rootOf returns the innermost root that contains p, or the working
+// directory.
+func (e *Executor) rootOf(p string) string {
+	best := ""
+	for _, dir := range e.Roots() {
+		if rel, err := filepath.Rel(dir, p); err == nil && !strings.HasPrefix(rel, "..") && len(dir) > len(best) {
+			best = dir
+		}
+	}
+	if best == "" {
+		return e.workDir
+	}
+	return best
 }
 
 func (e *Executor) executeBash(call ToolCall) ToolResult {
@@ -79,8 +314,13 @@ func (e *Executor) executeBash(call ToolCall) ToolResult {
 		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: command", IsError: true}
 	}
 
+	dir, env, errResult := e.bashEnvironment(call)
+	if errResult != nil {
+		return *errResult
+	}
+
 	if bg, _ := call.Input["run_in_background"].(bool); bg {
-		return e.executeBashBackground(call, command)
+		return e.executeBashBackground(call, command, dir, env)
 	}
 
 	timeout := 120000.0
@@ -91,121 +331,183 @@ func (e *Executor) executeBash(call ToolCall) ToolResult {
 		}
 	}
 
-	cmd := exec.Command("bash", "-c", command)
-	cmd.Dir = e.workDir
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+	defer cancel()
+	e.fgMu.Lock()
+	e.fgCancel = cancel
+	e.fgMu.Unlock()
+	defer func() {
+		e.fgMu.Lock()
+		e.fgCancel = nil
+		e.fgMu.Unlock()
+	}()
+
+	cmd := e.shell.Command(ctx, command)
+	cmd.Dir = dir
+	cmd.Env = env
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = 2 * time.Second
+
+	out := &lineWriter{onLine: e.onOutput}
+	cmd.Stdout = out
+	cmd.Stderr = out
 
-	output, err := cmd.CombinedOutput()
-	result := string(output)
+	err := cmd.Run()
+	out.flush()
+	result := out.buf.String()
 
 	if err != nil {
-		if len(result) == 0 {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			result += fmt.Sprintf("\nCommand timed out after %s", time.Duration(timeout)*time.Millisecond)
+		case ctx.Err() == context.Canceled:
+			result += "\nCommand interrupted by user"
+		case len(result) == 0:
 			result = err.Error()
 		}
-		return ToolResult{ToolUseID: call.ID, Content: result, IsError: true}
+		return ToolResult{ToolUseID: call.ID, Content: result + cwdNote(call, dir), IsError: true, ExitCode: exitCode}
 	}
 
-	_ = timeout
-	return ToolResult{ToolUseID: call.ID, Content: result}
+	return ToolResult{ToolUseID: call.ID, Content: result + cwdNote(call, dir)}
 }
 
-func (e *Executor) executeBashBackground(call ToolCall, command string) ToolResult {
-	cmd := exec.Command("bash", "-c", command)
-	cmd.Dir = e.workDir
-
-	shell := &bgShell{cmd: cmd}
-
-	stdout, _ := cmd.StdoutPipe()
-	cmd.Stderr = cmd.Stdout
-
-	if err := cmd.Start(); err != nil {
-		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Failed to start: %v", err), IsError: true}
+// bashEnvironment resolves the optional cwd and env parameters. A nil env
+// means the command inherits the CLI's environment unchanged.
+func (e *Executor) bashEnvironment(call ToolCall) (string, []string, *ToolResult) {
+	dir := e.workDir
+	if cwd, ok := call.Input["cwd"].(string); ok && cwd != "" {
+		dir = e.resolvePath(cwd)
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			return "", nil, &ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("cwd is not a directory: %s", cwd), IsError: true}
+		}
 	}
 
-	bashID := call.ID
-	e.bgMu.Lock()
-	e.bgShells[bashID] = shell
-	e.bgMu.Unlock()
-
-	go func() {
-		buf := make([]byte, 4096)
-		for {
-			n, err := stdout.Read(buf)
-			if n > 0 {
-				shell.mu.Lock()
-				shell.output.Write(buf[:n])
-				shell.mu.Unlock()
-			}
-			if err != nil {
-				break
+	env := e.Environ()
+	if vars, ok := call.Input["env"].(map[string]interface{}); ok && len(vars) > 0 {
+		for k, v := range vars {
+			if k == "" || strings.ContainsAny(k, "=\x00") {
+				return "", nil, &ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Invalid environment variable name: %q", k), IsError: true}
 			}
+			env = append(env, fmt.Sprintf("%s=%v", k, v))
 		}
-	}()
-
-	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Background process started (id: %s)", bashID)}
-}
-
-func (e *Executor) executeBashOutput(call ToolCall) ToolResult {
-	bashID, _ := call.Input["bash_id"].(string)
-	if bashID == "" {
-		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: bash_id", IsError: true}
 	}
+	return dir, env, nil
+}
 
-	e.bgMu.Lock()
-	shell, exists := e.bgShells[bashID]
-	e.bgMu.Unlock()
-
-	if !exists {
-		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("No background shell: %s", bashID), IsError: true}
+func cwdNote(call ToolCall, dir string) string {
+	if cwd, _ := call.Input["cwd"].(string); cwd == "" {
+		return ""
 	}
+	return fmt.Sprintf("\n[cwd: %s]", dir)
+}
 
-	shell.mu.Lock()
-	output := shell.output.String()
-	shell.output.Reset()
-	shell.mu.Unlock()
+// SetOutputHandler receives foreground Bash output line by line while the
+// command runs. Pass nil to stop streaming.
+func (e *Executor) SetOutputHandler(fn func(line string)) {
+	e.fgMu.Lock()
+	e.onOutputFn = fn
+	e.fgMu.Unlock()
+}
 
-	if output == "" {
-		output = "(no new output)"
+func (e *Executor) onOutput(line string) {
+	e.fgMu.Lock()
+	fn := e.onOutputFn
+	e.fgMu.Unlock()
+	if fn != nil {
+		fn(line)
 	}
-	return ToolResult{ToolUseID: call.ID, Content: output}
 }
 
-func (e *Executor) executeKillBash(call ToolCall) ToolResult {
-	shellID, _ := call.Input["shell_id"].(string)
-	if shellID == "" {
-		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: shell_id", IsError: true}
+// Interrupt stops the running foreground Bash command, if any.
+func (e *Executor) Interrupt() bool {
+	e.fgMu.Lock()
+	defer e.fgMu.Unlock()
+	if e.fgCancel == nil {
+		return false
 	}
+	e.fgCancel()
+	return true
+}
 
-	e.bgMu.Lock()
-	shell, exists := e.bgShells[shellID]
-	if exists {
-		delete(e.bgShells, shellID)
-	}
-	e.bgMu.Unlock()
+// lineWriter buffers everything written to it and reports complete lines.
+type lineWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	partial []byte
+	onLine  func(string)
+}
 
-	if !exists {
-		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("No background shell: %s", shellID), IsError: true}
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(strings.TrimRight(string(w.partial[:i]), "\r"))
+		w.partial = w.partial[i+1:]
 	}
+	return len(p), nil
+}
 
-	if shell.cmd.Process != nil {
-		shell.cmd.Process.Kill()
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.onLine(string(w.partial))
+		w.partial = nil
 	}
-	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Shell %s terminated", shellID)}
 }
 
+const (
+	readDefaultLines = 2000
+	readMaxBytes     = 256 * 1024
+	readMaxLineLen   = 2000
+	binarySniffLen   = 8000
+)
+
 func (e *Executor) executeRead(call ToolCall) ToolResult {
 	filePath, _ := call.Input["file_path"].(string)
 	if filePath == "" {
 		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: file_path", IsError: true}
 	}
 
-	content, err := os.ReadFile(e.resolvePath(filePath))
-	if err != nil {
-		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
-	}
+	resolved := e.resolvePath(filePath)
+	var reader *bufio.Reader
+	var size int64
+	if st := e.stagedAt(resolved); st != nil {
+		if st.deleted {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %s is deleted (staged for review)", filePath), IsError: true}
+		}
+		reader, size = bufio.NewReader(strings.NewReader(st.content)), int64(len(st.content))
+	} else {
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+		}
+		if info.IsDir() {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("%s is a directory; use Glob or Bash ls to list it", filePath), IsError: true}
+		}
 
-	lines := strings.Split(string(content), "\n")
-	offset, limit := 0, len(lines)
+		f, err := os.Open(resolved)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+		}
+		defer f.Close()
+		reader, size = bufio.NewReader(f), info.Size()
+	}
 
+	offset, limit := 0, readDefaultLines
 	if v, ok := call.Input["offset"].(float64); ok {
 		offset = int(v) - 1
 		if offset < 0 {
@@ -213,35 +515,128 @@ func (e *Executor) executeRead(call ToolCall) ToolResult {
 		}
 	}
 	if v, ok := call.Input["limit"].(float64); ok && int(v) > 0 {
-		limit = offset + int(v)
+		limit = int(v)
 	}
-	if offset >= len(lines) {
-		return ToolResult{ToolUseID: call.ID, Content: "Offset beyond file length", IsError: true}
+
+	head, _ := reader.Peek(binarySniffLen)
+	if kind := doctext.Detect(resolved, head); kind != "" {
+		return readDocument(call, reader, size, kind, offset, limit)
+	}
+	if isBinary(head) {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Binary file (%s, %s); contents not shown", formatSize(size), http.DetectContentType(head))}
 	}
-	if limit > len(lines) {
-		limit = len(lines)
+
+	if result, ok := e.compactReread(call, resolved, offset+1, limit); ok {
+		return result
 	}
 
 	var sb strings.Builder
-	for i := offset; i < limit; i++ {
-		fmt.Fprintf(&sb, "%5d│%s\n", i+1, lines[i])
+	hash := sha256.New()
+	lineNo, shown, truncatedLines := 0, 0, 0
+	more := false
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			break
+		}
+		lineNo++
+		if lineNo <= offset {
+			continue
+		}
+		if shown >= limit || sb.Len() >= readMaxBytes {
+			more = true
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if shown > 0 {
+			hash.Write([]byte("\n"))
+		}
+		hash.Write([]byte(line))
+		if len(line) > readMaxLineLen {
+			line = fmt.Sprintf("%s... [line truncated, %d more chars]", truncateUTF8(line, readMaxLineLen), len(line)-readMaxLineLen)
+			truncatedLines++
+		}
+		fmt.Fprintf(&sb, "%5d│%s\n", lineNo, line)
+		shown++
+		if err != nil {
+			break
+		}
+	}
+
+	if shown == 0 && offset > 0 {
+		return ToolResult{ToolUseID: call.ID, Content: "Offset beyond file length", IsError: true}
+	}
+	if more {
+		fmt.Fprintf(&sb, "\n[File is %s; showing lines %d-%d. Use offset/limit to read further.]\n", formatSize(size), offset+1, offset+shown)
+	}
+	if truncatedLines > 0 {
+		fmt.Fprintf(&sb, "[%d long line(s) truncated to %d chars]\n", truncatedLines, readMaxLineLen)
+	}
+	if shown > 0 {
+		fmt.Fprintf(&sb, "[range_hash for lines %d-%d: %s]\n", offset+1, offset+shown, hex.EncodeToString(hash.Sum(nil)[:4]))
+		e.rememberRead(resolved, offset+1, offset+shown)
 	}
 	return ToolResult{ToolUseID: call.ID, Content: sb.String()}
 }
 
+// isBinary treats content with NUL bytes or mostly invalid UTF-8 as binary.
+func isBinary(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	invalid := 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && size == 1 && len(head)-i >= utf8.UTFMax {
+			invalid++
+		}
+		i += size
+	}
+	return invalid*10 > len(head)
+}
+
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
+
+// redactedWrite refuses content copied from a redacted tool result, which
+// would replace a real secret in the file with its mask.
+const redactedWrite = "Content contains " + redact.Marker + "...], a secret masked in a tool result; keep the original text rather than writing the mask into the file"
+
 func (e *Executor) executeWrite(call ToolCall) ToolResult {
 	filePath, _ := call.Input["file_path"].(string)
 	content, _ := call.Input["content"].(string)
 	if filePath == "" {
 		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: file_path", IsError: true}
 	}
-
-	resolved := e.resolvePath(filePath)
-	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
-		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error creating dirs: %v", err), IsError: true}
+	if strings.Contains(content, redact.Marker) {
+		return ToolResult{ToolUseID: call.ID, Content: redactedWrite, IsError: true}
 	}
 
-	if err := os.WriteFile(resolved, []byte(content), 0644); err != nil {
+	resolved := e.resolvePath(filePath)
+	if err := e.writeWithFormat(resolved, content, e.statFormat(resolved)); err != nil {
 		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
 	}
 	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Written: %s", filePath)}
@@ -255,24 +650,67 @@ func (e *Executor) executeEdit(call ToolCall) ToolResult {
 	if filePath == "" || oldStr == "" {
 		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameters", IsError: true}
 	}
+	if strings.Contains(newStr, redact.Marker) {
+		return ToolResult{ToolUseID: call.ID, Content: redactedWrite, IsError: true}
+	}
 
 	resolved := e.resolvePath(filePath)
-	content, err := os.ReadFile(resolved)
+	content, format, err := e.readWithFormat(resolved)
 	if err != nil {
 		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
 	}
 
-	if !strings.Contains(string(content), oldStr) {
-		return ToolResult{ToolUseID: call.ID, Content: "String not found in file", IsError: true}
+	replaceAll, _ := call.Input["replace_all"].(bool)
+	newContent, n, err := replaceOccurrences(content, normalizeNewlines(oldStr), normalizeNewlines(newStr), replaceAll)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: err.Error(), IsError: true}
 	}
 
-	newContent := strings.Replace(string(content), oldStr, newStr, 1)
-	if err := os.WriteFile(resolved, []byte(newContent), 0644); err != nil {
+	if err := e.writeWithFormat(resolved, newContent, format); err != nil {
 		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
 	}
+	if n > 1 {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Edited: %s (%d occurrences replaced)", filePath, n)}
+	}
 	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Edited: %s", filePath)}
 }
 
+// replaceOccurrences replaces oldStr in text. Without replaceAll the match
+// must be unique, so an ambiguous old_string fails instead of silently
+// editing the first of several locations.
+func replaceOccurrences(text, oldStr, newStr string, replaceAll bool) (string, int, error) {
+	count := strings.Count(text, oldStr)
+	switch {
+	case count == 0:
+		return "", 0, fmt.Errorf("String not found in file")
+	case replaceAll:
+		return strings.ReplaceAll(text, oldStr, newStr), count, nil
+	case count > 1:
+		return "", 0, fmt.Errorf("Found %d matches of old_string (lines %s). Add surrounding context to make it unique, or set replace_all to true", count, matchLines(text, oldStr))
+	}
+	return strings.Replace(text, oldStr, newStr, 1), 1, nil
+}
+
+func matchLines(text, substr string) string {
+	const maxListed = 10
+	var lines []string
+	line, pos := 1, 0
+	for len(lines) < maxListed {
+		i := strings.Index(text[pos:], substr)
+		if i < 0 {
+			break
+		}
+		line += strings.Count(text[pos:pos+i], "\n")
+		lines = append(lines, fmt.Sprint(line))
+		line += strings.Count(substr, "\n")
+		pos += i + len(substr)
+	}
+	if strings.Count(text[pos:], substr) > 0 {
+		lines = append(lines, "...")
+	}
+	return strings.Join(lines, ", ")
+}
+
 func (e *Executor) executeMultiEdit(call ToolCall) ToolResult {
 	filePath, _ := call.Input["file_path"].(string)
 	if filePath == "" {
@@ -285,12 +723,11 @@ func (e *Executor) executeMultiEdit(call ToolCall) ToolResult {
 	}
 
 	resolved := e.resolvePath(filePath)
-	content, err := os.ReadFile(resolved)
+	text, format, err := e.readWithFormat(resolved)
 	if err != nil {
 		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
 	}
 
-	text := string(content)
 	for i, raw := range editsRaw {
 		edit, ok := raw.(map[string]interface{})
 		if !ok {
@@ -301,17 +738,17 @@ func (e *Executor) executeMultiEdit(call ToolCall) ToolResult {
 		if oldStr == "" {
 			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Empty old_string at edit %d", i), IsError: true}
 		}
-		if !strings.Contains(text, oldStr) {
-			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("String not found at edit %d", i), IsError: true}
+		if strings.Contains(newStr, redact.Marker) {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Edit %d: %s", i, redactedWrite), IsError: true}
 		}
-		if replaceAll, _ := edit["replace_all"].(bool); replaceAll {
-			text = strings.ReplaceAll(text, oldStr, newStr)
-		} else {
-			text = strings.Replace(text, oldStr, newStr, 1)
+		replaceAll, _ := edit["replace_all"].(bool)
+		text, _, err = replaceOccurrences(text, normalizeNewlines(oldStr), normalizeNewlines(newStr), replaceAll)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Edit %d: %v", i, err), IsError: true}
 		}
 	}
 
-	if err := os.WriteFile(resolved, []byte(text), 0644); err != nil {
+	if err := e.writeWithFormat(resolved, text, format); err != nil {
 		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
 	}
 	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Applied %d edits to %s", len(editsRaw), filePath)}
@@ -322,11 +759,19 @@ func (e *Executor) executeGlob(call ToolCall) ToolResult {
 	if pattern == "" {
 		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: pattern", IsError: true}
 	}
+	includeIgnored, _ := call.Input["include_ignored"].(bool)
 
-	resolved := e.resolvePath(pattern)
-	matches, err := filepath.Glob(resolved)
-	if err != nil {
-		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	roots := e.Roots()
+	if filepath.IsAbs(pattern) {
+		roots = []string{e.rootOf(pattern)}
+	}
+	var matches []string
+	for _, root := range roots {
+		found, err := e.globRoot(root, pattern, includeIgnored)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+		}
+		matches = append(matches, found...)
 	}
 
 	if len(matches) == 0 {
@@ -336,14 +781,130 @@ func (e *Executor) executeGlob(call ToolCall) ToolResult {
 	// Make paths relative to workDir
 	var relative []string
 	for _, m := range matches {
+		if _, denied := e.deniedPath(m); denied {
+			continue
+		}
 		rel, err := filepath.Rel(e.workDir, m)
-		if err != nil {
+		if err != nil || strings.HasPrefix(rel, "..") {
 			relative = append(relative, m)
 		} else {
 			relative = append(relative, rel)
 		}
 	}
-	return ToolResult{ToolUseID: call.ID, Content: strings.Join(relative, "\n")}
+	if len(relative) == 0 {
+		return ToolResult{ToolUseID: call.ID, Content: "No files found"}
+	}
+	return ToolResult{ToolUseID: call.ID, Content: paginate(call, relative, false, globDefaultLimit, "files")}
+}
+
+// globRoot matches pattern in one directory tree. Patterns with "**" walk
+// the tree, so results are never stale. Directories the pattern names
+// before its first wildcard, such as ".github" or "vendor/x", are searched
+// even when they would be skipped by default or ignored.
+func (e *Executor) globRoot(root, pattern string, includeIgnored bool) ([]string, error) {
+	var ignored *ignore.Matcher
+	if !includeIgnored {
+		ignored = ignore.New(root)
+	}
+	base := literalPrefix(root, pattern)
+
+	if rel, ok := indexPattern(root, pattern); ok {
+		var matches []string
+		start := filepath.Join(root, filepath.FromSlash(base))
+		filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == start {
+				return nil
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if skip, err := ignored.Skip(p, d.IsDir()); skip {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if r, err := filepath.Rel(root, p); err == nil && index.MatchGlob(rel, filepath.ToSlash(r)) {
+				matches = append(matches, p)
+			}
+			return nil
+		})
+		return matches, nil
+	}
+
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(root, filepath.FromSlash(pattern))
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if ignored == nil {
+		return matches, nil
+	}
+	kept := matches[:0]
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if !ignoredBelow(ignored, filepath.Join(root, filepath.FromSlash(base)), m, err == nil && info.IsDir()) {
+			kept = append(kept, m)
+		}
+	}
+	return kept, nil
+}
+
+// literalPrefix returns the directories a pattern names before its first
+// wildcard, slash-separated and relative to root.
+func literalPrefix(root, pattern string) string {
+	if filepath.IsAbs(pattern) {
+		rel, err := filepath.Rel(root, pattern)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return ""
+		}
+		pattern = rel
+	}
+	parts := strings.Split(filepath.ToSlash(strings.TrimPrefix(pattern, "./")), "/")
+	var dirs []string
+	for _, part := range parts[:len(parts)-1] {
+		if strings.ContainsAny(part, "*?[") {
+			break
+		}
+		dirs = append(dirs, part)
+	}
+	return strings.Join(dirs, "/")
+}
+
+// ignoredBelow reports whether p, or a directory between base and p, is
+// ignored; base itself and its parents are not checked.
+func ignoredBelow(m *ignore.Matcher, base, p string, isDir bool) bool {
+	rel, err := filepath.Rel(base, p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return m.Ignored(p, isDir)
+	}
+	cur := base
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		cur = filepath.Join(cur, part)
+		if skip, _ := m.Skip(cur, i < len(parts)-1 || isDir); skip {
+			return true
+		}
+	}
+	return false
+}
+
+// indexPattern reports whether a Glob pattern needs a walk ("**" is not
+// supported by filepath.Glob) and returns it relative to root.
+func indexPattern(root, pattern string) (string, bool) {
+	if !strings.Contains(pattern, "**") {
+		return "", false
+	}
+	if filepath.IsAbs(pattern) {
+		rel, err := filepath.Rel(root, pattern)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", false
+		}
+		pattern = rel
+	}
+	return filepath.ToSlash(strings.TrimPrefix(pattern, "./")), true
 }
 
 func (e *Executor) executeGrep(call ToolCall) ToolResult {
@@ -352,23 +913,90 @@ func (e *Executor) executeGrep(call ToolCall) ToolResult {
 		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: pattern", IsError: true}
 	}
 
-	args := []string{"-rn", pattern}
+	// Without a path every root is searched.
+	roots := e.Roots()
 	if path, ok := call.Input["path"].(string); ok && path != "" {
-		args = append(args, e.resolvePath(path))
-	} else {
-		args = append(args, e.workDir)
+		roots = []string{e.resolvePath(path)}
+	}
+	include, _ := call.Input["include"].(string)
+	var exclude []string
+	if raw, ok := call.Input["exclude"].([]interface{}); ok {
+		for _, x := range raw {
+			if s, ok := x.(string); ok && s != "" {
+				exclude = append(exclude, s)
+			}
+		}
 	}
 
-	if include, ok := call.Input["include"].(string); ok && include != "" {
-		args = append(args, "--include", include)
+	includeIgnored, _ := call.Input["include_ignored"].(bool)
+	var lines []string
+	stopped := false
+	for _, dir := range roots {
+		var ignored *ignore.Matcher
+		if !includeIgnored {
+			ignored = ignore.New(e.rootOf(dir))
+		}
+		found, cut, err := e.grepRoot(pattern, dir, include, exclude, ignored)
+		if err != nil {
+			return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+		}
+		lines = append(lines, found...)
+		stopped = stopped || cut
 	}
 
-	cmd := exec.Command("grep", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil && len(output) == 0 {
+	lines = e.filterDeniedLines(lines)
+	if len(lines) == 0 {
 		return ToolResult{ToolUseID: call.ID, Content: "No matches found"}
 	}
-	return ToolResult{ToolUseID: call.ID, Content: string(output)}
+	return ToolResult{ToolUseID: call.ID, Content: paginate(call, lines, stopped, grepDefaultLimit, "matches")}
+}
+
+// grepRoot searches one directory tree, with grep when it is installed.
+func (e *Executor) grepRoot(pattern, root, include string, exclude []string, ignored *ignore.Matcher) ([]string, bool, error) {
+	// Windows usually has no grep; search in-process instead.
+	if _, err := exec.LookPath("grep"); err != nil {
+		return e.grepInProcess(pattern, root, include, exclude, ignored)
+	}
+	args := []string{"-rn", pattern, root}
+	if include != "" {
+		args = append(args, "--include", include)
+	}
+	for _, x := range exclude {
+		args = append(args, "--exclude-dir", x, "--exclude", x)
+	}
+	if ignored != nil {
+		// Spares grep the biggest directories; the ignore files are
+		// applied to its output below.
+		for _, d := range ignore.DefaultDirs() {
+			if ignored.Ignored(d, true) {
+				args = append(args, "--exclude-dir", d)
+			}
+		}
+	}
+	var lines []string
+	output, _ := exec.Command("grep", args...).CombinedOutput()
+	if out := strings.TrimRight(string(output), "\n"); out != "" {
+		lines = strings.Split(out, "\n")
+	}
+	if ignored != nil {
+		lines = dropIgnoredLines(lines, ignored)
+	}
+	return lines, false, nil
+}
+
+// ToolNames returns the names of all built-in tools, optional ones
+// included.
+func ToolNames() []string {
+	var names []string
+	for _, def := range GetToolDefinitions() {
+		var t struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(def, &t) == nil {
+			names = append(names, t.Name)
+		}
+	}
+	return names
 }
 
 func GetToolDefinitions() []json.RawMessage {
@@ -379,22 +1007,60 @@ func GetToolDefinitions() []json.RawMessage {
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"command":     map[string]string{"type": "string", "description": "The bash command to execute"},
-					"description": map[string]string{"type": "string", "description": "Short description of what this command does"},
-					"timeout":     map[string]interface{}{"type": "number", "description": "Timeout in milliseconds (max 600000)"},
+					"command":           map[string]string{"type": "string", "description": "The bash command to execute"},
+					"description":       map[string]string{"type": "string", "description": "Short description of what this command does"},
+					"timeout":           map[string]interface{}{"type": "number", "description": "Timeout in milliseconds (max 600000)"},
+					"run_in_background": map[string]interface{}{"type": "boolean", "description": "Run the command in the background; poll it with BashOutput"},
+					"cwd":               map[string]string{"type": "string", "description": "Directory to run the command in (default: the working directory)"},
+					"env": map[string]interface{}{
+						"type":                 "object",
+						"description":          "Extra environment variables for this command only",
+						"additionalProperties": map[string]string{"type": "string"},
+					},
 				},
 				"required": []string{"command"},
 			},
 		},
+		{
+			"name":        "BashOutput",
+			"description": "Get new output from a background shell along with its status (running, or exited with its exit code).",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"bash_id": map[string]string{"type": "string", "description": "ID of the background shell"},
+				},
+				"required": []string{"bash_id"},
+			},
+		},
+		{
+			"name":        "KillBash",
+			"description": "Terminate a background shell.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"shell_id": map[string]string{"type": "string", "description": "ID of the background shell"},
+				},
+				"required": []string{"shell_id"},
+			},
+		},
+		{
+			"name":        "ListShells",
+			"description": "List background shells with their status, exit code and command.",
+			"input_schema": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
 		{
 			"name":        "Read",
-			"description": "Read the contents of a file. Supports offset and limit for partial reads.",
+			"description": "Read the contents of a file. Supports offset and limit for partial reads. Returns at most 2000 lines (or 256 KB) per call, truncates very long lines and refuses binary files. The text of PDFs, .docx and .pptx files is extracted, with page markers, and paged with offset and limit like any file.",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"file_path": map[string]string{"type": "string", "description": "Path to the file to read"},
 					"offset":    map[string]interface{}{"type": "number", "description": "Line number to start reading from (1-based)"},
 					"limit":     map[string]interface{}{"type": "number", "description": "Number of lines to read"},
+					"full":      map[string]interface{}{"type": "boolean", "description": "Show the lines even if you read them before and they did not change"},
 				},
 				"required": []string{"file_path"},
 			},
@@ -413,17 +1079,33 @@ func GetToolDefinitions() []json.RawMessage {
 		},
 		{
 			"name":        "Edit",
-			"description": "Edit a file by replacing the first occurrence of old_string with new_string.",
+			"description": "Edit a file by replacing old_string with new_string. old_string must match exactly once unless replace_all is set.",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"file_path":  map[string]string{"type": "string", "description": "Path to the file to edit"},
-					"old_string": map[string]string{"type": "string", "description": "The string to find and replace"},
-					"new_string": map[string]string{"type": "string", "description": "The replacement string"},
+					"file_path":   map[string]string{"type": "string", "description": "Path to the file to edit"},
+					"old_string":  map[string]string{"type": "string", "description": "The string to find and replace"},
+					"new_string":  map[string]string{"type": "string", "description": "The replacement string"},
+					"replace_all": map[string]interface{}{"type": "boolean", "description": "Replace every occurrence of old_string (default false)"},
 				},
 				"required": []string{"file_path", "old_string", "new_string"},
 			},
 		},
+		{
+			"name":        "EditLines",
+			"description": "Replace an explicit line range of a file. range_hash must be the hash Read reported for exactly lines start_line-end_line (use Read with offset/limit to get it); the edit is refused if those lines changed. More reliable than Edit for generated or repetitive files. Set end_line to start_line-1 to insert before start_line, and new_content to \"\" to delete the lines.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"file_path":   map[string]string{"type": "string", "description": "Path to the file to edit"},
+					"start_line":  map[string]interface{}{"type": "integer", "description": "First line to replace (1-based)"},
+					"end_line":    map[string]interface{}{"type": "integer", "description": "Last line to replace (inclusive)"},
+					"range_hash":  map[string]string{"type": "string", "description": "Hash of the current lines start_line-end_line as reported by Read"},
+					"new_content": map[string]string{"type": "string", "description": "Replacement lines"},
+				},
+				"required": []string{"file_path", "start_line", "end_line", "new_content"},
+			},
+		},
 		{
 			"name":        "MultiEdit",
 			"description": "Apply multiple edits to a single file.",
@@ -447,30 +1129,191 @@ func GetToolDefinitions() []json.RawMessage {
 				"required": []string{"file_path", "edits"},
 			},
 		},
+		{
+			"name":        "ApplyPatch",
+			"description": "Apply a unified diff to one or more files. Hunks are located by their context lines, tolerating shifted line numbers and whitespace differences. Reports success or failure per hunk; a file is only written when all of its hunks apply. Use /dev/null as the old path to create a file and as the new path to delete one.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"patch": map[string]string{"type": "string", "description": "Unified diff with ---/+++ file headers and @@ hunks"},
+				},
+				"required": []string{"patch"},
+			},
+		},
+		{
+			"name":        "Archive",
+			"description": "Inspect zip/jar/whl, tar and tar.gz archives without shell commands. action \"list\" shows members with sizes, \"read\" prints text members (up to 256KB), \"extract\" writes members to a destination inside the working directory (up to 100MB; links and paths escaping the destination are skipped).",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action":       map[string]interface{}{"type": "string", "enum": []string{"list", "read", "extract"}, "description": "What to do (default list)"},
+					"archive_path": map[string]string{"type": "string", "description": "Path to the archive"},
+					"members": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "Member names or directory prefixes for read/extract (extract defaults to all)",
+					},
+					"destination": map[string]string{"type": "string", "description": "Directory to extract into"},
+				},
+				"required": []string{"archive_path"},
+			},
+		},
+		{
+			"name":        "Stat",
+			"description": "Get type, size, mode, modification time and a content hash for files or directories. Cheaper than running ls -l, stat or sha256sum to verify artifacts or detect changes.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]string{"type": "string", "description": "File or directory to inspect"},
+					"paths": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "Several paths to inspect at once",
+					},
+					"hash": map[string]interface{}{"type": "string", "enum": []string{"sha256", "sha1", "md5", "none"}, "description": "Hash algorithm for regular files (default sha256)"},
+				},
+			},
+		},
+		{
+			"name":        "HttpRequest",
+			"description": "Send an HTTP request and return the status, response headers and body (truncated at 64KB). Pass either method/url/headers/body, or curl with a curl command line copied from docs or a bug report; fields given alongside curl override it.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"method":           map[string]string{"type": "string", "description": "HTTP method (default GET)"},
+					"url":              map[string]string{"type": "string", "description": "http:// or https:// URL"},
+					"headers":          map[string]interface{}{"type": "object", "additionalProperties": map[string]string{"type": "string"}, "description": "Request headers"},
+					"body":             map[string]string{"type": "string", "description": "Request body"},
+					"curl":             map[string]string{"type": "string", "description": "A curl command to import instead of the fields above"},
+					"follow_redirects": map[string]interface{}{"type": "boolean", "description": "Follow redirects (default true, or -L for curl)"},
+					"timeout":          map[string]interface{}{"type": "number", "description": "Seconds before giving up (default 30, max 120)"},
+				},
+			},
+		},
+		{
+			"name":        "ApiDiff",
+			"description": "Check an API for regressions. action \"snapshot\" replays a request collection and stores the responses as a baseline; after changing code, action \"compare\" replays it again and reports status changes and structural JSON differences per request. The collection is a .har file or a JSON array of {name, method, url, headers, body} or {name, curl} objects.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action":     map[string]interface{}{"type": "string", "enum": []string{"snapshot", "compare"}},
+					"collection": map[string]string{"type": "string", "description": "Path to the request collection (.har or .json)"},
+					"baseline":   map[string]string{"type": "string", "description": "Baseline file (default .apipod/apidiff/<collection name>.json)"},
+					"base_url":   map[string]string{"type": "string", "description": "Replace the scheme and host of every request, e.g. http://localhost:8080"},
+					"headers":    map[string]interface{}{"type": "object", "additionalProperties": map[string]string{"type": "string"}, "description": "Headers set on every request, e.g. Authorization"},
+					"ignore": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "JSON paths to ignore when comparing, * matching any key or index, e.g. $.data[*].updated_at",
+					},
+					"timeout": map[string]interface{}{"type": "number", "description": "Seconds per request (default 30, max 120)"},
+				},
+				"required": []string{"action", "collection"},
+			},
+		},
+		{
+			"name":        "WebSocket",
+			"description": "Connect to a ws:// or wss:// endpoint, send text frames in order, and return the messages received until the timeout or max_messages is reached. Use it to verify realtime endpoints instead of ad-hoc scripts.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]string{"type": "string", "description": "ws:// or wss:// URL"},
+					"messages": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "Text frames to send after connecting",
+					},
+					"headers":      map[string]interface{}{"type": "object", "additionalProperties": map[string]string{"type": "string"}, "description": "Extra handshake headers, e.g. Authorization"},
+					"subprotocol":  map[string]string{"type": "string", "description": "Sec-WebSocket-Protocol to request"},
+					"timeout":      map[string]interface{}{"type": "number", "description": "Seconds to wait for messages (default 5, max 60)"},
+					"max_messages": map[string]interface{}{"type": "integer", "description": "Stop after this many received messages (default 20)"},
+				},
+				"required": []string{"url"},
+			},
+		},
+		{
+			"name":        "Grpc",
+			"description": "Call gRPC services via server reflection (requires grpcurl). action \"list\" lists services (or the methods of symbol), \"describe\" shows a service, method or message definition, \"call\" invokes method with a JSON request and returns the JSON response.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action":    map[string]interface{}{"type": "string", "enum": []string{"list", "describe", "call"}, "description": "What to do (default list)"},
+					"symbol":    map[string]string{"type": "string", "description": "Service, method or message name for list/describe"},
+					"method":    map[string]string{"type": "string", "description": "Fully qualified method for call, e.g. pkg.Service/Method"},
+					"request":   map[string]string{"type": "string", "description": "JSON-encoded request message for call (default {})"},
+					"headers":   map[string]interface{}{"type": "object", "additionalProperties": map[string]string{"type": "string"}, "description": "Extra metadata headers"},
+					"target":    map[string]string{"type": "string", "description": "host:port overriding the configured target"},
+					"plaintext": map[string]interface{}{"type": "boolean", "description": "Use plaintext instead of TLS"},
+				},
+			},
+		},
+		{
+			"name":        "GitHost",
+			"description": "Work with the GitHub or GitLab project of the origin remote. action \"list_issues\" lists issues, \"view_issue\" shows an issue with its comments, \"create_branch\" creates and switches to a local branch, \"create_pr\" pushes the current branch and opens a pull request (a merge request on GitLab) from it. Commit the changes before create_pr.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"action": map[string]interface{}{"type": "string", "enum": []string{"list_issues", "view_issue", "create_branch", "create_pr"}, "description": "What to do"},
+					"number": map[string]interface{}{"type": "integer", "description": "Issue number for view_issue"},
+					"state":  map[string]interface{}{"type": "string", "enum": []string{"open", "closed", "all"}, "description": "Issues to list (default open)"},
+					"label":  map[string]string{"type": "string", "description": "Only list issues with this label"},
+					"limit":  map[string]interface{}{"type": "integer", "description": "Maximum issues to list (default 20, max 100)"},
+					"branch": map[string]string{"type": "string", "description": "Name of the branch for create_branch, e.g. fix/issue-42"},
+					"base":   map[string]string{"type": "string", "description": "Branch to start from (create_branch) or to merge into (create_pr; default the project's default branch)"},
+					"title":  map[string]string{"type": "string", "description": "Pull request title"},
+					"body":   map[string]string{"type": "string", "description": "Pull request description; mention the issue it fixes, e.g. Fixes #42"},
+					"draft":  map[string]interface{}{"type": "boolean", "description": "Open the pull request as a draft"},
+				},
+				"required": []string{"action"},
+			},
+		},
 		{
 			"name":        "Glob",
-			"description": "Find files matching a glob pattern.",
+			"description": "Find files matching a glob pattern. Returns at most 500 paths per call; use offset to page through more, or a narrower pattern.",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
-					"pattern": map[string]string{"type": "string", "description": "Glob pattern to match files (e.g. '**/*.go')"},
+					"pattern":         map[string]string{"type": "string", "description": "Glob pattern to match files (e.g. '**/*.go'); '**' matches any number of directories"},
+					"head_limit":      map[string]interface{}{"type": "number", "description": "Maximum number of paths to return (default 500)"},
+					"include_ignored": map[string]interface{}{"type": "boolean", "description": "Also match files excluded by .gitignore, .apipodignore and the default skips (node_modules, vendor, build output)"},
+					"offset":          map[string]interface{}{"type": "number", "description": "Number of paths to skip, to page through large results"},
 				},
 				"required": []string{"pattern"},
 			},
 		},
 		{
 			"name":        "Grep",
-			"description": "Search for a pattern in files using grep.",
+			"description": "Search for a pattern in files using grep. Returns at most 250 matching lines per call; use offset to page through more, or refine the pattern, path or include.",
 			"input_schema": map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"pattern": map[string]string{"type": "string", "description": "Pattern to search for"},
 					"path":    map[string]string{"type": "string", "description": "Directory or file to search in"},
 					"include": map[string]string{"type": "string", "description": "File pattern to include (e.g. '*.go')"},
+					"exclude": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]string{"type": "string"},
+						"description": "File or directory name patterns to skip (e.g. 'vendor', '*.min.js')",
+					},
+					"head_limit":      map[string]interface{}{"type": "number", "description": "Maximum number of matching lines to return (default 250)"},
+					"offset":          map[string]interface{}{"type": "number", "description": "Number of matching lines to skip, to page through large results"},
+					"include_ignored": map[string]interface{}{"type": "boolean", "description": "Also search files excluded by .gitignore, .apipodignore and the default skips (node_modules, vendor, build output)"},
 				},
 				"required": []string{"pattern"},
 			},
 		},
+		{
+			"name":        "SemanticSearch",
+			"description": "Find code relevant to a natural-language query (e.g. 'where are auth tokens refreshed') using embeddings of the repository. Returns the best matching snippet per file. Prefer this over repeated Grep calls when you don't know the exact identifiers.",
+			"input_schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]string{"type": "string", "description": "What you are looking for, in plain language"},
+					"limit": map[string]interface{}{"type": "number", "description": "Maximum number of files to return (default 8)"},
+				},
+				"required": []string{"query"},
+			},
+		},
 	}
 
 	var result []json.RawMessage
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSessionEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	a := NewExecutor(t.TempDir())
+	b := NewExecutor(t.TempDir())
+	a.SetEnv(map[string]string{"APIPOD_TEST_VAR": "a"})
+	b.SetEnv(map[string]string{"APIPOD_TEST_VAR": "b"})
+
+	for e, want := range map[*Executor]string{a: "a", b: "b"} {
+		r := e.Execute(ToolCall{ID: "1", Name: "Bash", Input: map[string]interface{}{"command": "echo $APIPOD_TEST_VAR"}})
+		if r.IsError || !strings.Contains(r.Content, want) {
+			t.Errorf("Bash saw %q, want %q", r.Content, want)
+		}
+	}
+	r := a.Execute(ToolCall{ID: "1", Name: "Bash", Input: map[string]interface{}{
+		"command": "echo $APIPOD_TEST_VAR",
+		"env":     map[string]interface{}{"APIPOD_TEST_VAR": "call"},
+	}})
+	if !strings.Contains(r.Content, "call") {
+		t.Errorf("the call's env did not win: %q", r.Content)
+	}
+	if v, ok := os.LookupEnv("APIPOD_TEST_VAR"); ok {
+		t.Errorf("process environment changed: APIPOD_TEST_VAR=%q", v)
+	}
+}
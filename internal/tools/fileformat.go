@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"os"
+	"strings"
+)
+
+// fileFormat captures the on-disk conventions of an existing file so that
+// rewriting it does not churn line endings, the final newline or its mode.
+type fileFormat struct {
+	exists          bool
+	crlf            bool
+	trailingNewline bool
+	mode            os.FileMode
+}
+
+const defaultFileMode os.FileMode = 0644
+
+// readWithFormat returns the file content with line endings normalized to LF
+// along with the format needed to write it back the same way.
+func readWithFormat(path string) (string, fileFormat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fileFormat{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fileFormat{}, err
+	}
+	content, f := parseFormat(string(data), info.Mode().Perm())
+	return content, f, nil
+}
+
+// parseFormat is readWithFormat for content that is already in memory.
+func parseFormat(content string, mode os.FileMode) (string, fileFormat) {
+	crlf := strings.Count(content, "\r\n")
+	lf := strings.Count(content, "\n") - crlf
+	f := fileFormat{
+		exists:          true,
+		crlf:            crlf > lf,
+		trailingNewline: strings.HasSuffix(content, "\n"),
+		mode:            mode,
+	}
+	return strings.ReplaceAll(content, "\r\n", "\n"), f
+}
+
+// statFormat is like readWithFormat for callers that replace the whole file
+// and only need the existing conventions. A missing file yields the zero
+// format, which writes content unchanged with the default mode.
+func (e *Executor) statFormat(path string) fileFormat {
+	_, f, err := e.readWithFormat(path)
+	if err != nil {
+		return fileFormat{}
+	}
+	return f
+}
+
+// normalizeNewlines strips CR from model-supplied strings so they match LF
+// normalized file content.
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+func (f fileFormat) apply(content string) string {
+	if !f.exists {
+		return content
+	}
+	content = normalizeNewlines(content)
+	if content != "" {
+		hasNewline := strings.HasSuffix(content, "\n")
+		if f.trailingNewline && !hasNewline {
+			content += "\n"
+		} else if !f.trailingNewline && hasNewline {
+			content = strings.TrimRight(content, "\n")
+		}
+	}
+	if f.crlf {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	return content
+}
+
+func writeWithFormat(path, content string, f fileFormat) error {
+	mode := f.mode
+	if !f.exists || mode == 0 {
+		mode = defaultFileMode
+	}
+	if err := os.WriteFile(path, []byte(f.apply(content)), mode); err != nil {
+		return err
+	}
+	if f.exists {
+		// WriteFile only applies the mode when creating the file.
+		return os.Chmod(path, mode)
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	gitHostTimeout  = 30 * time.Second
+	gitHostMaxBody  = 8 << 20
+	gitHostMaxLimit = 100
+)
+
+// gitHost is the GitHub or GitLab project behind a git remote.
+type gitHost struct {
+	kind    string // "github" or "gitlab"
+	host    string
+	project string // owner/repo, or group/subgroup/project on GitLab
+}
+
+// SetGitHostTokens sets the API tokens of the GitHost tool. Without one the
+// tool falls back to GITHUB_TOKEN, GH_TOKEN or GITLAB_TOKEN, then to the
+// login of the gh or glab CLI.
+func (e *Executor) SetGitHostTokens(github, gitlab string) {
+	e.githubToken, e.gitlabToken = github, gitlab
+}
+
+// parseRemote reads the host and project from a remote URL in any of the
+// forms git accepts: https://host/owner/repo.git, git@host:owner/repo.git
+// or ssh://git@host:22/owner/repo.
+func parseRemote(remote string) (*gitHost, error) {
+	remote = strings.TrimSpace(remote)
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if at := strings.Index(remote, "@"); at >= 0 && strings.Contains(remote[at:], ":") {
+		host, path, _ = strings.Cut(remote[at+1:], ":")
+	} else {
+		return nil, fmt.Errorf("cannot read the host of remote %q", remote)
+	}
+	h := &gitHost{host: host, project: strings.TrimSuffix(strings.Trim(path, "/"), ".git")}
+	switch {
+	case strings.Contains(host, "github"):
+		h.kind = "github"
+	case strings.Contains(host, "gitlab"):
+		h.kind = "gitlab"
+	default:
+		return nil, fmt.Errorf("remote %s is neither GitHub nor GitLab", host)
+	}
+	if strings.Count(h.project, "/") < 1 {
+		return nil, fmt.Errorf("cannot read the project of remote %q", remote)
+	}
+	return h, nil
+}
+
+func (h *gitHost) apiBase() string {
+	switch {
+	case h.kind == "github" && h.host == "github.com":
+		return "https://api.github.com/"
+	case h.kind == "github":
+		return "https://" + h.host + "/api/v3/"
+	default:
+		return "https://" + h.host + "/api/v4/"
+	}
+}
+
+// projectPath is the API path of the project.
+func (h *gitHost) projectPath() string {
+	if h.kind == "github" {
+		return "repos/" + h.project
+	}
+	return "projects/" + url.PathEscape(h.project)
+}
+
+func (e *Executor) executeGitHost(call ToolCall) ToolResult {
+	fail := func(format string, args ...interface{}) ToolResult {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf(format, args...), IsError: true}
+	}
+	action, _ := call.Input["action"].(string)
+	if action == "create_branch" {
+		name, _ := call.Input["branch"].(string)
+		if name == "" {
+			return fail("branch is required for create_branch")
+		}
+		args := []string{"checkout", "-b", name}
+		if from, _ := call.Input["base"].(string); from != "" {
+			args = append(args, from)
+		}
+		if _, err := e.git(args...); err != nil {
+			return fail("%v", err)
+		}
+		return ToolResult{ToolUseID: call.ID, Content: "Created and switched to branch " + name}
+	}
+
+	remote, err := e.git("remote", "get-url", "origin")
+	if err != nil {
+		return fail("No origin remote: %v", err)
+	}
+	h, err := parseRemote(remote)
+	if err != nil {
+		return fail("%v", err)
+	}
+	var out string
+	switch action {
+	case "list_issues":
+		out, err = e.listIssues(h, call.Input)
+	case "view_issue":
+		out, err = e.viewIssue(h, call.Input)
+	case "create_pr":
+		out, err = e.createPullRequest(h, call.Input)
+	default:
+		return fail("Unknown action %q; use list_issues, view_issue, create_branch or create_pr", action)
+	}
+	if err != nil {
+		return fail("%v", err)
+	}
+	return ToolResult{ToolUseID: call.ID, Content: out}
+}
+
+type hostIssue struct {
+	Number      int    `json:"number"`
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	State       string `json:"state"`
+	Body        string `json:"body"`
+	Description string `json:"description"`
+	URL         string `json:"html_url"`
+	WebURL      string `json:"web_url"`
+	User        *struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Author *struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	// GitHub labels are objects, GitLab labels strings.
+	Labels      []json.RawMessage `json:"labels"`
+	PullRequest json.RawMessage   `json:"pull_request"`
+}
+
+// normalize fills the GitHub fields from their GitLab counterparts.
+func (i *hostIssue) normalize() {
+	if i.Number == 0 {
+		i.Number = i.IID
+	}
+	if i.Body == "" {
+		i.Body = i.Description
+	}
+	if i.URL == "" {
+		i.URL = i.WebURL
+	}
+}
+
+func (i *hostIssue) author() string {
+	switch {
+	case i.User != nil:
+		return i.User.Login
+	case i.Author != nil:
+		return i.Author.Username
+	}
+	return ""
+}
+
+func (i *hostIssue) labels() []string {
+	var names []string
+	for _, raw := range i.Labels {
+		var name string
+		if json.Unmarshal(raw, &name) != nil {
+			var obj struct {
+				Name string `json:"name"`
+			}
+			json.Unmarshal(raw, &obj)
+			name = obj.Name
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (e *Executor) listIssues(h *gitHost, input map[string]interface{}) (string, error) {
+	state, _ := input["state"].(string)
+	limit := gitHostMaxLimit / 5
+	if n, ok := input["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), gitHostMaxLimit)
+	}
+	q := url.Values{"per_page": {fmt.Sprint(limit)}}
+	switch {
+	case state == "" || state == "open":
+		q.Set("state", map[string]string{"github": "open", "gitlab": "opened"}[h.kind])
+	default:
+		q.Set("state", state)
+	}
+	if label, _ := input["label"].(string); label != "" {
+		q.Set("labels", label)
+	}
+	data, err := e.hostAPI(h, "GET", h.projectPath()+"/issues?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	var issues []hostIssue
+	if err := json.Unmarshal(data, &issues); err != nil {
+		return "", fmt.Errorf("parse issues: %w", err)
+	}
+	var b strings.Builder
+	for _, i := range issues {
+		// GitHub lists pull requests as issues too.
+		if len(i.PullRequest) > 0 {
+			continue
+		}
+		i.normalize()
+		fmt.Fprintf(&b, "#%d %s", i.Number, i.Title)
+		if labels := i.labels(); len(labels) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(labels, ", "))
+		}
+		if a := i.author(); a != "" {
+			fmt.Fprintf(&b, " (@%s)", a)
+		}
+		b.WriteByte('\n')
+	}
+	if b.Len() == 0 {
+		return "No issues found", nil
+	}
+	return b.String(), nil
+}
+
+func (e *Executor) viewIssue(h *gitHost, input map[string]interface{}) (string, error) {
+	n, _ := input["number"].(float64)
+	if n <= 0 {
+		return "", fmt.Errorf("number is required for view_issue")
+	}
+	path := fmt.Sprintf("%s/issues/%d", h.projectPath(), int(n))
+	data, err := e.hostAPI(h, "GET", path, nil)
+	if err != nil {
+		return "", err
+	}
+	var issue hostIssue
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return "", fmt.Errorf("parse issue: %w", err)
+	}
+	issue.normalize()
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s\nState: %s · Author: @%s", issue.Number, issue.Title, issue.State, issue.author())
+	if labels := issue.labels(); len(labels) > 0 {
+		fmt.Fprintf(&b, " · Labels: %s", strings.Join(labels, ", "))
+	}
+	fmt.Fprintf(&b, "\n%s\n\n%s\n", issue.URL, strings.TrimSpace(issue.Body))
+
+	commentsPath := path + "/comments"
+	if h.kind == "gitlab" {
+		commentsPath = path + "/notes?sort=asc"
+	}
+	if data, err := e.hostAPI(h, "GET", commentsPath, nil); err == nil {
+		var comments []hostIssue
+		json.Unmarshal(data, &comments)
+		for _, c := range comments {
+			c.normalize()
+			fmt.Fprintf(&b, "\n--- @%s:\n%s\n", c.author(), strings.TrimSpace(c.Body))
+		}
+	}
+	return b.String(), nil
+}
+
+// createPullRequest pushes the current branch and opens a pull request
+// (a merge request on GitLab) from it.
+func (e *Executor) createPullRequest(h *gitHost, input map[string]interface{}) (string, error) {
+	title, _ := input["title"].(string)
+	if title == "" {
+		return "", fmt.Errorf("title is required for create_pr")
+	}
+	body, _ := input["body"].(string)
+	draft, _ := input["draft"].(bool)
+	head, err := e.git("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	base, _ := input["base"].(string)
+	if base == "" {
+		data, err := e.hostAPI(h, "GET", h.projectPath(), nil)
+		if err != nil {
+			return "", err
+		}
+		var project struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		json.Unmarshal(data, &project)
+		base = project.DefaultBranch
+	}
+	if head == base {
+		return "", fmt.Errorf("the current branch is %s, the base branch; create a branch for the change first", head)
+	}
+	if _, err := e.git("push", "-u", "origin", head); err != nil {
+		return "", err
+	}
+
+	var path string
+	var req map[string]interface{}
+	if h.kind == "github" {
+		path = h.projectPath() + "/pulls"
+		req = map[string]interface{}{"title": title, "head": head, "base": base, "body": body, "draft": draft}
+	} else {
+		path = h.projectPath() + "/merge_requests"
+		if draft {
+			title = "Draft: " + title
+		}
+		req = map[string]interface{}{"title": title, "source_branch": head, "target_branch": base, "description": body}
+	}
+	data, err := e.hostAPI(h, "POST", path, req)
+	if err != nil {
+		return "", err
+	}
+	var created hostIssue
+	json.Unmarshal(data, &created)
+	created.normalize()
+	return fmt.Sprintf("Opened #%d from %s into %s: %s", created.Number, head, base, created.URL), nil
+}
+
+// hostAPI calls the host's REST API with a token, or through the gh or
+// glab CLI, which use their own login, when there is none.
+func (e *Executor) hostAPI(h *gitHost, method, path string, body interface{}) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gitHostTimeout)
+	defer cancel()
+
+	if token := e.gitHostToken(h.kind); token != "" {
+		req, err := http.NewRequestWithContext(ctx, method, h.apiBase()+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if h.kind == "github" {
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Accept", "application/vnd.github+json")
+		} else {
+			req.Header.Set("PRIVATE-TOKEN", token)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, gitHostMaxBody))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, apiMessage(data))
+		}
+		return data, nil
+	}
+
+	cli := map[string]string{"github": "gh", "gitlab": "glab"}[h.kind]
+	if _, err := exec.LookPath(cli); err != nil {
+		env := map[string]string{"github": "GITHUB_TOKEN", "gitlab": "GITLAB_TOKEN"}[h.kind]
+		return nil, fmt.Errorf("no %s token: set %s_token in the config or %s, or log in with the %s CLI", h.kind, h.kind, env, cli)
+	}
+	args := []string{"api", "--hostname", h.host, "-X", method, path}
+	if payload != nil {
+		args = append(args, "--input", "-")
+	}
+	cmd := exec.CommandContext(ctx, cli, args...)
+	cmd.Dir = e.workDir
+	cmd.Env = e.Environ()
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if m := apiMessage(data); m != "" {
+			msg = m
+		}
+		return nil, fmt.Errorf("%s api %s %s: %s", cli, method, path, msg)
+	}
+	return data, nil
+}
+
+func (e *Executor) gitHostToken(kind string) string {
+	if kind == "github" {
+		for _, t := range []string{e.githubToken, os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN")} {
+			if t != "" {
+				return t
+			}
+		}
+		return ""
+	}
+	if e.gitlabToken != "" {
+		return e.gitlabToken
+	}
+	return os.Getenv("GITLAB_TOKEN")
+}
+
+// apiMessage extracts the error message of a GitHub or GitLab API response.
+func apiMessage(data []byte) string {
+	var resp struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	if json.Unmarshal(data, &resp) != nil {
+		return strings.TrimSpace(string(data))
+	}
+	if resp.Message != nil {
+		return fmt.Sprint(resp.Message)
+	}
+	return resp.Error
+}
+
+// git runs git in the working directory and returns its trimmed output.
+func (e *Executor) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", e.workDir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/rpay/apipod-cli/internal/ignore"
+)
+
+// grepMaxMatches stops an in-process search early; results are paginated
+// well below it.
+const grepMaxMatches = 10000
+
+// grepInProcess emulates grep -rn for systems without grep, skipping VCS
+// directories and binary files. stopped reports that the search ended at
+// grepMaxMatches.
+func (e *Executor) grepInProcess(pattern, root, include string, exclude []string, ignored *ignore.Matcher) (lines []string, stopped bool, err error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	matches := 0
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || matches >= grepMaxMatches {
+			return nil
+		}
+		for _, x := range exclude {
+			if ok, _ := filepath.Match(x, d.Name()); ok && p != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if skip, err := ignored.Skip(p, d.IsDir()); skip {
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if include != "" {
+			if ok, _ := filepath.Match(include, d.Name()); !ok {
+				return nil
+			}
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		reader := bufio.NewReader(f)
+		if head, _ := reader.Peek(binarySniffLen); isBinary(head) {
+			return nil
+		}
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for n := 1; scanner.Scan(); n++ {
+			if re.MatchString(scanner.Text()) {
+				lines = append(lines, fmt.Sprintf("%s:%d:%s", p, n, scanner.Text()))
+				if matches++; matches >= grepMaxMatches {
+					break
+				}
+			}
+		}
+		return nil
+	})
+
+	return lines, matches >= grepMaxMatches, nil
+}
+
+// grepLine matches the "path:line:" prefix of grep -n output.
+var grepLine = regexp.MustCompile(`^(.+?):\d+:`)
+
+// dropIgnoredLines removes grep output lines from ignored files.
+func dropIgnoredLines(lines []string, ignored *ignore.Matcher) []string {
+	kept := lines[:0]
+	for _, line := range lines {
+		if m := grepLine.FindStringSubmatch(line); m != nil && ignored.Ignored(m[1], false) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	grpcTimeout   = 30 * time.Second
+	grpcMaxOutput = 64 * 1024
+)
+
+// GrpcTarget is the server the Grpc tool talks to by default.
+type GrpcTarget struct {
+	Address   string
+	Plaintext bool
+	Headers   map[string]string
+}
+
+// SetGrpcTarget enables the Grpc tool for target.
+func (e *Executor) SetGrpcTarget(t *GrpcTarget) {
+	e.grpc = t
+}
+
+// executeGrpc drives grpcurl, which speaks server reflection and converts
+// between JSON and protobuf without compiled stubs.
+func (e *Executor) executeGrpc(call ToolCall) ToolResult {
+	if _, err := exec.LookPath("grpcurl"); err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: "grpcurl not found in PATH; install it from https://github.com/fullstorydev/grpcurl", IsError: true}
+	}
+
+	target := GrpcTarget{}
+	if e.grpc != nil {
+		target = *e.grpc
+	}
+	if addr, ok := call.Input["target"].(string); ok && addr != "" {
+		target.Address = addr
+	}
+	if v, ok := call.Input["plaintext"].(bool); ok {
+		target.Plaintext = v
+	}
+	if target.Address == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "No target: pass target (host:port) or configure grpc.target", IsError: true}
+	}
+
+	action, _ := call.Input["action"].(string)
+	method, _ := call.Input["method"].(string)
+	symbol, _ := call.Input["symbol"].(string)
+
+	args := []string{"-max-time", fmt.Sprint(int(grpcTimeout / time.Second))}
+	if target.Plaintext {
+		args = append(args, "-plaintext")
+	}
+	headers := make(map[string]string)
+	for k, v := range target.Headers {
+		headers[k] = v
+	}
+	if extra, ok := call.Input["headers"].(map[string]interface{}); ok {
+		for k, v := range extra {
+			if s, ok := v.(string); ok {
+				headers[k] = s
+			}
+		}
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-H", k+": "+headers[k])
+	}
+
+	switch action {
+	case "", "list":
+		args = append(args, target.Address, "list")
+		if symbol != "" {
+			args = append(args, symbol)
+		}
+	case "describe":
+		args = append(args, target.Address, "describe")
+		if symbol != "" {
+			args = append(args, symbol)
+		}
+	case "call":
+		if method == "" {
+			return ToolResult{ToolUseID: call.ID, Content: "call needs method (package.Service/Method)", IsError: true}
+		}
+		data, _ := call.Input["request"].(string)
+		if data == "" {
+			data = "{}"
+		}
+		args = append(args, "-d", data, target.Address, method)
+	default:
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Unknown action %q (use list, describe or call)", action), IsError: true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), grpcTimeout+5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "grpcurl", args...)
+	cmd.Dir = e.workDir
+	cmd.Env = e.Environ()
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	text := strings.TrimRight(out.String(), "\n")
+	if len(text) > grpcMaxOutput {
+		text = truncateUTF8(text, grpcMaxOutput) + fmt.Sprintf("\n... [%d more bytes]", len(text)-grpcMaxOutput)
+	}
+	if err != nil {
+		if text == "" {
+			text = err.Error()
+		}
+		return ToolResult{ToolUseID: call.ID, Content: text, IsError: true}
+	}
+	if text == "" {
+		text = "(empty response)"
+	}
+	return ToolResult{ToolUseID: call.ID, Content: text}
+}
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rpay/apipod-cli/internal/har"
+	"github.com/rpay/apipod-cli/internal/httpreq"
+)
+
+const (
+	httpDefaultTimeout = 30 * time.Second
+	httpMaxTimeout     = 120 * time.Second
+	httpMaxBody        = 64 * 1024
+	// httpHistoryLen is how many requests are kept for export.
+	httpHistoryLen = 50
+)
+
+func (e *Executor) executeHTTPRequest(call ToolCall) ToolResult {
+	req, err := httpRequestInput(call.Input)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: err.Error(), IsError: true}
+	}
+
+	timeout := httpDefaultTimeout
+	if v, ok := call.Input["timeout"].(float64); ok && v > 0 {
+		timeout = time.Duration(v * float64(time.Second))
+		if timeout > httpMaxTimeout {
+			timeout = httpMaxTimeout
+		}
+	}
+
+	httpReq, err := newHTTPRequest(req)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	hc := newHTTPClient(req, timeout)
+
+	e.recordHTTP(*req)
+	started := time.Now()
+	resp, err := hc.Do(httpReq)
+	if err != nil {
+		e.captureHAR(har.Exchange{Req: httpReq, ReqBody: req.Body, Started: started, Elapsed: time.Since(started), Err: err})
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Request failed: %v", err), IsError: true}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpMaxBody+1))
+	elapsed := time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		e.captureHAR(har.Exchange{Req: httpReq, ReqBody: req.Body, Started: started, Elapsed: elapsed, Err: err})
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Read response: %v", err), IsError: true}
+	}
+	truncated := len(body) > httpMaxBody
+	if truncated {
+		body = body[:httpMaxBody]
+	}
+	harNote := e.captureHAR(har.Exchange{
+		Req:       httpReq,
+		ReqBody:   req.Body,
+		Resp:      resp,
+		RespBody:  body,
+		Truncated: truncated,
+		Started:   started,
+		Elapsed:   elapsed,
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s (%s)\n", resp.Proto, resp.Status, elapsed)
+	names := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range resp.Header[k] {
+			fmt.Fprintf(&sb, "%s: %s\n", k, v)
+		}
+	}
+	sb.WriteString("\n")
+	switch {
+	case len(body) == 0:
+		sb.WriteString("[empty body]\n")
+	case !utf8.Valid(body) && isBinary(body[:min(len(body), binarySniffLen)]):
+		fmt.Fprintf(&sb, "[binary body, %s]\n", formatSize(int64(len(body))))
+	default:
+		sb.Write(body)
+		if truncated {
+			fmt.Fprintf(&sb, "\n[truncated at %s]", formatSize(httpMaxBody))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(harNote)
+	return ToolResult{ToolUseID: call.ID, Content: sb.String()}
+}
+
+func newHTTPRequest(req *httpreq.Request) (*http.Request, error) {
+	httpReq, err := http.NewRequest(req.Method, req.URL, strings.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Name, "Host") {
+			httpReq.Host = h.Value
+			continue
+		}
+		httpReq.Header.Add(h.Name, h.Value)
+	}
+	return httpReq, nil
+}
+
+func newHTTPClient(req *httpreq.Request, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if req.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	hc := &http.Client{Timeout: timeout, Transport: transport}
+	if req.NoFollow {
+		hc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	return hc
+}
+
+// httpRequestInput builds the request from either a pasted curl command or
+// the method/url/headers/body fields. Fields given alongside curl override
+// what the command says.
+func httpRequestInput(input map[string]interface{}) (*httpreq.Request, error) {
+	req := &httpreq.Request{Method: "GET"}
+	if cmd, _ := input["curl"].(string); cmd != "" {
+		parsed, err := httpreq.ParseCurl(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse curl command: %v", err)
+		}
+		req = parsed
+	}
+	if m, _ := input["method"].(string); m != "" {
+		req.Method = strings.ToUpper(m)
+	}
+	if u, _ := input["url"].(string); u != "" {
+		req.URL = u
+	}
+	if headers, ok := input["headers"].(map[string]interface{}); ok {
+		m := make(map[string]string, len(headers))
+		for k, v := range headers {
+			if s, ok := v.(string); ok {
+				m[k] = s
+			}
+		}
+		for _, h := range httpreq.SortedHeaders(m) {
+			req.SetHeader(h.Name, h.Value)
+		}
+	}
+	if b, ok := input["body"].(string); ok && b != "" {
+		req.Body = b
+	}
+	if f, ok := input["follow_redirects"].(bool); ok {
+		req.NoFollow = !f
+	}
+
+	if req.URL == "" {
+		return nil, fmt.Errorf("Missing required parameter: url (or curl)")
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url must be an http:// or https:// URL")
+	}
+	return req, nil
+}
+
+func (e *Executor) recordHTTP(req httpreq.Request) {
+	e.httpMu.Lock()
+	defer e.httpMu.Unlock()
+	e.httpLog = append(e.httpLog, req)
+	if len(e.httpLog) > httpHistoryLen {
+		e.httpLog = e.httpLog[len(e.httpLog)-httpHistoryLen:]
+	}
+}
+
+// SetHARRecorder captures every HttpRequest exchange into r; nil stops
+// capturing.
+func (e *Executor) SetHARRecorder(r *har.Recorder) {
+	e.httpMu.Lock()
+	defer e.httpMu.Unlock()
+	e.har = r
+}
+
+// captureHAR records an exchange when capture is on. A failed write stops
+// capturing and returns a note for the tool result instead of failing the
+// request.
+func (e *Executor) captureHAR(x har.Exchange) string {
+	e.httpMu.Lock()
+	r := e.har
+	e.httpMu.Unlock()
+	if r == nil {
+		return ""
+	}
+	if err := r.Add(x); err != nil {
+		e.SetHARRecorder(nil)
+		return fmt.Sprintf("[HAR capture stopped: %v]\n", err)
+	}
+	return ""
+}
+
+// HTTPHistory returns the requests made by the HttpRequest tool, oldest
+// first.
+func (e *Executor) HTTPHistory() []httpreq.Request {
+	e.httpMu.Lock()
+	defer e.httpMu.Unlock()
+	return append([]httpreq.Request(nil), e.httpLog...)
+}
+
+// HTTPRequestURL returns the target of an HttpRequest call, parsing the curl
+// input when no url is given.
+func HTTPRequestURL(input map[string]interface{}) (method, rawURL string) {
+	req, err := httpRequestInput(input)
+	if err != nil {
+		u, _ := input["url"].(string)
+		return "", u
+	}
+	return req.Method, req.URL
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default number of results Grep and Glob return per call, so a broad
+// search does not flood the context.
+const (
+	grepDefaultLimit = 250
+	globDefaultLimit = 500
+)
+
+// paginate returns the page of results selected by the call's offset and
+// head_limit, with a note saying how many there are in all when some are
+// left out. atLeast means the search stopped early and the total is a
+// lower bound.
+func paginate(call ToolCall, results []string, atLeast bool, defaultLimit int, noun string) string {
+	offset := 0
+	if v, ok := call.Input["offset"].(float64); ok && v > 0 {
+		offset = int(v)
+	}
+	limit := defaultLimit
+	if v, ok := call.Input["head_limit"].(float64); ok && v > 0 {
+		limit = int(v)
+	}
+
+	total := len(results)
+	count := groupThousands(total)
+	if atLeast {
+		count += "+"
+	}
+	if offset >= total {
+		return fmt.Sprintf("(offset %d is past the last of %s %s)", offset, count, noun)
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	page := strings.Join(results[offset:end], "\n")
+	if offset == 0 && end == total && !atLeast {
+		return page
+	}
+
+	shown := fmt.Sprintf("%s–%s", groupThousands(offset+1), groupThousands(end))
+	if offset == 0 {
+		shown = groupThousands(end)
+	}
+	note := fmt.Sprintf("(showing %s of %s %s", shown, count, noun)
+	if end < total {
+		note += fmt.Sprintf(" — refine the pattern, or pass offset=%d for the next page", end)
+	}
+	return page + "\n" + note + ")"
+}
+
+// groupThousands formats n as 5,432.
+func groupThousands(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,371 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Hunks are searched for this many lines around their stated position before
+// falling back to a whole-file scan.
+const patchSearchWindow = 200
+
+type filePatch struct {
+	oldPath string
+	newPath string
+	// rename is set for git diffs moving oldPath to newPath. Plain diffs
+	// such as "--- f.orig" / "+++ f" name two paths without moving one.
+	rename bool
+	hunks  []patchHunk
+}
+
+type patchHunk struct {
+	header   string
+	oldStart int
+	oldLines []string
+	newLines []string
+}
+
+func (e *Executor) executeApplyPatch(call ToolCall) ToolResult {
+	patch, _ := call.Input["patch"].(string)
+	if patch == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: patch", IsError: true}
+	}
+
+	files, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Invalid patch: %v", err), IsError: true}
+	}
+
+	var report strings.Builder
+	failed := false
+	for _, fp := range files {
+		if !e.applyFilePatch(fp, &report) {
+			failed = true
+		}
+	}
+	return ToolResult{ToolUseID: call.ID, Content: strings.TrimRight(report.String(), "\n"), IsError: failed}
+}
+
+// applyFilePatch applies all hunks of one file and writes the result only
+// when every hunk succeeded, so a file is never left half-patched.
+func (e *Executor) applyFilePatch(fp filePatch, report *strings.Builder) bool {
+	target := fp.newPath
+	if target == "" {
+		target = fp.oldPath
+	}
+	resolved := e.resolvePath(target)
+	for _, p := range []string{fp.oldPath, fp.newPath} {
+		if p == "" {
+			continue
+		}
+		if d, denied := e.deniedPath(p); denied {
+			fmt.Fprintf(report, "%s: blocked, %s is on the sensitive-path deny list\n", p, d)
+			return false
+		}
+	}
+
+	var lines []string
+	var format fileFormat
+	trailingNewline := true
+	if fp.oldPath != "" {
+		content, f, err := e.readWithFormat(e.resolvePath(fp.oldPath))
+		if err != nil {
+			fmt.Fprintf(report, "%s: %v\n", target, err)
+			return false
+		}
+		format = f
+		lines, trailingNewline = splitLines(content)
+	}
+
+	lines, results, ok := applyHunks(lines, fp.hunks)
+
+	switch {
+	case !ok:
+		fmt.Fprintf(report, "%s: not modified\n", target)
+	case fp.newPath == "":
+		if err := e.removeFile(resolved); err != nil {
+			fmt.Fprintf(report, "%s: %v\n", target, err)
+			return false
+		}
+		fmt.Fprintf(report, "%s: deleted\n", target)
+	default:
+		out := strings.Join(lines, "\n")
+		if trailingNewline && len(lines) > 0 {
+			out += "\n"
+		}
+		if err := e.writeWithFormat(resolved, out, format); err != nil {
+			fmt.Fprintf(report, "%s: %v\n", target, err)
+			return false
+		}
+		switch {
+		case fp.oldPath == "":
+			fmt.Fprintf(report, "%s: created\n", target)
+		case fp.rename && filepath.Clean(fp.oldPath) != filepath.Clean(fp.newPath):
+			// A rename: the old file goes once the new one is written.
+			if err := e.removeFile(e.resolvePath(fp.oldPath)); err != nil {
+				fmt.Fprintf(report, "%s: written, but %s could not be removed: %v\n", target, fp.oldPath, err)
+				return false
+			}
+			fmt.Fprintf(report, "%s: renamed from %s\n", target, fp.oldPath)
+		default:
+			fmt.Fprintf(report, "%s: patched\n", target)
+		}
+	}
+	for _, r := range results {
+		report.WriteString(r + "\n")
+	}
+	return ok
+}
+
+// applyHunks applies hunks to lines in order and reports each one. ok is
+// false when any hunk could not be placed; lines then still holds the
+// hunks that were.
+func applyHunks(lines []string, hunks []patchHunk) ([]string, []string, bool) {
+	ok := true
+	var results []string
+	offset := 0
+	for i, h := range hunks {
+		want := h.oldStart - 1 + offset
+		at, placed, how := locateHunk(lines, h, want)
+		if at < 0 || at+len(placed.oldLines) > len(lines) {
+			ok = false
+			results = append(results, fmt.Sprintf("  hunk %d %s: FAILED (context not found)", i+1, h.header))
+			continue
+		}
+		end := at + len(placed.oldLines)
+		// The context kept is the file's own, which may differ from the
+		// patch's in whitespace.
+		lead, trail := contextLines(placed)
+		replaced := append([]string{}, lines[at:at+lead]...)
+		replaced = append(replaced, placed.newLines[lead:len(placed.newLines)-trail]...)
+		replaced = append(replaced, lines[end-trail:end]...)
+		lines = append(lines[:at:at], append(replaced, lines[end:]...)...)
+		// A hunk placed without its outer context lines starts one line
+		// later than the whole hunk would.
+		start := at - (len(h.oldLines)-len(placed.oldLines))/2
+		offset = start - (h.oldStart - 1) + len(h.newLines) - len(h.oldLines)
+		results = append(results, fmt.Sprintf("  hunk %d %s: applied at line %d%s", i+1, h.header, at+1, how))
+	}
+	return lines, results, ok
+}
+
+// locateHunk finds where a hunk's old lines occur, preferring the position
+// nearest to the expected one, and returns the hunk to apply there. Exact
+// matches win over whitespace-insensitive ones; as a last resort the first
+// and last context lines are dropped, and only the block between them is
+// replaced, so the file keeps its own lines where the patch's context
+// differed.
+func locateHunk(lines []string, h patchHunk, want int) (int, patchHunk, string) {
+	if len(h.oldLines) == 0 {
+		if want < 0 {
+			want = 0
+		}
+		if want > len(lines) {
+			want = len(lines)
+		}
+		return want, h, ""
+	}
+
+	matchers := []struct {
+		eq   func(a, b string) bool
+		note string
+	}{
+		{func(a, b string) bool { return a == b }, ""},
+		{func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }, ", ignoring whitespace"},
+	}
+	for _, m := range matchers {
+		if at := searchBlock(lines, h.oldLines, want, m.eq); at >= 0 {
+			return at, h, offsetNote(at, want) + m.note
+		}
+	}
+
+	if len(h.oldLines) > 2 && isContext(h, 0) && isContext(h, len(h.oldLines)-1) {
+		trimmed := h
+		trimmed.oldLines = h.oldLines[1 : len(h.oldLines)-1]
+		trimmed.newLines = h.newLines[1 : len(h.newLines)-1]
+		eq := func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }
+		if at := searchBlock(lines, trimmed.oldLines, want+1, eq); at >= 0 {
+			return at, trimmed, offsetNote(at, want+1) + ", fuzz 1"
+		}
+	}
+	return -1, h, ""
+}
+
+// contextLines counts the unchanged lines at the start and end of a hunk.
+func contextLines(h patchHunk) (int, int) {
+	n := len(h.oldLines)
+	if len(h.newLines) < n {
+		n = len(h.newLines)
+	}
+	lead := 0
+	for lead < n && h.oldLines[lead] == h.newLines[lead] {
+		lead++
+	}
+	trail := 0
+	for trail < n-lead && h.oldLines[len(h.oldLines)-1-trail] == h.newLines[len(h.newLines)-1-trail] {
+		trail++
+	}
+	return lead, trail
+}
+
+// isContext reports whether the old line at i is unchanged in the new lines.
+func isContext(h patchHunk, i int) bool {
+	if i == 0 {
+		return len(h.newLines) > 0 && h.oldLines[0] == h.newLines[0]
+	}
+	return len(h.newLines) > 0 && h.oldLines[len(h.oldLines)-1] == h.newLines[len(h.newLines)-1]
+}
+
+func searchBlock(lines, block []string, want int, eq func(a, b string) bool) int {
+	matchAt := func(at int) bool {
+		if at < 0 || at+len(block) > len(lines) {
+			return false
+		}
+		for i, l := range block {
+			if !eq(lines[at+i], l) {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; d <= patchSearchWindow; d++ {
+		if matchAt(want - d) {
+			return want - d
+		}
+		if d > 0 && matchAt(want+d) {
+			return want + d
+		}
+	}
+	for at := 0; at+len(block) <= len(lines); at++ {
+		if matchAt(at) {
+			return at
+		}
+	}
+	return -1
+}
+
+func offsetNote(at, want int) string {
+	if at == want {
+		return ""
+	}
+	return fmt.Sprintf(" (offset %+d)", at-want)
+}
+
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	trailing := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), trailing
+}
+
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []filePatch
+	var cur *filePatch
+	var hunk *patchHunk
+
+	flush := func() {
+		if hunk != nil && cur != nil {
+			cur.hunks = append(cur.hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			flush()
+			oldRaw, newRaw := strings.TrimPrefix(line, "--- "), strings.TrimPrefix(lines[i+1], "+++ ")
+			files = append(files, filePatch{
+				oldPath: diffPath(oldRaw),
+				newPath: diffPath(newRaw),
+				rename:  strings.HasPrefix(oldRaw, "a/") && strings.HasPrefix(newRaw, "b/"),
+			})
+			cur = &files[len(files)-1]
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("hunk before file header at line %d", i+1)
+			}
+			flush()
+			start, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			hunk = &patchHunk{header: hunkLabel(line), oldStart: start}
+		case hunk != nil && strings.HasPrefix(line, " "):
+			hunk.oldLines = append(hunk.oldLines, line[1:])
+			hunk.newLines = append(hunk.newLines, line[1:])
+		case hunk != nil && line == "" && i+1 < len(lines) && isHunkBody(lines[i+1]):
+			// Some generators drop the leading space on blank context lines.
+			hunk.oldLines = append(hunk.oldLines, "")
+			hunk.newLines = append(hunk.newLines, "")
+		case hunk != nil && strings.HasPrefix(line, "-"):
+			hunk.oldLines = append(hunk.oldLines, line[1:])
+		case hunk != nil && strings.HasPrefix(line, "+"):
+			hunk.newLines = append(hunk.newLines, line[1:])
+		}
+	}
+	flush()
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file headers (--- / +++) found")
+	}
+	for i := range files {
+		if len(files[i].hunks) == 0 {
+			return nil, fmt.Errorf("no hunks for %s", files[i].newPath)
+		}
+	}
+	return files, nil
+}
+
+func isHunkBody(line string) bool {
+	if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") {
+		return false
+	}
+	return line == "" || line[0] == ' ' || line[0] == '+' || line[0] == '-'
+}
+
+func diffPath(p string) string {
+	if tab := strings.IndexByte(p, '\t'); tab >= 0 {
+		p = p[:tab]
+	}
+	p = strings.TrimSpace(p)
+	if p == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		p = p[2:]
+	}
+	return p
+}
+
+func parseHunkHeader(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	old := strings.TrimPrefix(fields[1], "-")
+	if comma := strings.IndexByte(old, ','); comma >= 0 {
+		old = old[:comma]
+	}
+	start, err := strconv.Atoi(old)
+	if err != nil {
+		return 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	if start == 0 {
+		start = 1
+	}
+	return start, nil
+}
+
+func hunkLabel(line string) string {
+	if end := strings.Index(line[2:], "@@"); end >= 0 {
+		return strings.TrimSpace(line[:end+4])
+	}
+	return line
+}
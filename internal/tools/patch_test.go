@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		patch string
+		want  string
+		ok    bool
+	}{
+		{
+			name:  "exact",
+			file:  "a\nb\nc\nd\n",
+			patch: "@@ -2,2 +2,2 @@\n b\n-c\n+C\n",
+			want:  "a\nb\nC\nd\n",
+			ok:    true,
+		},
+		{
+			name:  "offset",
+			file:  "x\nx\na\nb\nc\n",
+			patch: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:  "x\nx\na\nB\nc\n",
+			ok:    true,
+		},
+		{
+			name:  "whitespace",
+			file:  "a\n  b\nc\n",
+			patch: "@@ -1,3 +1,3 @@\n a\n b\n-c\n+C\n",
+			want:  "a\n  b\nC\n",
+			ok:    true,
+		},
+		{
+			name:  "fuzzy keeps the file's context",
+			file:  "p\nb\nc\nq\n",
+			patch: "@@ -1,4 +1,4 @@\n P\n b\n-c\n+C\n Q\n",
+			want:  "p\nb\nC\nq\n",
+			ok:    true,
+		},
+		{
+			name:  "fuzzy with trailing context past EOF",
+			file:  "x\nb\nc\n",
+			patch: "@@ -1,4 +1,4 @@\n Q\n b\n-c\n+C\n Y\n",
+			want:  "x\nb\nC\n",
+			ok:    true,
+		},
+		{
+			name:  "fuzzy with leading context before the start",
+			file:  "b\nc\nx\n",
+			patch: "@@ -1,4 +1,4 @@\n Q\n b\n-c\n+C\n x\n",
+			want:  "b\nC\nx\n",
+			ok:    true,
+		},
+		{
+			name:  "append at EOF",
+			file:  "a\nb\n",
+			patch: "@@ -2,1 +2,2 @@\n b\n+c\n",
+			want:  "a\nb\nc\n",
+			ok:    true,
+		},
+		{
+			name:  "hunk longer than the file",
+			file:  "a\n",
+			patch: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:  "a\n",
+			ok:    false,
+		},
+		{
+			name:  "mismatch",
+			file:  "a\nb\nc\n",
+			patch: "@@ -1,3 +1,3 @@\n a\n-z\n+Z\n c\n",
+			want:  "a\nb\nc\n",
+			ok:    false,
+		},
+		{
+			name:  "second hunk after a fuzzy first",
+			file:  "p\nb\nc\nq\nr\ns\nt\n",
+			patch: "@@ -1,4 +1,4 @@\n P\n b\n-c\n+C\n Q\n@@ -5,3 +5,4 @@\n r\n s\n+S\n t\n",
+			want:  "p\nb\nC\nq\nr\ns\nS\nt\n",
+			ok:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := parseUnifiedDiff("--- a/f\n+++ b/f\n" + tt.patch)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			lines, _ := splitLines(tt.file)
+			got, results, ok := applyHunks(lines, files[0].hunks)
+			if ok != tt.ok {
+				t.Errorf("ok = %v, want %v\n%s", ok, tt.ok, strings.Join(results, "\n"))
+			}
+			if s := strings.Join(got, "\n") + "\n"; s != tt.want {
+				t.Errorf("got %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchDenied(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	keys := filepath.Join(home, ".ssh", "authorized_keys")
+	if err := os.MkdirAll(filepath.Dir(keys), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keys, []byte("ssh-rsa GOOD\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	e := NewExecutor(t.TempDir())
+
+	patches := map[string]string{
+		"headers": "--- " + keys + "\n+++ " + keys + "\n@@ -1,1 +1,2 @@\n ssh-rsa GOOD\n+ssh-rsa EVIL\n",
+		"rename":  "diff --git a/x b/y\nrename from x\nrename to " + keys + "\n--- a/x\n+++ b/y\n@@ -0,0 +1,1 @@\n+ssh-rsa EVIL\n",
+	}
+	for name, patch := range patches {
+		t.Run(name, func(t *testing.T) {
+			r := e.Execute(ToolCall{ID: "1", Name: "ApplyPatch", Input: map[string]interface{}{"patch": patch}})
+			if !r.IsError || !strings.Contains(r.Content, "blocked") {
+				t.Errorf("ApplyPatch = %q, want it blocked", r.Content)
+			}
+		})
+	}
+
+	// applyFilePatch checks on its own, whatever called it.
+	files, err := parseUnifiedDiff(patches["headers"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report strings.Builder
+	if e.applyFilePatch(files[0], &report) {
+		t.Errorf("applyFilePatch succeeded: %s", report.String())
+	}
+
+	if data, _ := os.ReadFile(keys); string(data) != "ssh-rsa GOOD\n" {
+		t.Errorf("authorized_keys changed to %q", data)
+	}
+}
+
+func TestApplyPatchRename(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		keepOld bool
+	}{
+		{"git rename", "--- a/old.txt\n+++ b/new.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n", false},
+		{"plain diff of a copy", "--- old.txt\n+++ new.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "old.txt"), []byte("a\nb\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			r := NewExecutor(dir).Execute(ToolCall{ID: "1", Name: "ApplyPatch", Input: map[string]interface{}{"patch": tt.patch}})
+			if r.IsError {
+				t.Fatalf("ApplyPatch: %s", r.Content)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dir, "new.txt")); string(data) != "a\nB\n" {
+				t.Errorf("new.txt = %q", data)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "old.txt")); (err == nil) != tt.keepOld {
+				t.Errorf("old.txt exists = %v, want %v", err == nil, tt.keepOld)
+			}
+		})
+	}
+}
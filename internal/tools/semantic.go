@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/semantic"
+)
+
+// SetSemanticSearch enables the SemanticSearch tool backed by engine.
+func (e *Executor) SetSemanticSearch(engine *semantic.Engine) {
+	e.semantic = engine
+}
+
+func (e *Executor) executeSemanticSearch(call ToolCall) ToolResult {
+	if e.semantic == nil {
+		return ToolResult{ToolUseID: call.ID, Content: "SemanticSearch is not enabled", IsError: true}
+	}
+	query, _ := call.Input["query"].(string)
+	if query == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: query", IsError: true}
+	}
+	limit := 8
+	if v, ok := call.Input["limit"].(float64); ok && v > 0 {
+		limit = int(v)
+	}
+
+	results, err := e.semantic.Search(query, limit)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	if len(results) == 0 {
+		return ToolResult{ToolUseID: call.ID, Content: "No relevant code found"}
+	}
+
+	var sb strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&sb, "%s:%d-%d (score %.2f)\n%s\n", r.Path, r.Start, r.End, r.Score, r.Snippet)
+	}
+	return ToolResult{ToolUseID: call.ID, Content: strings.TrimRight(sb.String(), "\n")}
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Shell runs the commands of the Bash tool. Unix uses bash (or sh); Windows
+// prefers Git Bash and falls back to PowerShell or cmd.
+type Shell struct {
+	Name string
+	Path string
+	// Args precede the command string, e.g. ["-c"].
+	Args []string
+}
+
+// ShellByName finds a shell by name ("bash", "sh", "zsh", "pwsh",
+// "powershell", "cmd") or by path to its executable.
+func ShellByName(name string) (Shell, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return Shell{}, fmt.Errorf("shell %q not found: %w", name, err)
+	}
+	return shellFor(path), nil
+}
+
+// shellFor picks the arguments for the shell at path from its file name.
+func shellFor(path string) Shell {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	switch base {
+	case "pwsh", "powershell":
+		return Shell{Name: base, Path: path, Args: []string{"-NoProfile", "-NonInteractive", "-Command"}}
+	case "cmd":
+		return Shell{Name: base, Path: path, Args: []string{"/S", "/C"}}
+	default:
+		return Shell{Name: base, Path: path, Args: []string{"-c"}}
+	}
+}
+
+// Command returns the command that runs script in the shell.
+func (s Shell) Command(ctx context.Context, script string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, s.Path, append(append([]string(nil), s.Args...), script)...)
+	prepareShellCommand(cmd, s, script)
+	return cmd
+}
+
+// DefaultShell returns the shell used when none is configured.
+func DefaultShell() Shell {
+	return defaultShell()
+}
+
+// SetShell changes the shell of the Bash tool; "" restores the default.
+func (e *Executor) SetShell(name string) error {
+	if name == "" {
+		e.shell = defaultShell()
+		return nil
+	}
+	sh, err := ShellByName(name)
+	if err != nil {
+		return err
+	}
+	e.shell = sh
+	return nil
+}
+
+// Shell returns the shell used by the Bash tool.
+func (e *Executor) Shell() Shell {
+	return e.shell
+}
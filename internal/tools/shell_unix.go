@@ -0,0 +1,16 @@
+//go:build !windows
+
+package tools
+
+import "os/exec"
+
+func defaultShell() Shell {
+	for _, name := range []string{"bash", "sh"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return shellFor(path)
+		}
+	}
+	return Shell{Name: "sh", Path: "/bin/sh", Args: []string{"-c"}}
+}
+
+func prepareShellCommand(cmd *exec.Cmd, s Shell, script string) {}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// defaultShell prefers Git Bash so the bash commands models tend to write
+// keep working, then PowerShell, then cmd.
+func defaultShell() Shell {
+	var candidates []string
+	for _, env := range []string{"ProgramFiles", "ProgramW6432", "LocalAppData"} {
+		if dir := os.Getenv(env); dir != "" {
+			candidates = append(candidates,
+				filepath.Join(dir, "Git", "bin", "bash.exe"),
+				filepath.Join(dir, "Programs", "Git", "bin", "bash.exe"))
+		}
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return shellFor(p)
+		}
+	}
+	// bash.exe on PATH may be the WSL launcher, which runs in a different
+	// filesystem; only trust it when it belongs to Git.
+	if p, err := exec.LookPath("bash"); err == nil && filepath.Base(filepath.Dir(filepath.Dir(p))) == "Git" {
+		return shellFor(p)
+	}
+	for _, name := range []string{"pwsh", "powershell"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return shellFor(p)
+		}
+	}
+	comspec := os.Getenv("ComSpec")
+	if comspec == "" {
+		comspec = "cmd.exe"
+	}
+	return shellFor(comspec)
+}
+
+// prepareShellCommand passes the script to cmd.exe verbatim: cmd does not
+// parse its command line with the quoting rules exec uses for arguments.
+func prepareShellCommand(cmd *exec.Cmd, s Shell, script string) {
+	if s.Name != "cmd" {
+		return
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `/S /C "` + script + `"`}
+}
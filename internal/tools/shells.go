@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// shellBufferLimit bounds the unread output kept per background shell;
+	// older output is dropped first.
+	shellBufferLimit = 1 << 20
+	// Exited shells whose output has been read are forgotten after this long.
+	shellReapAfter = 10 * time.Minute
+)
+
+type bgShell struct {
+	id      string
+	command string
+	cmd     *exec.Cmd
+	started time.Time
+
+	mu       sync.Mutex
+	output   strings.Builder
+	dropped  int
+	exited   bool
+	exitCode int
+	ended    time.Time
+}
+
+// ShellInfo describes a background shell for listings.
+type ShellInfo struct {
+	ID       string
+	Command  string
+	Running  bool
+	ExitCode int
+	Started  time.Time
+	Ended    time.Time
+	Pending  int
+}
+
+func (s *bgShell) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.output.Write(p)
+	if over := s.output.Len() - shellBufferLimit; over > 0 {
+		rest := s.output.String()[over:]
+		s.output.Reset()
+		s.output.WriteString(rest)
+		s.dropped += over
+	}
+	return len(p), nil
+}
+
+// wait reaps the process and records how it exited.
+func (s *bgShell) wait() {
+	err := s.cmd.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exited = true
+	s.ended = time.Now()
+	s.exitCode = 0
+	if err != nil {
+		s.exitCode = -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			s.exitCode = exitErr.ExitCode()
+		}
+	}
+}
+
+func (s *bgShell) info() ShellInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ShellInfo{
+		ID:       s.id,
+		Command:  s.command,
+		Running:  !s.exited,
+		ExitCode: s.exitCode,
+		Started:  s.started,
+		Ended:    s.ended,
+		Pending:  s.output.Len(),
+	}
+}
+
+func (s *bgShell) status() string {
+	info := s.info()
+	if info.Running {
+		return fmt.Sprintf("running for %s", time.Since(info.Started).Round(time.Second))
+	}
+	return fmt.Sprintf("exited with code %d after %s", info.ExitCode, info.Ended.Sub(info.Started).Round(time.Second))
+}
+
+func (e *Executor) executeBashBackground(call ToolCall, command, dir string, env []string) ToolResult {
+	cmd := e.shell.Command(context.Background(), command)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	setProcessGroup(cmd)
+
+	shell := &bgShell{id: call.ID, command: command, cmd: cmd, started: time.Now()}
+	cmd.Stdout = shell
+	cmd.Stderr = shell
+
+	if err := cmd.Start(); err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Failed to start: %v", err), IsError: true}
+	}
+	go shell.wait()
+
+	e.bgMu.Lock()
+	e.reapShellsLocked()
+	e.bgShells[shell.id] = shell
+	e.bgMu.Unlock()
+
+	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Background process started (id: %s)", shell.id) + cwdNote(call, dir)}
+}
+
+// reapShellsLocked forgets exited shells whose output was fully read a while
+// ago. The caller holds bgMu.
+func (e *Executor) reapShellsLocked() {
+	for id, shell := range e.bgShells {
+		info := shell.info()
+		if !info.Running && info.Pending == 0 && time.Since(info.Ended) > shellReapAfter {
+			delete(e.bgShells, id)
+		}
+	}
+}
+
+func (e *Executor) executeBashOutput(call ToolCall) ToolResult {
+	bashID, _ := call.Input["bash_id"].(string)
+	if bashID == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: bash_id", IsError: true}
+	}
+
+	e.bgMu.Lock()
+	shell, exists := e.bgShells[bashID]
+	e.bgMu.Unlock()
+
+	if !exists {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("No background shell: %s", bashID), IsError: true}
+	}
+
+	shell.mu.Lock()
+	output := shell.output.String()
+	dropped := shell.dropped
+	shell.output.Reset()
+	shell.dropped = 0
+	shell.mu.Unlock()
+
+	if output == "" {
+		output = "(no new output)"
+	}
+	if dropped > 0 {
+		output = fmt.Sprintf("[%d bytes of earlier output dropped]\n", dropped) + output
+	}
+	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("%s\n[status: %s]", strings.TrimRight(output, "\n"), shell.status())}
+}
+
+func (e *Executor) executeKillBash(call ToolCall) ToolResult {
+	shellID, _ := call.Input["shell_id"].(string)
+	if shellID == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: shell_id", IsError: true}
+	}
+
+	e.bgMu.Lock()
+	shell, exists := e.bgShells[shellID]
+	if exists {
+		delete(e.bgShells, shellID)
+	}
+	e.bgMu.Unlock()
+
+	if !exists {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("No background shell: %s", shellID), IsError: true}
+	}
+
+	if shell.info().Running {
+		killProcessGroup(shell.cmd)
+	}
+	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Shell %s terminated", shellID)}
+}
+
+func (e *Executor) executeListShells(call ToolCall) ToolResult {
+	shells := e.Shells()
+	if len(shells) == 0 {
+		return ToolResult{ToolUseID: call.ID, Content: "No background shells"}
+	}
+	var sb strings.Builder
+	for _, s := range shells {
+		state := "running"
+		if !s.Running {
+			state = fmt.Sprintf("exited %d", s.ExitCode)
+		}
+		fmt.Fprintf(&sb, "%s  [%s]  %s", s.ID, state, s.Command)
+		if s.Pending > 0 {
+			fmt.Fprintf(&sb, "  (%d bytes unread)", s.Pending)
+		}
+		sb.WriteString("\n")
+	}
+	return ToolResult{ToolUseID: call.ID, Content: sb.String()}
+}
+
+// Shells lists the background shells, oldest first.
+func (e *Executor) Shells() []ShellInfo {
+	e.bgMu.Lock()
+	e.reapShellsLocked()
+	out := make([]ShellInfo, 0, len(e.bgShells))
+	for _, shell := range e.bgShells {
+		out = append(out, shell.info())
+	}
+	e.bgMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.Before(out[j].Started) })
+	return out
+}
+
+// Close kills all background shells that are still running.
+func (e *Executor) Close() {
+	e.bgMu.Lock()
+	defer e.bgMu.Unlock()
+	for id, shell := range e.bgShells {
+		if shell.info().Running {
+			killProcessGroup(shell.cmd)
+		}
+		delete(e.bgShells, id)
+	}
+}
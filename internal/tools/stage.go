@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// stagedFile is a file change held in memory for review. Contents are the
+// exact bytes on disk and the bytes that would be written.
+type stagedFile struct {
+	original string
+	existed  bool
+	content  string
+	deleted  bool
+	mode     os.FileMode
+}
+
+// StagedChange is a file change waiting for review.
+type StagedChange struct {
+	Path    string
+	Old     string
+	New     string
+	Created bool
+	Deleted bool
+}
+
+// SetStaging makes Write, Edit, MultiEdit, EditLines and ApplyPatch keep
+// their changes in memory instead of writing them. Read sees staged
+// content; Bash, Grep and Glob see the files on disk. Turning staging off
+// keeps what is already staged until it is applied or dropped.
+func (e *Executor) SetStaging(on bool) {
+	e.staging = on
+}
+
+func (e *Executor) Staging() bool {
+	return e.staging
+}
+
+// StagedChanges returns the staged changes sorted by path, leaving out
+// files that were changed back to what is on disk.
+func (e *Executor) StagedChanges() []StagedChange {
+	var out []StagedChange
+	for path, st := range e.staged {
+		unchanged := st.existed && !st.deleted && st.original == st.content
+		if unchanged || !st.existed && st.deleted {
+			continue
+		}
+		out = append(out, StagedChange{
+			Path:    path,
+			Old:     st.original,
+			New:     st.content,
+			Created: !st.existed,
+			Deleted: st.deleted,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// ApplyStaged writes content to a staged file, or deletes it when remove
+// is set, and forgets the staged change.
+func (e *Executor) ApplyStaged(path, content string, remove bool) error {
+	st, ok := e.staged[path]
+	if !ok {
+		return fmt.Errorf("%s has no staged change", path)
+	}
+	delete(e.staged, path)
+	if remove {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), st.mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, st.mode)
+}
+
+// DropStaged forgets the staged change of path, leaving the file on disk
+// as it is.
+func (e *Executor) DropStaged(path string) {
+	delete(e.staged, path)
+}
+
+func (e *Executor) stagedAt(path string) *stagedFile {
+	return e.staged[filepath.Clean(path)]
+}
+
+func (e *Executor) readWithFormat(path string) (string, fileFormat, error) {
+	if st := e.stagedAt(path); st != nil {
+		if st.deleted {
+			return "", fileFormat{}, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+		}
+		content, f := parseFormat(st.content, st.mode)
+		return content, f, nil
+	}
+	return readWithFormat(path)
+}
+
+func (e *Executor) writeWithFormat(path, content string, f fileFormat) error {
+	if !e.staging {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return writeWithFormat(path, content, f)
+	}
+	st := e.stage(path)
+	st.content, st.deleted = f.apply(content), false
+	if f.exists && f.mode != 0 {
+		st.mode = f.mode
+	}
+	return nil
+}
+
+func (e *Executor) removeFile(path string) error {
+	if !e.staging {
+		return os.Remove(path)
+	}
+	st := e.stage(path)
+	if st.deleted {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrNotExist}
+	}
+	st.content, st.deleted = "", true
+	return nil
+}
+
+// stage returns the staged entry of path, recording the file as it is on
+// disk the first time.
+func (e *Executor) stage(path string) *stagedFile {
+	path = filepath.Clean(path)
+	if st, ok := e.staged[path]; ok {
+		return st
+	}
+	st := &stagedFile{mode: defaultFileMode, deleted: true}
+	if info, err := os.Stat(path); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			st.original, st.existed, st.mode = string(data), true, info.Mode().Perm()
+			st.content, st.deleted = st.original, false
+		}
+	}
+	if e.staged == nil {
+		e.staged = make(map[string]*stagedFile)
+	}
+	e.staged[path] = st
+	return st
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// statMaxHashSize skips hashing files larger than this.
+const statMaxHashSize = 1 << 30
+
+func (e *Executor) executeStat(call ToolCall) ToolResult {
+	var paths []string
+	if p, ok := call.Input["path"].(string); ok && p != "" {
+		paths = append(paths, p)
+	}
+	if raw, ok := call.Input["paths"].([]interface{}); ok {
+		for _, v := range raw {
+			if p, ok := v.(string); ok && p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: path or paths", IsError: true}
+	}
+
+	algo, _ := call.Input["hash"].(string)
+	if algo == "" {
+		algo = "sha256"
+	}
+	if algo != "none" && newHash(algo) == nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Unsupported hash %q (use sha256, sha1, md5 or none)", algo), IsError: true}
+	}
+
+	var sb strings.Builder
+	failed := 0
+	for _, p := range paths {
+		line, err := statLine(e.resolvePath(p), algo)
+		if err != nil {
+			failed++
+			fmt.Fprintf(&sb, "%s: %v\n", p, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", p, line)
+	}
+	return ToolResult{ToolUseID: call.ID, Content: sb.String(), IsError: failed == len(paths)}
+}
+
+func statLine(p, algo string) (string, error) {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return "", err
+	}
+	kind := "file"
+	switch {
+	case info.IsDir():
+		kind = "directory"
+	case info.Mode()&os.ModeSymlink != 0:
+		target, _ := os.Readlink(p)
+		kind = "symlink -> " + target
+	case !info.Mode().IsRegular():
+		kind = "special"
+	}
+
+	parts := []string{
+		kind,
+		fmt.Sprintf("size=%d (%s)", info.Size(), formatSize(info.Size())),
+		"mode=" + info.Mode().String(),
+		"mtime=" + info.ModTime().Format(time.RFC3339),
+	}
+	if info.Mode().IsRegular() && algo != "none" {
+		if info.Size() > statMaxHashSize {
+			parts = append(parts, algo+"=skipped (file too large)")
+		} else {
+			sum, err := hashFile(p, algo)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, algo+"="+sum)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func newHash(algo string) hash.Hash {
+	switch algo {
+	case "sha256":
+		return sha256.New()
+	case "sha1":
+		return sha1.New()
+	case "md5":
+		return md5.New()
+	default:
+		return nil
+	}
+}
+
+func hashFile(p, algo string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := newHash(algo)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
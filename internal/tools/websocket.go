@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	wsDefaultTimeout  = 5 * time.Second
+	wsMaxTimeout      = 60 * time.Second
+	wsDefaultMessages = 20
+	wsMaxMessageLen   = 4096
+	wsMaxOutput       = 64 * 1024
+)
+
+func (e *Executor) executeWebSocket(call ToolCall) ToolResult {
+	rawURL, _ := call.Input["url"].(string)
+	if rawURL == "" {
+		return ToolResult{ToolUseID: call.ID, Content: "Missing required parameter: url", IsError: true}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") {
+		return ToolResult{ToolUseID: call.ID, Content: "url must be a ws:// or wss:// URL", IsError: true}
+	}
+
+	timeout := wsDefaultTimeout
+	if v, ok := call.Input["timeout"].(float64); ok && v > 0 {
+		timeout = time.Duration(v * float64(time.Second))
+		if timeout > wsMaxTimeout {
+			timeout = wsMaxTimeout
+		}
+	}
+	maxMessages := wsDefaultMessages
+	if v, ok := call.Input["max_messages"].(float64); ok && v > 0 {
+		maxMessages = int(v)
+	}
+
+	origin := "http://" + u.Host
+	if u.Scheme == "wss" {
+		origin = "https://" + u.Host
+	}
+	cfg, err := websocket.NewConfig(rawURL, origin)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Error: %v", err), IsError: true}
+	}
+	if headers, ok := call.Input["headers"].(map[string]interface{}); ok {
+		for k, v := range headers {
+			if s, ok := v.(string); ok {
+				cfg.Header.Set(k, s)
+			}
+		}
+	}
+	if proto, ok := call.Input["subprotocol"].(string); ok && proto != "" {
+		cfg.Protocol = []string{proto}
+	}
+	cfg.Dialer = &net.Dialer{Timeout: timeout}
+
+	started := time.Now()
+	ws, err := websocket.DialConfig(cfg)
+	if err != nil {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf("Connection failed: %v", err), IsError: true}
+	}
+	defer ws.Close()
+	ws.SetDeadline(started.Add(timeout))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Connected to %s in %s\n", rawURL, time.Since(started).Round(time.Millisecond))
+
+	if raw, ok := call.Input["messages"].([]interface{}); ok {
+		for _, m := range raw {
+			msg, ok := m.(string)
+			if !ok {
+				continue
+			}
+			if err := websocket.Message.Send(ws, msg); err != nil {
+				fmt.Fprintf(&sb, "Send failed: %v\n", err)
+				return ToolResult{ToolUseID: call.ID, Content: sb.String(), IsError: true}
+			}
+			fmt.Fprintf(&sb, "→ %s\n", clipMessage(msg))
+		}
+	}
+
+	received := 0
+receive:
+	for received < maxMessages && sb.Len() < wsMaxOutput {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				fmt.Fprintf(&sb, "[timeout after %s]\n", timeout)
+			case errors.Is(err, io.EOF):
+				sb.WriteString("[connection closed by server]\n")
+			default:
+				fmt.Fprintf(&sb, "[receive error: %v]\n", err)
+			}
+			break receive
+		}
+		received++
+		elapsed := time.Since(started).Round(time.Millisecond)
+		if utf8.Valid(data) {
+			fmt.Fprintf(&sb, "← [+%s] %s\n", elapsed, clipMessage(string(data)))
+		} else {
+			fmt.Fprintf(&sb, "← [+%s] binary frame, %d bytes\n", elapsed, len(data))
+		}
+	}
+	if received == maxMessages {
+		fmt.Fprintf(&sb, "[stopped after %d messages]\n", maxMessages)
+	}
+	fmt.Fprintf(&sb, "%d message(s) received\n", received)
+	return ToolResult{ToolUseID: call.ID, Content: sb.String()}
+}
+
+func clipMessage(s string) string {
+	if len(s) <= wsMaxMessageLen {
+		return s
+	}
+	return fmt.Sprintf("%s... [%d more bytes]", truncateUTF8(s, wsMaxMessageLen), len(s)-wsMaxMessageLen)
+}
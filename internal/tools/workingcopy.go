@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpay/apipod-cli/internal/linediff"
+)
+
+// workingCopy is a file as the model last saw it, through Read or its own
+// edits, and the lines Read showed of it.
+type workingCopy struct {
+	content  string
+	from, to int
+}
+
+// SetCompactRereads makes Read answer a repeated read of lines the model
+// already has with a short note when the file is unchanged, or with only
+// the changed lines, instead of the full contents again.
+func (e *Executor) SetCompactRereads(on bool) {
+	e.copiesMu.Lock()
+	defer e.copiesMu.Unlock()
+	e.compactRereads = on
+	e.copies = nil
+}
+
+// ForgetReads drops what the model has seen, so the next Read of every
+// file is shown in full; call it when the conversation is cleared or
+// replaced.
+func (e *Executor) ForgetReads() {
+	e.copiesMu.Lock()
+	defer e.copiesMu.Unlock()
+	e.copies = nil
+}
+
+// rereadContextLines are the unchanged lines shown around each change.
+const rereadContextLines = 3
+
+// compactReread answers a Read of lines from-to of path from the working
+// copy, or returns ok=false when the lines must be shown in full.
+func (e *Executor) compactReread(call ToolCall, path string, from, limit int) (ToolResult, bool) {
+	e.copiesMu.Lock()
+	defer e.copiesMu.Unlock()
+	if !e.compactRereads {
+		return ToolResult{}, false
+	}
+	if full, _ := call.Input["full"].(bool); full {
+		return ToolResult{}, false
+	}
+	wc := e.copies[filepath.Clean(path)]
+	if wc == nil {
+		return ToolResult{}, false
+	}
+	content, _, err := e.readWithFormat(path)
+	if err != nil {
+		return ToolResult{}, false
+	}
+	lines, _ := splitLines(content)
+	to := min(from+limit-1, len(lines))
+	// A copy that reached the end of the file covers lines added since.
+	covered := wc.to
+	if wc.to >= lineCount(wc.content) {
+		covered = len(lines)
+	}
+	if from < wc.from || to > covered || from > to {
+		return ToolResult{}, false
+	}
+	hash := rangeHash(lines[from-1 : to])
+	name := filepath.Base(path)
+
+	if content == wc.content {
+		return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf(
+			"[%s is unchanged since you last read it (lines %d-%d of %d); use the contents you already have. range_hash for lines %d-%d: %s. Pass full=true to see them again.]\n",
+			name, from, to, len(lines), from, to, hash)}, true
+	}
+
+	hunks := linediff.Hunks(linediff.Diff(linediff.Split(wc.content), linediff.Split(content)), rereadContextLines)
+	var sb strings.Builder
+	changed := 0
+	for _, h := range hunks {
+		newLine := h.NewStart + 1
+		fmt.Fprintf(&sb, "@@ lines %d-%d @@\n", newLine, h.NewStart+h.NewLines)
+		for _, l := range h.Lines {
+			text := strings.TrimRight(l.Text, "\r\n")
+			switch l.Kind {
+			case linediff.Delete:
+				fmt.Fprintf(&sb, "     -│%s\n", text)
+				changed++
+			case linediff.Insert:
+				fmt.Fprintf(&sb, "%5d+│%s\n", newLine, text)
+				newLine++
+				changed++
+			default:
+				fmt.Fprintf(&sb, "%5d │%s\n", newLine, text)
+				newLine++
+			}
+		}
+	}
+	// A rewrite is cheaper to show in full than as a diff.
+	if changed > (to-from+1)/2 {
+		return ToolResult{}, false
+	}
+	wc.content = content
+	wc.to = max(wc.to, to)
+	return ToolResult{ToolUseID: call.ID, Content: fmt.Sprintf(
+		"[%s changed since you last read it; only the changes are shown, with current line numbers (+ added, - removed). Pass full=true for all lines.]\n%s[range_hash for lines %d-%d: %s]\n",
+		name, sb.String(), from, to, hash)}, true
+}
+
+// rememberRead records that Read showed lines from-to of path.
+func (e *Executor) rememberRead(path string, from, to int) {
+	e.copiesMu.Lock()
+	defer e.copiesMu.Unlock()
+	if !e.compactRereads || to < from {
+		return
+	}
+	content, _, err := e.readWithFormat(path)
+	if err != nil {
+		return
+	}
+	key := filepath.Clean(path)
+	if e.copies == nil {
+		e.copies = make(map[string]*workingCopy)
+	}
+	// Adjacent or overlapping reads of the same content extend the range.
+	if wc := e.copies[key]; wc != nil && wc.content == content && from <= wc.to+1 && to >= wc.from-1 {
+		wc.from, wc.to = min(wc.from, from), max(wc.to, to)
+		return
+	}
+	e.copies[key] = &workingCopy{content: content, from: from, to: to}
+}
+
+// rememberEdit updates the working copy after the model changed path
+// itself, since it knows what it wrote. A Write shows the model the whole
+// file.
+func (e *Executor) rememberEdit(toolName, path string) {
+	e.copiesMu.Lock()
+	defer e.copiesMu.Unlock()
+	if !e.compactRereads {
+		return
+	}
+	key := filepath.Clean(path)
+	content, _, err := e.readWithFormat(path)
+	if err != nil {
+		delete(e.copies, key)
+		return
+	}
+	wc := e.copies[key]
+	if toolName == "Write" {
+		if e.copies == nil {
+			e.copies = make(map[string]*workingCopy)
+		}
+		e.copies[key] = &workingCopy{content: content, from: 1, to: max(lineCount(content), 1)}
+		return
+	}
+	if wc == nil {
+		return
+	}
+	wasWhole := wc.from == 1 && wc.to >= lineCount(wc.content)
+	wc.content = content
+	if wasWhole {
+		wc.to = max(lineCount(content), 1)
+	}
+}
+
+// lineCount is the number of lines of content.
+func lineCount(content string) int {
+	lines, _ := splitLines(content)
+	return len(lines)
+}
@@ -0,0 +1,259 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// MaxOutput caps how much of each tool output is exported.
+const MaxOutput = 2000
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Entry is one prompt, response text, tool call or tool result.
+type Entry struct {
+	Role      string          `json:"role"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Tool      string          `json:"tool,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+	Usage     *Usage          `json:"usage,omitempty"`
+}
+
+// ToolStat sums up the calls made to one tool.
+type ToolStat struct {
+	Name        string `json:"name"`
+	Calls       int    `json:"calls"`
+	Errors      int    `json:"errors"`
+	Denied      int    `json:"denied"`
+	DurationMS  int64  `json:"duration_ms"`
+	OutputBytes int    `json:"output_bytes"`
+}
+
+// Transcript is a whole session prepared for sharing.
+type Transcript struct {
+	Model    string     `json:"model"`
+	WorkDir  string     `json:"work_dir"`
+	Exported time.Time  `json:"exported"`
+	Usage    Usage      `json:"usage"`
+	Tools    []ToolStat `json:"tools,omitempty"`
+	Entries  []Entry    `json:"entries"`
+}
+
+// Extension returns the file extension for an export format: "markdown"
+// (or "md"), "json" or "html".
+func Extension(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", "markdown", "md":
+		return ".md", nil
+	case "json":
+		return ".json", nil
+	case "html":
+		return ".html", nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (use markdown, json or html)", format)
+	}
+}
+
+// Write renders t in the format picked by ext.
+func (t *Transcript) Write(w io.Writer, ext string) error {
+	switch ext {
+	case ".json":
+		return t.WriteJSON(w)
+	case ".html":
+		return t.WriteHTML(w)
+	default:
+		return t.WriteMarkdown(w)
+	}
+}
+
+// Truncate shortens tool output to MaxOutput bytes.
+func Truncate(s string) string {
+	if len(s) <= MaxOutput {
+		return s
+	}
+	cut := MaxOutput
+	for cut > 0 && s[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n… (%d more bytes)", len(s)-cut)
+}
+
+// Redact rewrites the working directory, every text and every tool input
+// with mask, e.g. to strip credentials before the transcript is shared. An
+// input that is no longer valid JSON afterwards is kept as a string.
+func (t *Transcript) Redact(mask func(string) string) {
+	t.WorkDir = mask(t.WorkDir)
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		e.Text = mask(e.Text)
+		if len(e.Input) == 0 {
+			continue
+		}
+		masked := mask(string(e.Input))
+		if json.Valid([]byte(masked)) {
+			e.Input = json.RawMessage(masked)
+		} else {
+			e.Input, _ = json.Marshal(masked)
+		}
+	}
+}
+
+func (t *Transcript) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+func (t *Transcript) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# apipod-cli transcript\n\n")
+	fmt.Fprintf(&b, "_%s · %s · %s_\n", t.Model, t.WorkDir, t.Exported.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "_tokens: %d in, %d out_\n", t.Usage.InputTokens, t.Usage.OutputTokens)
+	if len(t.Tools) > 0 {
+		b.WriteString("\n| Tool | Calls | Failed | Denied | Time | Output |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, st := range t.Tools {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %s | %d B |\n", st.Name, st.Calls, st.Errors, st.Denied, st.duration(), st.OutputBytes)
+		}
+	}
+
+	lastRole := ""
+	for _, e := range t.Entries {
+		if e.Type != "tool_result" && e.Role != lastRole {
+			if e.Role == "user" {
+				b.WriteString("\n## You\n\n")
+			} else {
+				b.WriteString("\n## Assistant\n\n")
+			}
+			lastRole = e.Role
+		}
+		switch e.Type {
+		case "text":
+			b.WriteString(e.Text + "\n\n")
+		case "thinking":
+			fmt.Fprintf(&b, "<details><summary>Reasoning</summary>\n\n%s\n\n</details>\n\n", e.Text)
+		case "tool_use":
+			fmt.Fprintf(&b, "**▸ %s**\n\n```json\n%s\n```\n\n", e.Tool, indentJSON(e.Input))
+		case "tool_result":
+			label := "Output"
+			if e.IsError {
+				label = "Error"
+			}
+			f := fence(e.Text)
+			fmt.Fprintf(&b, "<details><summary>%s · %s</summary>\n\n%s\n%s\n%s\n\n</details>\n\n",
+				label, e.Tool, f, e.Text, f)
+		}
+		if e.Usage != nil {
+			fmt.Fprintf(&b, "_↳ tokens: %d in, %d out_\n\n", e.Usage.InputTokens, e.Usage.OutputTokens)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+const htmlStyle = `body{font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;max-width:900px;margin:2em auto;padding:0 1em;color:#222}
+.meta{color:#777;font-size:.9em}
+.turn{margin:1.5em 0;padding:.5em 1em;border-left:4px solid #ccc}
+.user{border-color:#5f5fff}.assistant{border-color:#aaa}
+.role{font-weight:bold;margin-bottom:.5em}
+.text{white-space:pre-wrap}
+pre{background:#f5f5f5;padding:.75em;overflow-x:auto;font-size:.85em}
+.error pre{background:#fdecec}
+.tool{font-weight:bold;color:#555}
+.thinking{color:#666;font-style:italic}
+.usage{color:#888;font-size:.85em}
+.tools{border-collapse:collapse;font-size:.85em;color:#555}
+.tools td,.tools th{padding:.2em .8em;text-align:right}.tools td:first-child,.tools th:first-child{text-align:left}`
+
+func (t *Transcript) WriteHTML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>apipod-cli transcript</title>\n")
+	b.WriteString("<style>" + htmlStyle + "</style></head><body>\n")
+	b.WriteString("<h1>apipod-cli transcript</h1>\n")
+	fmt.Fprintf(&b, "<p class=\"meta\">%s · %s · %s<br>tokens: %d in, %d out</p>\n",
+		html.EscapeString(t.Model), html.EscapeString(t.WorkDir), t.Exported.Format("2006-01-02 15:04"),
+		t.Usage.InputTokens, t.Usage.OutputTokens)
+	if len(t.Tools) > 0 {
+		b.WriteString("<table class=\"tools\"><tr><th>Tool</th><th>Calls</th><th>Failed</th><th>Denied</th><th>Time</th><th>Output</th></tr>\n")
+		for _, st := range t.Tools {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td><td>%d B</td></tr>\n",
+				html.EscapeString(st.Name), st.Calls, st.Errors, st.Denied, st.duration(), st.OutputBytes)
+		}
+		b.WriteString("</table>\n")
+	}
+
+	lastRole := ""
+	for _, e := range t.Entries {
+		if e.Type != "tool_result" && e.Role != lastRole {
+			if lastRole != "" {
+				b.WriteString("</div>\n")
+			}
+			name := "You"
+			if e.Role == "assistant" {
+				name = "Assistant"
+			}
+			fmt.Fprintf(&b, "<div class=\"turn %s\"><div class=\"role\">%s</div>\n", e.Role, name)
+			lastRole = e.Role
+		}
+		switch e.Type {
+		case "text":
+			fmt.Fprintf(&b, "<div class=\"text\">%s</div>\n", html.EscapeString(e.Text))
+		case "thinking":
+			fmt.Fprintf(&b, "<details class=\"thinking\"><summary>Reasoning</summary><div class=\"text\">%s</div></details>\n",
+				html.EscapeString(e.Text))
+		case "tool_use":
+			fmt.Fprintf(&b, "<div class=\"tool\">▸ %s</div><pre>%s</pre>\n",
+				html.EscapeString(e.Tool), html.EscapeString(indentJSON(e.Input)))
+		case "tool_result":
+			class, label := "", "Output"
+			if e.IsError {
+				class, label = " class=\"error\"", "Error"
+			}
+			fmt.Fprintf(&b, "<details%s><summary>%s · %s</summary><pre>%s</pre></details>\n",
+				class, label, html.EscapeString(e.Tool), html.EscapeString(e.Text))
+		}
+		if e.Usage != nil {
+			fmt.Fprintf(&b, "<div class=\"usage\">↳ tokens: %d in, %d out</div>\n", e.Usage.InputTokens, e.Usage.OutputTokens)
+		}
+	}
+	if lastRole != "" {
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</body></html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (st ToolStat) duration() string {
+	return (time.Duration(st.DurationMS) * time.Millisecond).String()
+}
+
+// fence returns a code fence longer than any backtick run in s.
+func fence(s string) string {
+	f := "```"
+	for strings.Contains(s, f) {
+		f += "`"
+	}
+	return f
+}
+
+func indentJSON(raw json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return string(raw)
+	}
+	return string(out)
+}
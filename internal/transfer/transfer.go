@@ -0,0 +1,161 @@
+// Package transfer moves small files between the machine running
+// apipod-cli and the user's local terminal over the terminal connection
+// itself, using the iTerm2 file escape sequences (OSC 1337), so it works
+// through SSH without scp or a clipboard.
+package transfer
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// MaxSize bounds a transfer; the data travels base64-encoded through the
+// terminal, which is slow for anything large.
+const MaxSize = 10 << 20
+
+// Supported reports whether the local terminal understands OSC 1337 file
+// transfers. LC_TERMINAL is forwarded by OpenSSH by default, so this also
+// holds in SSH sessions started from iTerm2 or WezTerm.
+func Supported() bool {
+	for _, v := range []string{os.Getenv("LC_TERMINAL"), os.Getenv("TERM_PROGRAM")} {
+		switch v {
+		case "iTerm2", "iTerm.app", "WezTerm":
+			return true
+		}
+	}
+	return false
+}
+
+// wrap passes an escape sequence through tmux to the outer terminal.
+func wrap(seq string) string {
+	if os.Getenv("TMUX") == "" {
+		return seq
+	}
+	return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+}
+
+// Send has the local terminal save data as a download named name.
+func Send(w io.Writer, name string, data []byte) error {
+	if len(data) > MaxSize {
+		return fmt.Errorf("%s is %d bytes; transfers are limited to %d", name, len(data), MaxSize)
+	}
+	seq := fmt.Sprintf("\033]1337;File=name=%s;size=%d;inline=0:%s\a",
+		base64.StdEncoding.EncodeToString([]byte(name)), len(data), base64.StdEncoding.EncodeToString(data))
+	_, err := io.WriteString(w, wrap(seq))
+	return err
+}
+
+// File is one file received from the local machine.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Receive asks the local terminal to let the user pick files and reads
+// them from stdin. It returns no files if the user cancels.
+func Receive() ([]File, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("uploads need an interactive terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Fprint(os.Stdout, wrap("\033]1337;RequestUpload=format=tgz\a"))
+	r := bufio.NewReader(os.Stdin)
+	status, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if status != "ok" {
+		return nil, nil
+	}
+	var encoded strings.Builder
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		encoded.WriteString(line)
+		if encoded.Len() > MaxSize*4/3+4 {
+			return nil, fmt.Errorf("upload is larger than %d bytes", MaxSize)
+		}
+	}
+	archive, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("decode upload: %w", err)
+	}
+	return untar(archive)
+}
+
+// readLine reads up to a CR or LF; in raw mode the terminal may send
+// either.
+func readLine(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == '\r' || c == '\n' {
+			if c == '\r' {
+				if next, err := r.Peek(1); err == nil && next[0] == '\n' {
+					r.ReadByte()
+				}
+			}
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+	}
+}
+
+func untar(archive []byte) ([]File, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("read upload: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	var files []File
+	var total int
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read upload: %w", err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.Clean(filepath.FromSlash(h.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("upload contains unsafe path %q", h.Name)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, MaxSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("read upload: %w", err)
+		}
+		if total += len(data); total > MaxSize {
+			return nil, fmt.Errorf("upload is larger than %d bytes", MaxSize)
+		}
+		files = append(files, File{Name: name, Data: data})
+	}
+	return files, nil
+}
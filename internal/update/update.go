@@ -0,0 +1,294 @@
+// Package update checks for new releases and replaces the running binary
+// with a verified download.
+package update
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the GitHub API endpoint of the latest release. A custom
+// endpoint must return the same JSON shape.
+const DefaultURL = "https://api.github.com/repos/rpay/apipod-cli/releases/latest"
+
+// ChecksumsFile is the release asset listing the SHA-256 of every binary,
+// as written by `shasum -a 256` in scripts/build.sh.
+const ChecksumsFile = "checksums.txt"
+
+// checkInterval is how often the background check runs.
+const checkInterval = 24 * time.Hour
+
+// Release is a published version and its downloads.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is one file of a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version is the release's version without a leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+func (r *Release) asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Updater finds and installs releases.
+type Updater struct {
+	// Current is the running version, e.g. "0.1.0".
+	Current string
+	// URL is the release endpoint; "" uses DefaultURL.
+	URL string
+	// StatePath is where the background check remembers its last result.
+	StatePath string
+	HTTP      *http.Client
+}
+
+func (u *Updater) client() *http.Client {
+	if u.HTTP != nil {
+		return u.HTTP
+	}
+	return &http.Client{Timeout: 5 * time.Minute}
+}
+
+// Latest fetches the latest release.
+func (u *Updater) Latest() (*Release, error) {
+	url := u.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("release endpoint error (status %d): %s", resp.StatusCode, string(body))
+	}
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	if rel.Tag == "" {
+		return nil, fmt.Errorf("release endpoint returned no version")
+	}
+	return &rel, nil
+}
+
+// Newer reports whether version a is newer than b. Versions are dotted
+// numbers with an optional "v" prefix; a pre-release suffix ("-rc1") sorts
+// before the release.
+func Newer(a, b string) bool {
+	pa, sa := splitVersion(a)
+	pb, sb := splitVersion(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	if sa == "" || sb == "" {
+		return sa == "" && sb != ""
+	}
+	return sa > sb
+}
+
+func splitVersion(v string) ([]int, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, pre, _ := strings.Cut(v, "-")
+	var parts []int
+	for _, f := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(f)
+		parts = append(parts, n)
+	}
+	return parts, pre
+}
+
+// AssetName is the binary built for this platform by scripts/build.sh.
+func AssetName() string {
+	name := fmt.Sprintf("apipod-cli-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Apply downloads the release's binary for this platform, checks it
+// against the release checksums and replaces exe with it. The new binary
+// is written next to exe and renamed over it, so an interrupted update
+// leaves the old binary in place.
+func (u *Updater) Apply(rel *Release, exe string) error {
+	name := AssetName()
+	bin := rel.asset(name)
+	if bin == nil {
+		return fmt.Errorf("release %s has no build for %s/%s", rel.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	sums := rel.asset(ChecksumsFile)
+	if sums == nil {
+		return fmt.Errorf("release %s has no %s; refusing to install an unverified binary", rel.Tag, ChecksumsFile)
+	}
+	want, err := u.checksum(sums.URL, name)
+	if err != nil {
+		return err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".apipod-cli-update-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	resp, err := u.client().Get(bin.URL)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		tmp.Close()
+		return fmt.Errorf("download %s: status %d", name, resp.StatusCode)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("download %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", tmp.Name(), err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("chmod: %w", err)
+	}
+	return replace(tmp.Name(), exe)
+}
+
+// checksum returns the expected SHA-256 of name from a checksums file.
+func (u *Updater) checksum(url, name string) (string, error) {
+	resp, err := u.client().Get(url)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", ChecksumsFile, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download %s: status %d", ChecksumsFile, resp.StatusCode)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("read %s: %w", ChecksumsFile, err)
+	}
+	return "", fmt.Errorf("%s has no entry for %s", ChecksumsFile, name)
+}
+
+// replace renames src over dst. Windows does not allow replacing a running
+// executable, so there the old binary is moved aside first.
+func replace(src, dst string) error {
+	if runtime.GOOS == "windows" {
+		old := dst + ".old"
+		os.Remove(old)
+		if err := os.Rename(dst, old); err != nil {
+			return fmt.Errorf("move old binary: %w", err)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			os.Rename(old, dst)
+			return fmt.Errorf("install new binary: %w", err)
+		}
+		return nil
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("install new binary: %w", err)
+	}
+	return nil
+}
+
+// state is what the background check remembers between runs.
+type state struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+func (u *Updater) readState() state {
+	var st state
+	if data, err := os.ReadFile(u.StatePath); err == nil {
+		json.Unmarshal(data, &st)
+	}
+	return st
+}
+
+// Notice returns the newer version found by an earlier check, or "". It
+// never waits on the network: when the last check is more than a day old a
+// new one runs in the background and its result shows on the next start.
+func (u *Updater) Notice() string {
+	st := u.readState()
+	if time.Since(st.CheckedAt) > checkInterval {
+		go u.refresh()
+	}
+	if st.Latest != "" && Newer(st.Latest, u.Current) {
+		return st.Latest
+	}
+	return ""
+}
+
+func (u *Updater) refresh() {
+	st := state{CheckedAt: time.Now()}
+	// A failed check is recorded too, so an offline machine does not
+	// retry on every start.
+	if rel, err := u.Latest(); err == nil {
+		st.Latest = rel.Version()
+	} else {
+		st.Latest = u.readState().Latest
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(u.StatePath), 0o700); err != nil {
+		return
+	}
+	os.WriteFile(u.StatePath, data, 0o600)
+}
@@ -0,0 +1,91 @@
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyVerifiesChecksum(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	good := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		checksums string
+		noSums    bool
+		wantErr   string
+	}{
+		{name: "match", checksums: good + "  " + AssetName() + "\n"},
+		{name: "binary mode entry", checksums: strings.ToUpper(good) + " *" + AssetName() + "\n"},
+		{name: "mismatch", checksums: strings.Repeat("0", 64) + "  " + AssetName() + "\n", wantErr: "checksum mismatch"},
+		{name: "no entry", checksums: good + "  apipod-cli-plan9-mips\n", wantErr: "no entry"},
+		{name: "no checksums file", noSums: true, wantErr: "unverified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/bin":
+					w.Write(binary)
+				case "/sums":
+					w.Write([]byte(tt.checksums))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			rel := &Release{Tag: "v9.9.9", Assets: []Asset{{Name: AssetName(), URL: srv.URL + "/bin"}}}
+			if !tt.noSums {
+				rel.Assets = append(rel.Assets, Asset{Name: ChecksumsFile, URL: srv.URL + "/sums"})
+			}
+			exe := filepath.Join(t.TempDir(), "apipod-cli")
+			if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := (&Updater{HTTP: srv.Client()}).Apply(rel, exe)
+			data, _ := os.ReadFile(exe)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Apply: %v", err)
+				}
+				if string(data) != string(binary) {
+					t.Errorf("exe = %q, want the new binary", data)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Apply error = %v, want %q", err, tt.wantErr)
+			}
+			if string(data) != "old binary" {
+				t.Errorf("exe replaced despite the error: %q", data)
+			}
+		})
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.0", "1.1.9", true},
+		{"v1.10.0", "1.9.0", true},
+		{"1.2.0", "1.2.0", false},
+		{"1.2.0-rc1", "1.2.0", false},
+		{"1.2.0", "1.2.0-rc1", true},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package workflow reads apipod.workflow.yaml files: a sequence of steps,
+// each a prompt for the agent with the files it should produce and a
+// command that must pass before the next step starts.
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultFile is the workflow `apipod-cli workflow` runs when no file is
+// given.
+const DefaultFile = "apipod.workflow.yaml"
+
+// DefaultMaxAttempts is how often a step is tried when its check fails.
+const DefaultMaxAttempts = 3
+
+// Workflow is a named sequence of steps.
+type Workflow struct {
+	Name  string
+	Model string
+	Steps []Step
+}
+
+// Step is one prompt of a workflow. It passes when every artifact exists
+// and the success command exits 0; until then the agent is given the
+// failure and tries again, up to MaxAttempts times.
+type Step struct {
+	Name        string
+	Prompt      string
+	Artifacts   []string
+	Success     string
+	MaxAttempts int
+}
+
+// Load reads and checks a workflow file.
+func Load(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow: %w", err)
+	}
+	wf, err := Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return wf, nil
+}
+
+// Parse reads a workflow:
+//
+//	name: Add CSV export
+//	max_attempts: 2
+//	steps:
+//	  - name: Writer
+//	    prompt: Add a CSV writer in internal/export.
+//	    artifacts: [internal/export/csv.go]
+//	    success: go test ./internal/export/...
+func Parse(text string) (*Workflow, error) {
+	node, err := parseYAML(text)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("a workflow must be a mapping with steps")
+	}
+	if err := known(doc, "workflow", "name", "model", "max_attempts", "steps"); err != nil {
+		return nil, err
+	}
+	wf := &Workflow{Name: str(doc["name"]), Model: str(doc["model"])}
+	attempts, err := positive(doc["max_attempts"], DefaultMaxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("max_attempts %w", err)
+	}
+	steps, ok := doc["steps"].([]interface{})
+	if !ok || len(steps) == 0 {
+		return nil, fmt.Errorf("the workflow has no steps")
+	}
+	for i, raw := range steps {
+		n := i + 1
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("step %d: expected name, prompt, artifacts and success", n)
+		}
+		if err := known(m, fmt.Sprintf("step %d", n), "name", "prompt", "artifacts", "success", "max_attempts"); err != nil {
+			return nil, err
+		}
+		step := Step{
+			Name:    str(m["name"]),
+			Prompt:  strings.TrimSpace(str(m["prompt"])),
+			Success: strings.TrimSpace(str(m["success"])),
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("Step %d", n)
+		}
+		if step.Prompt == "" {
+			return nil, fmt.Errorf("step %d: the step has no prompt", n)
+		}
+		switch a := m["artifacts"].(type) {
+		case nil:
+		case string:
+			step.Artifacts = []string{a}
+		case []interface{}:
+			for _, item := range a {
+				if s := str(item); s != "" {
+					step.Artifacts = append(step.Artifacts, s)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("step %d: artifacts must be a list of paths", n)
+		}
+		if step.MaxAttempts, err = positive(m["max_attempts"], attempts); err != nil {
+			return nil, fmt.Errorf("step %d: max_attempts %w", n, err)
+		}
+		wf.Steps = append(wf.Steps, step)
+	}
+	return wf, nil
+}
+
+// StepIndex returns the index of the step given by its number (from 1)
+// or name, for --from.
+func (wf *Workflow) StepIndex(step string) (int, error) {
+	if n, err := strconv.Atoi(step); err == nil {
+		if n < 1 || n > len(wf.Steps) {
+			return 0, fmt.Errorf("the workflow has steps 1 to %d", len(wf.Steps))
+		}
+		return n - 1, nil
+	}
+	for i, s := range wf.Steps {
+		if strings.EqualFold(s.Name, step) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no step named %q", step)
+}
+
+// known rejects keys other than names, so a typo is not silently ignored.
+func known(m map[string]interface{}, what string, names ...string) error {
+	var unknown []string
+	for k := range m {
+		found := false
+		for _, name := range names {
+			found = found || k == name
+		}
+		if !found {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%s: unknown %s (use %s)", what, strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func positive(v interface{}, def int) (int, error) {
+	if v == nil {
+		return def, nil
+	}
+	n, err := strconv.Atoi(str(v))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("must be a whole number of at least 1")
+	}
+	return n, nil
+}
@@ -0,0 +1,227 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseYAML reads the block-style YAML subset workflow files use:
+// mappings, "- " sequences (of scalars or mappings), "[a, b]" lists,
+// quoted or plain scalars, "|" and ">" block text and # comments. Values
+// are map[string]interface{}, []interface{} or string.
+func parseYAML(text string) (interface{}, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")}
+	node, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.i < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return node, nil
+}
+
+type yamlParser struct {
+	lines []string
+	i     int
+}
+
+// mapKey matches the start of a "key: value" line.
+var mapKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*:(\s|$)`)
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.i+1, fmt.Sprintf(format, args...))
+}
+
+// skip moves past blank and comment lines.
+func (p *yamlParser) skip() {
+	for p.i < len(p.lines) {
+		t := strings.TrimSpace(p.lines[p.i])
+		if t != "" && !strings.HasPrefix(t, "#") {
+			return
+		}
+		p.i++
+	}
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// node parses the mapping or sequence starting at the next line, which
+// must be indented at least min.
+func (p *yamlParser) node(min int) (interface{}, error) {
+	p.skip()
+	if p.i >= len(p.lines) {
+		return nil, nil
+	}
+	line := p.lines[p.i]
+	if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+		return nil, p.errorf("tabs are not allowed for indentation")
+	}
+	ind := indentOf(line)
+	if ind < min {
+		return nil, nil
+	}
+	if t := strings.TrimSpace(line); t == "-" || strings.HasPrefix(t, "- ") {
+		return p.sequence(ind)
+	}
+	return p.mapping(ind)
+}
+
+func (p *yamlParser) mapping(ind int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for {
+		p.skip()
+		if p.i >= len(p.lines) || indentOf(p.lines[p.i]) < ind {
+			return m, nil
+		}
+		line := p.lines[p.i]
+		if indentOf(line) > ind {
+			return nil, p.errorf("unexpected indentation")
+		}
+		text := strings.TrimSpace(line)
+		if strings.HasPrefix(text, "- ") || text == "-" {
+			return m, nil
+		}
+		if !mapKey.MatchString(text) {
+			return nil, p.errorf("expected \"key: value\"")
+		}
+		key, rest, _ := strings.Cut(text, ":")
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("%s is set twice", key)
+		}
+		p.i++
+		value, err := p.value(rest, ind)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+}
+
+func (p *yamlParser) sequence(ind int) (interface{}, error) {
+	var list []interface{}
+	for {
+		p.skip()
+		if p.i >= len(p.lines) || indentOf(p.lines[p.i]) != ind {
+			return list, nil
+		}
+		line := p.lines[p.i]
+		text := strings.TrimSpace(line)
+		if text != "-" && !strings.HasPrefix(text, "- ") {
+			return list, nil
+		}
+		item := strings.TrimSpace(text[1:])
+		var value interface{}
+		var err error
+		switch {
+		case item == "":
+			p.i++
+			value, err = p.node(ind + 1)
+		case mapKey.MatchString(item):
+			// "- key: value" starts a mapping indented to where the key
+			// is; the line is reread as its first entry.
+			col := ind + strings.Index(line[ind:], item)
+			p.lines[p.i] = strings.Repeat(" ", col) + item
+			value, err = p.mapping(col)
+		default:
+			p.i++
+			value, err = p.value(item, ind)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+}
+
+// value parses what follows "key:" or "- " on a line indented ind.
+func (p *yamlParser) value(rest string, ind int) (interface{}, error) {
+	rest = stripComment(strings.TrimSpace(rest))
+	switch {
+	case rest == "|" || rest == "|-" || rest == ">" || rest == ">-":
+		var block []string
+		for p.i < len(p.lines) && (strings.TrimSpace(p.lines[p.i]) == "" || indentOf(p.lines[p.i]) > ind) {
+			block = append(block, p.lines[p.i])
+			p.i++
+		}
+		text := dedent(block)
+		if rest[0] == '>' {
+			text = fold(text)
+		}
+		return text, nil
+	case rest == "":
+		p.skip()
+		if p.i < len(p.lines) {
+			next := p.lines[p.i]
+			// A sequence may sit at the same indentation as its key.
+			if t := strings.TrimSpace(next); indentOf(next) == ind && (t == "-" || strings.HasPrefix(t, "- ")) {
+				return p.sequence(ind)
+			}
+		}
+		return p.node(ind + 1)
+	case strings.HasPrefix(rest, "["):
+		if !strings.HasSuffix(rest, "]") {
+			return nil, p.errorf("unterminated list")
+		}
+		var list []interface{}
+		for _, item := range strings.Split(rest[1:len(rest)-1], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, unquote(item))
+			}
+		}
+		return list, nil
+	}
+	return unquote(rest), nil
+}
+
+// stripComment drops a " #" comment from a plain value.
+func stripComment(s string) string {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		return s
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// dedent removes the indentation of the first non-empty line.
+func dedent(lines []string) string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			indent = indentOf(l)
+			break
+		}
+	}
+	if indent < 0 {
+		return ""
+	}
+	for i, l := range lines {
+		if len(l) >= indent {
+			lines[i] = l[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(l, " ")
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// fold joins the lines of each paragraph of a ">" block with spaces.
+func fold(text string) string {
+	paras := strings.Split(strings.TrimSpace(text), "\n\n")
+	for i, para := range paras {
+		paras[i] = strings.Join(strings.Fields(para), " ")
+	}
+	return strings.Join(paras, "\n") + "\n"
+}
@@ -0,0 +1,264 @@
+package workspace
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	KindGoWork = "go.work"
+	KindPnpm   = "pnpm"
+	KindNpm    = "npm-workspaces"
+	KindCargo  = "cargo"
+	KindBazel  = "bazel"
+
+	maxBazelDepth = 4
+)
+
+// Workspace describes a monorepo: its root, how it was detected and the
+// package directories it contains, relative to Root.
+type Workspace struct {
+	Root     string
+	Kind     string
+	Packages []string
+}
+
+// Detect walks up from dir looking for a monorepo marker and returns nil
+// when dir is not inside one.
+func Detect(dir string) *Workspace {
+	dir, _ = filepath.Abs(dir)
+	for {
+		if ws := detectAt(dir); ws != nil {
+			return ws
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func detectAt(dir string) *Workspace {
+	if data, err := os.ReadFile(filepath.Join(dir, "go.work")); err == nil {
+		return &Workspace{Root: dir, Kind: KindGoWork, Packages: goWorkUses(string(data))}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "pnpm-workspace.yaml")); err == nil {
+		return &Workspace{Root: dir, Kind: KindPnpm, Packages: expand(dir, pnpmPatterns(string(data)))}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		if patterns := npmWorkspaces(data); len(patterns) > 0 {
+			return &Workspace{Root: dir, Kind: KindNpm, Packages: expand(dir, patterns)}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Cargo.toml")); err == nil {
+		if members := cargoMembers(string(data)); members != nil {
+			return &Workspace{Root: dir, Kind: KindCargo, Packages: expand(dir, members)}
+		}
+	}
+	for _, marker := range []string{"MODULE.bazel", "WORKSPACE", "WORKSPACE.bazel"} {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return &Workspace{Root: dir, Kind: KindBazel, Packages: bazelPackages(dir)}
+		}
+	}
+	return nil
+}
+
+var goWorkUse = regexp.MustCompile(`^\s*use\s+(\S+)`)
+
+func goWorkUses(data string) []string {
+	var pkgs []string
+	inBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case line == "use (":
+			inBlock = true
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			pkgs = append(pkgs, cleanRel(strings.Trim(line, `"`)))
+		default:
+			if m := goWorkUse.FindStringSubmatch(line); m != nil && m[1] != "(" {
+				pkgs = append(pkgs, cleanRel(strings.Trim(m[1], `"`)))
+			}
+		}
+	}
+	return pkgs
+}
+
+func pnpmPatterns(data string) []string {
+	var patterns []string
+	inPackages := false
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(line, "packages:"):
+			inPackages = true
+		case inPackages && strings.HasPrefix(line, "- "):
+			p := strings.Trim(strings.TrimSpace(line[2:]), `"'`)
+			if !strings.HasPrefix(p, "!") {
+				patterns = append(patterns, p)
+			}
+		case inPackages && line != "" && !strings.HasPrefix(raw, " ") && !strings.HasPrefix(line, "#"):
+			inPackages = false
+		}
+	}
+	return patterns
+}
+
+func npmWorkspaces(data []byte) []string {
+	var pkg struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || len(pkg.Workspaces) == 0 {
+		return nil
+	}
+	var list []string
+	if json.Unmarshal(pkg.Workspaces, &list) == nil {
+		return list
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	json.Unmarshal(pkg.Workspaces, &obj)
+	return obj.Packages
+}
+
+var cargoMembersRe = regexp.MustCompile(`(?s)\[workspace\].*?members\s*=\s*\[(.*?)\]`)
+
+func cargoMembers(data string) []string {
+	if !strings.Contains(data, "[workspace]") {
+		return nil
+	}
+	m := cargoMembersRe.FindStringSubmatch(data)
+	if m == nil {
+		return []string{}
+	}
+	var members []string
+	for _, part := range strings.Split(m[1], ",") {
+		if p := strings.Trim(strings.TrimSpace(part), `"'`); p != "" {
+			members = append(members, p)
+		}
+	}
+	return members
+}
+
+// expand resolves workspace glob patterns to existing directories.
+func expand(root string, patterns []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, p := range patterns {
+		p = strings.TrimSuffix(strings.TrimSuffix(p, "/**"), "/")
+		matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(p)))
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.IsDir() {
+				rel, _ := filepath.Rel(root, m)
+				rel = filepath.ToSlash(rel)
+				if !seen[rel] {
+					seen[rel] = true
+					out = append(out, rel)
+				}
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func bazelPackages(root string) []string {
+	var out []string
+	filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		if rel != "." && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "bazel-") || d.Name() == "node_modules") {
+			return filepath.SkipDir
+		}
+		if strings.Count(rel, string(filepath.Separator)) >= maxBazelDepth {
+			return filepath.SkipDir
+		}
+		for _, build := range []string{"BUILD", "BUILD.bazel"} {
+			if _, err := os.Stat(filepath.Join(p, build)); err == nil && rel != "." {
+				out = append(out, filepath.ToSlash(rel))
+				break
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+func cleanRel(p string) string {
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+// PackageFor returns the package containing dir ("" when dir is outside
+// every package, e.g. at the workspace root).
+func (w *Workspace) PackageFor(dir string) string {
+	abs, _ := filepath.Abs(dir)
+	rel, err := filepath.Rel(w.Root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	best := ""
+	for _, p := range w.Packages {
+		if p == "." {
+			continue
+		}
+		if (rel == p || strings.HasPrefix(rel, p+"/")) && len(p) > len(best) {
+			best = p
+		}
+	}
+	return best
+}
+
+// Resolve maps a /scope argument (package name, package path or directory)
+// to an absolute directory.
+func (w *Workspace) Resolve(arg string) (string, bool) {
+	arg = strings.TrimSuffix(filepath.ToSlash(arg), "/")
+	for _, p := range w.Packages {
+		if p == arg || filepath.Base(p) == arg {
+			return filepath.Join(w.Root, filepath.FromSlash(p)), true
+		}
+	}
+	dir := arg
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(w.Root, filepath.FromSlash(arg))
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir, true
+	}
+	return "", false
+}
+
+// TestCommand suggests how to run the tests of pkg with the workspace's
+// tooling.
+func (w *Workspace) TestCommand(pkg string) string {
+	switch w.Kind {
+	case KindGoWork:
+		return "go test ./..."
+	case KindPnpm:
+		return "pnpm --filter ./" + pkg + " test"
+	case KindNpm:
+		return "npm test --workspace=" + pkg
+	case KindCargo:
+		return "cargo test -p " + filepath.Base(pkg)
+	case KindBazel:
+		return "bazel test //" + pkg + "/..."
+	}
+	return ""
+}
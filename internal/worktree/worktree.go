@@ -0,0 +1,144 @@
+// Package worktree runs a session in a temporary git worktree, so the
+// model's edits stay on their own branch until the user merges them into
+// their working tree or throws them away.
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Worktree is a checkout of the repository's HEAD on a new branch.
+type Worktree struct {
+	// Repo is the root of the user's working tree, Path the worktree's
+	// root and Dir the directory the session runs in: the same place
+	// relative to Path as it was relative to Repo.
+	Repo string
+	Path string
+	Dir  string
+
+	Branch string
+	Base   string
+}
+
+// Create adds a worktree for the repository containing dir, on a branch
+// named after the current time.
+func Create(dir string) (*Worktree, error) {
+	repo, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	base, err := git(repo, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("the repository has no commits yet")
+	}
+	common, err := git(repo, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	name := time.Now().Format("20060102-150405")
+	for i := 2; ; i++ {
+		if _, err := git(repo, "rev-parse", "--verify", "-q", "refs/heads/apipod/"+name); err != nil {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), i)
+	}
+	w := &Worktree{
+		Repo:   repo,
+		Path:   filepath.Join(common, "apipod-worktrees", name),
+		Branch: "apipod/" + name,
+		Base:   base,
+	}
+	if _, err := git(repo, "worktree", "add", "-q", "-b", w.Branch, w.Path, base); err != nil {
+		return nil, err
+	}
+	w.Dir = w.Path
+	if rel, err := filepath.Rel(repo, dir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		w.Dir = filepath.Join(w.Path, rel)
+		// Git does not track empty directories.
+		if err := os.MkdirAll(w.Dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Dirty reports whether the user's working tree has uncommitted changes,
+// which the worktree does not have.
+func Dirty(dir string) bool {
+	out, err := git(dir, "status", "--porcelain")
+	return err == nil && out != ""
+}
+
+// Commit records everything changed in the worktree on its branch and
+// returns a diffstat of the branch against where it started, or "" when
+// nothing changed.
+func (w *Worktree) Commit() (string, error) {
+	if _, err := git(w.Path, "add", "-A"); err != nil {
+		return "", err
+	}
+	if out, _ := git(w.Path, "status", "--porcelain"); out != "" {
+		args := []string{"commit", "-q", "--no-verify", "-m", "apipod session changes"}
+		// Without a configured identity git refuses to commit.
+		if _, err := git(w.Path, "config", "user.email"); err != nil {
+			args = append([]string{"-c", "user.name=apipod-cli", "-c", "user.email=apipod-cli@localhost"}, args...)
+		}
+		if _, err := git(w.Path, args...); err != nil {
+			return "", fmt.Errorf("commit changes: %w", err)
+		}
+	}
+	return git(w.Path, "diff", "--stat", w.Base, "HEAD")
+}
+
+// Merge applies the branch's changes to the user's working tree without
+// committing them, so they can be reviewed like their own edits.
+func (w *Worktree) Merge() error {
+	if _, err := git(w.Repo, "merge", "--squash", w.Branch); err != nil {
+		return fmt.Errorf("merge %s: %w", w.Branch, err)
+	}
+	return w.Discard()
+}
+
+// Keep removes the worktree but leaves its branch for later.
+func (w *Worktree) Keep() error {
+	if _, err := git(w.Repo, "worktree", "remove", "--force", w.Path); err != nil {
+		return fmt.Errorf("remove worktree: %w", err)
+	}
+	return nil
+}
+
+// Discard removes the worktree and its branch.
+func (w *Worktree) Discard() error {
+	if err := w.Keep(); err != nil {
+		return err
+	}
+	if _, err := git(w.Repo, "branch", "-D", w.Branch); err != nil {
+		return fmt.Errorf("delete branch: %w", err)
+	}
+	return nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		sub := args[0]
+		for i := 0; i+2 < len(args) && args[i] == "-c"; i += 2 {
+			sub = args[i+2]
+		}
+		// The last line of git's output says what went wrong.
+		if lines := strings.Split(strings.TrimSpace(stderr.String()), "\n"); lines[len(lines)-1] != "" {
+			msg := lines[len(lines)-1]
+			return "", fmt.Errorf("git %s: %s", sub, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", sub, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}